@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -49,7 +52,7 @@ func main() {
 	r := handler.NewRouter(pool, rdb, cfg)
 
 	// Scheduler
-	sched := scheduler.New(pool)
+	sched := scheduler.New(pool, cfg.StatsRetention, cfg.AccessLogRetention, cfg.FeedDir)
 	sched.Start()
 	defer sched.Stop()
 
@@ -62,13 +65,31 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	useTLS := cfg.TLS.CertFile != "" && cfg.TLS.KeyFile != ""
+	if cfg.TLS.SyncMTLSRequired && (!useTLS || cfg.TLS.ClientCAFile == "") {
+		log.Fatal("SYNC_MTLS_REQUIRED needs TLS_CERT_FILE, TLS_KEY_FILE and TLS_CLIENT_CA_FILE")
+	}
+	if useTLS && cfg.TLS.ClientCAFile != "" {
+		tlsCfg, err := clientCertTLSConfig(cfg.TLS.ClientCAFile)
+		if err != nil {
+			log.Fatalf("Failed to load client CA: %v", err)
+		}
+		srv.TLSConfig = tlsCfg
+	}
+
 	// Graceful shutdown
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		log.Printf("Server starting on port %s", cfg.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Server starting on port %s (tls: %t)", cfg.Port, useTLS)
+		var err error
+		if useTLS {
+			err = srv.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
@@ -85,3 +106,22 @@ func main() {
 
 	log.Println("Server stopped")
 }
+
+// clientCertTLSConfig verifies client certificates against the CA when one is
+// presented. Certificates are optional at the TLS layer so browsers can still
+// use the API; /sync enforces them when SYNC_MTLS_REQUIRED is set.
+func clientCertTLSConfig(caFile string) (*tls.Config, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return &tls.Config{
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  pool,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -9,6 +11,53 @@ type Config struct {
 	RedisURL    string
 	JWTSecret   string
 	Port        string
+
+	// SyncAuthRequired rejects unsigned helper requests to /sync.
+	SyncAuthRequired bool
+
+	TLS TLS
+
+	StatsRetention StatsRetention
+	SMTP           SMTP
+
+	// DiagnosticsTTL is how long uploaded diagnostics bundles are kept.
+	DiagnosticsTTL time.Duration
+	// AccessLogRetention is how long shipped access logs are kept.
+	AccessLogRetention time.Duration
+	// FeedDir holds the files that feed sources may read. File feeds are
+	// disabled when empty.
+	FeedDir string
+
+	// AppURL is the frontend base URL used in links sent by email.
+	AppURL string
+}
+
+// TLS configures HTTPS on the backend listener. ClientCAFile enables
+// verification of helper client certificates; SyncMTLSRequired makes a
+// verified certificate mandatory on /sync.
+type TLS struct {
+	CertFile         string
+	KeyFile          string
+	ClientCAFile     string
+	SyncMTLSRequired bool
+}
+
+// SMTP configures the email notification channel. Email is disabled when Host is empty.
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	TLSMode  string // none, starttls or tls
+}
+
+// StatsRetention defines how long each stats tier is kept before cleanup.
+type StatsRetention struct {
+	Raw     time.Duration
+	FiveMin time.Duration
+	Hourly  time.Duration
+	Daily   time.Duration
 }
 
 func Load() *Config {
@@ -17,6 +66,33 @@ func Load() *Config {
 		RedisURL:    getEnv("REDIS_URL", "redis://localhost:6379/0"),
 		JWTSecret:   getEnv("JWT_SECRET", "dev-secret-change-in-production"),
 		Port:        getEnv("PORT", "8080"),
+
+		SyncAuthRequired: getEnvBool("SYNC_AUTH_REQUIRED", true),
+		TLS: TLS{
+			CertFile:         getEnv("TLS_CERT_FILE", ""),
+			KeyFile:          getEnv("TLS_KEY_FILE", ""),
+			ClientCAFile:     getEnv("TLS_CLIENT_CA_FILE", ""),
+			SyncMTLSRequired: getEnvBool("SYNC_MTLS_REQUIRED", false),
+		},
+
+		StatsRetention: StatsRetention{
+			Raw:     getEnvDays("STATS_RETENTION_RAW_DAYS", 7),
+			FiveMin: getEnvDays("STATS_RETENTION_5M_DAYS", 30),
+			Hourly:  getEnvDays("STATS_RETENTION_1H_DAYS", 180),
+			Daily:   getEnvDays("STATS_RETENTION_1D_DAYS", 730),
+		},
+		SMTP: SMTP{
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     getEnvInt("SMTP_PORT", 587),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", "proxy-manager@localhost"),
+			TLSMode:  getEnv("SMTP_TLS", "starttls"),
+		},
+		DiagnosticsTTL:     getEnvDays("DIAGNOSTICS_TTL_DAYS", 7),
+		AccessLogRetention: getEnvDays("ACCESS_LOG_RETENTION_DAYS", 14),
+		FeedDir:            getEnv("FEED_DIR", "/var/lib/proxy-manager/feeds"),
+		AppURL:             getEnv("APP_URL", "http://localhost:3000"),
 	}
 }
 
@@ -26,3 +102,25 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
+func getEnvDays(key string, fallback int) time.Duration {
+	return time.Duration(getEnvInt(key, fallback)) * 24 * time.Hour
+}
@@ -50,11 +50,12 @@ type RuleAction string
 const (
 	ActionDirect RuleAction = "direct"
 	ActionParent RuleAction = "parent"
+	ActionDeny   RuleAction = "deny" // destination blocked; not valid as default_action
 )
 
 func (a RuleAction) IsValid() bool {
 	switch a {
-	case ActionDirect, ActionParent:
+	case ActionDirect, ActionParent, ActionDeny:
 		return true
 	}
 	return false
@@ -74,3 +75,174 @@ func (a ACLAction) IsValid() bool {
 	}
 	return false
 }
+
+// ACLApply is the address an ACL rule matches: the client's (in) or the
+// destination the proxy connects to (out).
+type ACLApply string
+
+const (
+	ACLApplyIn  ACLApply = "in"
+	ACLApplyOut ACLApply = "out"
+)
+
+func (a ACLApply) IsValid() bool {
+	switch a {
+	case ACLApplyIn, ACLApplyOut:
+		return true
+	}
+	return false
+}
+
+// ACLMethods are the HTTP methods an ACL rule can list (ip_allow.yaml methods).
+var ACLMethods = []string{"GET", "HEAD", "POST", "PUT", "DELETE", "CONNECT", "OPTIONS", "TRACE", "PATCH", "PUSH", "PURGE"}
+
+// StatsTier identifies the resolution at which proxy stats are stored.
+type StatsTier string
+
+const (
+	TierRaw    StatsTier = "raw"
+	Tier5m     StatsTier = "5m"
+	TierHourly StatsTier = "1h"
+	TierDaily  StatsTier = "1d"
+)
+
+func (t StatsTier) IsValid() bool {
+	switch t {
+	case TierRaw, Tier5m, TierHourly, TierDaily:
+		return true
+	}
+	return false
+}
+
+// StatsGroupBy selects how fleet stats series are grouped.
+type StatsGroupBy string
+
+const (
+	GroupByProxy  StatsGroupBy = "proxy"
+	GroupByConfig StatsGroupBy = "config"
+	GroupByFleet  StatsGroupBy = "fleet"
+)
+
+func (g StatsGroupBy) IsValid() bool {
+	switch g {
+	case GroupByProxy, GroupByConfig, GroupByFleet:
+		return true
+	}
+	return false
+}
+
+// AlertRuleType identifies the condition an alert rule evaluates.
+type AlertRuleType string
+
+const (
+	AlertProxyOffline   AlertRuleType = "proxy_offline"
+	AlertConfigMismatch AlertRuleType = "config_mismatch"
+	AlertErrorRate      AlertRuleType = "error_rate"
+	AlertAckFailed      AlertRuleType = "ack_failed"
+	AlertParentDown     AlertRuleType = "parent_down"
+	AlertConfigDrift    AlertRuleType = "config_drift"
+)
+
+func (t AlertRuleType) IsValid() bool {
+	switch t {
+	case AlertProxyOffline, AlertConfigMismatch, AlertErrorRate, AlertAckFailed, AlertParentDown, AlertConfigDrift:
+		return true
+	}
+	return false
+}
+
+// AlertStatus is the lifecycle of an alert: pending while the condition has not
+// held for the rule duration yet, then firing, then resolved.
+type AlertStatus string
+
+const (
+	AlertPending  AlertStatus = "pending"
+	AlertFiring   AlertStatus = "firing"
+	AlertResolved AlertStatus = "resolved"
+)
+
+func (s AlertStatus) IsValid() bool {
+	switch s {
+	case AlertPending, AlertFiring, AlertResolved:
+		return true
+	}
+	return false
+}
+
+// WebhookEvent is an event webhooks can subscribe to.
+type WebhookEvent string
+
+const (
+	EventConfigSubmit  WebhookEvent = "config.submit"
+	EventConfigApprove WebhookEvent = "config.approve"
+	EventConfigReject  WebhookEvent = "config.reject"
+	EventProxyOffline  WebhookEvent = "proxy.offline"
+	EventDeployFailed  WebhookEvent = "deploy.failed"
+	EventAlertFiring   WebhookEvent = "alert.firing"
+)
+
+func (e WebhookEvent) IsValid() bool {
+	switch e {
+	case EventConfigSubmit, EventConfigApprove, EventConfigReject, EventProxyOffline, EventDeployFailed, EventAlertFiring:
+		return true
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending DeliveryStatus = "pending"
+	DeliverySuccess DeliveryStatus = "success"
+	DeliveryFailed  DeliveryStatus = "failed"
+)
+
+// CommandType is a remote action queued for a proxy's helper.
+type CommandType string
+
+const (
+	CommandResync           CommandType = "resync"
+	CommandReload           CommandType = "reload"
+	CommandRestart          CommandType = "restart"
+	CommandDiagnostics      CommandType = "diagnostics"
+	CommandClearHostDB      CommandType = "clear_hostdb"
+	CommandConnectivityTest CommandType = "connectivity_test"
+)
+
+func (t CommandType) IsValid() bool {
+	switch t {
+	case CommandResync, CommandReload, CommandRestart, CommandDiagnostics, CommandClearHostDB, CommandConnectivityTest:
+		return true
+	}
+	return false
+}
+
+// CommandStatus is the lifecycle of a proxy command: pending until the helper
+// picks it up in GET /sync, delivered until it posts the result.
+type CommandStatus string
+
+const (
+	CommandPending   CommandStatus = "pending"
+	CommandDelivered CommandStatus = "delivered"
+	CommandSucceeded CommandStatus = "succeeded"
+	CommandFailed    CommandStatus = "failed"
+	CommandTimedOut  CommandStatus = "timed_out" // delivered but no result in time
+	CommandExpired   CommandStatus = "expired"   // never delivered (proxy offline)
+	CommandCancelled CommandStatus = "cancelled"
+)
+
+// FeedUpdateStatus is the review state of content fetched from a feed.
+type FeedUpdateStatus string
+
+const (
+	FeedUpdatePending   FeedUpdateStatus = "pending"
+	FeedUpdateApplied   FeedUpdateStatus = "applied"
+	FeedUpdateDiscarded FeedUpdateStatus = "discarded"
+)
+
+// Outcome of the last fetch of a feed (FeedSource.LastStatus).
+const (
+	FeedFetchChanged   = "changed"
+	FeedFetchUnchanged = "unchanged"
+	FeedFetchError     = "error"
+)
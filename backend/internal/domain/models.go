@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -38,6 +39,9 @@ type Config struct {
 	Status        ConfigStatus `json:"status"`
 	Version       int          `json:"version"`
 	DefaultAction RuleAction   `json:"default_action"`
+	// ConnectPorts are the ports and ranges CONNECT may tunnel to, space
+	// separated ("443 563 8000-8100"); nil keeps the proxy image's list
+	ConnectPorts *string `json:"connect_ports,omitempty"`
 
 	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
@@ -52,11 +56,13 @@ type Config struct {
 }
 
 type DomainRule struct {
-	ID        uuid.UUID  `json:"id"`
-	ConfigID  uuid.UUID  `json:"config_id"`
-	Domain    string     `json:"domain"`
-	Action    RuleAction `json:"action"`
-	Priority  int        `json:"priority"`
+	ID       uuid.UUID  `json:"id"`
+	ConfigID uuid.UUID  `json:"config_id"`
+	Domain   string     `json:"domain"`
+	Action   RuleAction `json:"action"`
+	Priority int        `json:"priority"`
+	// TLS is the rule's sni.yaml policy; nil for plain tunnel rules
+	TLS       *TLSPolicy `json:"tls,omitempty"`
 	CreatedAt time.Time  `json:"created_at"`
 }
 
@@ -70,11 +76,24 @@ type IPRangeRule struct {
 }
 
 type ClientACLRule struct {
+	ID       uuid.UUID `json:"id"`
+	ConfigID uuid.UUID `json:"config_id"`
+	CIDR     string    `json:"cidr"`
+	Action   ACLAction `json:"action"`
+	Apply    ACLApply  `json:"apply"`
+	// Methods the action applies to; empty means all
+	Methods   []string  `json:"methods"`
+	Priority  int       `json:"priority"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RecordOverride is a records.yaml value managed by a config. Name is the full
+// record name and must be in ManagedRecords.
+type RecordOverride struct {
 	ID        uuid.UUID `json:"id"`
 	ConfigID  uuid.UUID `json:"config_id"`
-	CIDR      string    `json:"cidr"`
-	Action    ACLAction `json:"action"`
-	Priority  int       `json:"priority"`
+	Name      string    `json:"name"`
+	Value     string    `json:"value"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -98,6 +117,230 @@ type Proxy struct {
 	RegisteredAt      time.Time  `json:"registered_at"`
 	RegisteredIP      *string    `json:"registered_ip,omitempty"`
 	CaptureLogsUntil  *time.Time `json:"capture_logs_until,omitempty"`
+	CaptureDebugTags  []string   `json:"capture_debug_tags,omitempty"` // ATS debug tags enabled during the capture
+	LastAckStatus     *string    `json:"last_ack_status,omitempty"`
+	LastAckMessage    *string    `json:"last_ack_message,omitempty"`
+	LastAckAt         *time.Time `json:"last_ack_at,omitempty"`
+	// SHA-256 of the client certificate the helper last registered with (mTLS)
+	ClientCertFingerprint *string `json:"client_cert_fingerprint,omitempty"`
+	// Labels (site, env, role, ...) set by an admin. Group selectors match them
+	// over HelperLabels, an admin label winning on the same key.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Labels reported by the helper (--labels), replaced on every registration
+	HelperLabels map[string]string `json:"helper_labels,omitempty"`
+	// Hash of the files last handed to the proxy for TargetConfigID when config
+	// overlays apply to it; without overlays the config's own hash is expected
+	TargetConfigID   *uuid.UUID `json:"-"`
+	TargetConfigHash *string    `json:"-"`
+}
+
+// ProxyGroup is the set of proxies whose labels include every label of the
+// selector; an empty selector matches all proxies. Configs assigned to a group
+// apply to its proxies unless a proxy has a config assigned directly.
+type ProxyGroup struct {
+	ID          uuid.UUID         `json:"id"`
+	Name        string            `json:"name"`
+	Description *string           `json:"description,omitempty"`
+	Selector    map[string]string `json:"selector"`
+	// Priority decides between active configs of overlapping groups (higher wins)
+	Priority   int        `json:"priority"`
+	ProxyCount int        `json:"proxy_count"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// ConfigOverlay holds extra rules of a config for a single proxy (Hostname) or
+// for the proxies of a group (GroupID); exactly one of the two is set. The
+// rules are merged on top of the config's own when generating the files of a
+// proxy, so they are versioned and approved along with the config.
+type ConfigOverlay struct {
+	ID          uuid.UUID           `json:"id"`
+	ConfigID    uuid.UUID           `json:"config_id"`
+	Hostname    *string             `json:"hostname,omitempty"`
+	GroupID     *uuid.UUID          `json:"group_id,omitempty"`
+	GroupName   *string             `json:"group_name,omitempty"`
+	Description *string             `json:"description,omitempty"`
+	Domains     []OverlayDomainRule `json:"domains"`
+	IPRanges    []OverlayIPRule     `json:"ip_ranges"`
+	ClientACL   []OverlayACLRule    `json:"client_acl"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+type OverlayDomainRule struct {
+	Domain   string     `json:"domain"`
+	Action   RuleAction `json:"action"`
+	Priority int        `json:"priority"`
+}
+
+type OverlayIPRule struct {
+	CIDR     string     `json:"cidr"`
+	Action   RuleAction `json:"action"`
+	Priority int        `json:"priority"`
+}
+
+type OverlayACLRule struct {
+	CIDR     string    `json:"cidr"`
+	Action   ACLAction `json:"action"`
+	Apply    ACLApply  `json:"apply,omitempty"`
+	Methods  []string  `json:"methods,omitempty"`
+	Priority int       `json:"priority"`
+}
+
+// RuleSet is a named list of domain and CIDR rules that configs reference
+// instead of copying. Every change makes a new version; configs keep the
+// version they were approved with (see ConfigRuleSet).
+type RuleSet struct {
+	ID          uuid.UUID       `json:"id"`
+	Name        string          `json:"name"`
+	Description *string         `json:"description,omitempty"`
+	Version     int             `json:"version"`
+	Domains     []RuleSetDomain `json:"domains"`
+	IPRanges    []RuleSetCIDR   `json:"ip_ranges"`
+	ConfigCount int             `json:"config_count"`
+	CreatedBy   *uuid.UUID      `json:"created_by,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+type RuleSetDomain struct {
+	Domain string     `json:"domain"`
+	Action RuleAction `json:"action"`
+}
+
+type RuleSetCIDR struct {
+	CIDR   string     `json:"cidr"`
+	Action RuleAction `json:"action"`
+}
+
+// RuleSetVersion is the content of a rule set at one version.
+type RuleSetVersion struct {
+	RuleSetID uuid.UUID       `json:"rule_set_id"`
+	Version   int             `json:"version"`
+	Domains   []RuleSetDomain `json:"domains"`
+	IPRanges  []RuleSetCIDR   `json:"ip_ranges"`
+	CreatedBy *uuid.UUID      `json:"created_by,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ConfigRuleSet is a config's reference to a rule set version. The rules are
+// expanded among the config's own at Priority.
+type ConfigRuleSet struct {
+	RuleSetID     uuid.UUID `json:"rule_set_id"`
+	Name          string    `json:"name"`
+	Version       int       `json:"version"`
+	LatestVersion int       `json:"latest_version"`
+	Priority      int       `json:"priority"`
+}
+
+// FeedSource keeps a rule set in sync with an external list (a URL or a file
+// under the feed directory). Fetched content that differs from the rule set
+// becomes a FeedUpdate to review.
+type FeedSource struct {
+	ID              uuid.UUID  `json:"id"`
+	Name            string     `json:"name"`
+	RuleSetID       uuid.UUID  `json:"rule_set_id"`
+	RuleSetName     string     `json:"rule_set_name"`
+	Source          string     `json:"source"`
+	Format          string     `json:"format"`
+	Action          RuleAction `json:"action"`
+	IntervalMinutes int        `json:"interval_minutes"`
+	Enabled         bool       `json:"enabled"`
+	LastFetchedAt   *time.Time `json:"last_fetched_at,omitempty"`
+	LastStatus      *string    `json:"last_status,omitempty"`
+	LastError       *string    `json:"last_error,omitempty"`
+	PendingUpdateID *uuid.UUID `json:"pending_update_id,omitempty"`
+	CreatedBy       *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// FeedUpdate is the content of a feed at one fetch, with its diff against the
+// rule set version it was compared to.
+type FeedUpdate struct {
+	ID             uuid.UUID        `json:"id"`
+	FeedID         uuid.UUID        `json:"feed_id"`
+	Status         FeedUpdateStatus `json:"status"`
+	BaseVersion    int              `json:"base_version"`
+	Domains        []RuleSetDomain  `json:"domains"`
+	IPRanges       []RuleSetCIDR    `json:"ip_ranges"`
+	Added          []string         `json:"added"`
+	Removed        []string         `json:"removed"`
+	Skipped        int              `json:"skipped"`
+	FetchedAt      time.Time        `json:"fetched_at"`
+	ReviewedBy     *uuid.UUID       `json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time       `json:"reviewed_at,omitempty"`
+	AppliedVersion *int             `json:"applied_version,omitempty"`
+}
+
+// ProxyParentStatus is the reachability of a parent proxy as last seen by a proxy.
+type ProxyParentStatus struct {
+	ProxyID   uuid.UUID `json:"proxy_id"`
+	Address   string    `json:"address"`
+	Port      int       `json:"port"`
+	IsUp      bool      `json:"is_up"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// ProxyDrift is the last drift check reported by a proxy's helper: whether the
+// files on disk still hash to the bundle it applied.
+type ProxyDrift struct {
+	ProxyID      uuid.UUID  `json:"proxy_id"`
+	Drifted      bool       `json:"drifted"`
+	ExpectedHash *string    `json:"expected_hash,omitempty"`
+	ActualHash   *string    `json:"actual_hash,omitempty"`
+	Files        []string   `json:"files"`
+	Remediated   bool       `json:"remediated"`
+	DetectedAt   *time.Time `json:"detected_at,omitempty"`
+	CheckedAt    time.Time  `json:"checked_at"`
+}
+
+// ProxyCommand is a remote command queued for a proxy and its result.
+type ProxyCommand struct {
+	ID             uuid.UUID         `json:"id"`
+	ProxyID        uuid.UUID         `json:"proxy_id"`
+	Type           CommandType       `json:"type"`
+	Args           map[string]string `json:"args,omitempty"`
+	Status         CommandStatus     `json:"status"`
+	TimeoutSeconds int               `json:"timeout_seconds"`
+	Output         *string           `json:"output,omitempty"`
+	Error          *string           `json:"error,omitempty"`
+	CreatedBy      *uuid.UUID        `json:"created_by,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	ExpiresAt      time.Time         `json:"expires_at"`
+	DeliveredAt    *time.Time        `json:"delivered_at,omitempty"`
+	FinishedAt     *time.Time        `json:"finished_at,omitempty"`
+}
+
+// DiagnosticsBundle is a tar.gz collected by a proxy's helper. The content is
+// only read on download.
+type DiagnosticsBundle struct {
+	ID        uuid.UUID  `json:"id"`
+	ProxyID   uuid.UUID  `json:"proxy_id"`
+	CommandID *uuid.UUID `json:"command_id,omitempty"`
+	SizeBytes int        `json:"size_bytes"`
+	SHA256    string     `json:"sha256"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+}
+
+// AccessLog is a request served by a proxy, shipped from the ATS access log.
+type AccessLog struct {
+	ID         int64     `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	ProxyID    uuid.UUID `json:"proxy_id"`
+	Hostname   string    `json:"hostname,omitempty"` // proxy hostname, filled on search
+	ClientIP   string    `json:"client_ip"`
+	Username   *string   `json:"username,omitempty"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	Host       string    `json:"host"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMs *int      `json:"duration_ms,omitempty"`
+	Route      *string   `json:"route,omitempty"`
+	Upstream   *string   `json:"upstream,omitempty"`
+	UserAgent  *string   `json:"user_agent,omitempty"`
 }
 
 type ConfigProxy struct {
@@ -129,6 +372,32 @@ type ProxyStat struct {
 	BrokenServerConns int   `json:"broken_server_conns"`
 	BytesIn           int64 `json:"bytes_in"`
 	BytesOut          int64 `json:"bytes_out"`
+
+	// Deltas desde a amostra anterior (os campos acima são cumulativos no ATS)
+	Delta ProxyStatDelta `json:"delta"`
+}
+
+// ProxyStatDelta holds the per-interval increase of each cumulative ATS counter.
+// CounterReset is set when any counter went backwards (ATS restarted), in which
+// case the current raw value is taken as the delta.
+type ProxyStatDelta struct {
+	IntervalSeconds   float64 `json:"interval_seconds"`
+	CounterReset      bool    `json:"counter_reset"`
+	TotalConnections  int64   `json:"total_connections"`
+	CacheHits         int64   `json:"cache_hits"`
+	CacheMisses       int64   `json:"cache_misses"`
+	Errors            int64   `json:"errors"`
+	TotalRequests     int64   `json:"total_requests"`
+	ConnectRequests   int64   `json:"connect_requests"`
+	Responses2xx      int64   `json:"responses_2xx"`
+	Responses3xx      int64   `json:"responses_3xx"`
+	Responses4xx      int64   `json:"responses_4xx"`
+	Responses5xx      int64   `json:"responses_5xx"`
+	ErrConnectFail    int64   `json:"err_connect_fail"`
+	ErrClientAbort    int64   `json:"err_client_abort"`
+	BrokenServerConns int64   `json:"broken_server_conns"`
+	BytesIn           int64   `json:"bytes_in"`
+	BytesOut          int64   `json:"bytes_out"`
 }
 
 type ProxyLog struct {
@@ -136,6 +405,7 @@ type ProxyLog struct {
 	ProxyID    uuid.UUID `json:"proxy_id"`
 	CapturedAt time.Time `json:"captured_at"`
 	LogLevel   *string   `json:"log_level,omitempty"`
+	Tag        *string   `json:"tag,omitempty"` // ATS debug tag of the line
 	Message    *string   `json:"message,omitempty"`
 	ExpiresAt  time.Time `json:"expires_at"`
 }
@@ -153,6 +423,100 @@ type AuditLog struct {
 	CreatedAt  time.Time  `json:"created_at"`
 }
 
+type AlertRule struct {
+	ID              uuid.UUID     `json:"id"`
+	Name            string        `json:"name"`
+	Type            AlertRuleType `json:"type"`
+	Threshold       float64       `json:"threshold"`
+	DurationMinutes int           `json:"duration_minutes"`
+	Enabled         bool          `json:"enabled"`
+	CreatedBy       *uuid.UUID    `json:"created_by,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+type Alert struct {
+	ID              uuid.UUID     `json:"id"`
+	RuleID          uuid.UUID     `json:"rule_id"`
+	RuleName        string        `json:"rule_name"`
+	RuleType        AlertRuleType `json:"rule_type"`
+	ProxyID         *uuid.UUID    `json:"proxy_id,omitempty"`
+	ProxyHostname   *string       `json:"proxy_hostname,omitempty"`
+	Subject         string        `json:"subject,omitempty"`
+	Status          AlertStatus   `json:"status"`
+	Message         *string       `json:"message,omitempty"`
+	Value           *float64      `json:"value,omitempty"`
+	StartedAt       time.Time     `json:"started_at"`
+	FiredAt         *time.Time    `json:"fired_at,omitempty"`
+	ResolvedAt      *time.Time    `json:"resolved_at,omitempty"`
+	LastEvaluatedAt time.Time     `json:"last_evaluated_at"`
+}
+
+type Webhook struct {
+	ID        uuid.UUID      `json:"id"`
+	Name      string         `json:"name"`
+	URL       string         `json:"url"`
+	Secret    string         `json:"-"`
+	Events    []WebhookEvent `json:"events"`
+	Enabled   bool           `json:"enabled"`
+	CreatedBy *uuid.UUID     `json:"created_by,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	WebhookID      uuid.UUID       `json:"webhook_id"`
+	Event          WebhookEvent    `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// EnrollmentToken lets a helper register and obtain its sync credentials.
+// Only the SHA-256 of the token is stored.
+type EnrollmentToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	ConfigID   *uuid.UUID `json:"config_id,omitempty"`
+	GroupID    *uuid.UUID `json:"group_id,omitempty"`
+	MaxUses    *int       `json:"max_uses,omitempty"`
+	UseCount   int        `json:"use_count"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ProxyCredential is the secret a proxy signs its sync requests with.
+// NextSecret is set while a rotation is pending.
+type ProxyCredential struct {
+	ProxyID           uuid.UUID  `json:"proxy_id"`
+	Secret            string     `json:"-"`
+	NextSecret        *string    `json:"-"`
+	EnrollmentTokenID *uuid.UUID `json:"enrollment_token_id,omitempty"`
+	IssuedAt          time.Time  `json:"issued_at"`
+	RotatedAt         *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt        *time.Time `json:"last_used_at,omitempty"`
+}
+
+// SigningKey is the Ed25519 key pair config bundles are signed with.
+// Keys are stored base64 encoded.
+type SigningKey struct {
+	ID         uuid.UUID  `json:"key_id"`
+	Algorithm  string     `json:"algorithm"`
+	PublicKey  string     `json:"public_key"`
+	PrivateKey string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at,omitempty"`
+}
+
 // Pagination is used for paginated list responses.
 type Pagination struct {
 	Page       int `json:"page"`
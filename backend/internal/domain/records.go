@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RecordKind is how a managed records.yaml value is validated and rendered.
+type RecordKind string
+
+const (
+	RecordInt   RecordKind = "int"
+	RecordPorts RecordKind = "ports" // space separated ports and ranges, e.g. "443 563 8000-8100"
+	RecordTags  RecordKind = "tags"  // debug tags separated by |, e.g. "http|parent"
+)
+
+// RecordSpec describes a record configs may override.
+type RecordSpec struct {
+	Kind RecordKind
+	Min  int64
+	Max  int64
+}
+
+// ConnectPortsRecord is the record a config's connect_ports sets.
+const ConnectPortsRecord = "proxy.config.http.connect_ports"
+
+// ManagedRecords is the allow-list of records.yaml values a config may
+// override. Every record here is reloadable by ATS (traffic_ctl config reload);
+// records that need a restart are kept in the proxy image.
+var ManagedRecords = map[string]RecordSpec{
+	ConnectPortsRecord:                                      {Kind: RecordPorts},
+	"proxy.config.http.connect_attempts_timeout":            {Kind: RecordInt, Min: 1, Max: 3600},
+	"proxy.config.http.connect_attempts_max_retries":        {Kind: RecordInt, Min: 0, Max: 100},
+	"proxy.config.http.keep_alive_no_activity_timeout_in":   {Kind: RecordInt, Min: 0, Max: 86400},
+	"proxy.config.http.keep_alive_no_activity_timeout_out":  {Kind: RecordInt, Min: 0, Max: 86400},
+	"proxy.config.http.transaction_no_activity_timeout_in":  {Kind: RecordInt, Min: 0, Max: 86400},
+	"proxy.config.http.transaction_no_activity_timeout_out": {Kind: RecordInt, Min: 0, Max: 86400},
+	"proxy.config.http.transaction_active_timeout_in":       {Kind: RecordInt, Min: 0, Max: 86400},
+	"proxy.config.http.transaction_active_timeout_out":      {Kind: RecordInt, Min: 0, Max: 86400},
+	"proxy.config.http.parent_proxy.fail_threshold":         {Kind: RecordInt, Min: 1, Max: 1000},
+	"proxy.config.http.parent_proxy.retry_time":             {Kind: RecordInt, Min: 1, Max: 86400},
+	"proxy.config.http.parent_proxy.total_connect_attempts": {Kind: RecordInt, Min: 1, Max: 100},
+	"proxy.config.http.insert_request_via_str":              {Kind: RecordInt, Min: 0, Max: 4},
+	"proxy.config.http.insert_response_via_str":             {Kind: RecordInt, Min: 0, Max: 4},
+	"proxy.config.diags.debug.tags":                         {Kind: RecordTags},
+}
+
+// DebugTagPattern matches a single ATS debug tag. proxy.config.diags.debug.tags
+// is a regex of tags joined by "|", so anything else would change its meaning.
+var DebugTagPattern = regexp.MustCompile(`^[A-Za-z0-9_.]{1,64}$`)
+
+// ValidateRecord checks a record override against ManagedRecords.
+func ValidateRecord(name, value string) error {
+	spec, ok := ManagedRecords[name]
+	if !ok {
+		return fmt.Errorf("'%s' is not a managed record", name)
+	}
+
+	switch spec.Kind {
+	case RecordInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("'%s' is not an integer", value)
+		}
+		if n < spec.Min || n > spec.Max {
+			return fmt.Errorf("%d is out of range (%d-%d)", n, spec.Min, spec.Max)
+		}
+	case RecordPorts:
+		if err := ValidatePortList(value); err != nil {
+			return err
+		}
+	case RecordTags:
+		for _, tag := range strings.Split(value, "|") {
+			if !DebugTagPattern.MatchString(tag) {
+				return fmt.Errorf("'%s' is not a valid tag list (use tag1|tag2)", value)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidatePortList checks a space separated list of ports and port ranges
+// ("443 563 8000-8100").
+func ValidatePortList(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return fmt.Errorf("port list cannot be empty")
+	}
+	for _, f := range fields {
+		lo, hi, isRange := strings.Cut(f, "-")
+		first, err := parsePort(lo)
+		if err != nil {
+			return err
+		}
+		if !isRange {
+			continue
+		}
+		last, err := parsePort(hi)
+		if err != nil {
+			return err
+		}
+		if last < first {
+			return fmt.Errorf("port range '%s' is reversed", f)
+		}
+	}
+	return nil
+}
+
+func parsePort(s string) (int, error) {
+	p, err := strconv.Atoi(s)
+	if err != nil || p < 1 || p > 65535 {
+		return 0, fmt.Errorf("'%s' is not a valid port (1-65535)", s)
+	}
+	return p, nil
+}
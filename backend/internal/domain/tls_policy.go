@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// TLSPolicy is the sni.yaml policy of a domain rule. Empty fields are left out
+// of sni.yaml, so ATS's defaults apply.
+type TLSPolicy struct {
+	// VerifyServerPolicy checks the origin's certificate: DISABLED, PERMISSIVE or ENFORCED
+	VerifyServerPolicy string `json:"verify_server_policy,omitempty"`
+	// HostSNIPolicy checks that the Host header matches the SNI: DISABLED, PERMISSIVE or ENFORCED
+	HostSNIPolicy string `json:"host_sni_policy,omitempty"`
+	// ForwardRoute terminates TLS and sends the decrypted traffic to this
+	// host:port instead of tunneling it
+	ForwardRoute string `json:"forward_route,omitempty"`
+	// HTTP2 offers (true) or withholds (false) h2 to the client
+	HTTP2 *bool `json:"http2,omitempty"`
+	// VerifyClient requires a client certificate: NONE, MODERATE or STRICT
+	VerifyClient string `json:"verify_client,omitempty"`
+}
+
+var (
+	tlsVerifyPolicies = []string{"DISABLED", "PERMISSIVE", "ENFORCED"}
+	tlsClientPolicies = []string{"NONE", "MODERATE", "STRICT"}
+
+	// hostnamePattern matches a hostname of one or more RFC 1123 labels
+	hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+)
+
+// IsEmpty reports whether the policy sets nothing.
+func (p *TLSPolicy) IsEmpty() bool {
+	return p == nil || *p == TLSPolicy{}
+}
+
+// Validate checks the policy values against what sni.yaml accepts.
+func (p *TLSPolicy) Validate() error {
+	if p.VerifyServerPolicy != "" && !slices.Contains(tlsVerifyPolicies, p.VerifyServerPolicy) {
+		return fmt.Errorf("verify_server_policy '%s' is not valid (DISABLED, PERMISSIVE or ENFORCED)", p.VerifyServerPolicy)
+	}
+	if p.HostSNIPolicy != "" && !slices.Contains(tlsVerifyPolicies, p.HostSNIPolicy) {
+		return fmt.Errorf("host_sni_policy '%s' is not valid (DISABLED, PERMISSIVE or ENFORCED)", p.HostSNIPolicy)
+	}
+	if p.VerifyClient != "" && !slices.Contains(tlsClientPolicies, p.VerifyClient) {
+		return fmt.Errorf("verify_client '%s' is not valid (NONE, MODERATE or STRICT)", p.VerifyClient)
+	}
+	if p.ForwardRoute != "" {
+		host, port, err := net.SplitHostPort(p.ForwardRoute)
+		if err != nil || host == "" {
+			return fmt.Errorf("forward_route '%s' is not valid (use host:port)", p.ForwardRoute)
+		}
+		if net.ParseIP(host) == nil && (len(host) > 253 || !hostnamePattern.MatchString(host)) {
+			return fmt.Errorf("forward_route host '%s' is not a hostname or IP address", host)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("forward_route port '%s' is not valid (1-65535)", port)
+		}
+	}
+	return nil
+}
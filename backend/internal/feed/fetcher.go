@@ -0,0 +1,182 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MaxSize is the largest feed document read, in bytes.
+const MaxSize = 16 << 20
+
+// ErrOutsideDir is returned for a file source that resolves outside the feed
+// directory.
+var ErrOutsideDir = errors.New("path is outside the feed directory")
+
+// ErrNonPublicAddress is returned for a URL source that is, or resolves to, a
+// loopback, private, link-local or otherwise non-public address.
+var ErrNonPublicAddress = errors.New("address is not public")
+
+// cgnat is the shared address space of carrier-grade NAT (RFC 6598).
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// Fetcher reads the document of a feed source. Implementations must be safe
+// for concurrent use.
+type Fetcher interface {
+	Fetch(ctx context.Context, source string) ([]byte, error)
+}
+
+// IsURL reports whether a source is fetched over HTTP(S) rather than read from
+// the feed directory.
+func IsURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// CheckURL checks a URL source: http or https, with a host that is not an IP
+// literal of a non-public address. Hostnames are checked when dialing.
+func CheckURL(source string) error {
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("'%s' is not a valid http(s) URL", source)
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(u.Hostname(), "[]")); err == nil && !IsPublicAddr(addr) {
+		return fmt.Errorf("%s: %w", u.Hostname(), ErrNonPublicAddress)
+	}
+	return nil
+}
+
+// IsPublicAddr reports whether a feed may be downloaded from addr.
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !cgnat.Contains(addr)
+}
+
+// SourceFetcher downloads http(s) sources and reads any other source as a file
+// under its directory.
+type SourceFetcher struct {
+	client *http.Client
+	dir    string
+}
+
+// NewSourceFetcher builds a fetcher. Without a client, one is built that only
+// connects to public addresses (checked after DNS resolution, so redirects and
+// rebinding cannot reach internal services either) and ignores proxy settings.
+func NewSourceFetcher(client *http.Client, dir string) *SourceFetcher {
+	if client == nil {
+		dialer := &net.Dialer{Timeout: 10 * time.Second, Control: dialPublicOnly}
+		client = &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+		}
+	}
+	return &SourceFetcher{client: client, dir: dir}
+}
+
+// dialPublicOnly refuses connections to non-public addresses.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("dial %s: %w", address, err)
+	}
+	if !IsPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("dial %s: %w", address, ErrNonPublicAddress)
+	}
+	return nil
+}
+
+func (f *SourceFetcher) Fetch(ctx context.Context, source string) ([]byte, error) {
+	if IsURL(source) {
+		return f.fetchURL(ctx, source)
+	}
+	path, err := ResolvePath(f.dir, source)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open feed file: %w", err)
+	}
+	defer file.Close()
+	return readLimited(file)
+}
+
+func (f *SourceFetcher) fetchURL(ctx context.Context, source string) ([]byte, error) {
+	if err := CheckURL(source); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build feed request: %w", err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch feed: HTTP %d", resp.StatusCode)
+	}
+	return readLimited(resp.Body)
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read feed: %w", err)
+	}
+	if len(data) > MaxSize {
+		return nil, fmt.Errorf("feed is larger than %d bytes", MaxSize)
+	}
+	return data, nil
+}
+
+// ResolvePath returns the file a source names under dir, relative to it or
+// absolute, refusing anything that resolves outside. Symlinks are followed
+// before the check, so a link under dir cannot point elsewhere; a file that
+// does not exist yet is checked by its name alone.
+func ResolvePath(dir, source string) (string, error) {
+	if dir == "" {
+		return "", errors.New("file feeds are disabled (no feed directory)")
+	}
+	path := source
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+	dir = filepath.Clean(dir)
+	if !within(dir, path) {
+		return "", ErrOutsideDir
+	}
+
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolve feed directory: %w", err)
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return path, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("resolve feed file: %w", err)
+	}
+	if !within(realDir, realPath) {
+		return "", ErrOutsideDir
+	}
+	return realPath, nil
+}
+
+// within reports whether path is below dir (both clean).
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
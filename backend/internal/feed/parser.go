@@ -0,0 +1,187 @@
+package feed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Entries are the domains and CIDRs found in a feed document.
+type Entries struct {
+	Domains []string
+	CIDRs   []string
+}
+
+// Parser extracts the entries of a feed document.
+type Parser func(data []byte) (*Entries, error)
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = map[string]Parser{
+		"plain": ParsePlain,
+		"m365":  ParseM365,
+		"aws":   ParseAWS,
+		"gcp":   ParseGCP,
+	}
+)
+
+// Register adds (or replaces) the parser of a format.
+func Register(format string, p Parser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	parsers[format] = p
+}
+
+// Lookup returns the parser of a format.
+func Lookup(format string) (Parser, bool) {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+	p, ok := parsers[format]
+	return p, ok
+}
+
+// Formats lists the registered formats, sorted.
+func Formats() []string {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+	formats := make([]string, 0, len(parsers))
+	for f := range parsers {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Normalize lowercases domains, drops duplicates and sorts both lists, so the
+// same document always yields the same entries.
+func (e *Entries) Normalize() {
+	e.Domains = uniqueSorted(e.Domains, strings.ToLower)
+	e.CIDRs = uniqueSorted(e.CIDRs, func(s string) string { return s })
+}
+
+func uniqueSorted(values []string, norm func(string) string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		v = norm(strings.TrimSpace(v))
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// isIPOrCIDR reports whether s is an IP address or a CIDR.
+func isIPOrCIDR(s string) bool {
+	if net.ParseIP(s) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(s)
+	return err == nil
+}
+
+// ParsePlain reads one entry per line: a domain, an IP or a CIDR. Blank lines
+// and "#" or ";" comments are skipped, and hosts-file lines ("0.0.0.0 host")
+// contribute their host names.
+func ParsePlain(data []byte) (*Entries, error) {
+	entries := &Entries{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 1 && net.ParseIP(fields[0]) != nil {
+			// hosts file: address followed by host names
+			entries.Domains = append(entries.Domains, fields[1:]...)
+			continue
+		}
+		if isIPOrCIDR(fields[0]) {
+			entries.CIDRs = append(entries.CIDRs, fields[0])
+		} else {
+			entries.Domains = append(entries.Domains, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("parse plain feed: %w", err)
+	}
+	return entries, nil
+}
+
+// ParseM365 reads the Microsoft 365 endpoints web service format
+// (endpoints.office.com/endpoints/...): a list of endpoint sets with urls and
+// ips.
+func ParseM365(data []byte) (*Entries, error) {
+	var sets []struct {
+		URLs []string `json:"urls"`
+		IPs  []string `json:"ips"`
+	}
+	if err := json.Unmarshal(data, &sets); err != nil {
+		return nil, fmt.Errorf("parse m365 feed: %w", err)
+	}
+	entries := &Entries{}
+	for _, s := range sets {
+		entries.Domains = append(entries.Domains, s.URLs...)
+		entries.CIDRs = append(entries.CIDRs, s.IPs...)
+	}
+	return entries, nil
+}
+
+// ParseAWS reads the AWS ip-ranges.json format.
+func ParseAWS(data []byte) (*Entries, error) {
+	var doc struct {
+		Prefixes []struct {
+			IPPrefix string `json:"ip_prefix"`
+		} `json:"prefixes"`
+		IPv6Prefixes []struct {
+			IPv6Prefix string `json:"ipv6_prefix"`
+		} `json:"ipv6_prefixes"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse aws feed: %w", err)
+	}
+	entries := &Entries{}
+	for _, p := range doc.Prefixes {
+		entries.CIDRs = append(entries.CIDRs, p.IPPrefix)
+	}
+	for _, p := range doc.IPv6Prefixes {
+		entries.CIDRs = append(entries.CIDRs, p.IPv6Prefix)
+	}
+	return entries, nil
+}
+
+// ParseGCP reads the Google Cloud cloud.json / goog.json format.
+func ParseGCP(data []byte) (*Entries, error) {
+	var doc struct {
+		Prefixes []struct {
+			IPv4Prefix string `json:"ipv4Prefix"`
+			IPv6Prefix string `json:"ipv6Prefix"`
+		} `json:"prefixes"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse gcp feed: %w", err)
+	}
+	entries := &Entries{}
+	for _, p := range doc.Prefixes {
+		if p.IPv4Prefix != "" {
+			entries.CIDRs = append(entries.CIDRs, p.IPv4Prefix)
+		}
+		if p.IPv6Prefix != "" {
+			entries.CIDRs = append(entries.CIDRs, p.IPv6Prefix)
+		}
+	}
+	return entries, nil
+}
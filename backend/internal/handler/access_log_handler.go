@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/service"
+)
+
+type AccessLogHandler struct {
+	accessLogSvc *service.AccessLogService
+}
+
+func NewAccessLogHandler(accessLogSvc *service.AccessLogService) *AccessLogHandler {
+	return &AccessLogHandler{accessLogSvc: accessLogSvc}
+}
+
+func (h *AccessLogHandler) List(w http.ResponseWriter, r *http.Request) {
+	page, limit := parsePagination(r)
+
+	q := r.URL.Query()
+	query := service.AccessLogQuery{
+		ProxyID:  q.Get("proxy_id"),
+		ClientIP: q.Get("client_ip"),
+		Host:     q.Get("host"),
+		Username: q.Get("username"),
+		Status:   q.Get("status"),
+	}
+	if f := q.Get("from"); f != "" {
+		if t, err := time.Parse(time.RFC3339, f); err == nil {
+			query.From = &t
+		}
+	}
+	if t := q.Get("to"); t != "" {
+		if parsed, err := time.Parse(time.RFC3339, t); err == nil {
+			query.To = &parsed
+		}
+	}
+
+	items, total, err := h.accessLogSvc.Search(r.Context(), query, page, limit)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	if items == nil {
+		items = []domain.AccessLog{}
+	}
+
+	respondJSON(w, http.StatusOK, paginatedResponse{
+		Data: items,
+		Pagination: domain.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages(total, limit),
+		},
+	})
+}
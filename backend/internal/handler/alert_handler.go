@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+	"github.com/ats-proxy/proxy-manager/backend/internal/service"
+)
+
+type AlertHandler struct {
+	alertSvc *service.AlertService
+}
+
+func NewAlertHandler(alertSvc *service.AlertService) *AlertHandler {
+	return &AlertHandler{alertSvc: alertSvc}
+}
+
+// List returns alerts, newest first. Defaults to open (firing) alerts; use
+// status=pending|firing|resolved to filter or status=all for history.
+func (h *AlertHandler) List(w http.ResponseWriter, r *http.Request) {
+	page, limit := parsePagination(r)
+
+	var filter repository.AlertFilter
+	switch st := r.URL.Query().Get("status"); st {
+	case "all":
+	case "":
+		firing := domain.AlertFiring
+		filter.Status = &firing
+	default:
+		status := domain.AlertStatus(st)
+		if !status.IsValid() {
+			respondError(w, http.StatusBadRequest, "bad_request", "Invalid status")
+			return
+		}
+		filter.Status = &status
+	}
+	if rid := r.URL.Query().Get("rule_id"); rid != "" {
+		if id, err := uuid.Parse(rid); err == nil {
+			filter.RuleID = &id
+		}
+	}
+	if pid := r.URL.Query().Get("proxy_id"); pid != "" {
+		if id, err := uuid.Parse(pid); err == nil {
+			filter.ProxyID = &id
+		}
+	}
+
+	alerts, total, err := h.alertSvc.List(r.Context(), filter, page, limit)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+	if alerts == nil {
+		alerts = []domain.Alert{}
+	}
+
+	respondJSON(w, http.StatusOK, paginatedResponse{
+		Data: alerts,
+		Pagination: domain.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages(total, limit),
+		},
+	})
+}
+
+func (h *AlertHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.alertSvc.ListRules(r.Context())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"data": rules})
+}
+
+func (h *AlertHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req service.AlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	rule, err := h.alertSvc.CreateRule(r.Context(), req, getUserID(r.Context()), clientIP(r), r.UserAgent())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, rule)
+}
+
+func (h *AlertHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid rule ID")
+		return
+	}
+
+	var req service.AlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	rule, err := h.alertSvc.UpdateRule(r.Context(), id, req, getUserID(r.Context()), clientIP(r), r.UserAgent())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, rule)
+}
+
+func (h *AlertHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid rule ID")
+		return
+	}
+
+	if err := h.alertSvc.DeleteRule(r.Context(), id, getUserID(r.Context()), clientIP(r), r.UserAgent()); err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/service"
+)
+
+type CommandHandler struct {
+	commandSvc *service.CommandService
+}
+
+func NewCommandHandler(commandSvc *service.CommandService) *CommandHandler {
+	return &CommandHandler{commandSvc: commandSvc}
+}
+
+func (h *CommandHandler) List(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid proxy ID")
+		return
+	}
+
+	commands, err := h.commandSvc.List(r.Context(), id)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"data": commands})
+}
+
+func (h *CommandHandler) Enqueue(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid proxy ID")
+		return
+	}
+
+	var req service.EnqueueCommandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	userID := getUserID(r.Context())
+	ip := clientIP(r)
+	ua := r.UserAgent()
+
+	cmd, err := h.commandSvc.Enqueue(r.Context(), id, req, userID, ip, ua)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, cmd)
+}
+
+func (h *CommandHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid proxy ID")
+		return
+	}
+	commandID, err := uuid.Parse(chi.URLParam(r, "commandID"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid command ID")
+		return
+	}
+
+	userID := getUserID(r.Context())
+	ip := clientIP(r)
+	ua := r.UserAgent()
+
+	if err := h.commandSvc.Cancel(r.Context(), id, commandID, userID, ip, ua); err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
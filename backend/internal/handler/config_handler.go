@@ -198,17 +198,19 @@ func (h *ConfigHandler) Preview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	parentConfig, sniYaml, ipAllowYaml, err := h.configSvc.GenerateConfigFiles(r.Context(), id)
+	// ?hostname= previews the files of that proxy, with its overlays merged in
+	files, err := h.configSvc.GenerateConfigFiles(r.Context(), id, r.URL.Query().Get("hostname"))
 	if err != nil {
 		respondDomainError(w, err)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{
-		"parent_config": parentConfig,
-		"sni_yaml":      sniYaml,
-		"ip_allow_yaml": ipAllowYaml,
-	})
+	respondJSON(w, http.StatusOK, files)
+}
+
+// ManagedRecords lists the records.yaml values configs may override.
+func (h *ConfigHandler) ManagedRecords(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, map[string]interface{}{"data": service.ManagedRecordList()})
 }
 
 func (h *ConfigHandler) Reject(w http.ResponseWriter, r *http.Request) {
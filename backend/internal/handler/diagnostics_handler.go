@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/service"
+)
+
+type DiagnosticsHandler struct {
+	diagnosticsSvc *service.DiagnosticsService
+}
+
+func NewDiagnosticsHandler(diagnosticsSvc *service.DiagnosticsService) *DiagnosticsHandler {
+	return &DiagnosticsHandler{diagnosticsSvc: diagnosticsSvc}
+}
+
+func (h *DiagnosticsHandler) List(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid proxy ID")
+		return
+	}
+
+	bundles, err := h.diagnosticsSvc.List(r.Context(), id)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"data": bundles})
+}
+
+func (h *DiagnosticsHandler) Download(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid proxy ID")
+		return
+	}
+	bundleID, err := uuid.Parse(chi.URLParam(r, "bundleID"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid bundle ID")
+		return
+	}
+
+	userID := getUserID(r.Context())
+	ip := clientIP(r)
+	ua := r.UserAgent()
+
+	bundle, data, err := h.diagnosticsSvc.Download(r.Context(), id, bundleID, userID, ip, ua)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	filename := fmt.Sprintf("diagnostics-%s.tar.gz", bundle.CreatedAt.UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("X-Content-SHA256", bundle.SHA256)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/feed"
+	"github.com/ats-proxy/proxy-manager/backend/internal/service"
+)
+
+type FeedHandler struct {
+	feedSvc    *service.FeedService
+	ruleSetSvc *service.RuleSetService
+}
+
+func NewFeedHandler(feedSvc *service.FeedService, ruleSetSvc *service.RuleSetService) *FeedHandler {
+	return &FeedHandler{feedSvc: feedSvc, ruleSetSvc: ruleSetSvc}
+}
+
+func (h *FeedHandler) List(w http.ResponseWriter, r *http.Request) {
+	feeds, err := h.feedSvc.List(r.Context())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"data": feeds})
+}
+
+// Formats lists the formats feed sources can be parsed with.
+func (h *FeedHandler) Formats(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, map[string]interface{}{"data": feed.Formats()})
+}
+
+func (h *FeedHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid feed ID")
+		return
+	}
+
+	f, err := h.feedSvc.Get(r.Context(), id)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, f)
+}
+
+func (h *FeedHandler) Updates(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid feed ID")
+		return
+	}
+
+	updates, err := h.feedSvc.ListUpdates(r.Context(), id)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"data": updates})
+}
+
+func (h *FeedHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req service.FeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	f, err := h.feedSvc.Create(r.Context(), req, getUserID(r.Context()), clientIP(r), r.UserAgent())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, f)
+}
+
+func (h *FeedHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid feed ID")
+		return
+	}
+
+	var req service.FeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	f, err := h.feedSvc.Update(r.Context(), id, req, getUserID(r.Context()), clientIP(r), r.UserAgent())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, f)
+}
+
+func (h *FeedHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid feed ID")
+		return
+	}
+
+	if err := h.feedSvc.Delete(r.Context(), id, getUserID(r.Context()), clientIP(r), r.UserAgent()); err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Refresh fetches a feed now instead of waiting for its interval. The update
+// is null when the rule set already matches the feed.
+func (h *FeedHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid feed ID")
+		return
+	}
+
+	update, err := h.feedSvc.RefreshNow(r.Context(), id)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"update": update})
+}
+
+func (h *FeedHandler) Apply(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid feed ID")
+		return
+	}
+	updateID, err := uuid.Parse(chi.URLParam(r, "updateID"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid update ID")
+		return
+	}
+
+	result, err := h.ruleSetSvc.ApplyFeedUpdate(r.Context(), id, updateID, getUserID(r.Context()), clientIP(r), r.UserAgent())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+func (h *FeedHandler) Discard(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid feed ID")
+		return
+	}
+	updateID, err := uuid.Parse(chi.URLParam(r, "updateID"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid update ID")
+		return
+	}
+
+	if err := h.feedSvc.Discard(r.Context(), id, updateID, getUserID(r.Context()), clientIP(r), r.UserAgent()); err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
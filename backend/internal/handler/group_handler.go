@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/service"
+)
+
+type GroupHandler struct {
+	groupSvc *service.GroupService
+}
+
+func NewGroupHandler(groupSvc *service.GroupService) *GroupHandler {
+	return &GroupHandler{groupSvc: groupSvc}
+}
+
+func (h *GroupHandler) List(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.groupSvc.List(r.Context())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"data": groups})
+}
+
+// Proxies returns the proxies whose labels currently match the group's selector.
+func (h *GroupHandler) Proxies(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid group ID")
+		return
+	}
+
+	proxies, err := h.groupSvc.ListProxies(r.Context(), id)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"data": proxies})
+}
+
+func (h *GroupHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req service.GroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	group, err := h.groupSvc.Create(r.Context(), req, getUserID(r.Context()), clientIP(r), r.UserAgent())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, group)
+}
+
+func (h *GroupHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid group ID")
+		return
+	}
+
+	var req service.GroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	group, err := h.groupSvc.Update(r.Context(), id, req, getUserID(r.Context()), clientIP(r), r.UserAgent())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, group)
+}
+
+func (h *GroupHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid group ID")
+		return
+	}
+
+	if err := h.groupSvc.Delete(r.Context(), id, getUserID(r.Context()), clientIP(r), r.UserAgent()); err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
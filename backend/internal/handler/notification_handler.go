@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/service"
+)
+
+type NotificationHandler struct {
+	notificationSvc *service.NotificationService
+}
+
+func NewNotificationHandler(notificationSvc *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationSvc: notificationSvc}
+}
+
+type notificationSubscriptions struct {
+	Events []domain.WebhookEvent `json:"events"`
+}
+
+func (h *NotificationHandler) Get(w http.ResponseWriter, r *http.Request) {
+	events, err := h.notificationSvc.Subscriptions(r.Context(), getUserID(r.Context()))
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, notificationSubscriptions{Events: events})
+}
+
+func (h *NotificationHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req notificationSubscriptions
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	events, err := h.notificationSvc.SetSubscriptions(r.Context(), getUserID(r.Context()), req.Events)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, notificationSubscriptions{Events: events})
+}
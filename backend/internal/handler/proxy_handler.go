@@ -2,10 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
 	"github.com/ats-proxy/proxy-manager/backend/internal/service"
 )
 
@@ -51,7 +54,8 @@ func (h *ProxyHandler) StartLogCapture(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		DurationMinutes int `json:"duration_minutes"`
+		DurationMinutes int      `json:"duration_minutes"`
+		DebugTags       []string `json:"debug_tags"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
@@ -65,7 +69,7 @@ func (h *ProxyHandler) StartLogCapture(w http.ResponseWriter, r *http.Request) {
 	ip := clientIP(r)
 	ua := r.UserAgent()
 
-	until, err := h.proxySvc.StartLogCapture(r.Context(), id, req.DurationMinutes, userID, ip, ua)
+	until, tags, err := h.proxySvc.StartLogCapture(r.Context(), id, req.DurationMinutes, req.DebugTags, userID, ip, ua)
 	if err != nil {
 		respondDomainError(w, err)
 		return
@@ -74,6 +78,7 @@ func (h *ProxyHandler) StartLogCapture(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"status":        "capturing",
 		"capture_until": until,
+		"debug_tags":    tags,
 	})
 }
 
@@ -133,32 +138,46 @@ func (h *ProxyHandler) AssignConfig(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
-func (h *ProxyHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
+func (h *ProxyHandler) SetLabels(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		respondError(w, http.StatusBadRequest, "bad_request", "Invalid proxy ID")
 		return
 	}
 
-	logs, err := h.proxySvc.GetLogs(r.Context(), id)
+	var req struct {
+		Labels map[string]string `json:"labels"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	labels, err := h.proxySvc.SetLabels(r.Context(), id, req.Labels, getUserID(r.Context()), clientIP(r), r.UserAgent())
 	if err != nil {
 		respondDomainError(w, err)
 		return
 	}
 
-	type logLine struct {
-		Timestamp string  `json:"timestamp"`
-		Level     *string `json:"level,omitempty"`
-		Message   *string `json:"message,omitempty"`
+	respondJSON(w, http.StatusOK, map[string]interface{}{"labels": labels})
+}
+
+func (h *ProxyHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid proxy ID")
+		return
+	}
+
+	logs, err := h.proxySvc.GetLogs(r.Context(), id)
+	if err != nil {
+		respondDomainError(w, err)
+		return
 	}
 
-	lines := make([]logLine, 0, len(logs))
+	lines := make([]proxyLogLine, 0, len(logs))
 	for _, l := range logs {
-		lines = append(lines, logLine{
-			Timestamp: l.CapturedAt.Format("2006-01-02T15:04:05Z"),
-			Level:     l.LogLevel,
-			Message:   l.Message,
-		})
+		lines = append(lines, newProxyLogLine(l))
 	}
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
@@ -166,3 +185,115 @@ func (h *ProxyHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
 		"lines":    lines,
 	})
 }
+
+type proxyLogLine struct {
+	Timestamp string  `json:"timestamp"`
+	Level     *string `json:"level,omitempty"`
+	Tag       *string `json:"tag,omitempty"`
+	Message   *string `json:"message,omitempty"`
+}
+
+func newProxyLogLine(l domain.ProxyLog) proxyLogLine {
+	return proxyLogLine{
+		Timestamp: l.CapturedAt.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Level:     l.LogLevel,
+		Tag:       l.Tag,
+		Message:   l.Message,
+	}
+}
+
+const (
+	// logStreamHeartbeat keeps proxies and load balancers from closing an idle stream
+	logStreamHeartbeat = 15 * time.Second
+	// logStreamGrace waits for the helper's last batch after the capture ends
+	logStreamGrace = 10 * time.Second
+)
+
+// StreamLogs tails the log capture of a proxy as Server-Sent Events: the lines
+// already captured, then each line as the helper sends it ("log" events), and
+// an "end" event once capture_logs_until has passed.
+func (h *ProxyHandler) StreamLogs(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid proxy ID")
+		return
+	}
+
+	until, err := h.proxySvc.CaptureUntil(r.Context(), id)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	// Subscribe before reading the backlog so no line falls in between
+	sub := h.proxySvc.SubscribeLogs(id)
+	defer h.proxySvc.UnsubscribeLogs(sub)
+
+	backlog, err := h.proxySvc.GetLogs(r.Context(), id)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, data interface{}) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(logStreamHeartbeat + 10*time.Second))
+		payload, _ := json.Marshal(data)
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	// The backlog comes newest first
+	seen := make(map[uuid.UUID]bool, len(backlog))
+	for i := len(backlog) - 1; i >= 0; i-- {
+		seen[backlog[i].ID] = true
+		if !send("log", newProxyLogLine(backlog[i])) {
+			return
+		}
+	}
+
+	end := time.NewTimer(time.Until(until) + logStreamGrace)
+	defer end.Stop()
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case lines := <-sub.Lines():
+			for _, l := range lines {
+				if seen[l.ID] {
+					continue
+				}
+				if !send("log", newProxyLogLine(l)) {
+					return
+				}
+			}
+
+		case <-heartbeat.C:
+			_ = rc.SetWriteDeadline(time.Now().Add(logStreamHeartbeat + 10*time.Second))
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+
+		case <-end.C:
+			// The capture may have been extended meanwhile
+			if latest, err := h.proxySvc.CaptureUntil(r.Context(), id); err == nil && latest.After(until) {
+				until = latest
+				end.Reset(time.Until(until) + logStreamGrace)
+				continue
+			}
+			send("end", map[string]interface{}{"capture_until": until})
+			return
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"context"
+	"log"
 	"net/http"
 	"time"
 
@@ -11,6 +13,8 @@ import (
 
 	"github.com/ats-proxy/proxy-manager/backend/internal/config"
 	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/feed"
+	"github.com/ats-proxy/proxy-manager/backend/internal/notify"
 	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
 	"github.com/ats-proxy/proxy-manager/backend/internal/service"
 )
@@ -33,27 +37,79 @@ func NewRouter(pool *pgxpool.Pool, rdb *redis.Client, cfg *config.Config) http.H
 	ipRangeRuleRepo := repository.NewIPRangeRuleRepo(pool)
 	parentProxyRepo := repository.NewParentProxyRepo(pool)
 	clientACLRepo := repository.NewClientACLRepo(pool)
+	configRecordRepo := repository.NewConfigRecordRepo(pool)
 	proxyRepo := repository.NewProxyRepo(pool)
 	configProxyRepo := repository.NewConfigProxyRepo(pool)
+	configGroupRepo := repository.NewConfigGroupRepo(pool)
+	proxyGroupRepo := repository.NewProxyGroupRepo(pool)
+	configOverlayRepo := repository.NewConfigOverlayRepo(pool)
+	ruleSetRepo := repository.NewRuleSetRepo(pool)
+	configRuleSetRepo := repository.NewConfigRuleSetRepo(pool)
+	feedSourceRepo := repository.NewFeedSourceRepo(pool)
+	feedUpdateRepo := repository.NewFeedUpdateRepo(pool)
 	proxyStatsRepo := repository.NewProxyStatsRepo(pool)
 	proxyLogsRepo := repository.NewProxyLogsRepo(pool)
 	auditRepo := repository.NewAuditRepo(pool)
+	statsRollupRepo := repository.NewStatsRollupRepo(pool)
+	parentStatusRepo := repository.NewProxyParentStatusRepo(pool)
+	proxyDriftRepo := repository.NewProxyDriftRepo(pool)
+	proxyCommandRepo := repository.NewProxyCommandRepo(pool)
+	proxyDiagnosticsRepo := repository.NewProxyDiagnosticsRepo(pool)
+	accessLogRepo := repository.NewAccessLogRepo(pool)
+	alertRuleRepo := repository.NewAlertRuleRepo(pool)
+	alertRepo := repository.NewAlertRepo(pool)
+	webhookRepo := repository.NewWebhookRepo(pool)
+	webhookDeliveryRepo := repository.NewWebhookDeliveryRepo(pool)
+	notificationSubRepo := repository.NewNotificationSubscriptionRepo(pool)
+	enrollmentTokenRepo := repository.NewEnrollmentTokenRepo(pool)
+	proxyCredentialRepo := repository.NewProxyCredentialRepo(pool)
+	signingKeyRepo := repository.NewSigningKeyRepo(pool)
+
+	// Sync push notifications (long-poll wake-ups across replicas via Redis)
+	syncNotifier := service.NewSyncNotifier(rdb)
+	go syncNotifier.Run(context.Background())
+
+	// Live log tails (captured lines fanned out across replicas via Redis)
+	logStream := service.NewLogStream(rdb)
+	go logStream.Run(context.Background())
 
 	// Services
 	authSvc := service.NewAuthService(userRepo, sessionRepo, cfg.JWTSecret)
 	userSvc := service.NewUserService(userRepo, auditRepo)
-	configSvc := service.NewConfigService(pool, configRepo, domainRuleRepo, ipRangeRuleRepo, parentProxyRepo, clientACLRepo, configProxyRepo, auditRepo)
-	syncSvc := service.NewSyncService(proxyRepo, configRepo, configProxyRepo, proxyStatsRepo, proxyLogsRepo, configSvc, rdb)
-	proxySvc := service.NewProxyService(proxyRepo, proxyStatsRepo, proxyLogsRepo, configRepo, configProxyRepo, auditRepo)
+	webhookSvc := service.NewWebhookService(webhookRepo, webhookDeliveryRepo, auditRepo)
+	notificationSvc := service.NewNotificationService(newMailSender(cfg.SMTP), userRepo, notificationSubRepo, cfg.AppURL)
+	configSvc := service.NewConfigService(pool, configRepo, domainRuleRepo, ipRangeRuleRepo, parentProxyRepo, clientACLRepo, configRecordRepo, configProxyRepo, configGroupRepo, proxyGroupRepo, configOverlayRepo, configRuleSetRepo, auditRepo, webhookSvc, notificationSvc, syncNotifier)
+	syncAuthSvc := service.NewSyncAuthService(enrollmentTokenRepo, proxyCredentialRepo, proxyRepo, configRepo, proxyGroupRepo, auditRepo, syncNotifier, rdb, cfg.SyncAuthRequired)
+	commandSvc := service.NewCommandService(proxyCommandRepo, proxyRepo, auditRepo, syncNotifier)
+	diagnosticsSvc := service.NewDiagnosticsService(proxyDiagnosticsRepo, proxyCommandRepo, proxyRepo, auditRepo, cfg.DiagnosticsTTL)
+	accessLogSvc := service.NewAccessLogService(accessLogRepo, cfg.AccessLogRetention)
+	syncSvc := service.NewSyncService(pool, proxyRepo, configRepo, configProxyRepo, proxyStatsRepo, proxyLogsRepo, parentStatusRepo, proxyDriftRepo, configSvc, commandSvc, diagnosticsSvc, accessLogSvc, webhookSvc, notificationSvc, syncAuthSvc, syncNotifier, logStream, service.NewBundleSigner(signingKeyRepo))
+	proxySvc := service.NewProxyService(proxyRepo, proxyStatsRepo, proxyLogsRepo, configRepo, configProxyRepo, proxyDriftRepo, auditRepo, syncNotifier, logStream)
+	groupSvc := service.NewGroupService(proxyGroupRepo, proxyRepo, configOverlayRepo, auditRepo, syncNotifier)
+	ruleSetSvc := service.NewRuleSetService(pool, ruleSetRepo, feedSourceRepo, auditRepo)
+	feedSvc := service.NewFeedService(feedSourceRepo, feedUpdateRepo, ruleSetRepo, auditRepo, feed.NewSourceFetcher(nil, cfg.FeedDir), cfg.FeedDir)
 	auditSvc := service.NewAuditService(auditRepo, userRepo)
+	statsSvc := service.NewStatsService(proxyRepo, configRepo, statsRollupRepo, cfg.StatsRetention)
+	alertSvc := service.NewAlertService(alertRuleRepo, alertRepo, auditRepo, webhookSvc)
 
 	// Handlers
 	authH := NewAuthHandler(authSvc)
 	userH := NewUserHandler(userSvc)
 	configH := NewConfigHandler(configSvc)
-	syncH := NewSyncHandler(syncSvc)
+	syncH := NewSyncHandler(syncSvc, syncAuthSvc)
 	proxyH := NewProxyHandler(proxySvc)
+	groupH := NewGroupHandler(groupSvc)
+	ruleSetH := NewRuleSetHandler(ruleSetSvc)
+	feedH := NewFeedHandler(feedSvc, ruleSetSvc)
+	commandH := NewCommandHandler(commandSvc)
+	diagnosticsH := NewDiagnosticsHandler(diagnosticsSvc)
+	accessLogH := NewAccessLogHandler(accessLogSvc)
 	auditH := NewAuditHandler(auditSvc)
+	statsH := NewStatsHandler(statsSvc)
+	alertH := NewAlertHandler(alertSvc)
+	webhookH := NewWebhookHandler(webhookSvc)
+	notificationH := NewNotificationHandler(notificationSvc)
+	syncAuthH := NewSyncAuthHandler(syncAuthSvc)
 
 	r.Route("/api/v1", func(r chi.Router) {
 		// Health
@@ -68,13 +124,27 @@ func NewRouter(pool *pgxpool.Pool, rdb *redis.Client, cfg *config.Config) http.H
 		r.Post("/auth/login", authH.Login)
 		r.Post("/auth/refresh", authH.Refresh)
 
-		// Sync (no auth - called by Helper)
+		// Sync (called by Helper, signed with the per-proxy secret)
 		r.Route("/sync", func(r chi.Router) {
-			r.Post("/register", syncH.Register)
-			r.Get("/", syncH.GetConfig)
-			r.Post("/ack", syncH.Ack)
-			r.Post("/stats", syncH.Stats)
-			r.Post("/logs", syncH.Logs)
+			r.Use(SyncMTLSMiddleware(cfg.TLS.SyncMTLSRequired))
+
+			// Register also accepts an enrollment token instead of a signature
+			r.With(SyncAuthMiddleware(syncAuthSvc, false)).Post("/register", syncH.Register)
+
+			// Public key of the config bundle signatures (no secret involved)
+			r.Get("/signing-key", syncH.SigningKey)
+
+			r.Group(func(r chi.Router) {
+				r.Use(SyncAuthMiddleware(syncAuthSvc, syncAuthSvc.Required()))
+				r.Get("/", syncH.GetConfig)
+				r.Post("/ack", syncH.Ack)
+				r.Post("/stats", syncH.Stats)
+				r.Post("/logs", syncH.Logs)
+				r.Post("/drift", syncH.Drift)
+				r.Post("/command-result", syncH.CommandResult)
+				r.Post("/diagnostics", syncH.Diagnostics)
+				r.Post("/access-logs", syncH.AccessLogs)
+			})
 		})
 
 		// Protected routes
@@ -85,6 +155,10 @@ func NewRouter(pool *pgxpool.Pool, rdb *redis.Client, cfg *config.Config) http.H
 			r.Post("/auth/beacon", authH.Beacon)
 			r.Post("/auth/logout", authH.Logout)
 
+			// Email notification subscriptions of the current user
+			r.Get("/me/notifications", notificationH.Get)
+			r.Put("/me/notifications", notificationH.Update)
+
 			// Users
 			r.Route("/users", func(r chi.Router) {
 				r.Use(RequireRole(domain.RoleRoot, domain.RoleAdmin))
@@ -98,6 +172,7 @@ func NewRouter(pool *pgxpool.Pool, rdb *redis.Client, cfg *config.Config) http.H
 			r.Route("/configs", func(r chi.Router) {
 				r.Get("/", configH.List)
 				r.Post("/", configH.Create)
+				r.Get("/records", configH.ManagedRecords)
 				r.Get("/{id}", configH.GetByID)
 				r.Put("/{id}", configH.Update)
 				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Delete("/{id}", configH.Delete)
@@ -114,8 +189,96 @@ func NewRouter(pool *pgxpool.Pool, rdb *redis.Client, cfg *config.Config) http.H
 				r.Get("/{id}", proxyH.GetByID)
 				r.Post("/{id}/logs", proxyH.StartLogCapture)
 				r.Get("/{id}/logs", proxyH.GetLogs)
+				r.Get("/{id}/logs/stream", proxyH.StreamLogs)
+				r.Get("/{id}/stats", statsH.ProxySeries)
+				r.Get("/{id}/commands", commandH.List)
+				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Post("/{id}/commands", commandH.Enqueue)
+				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Delete("/{id}/commands/{commandID}", commandH.Cancel)
+				r.Get("/{id}/diagnostics", diagnosticsH.List)
+				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Get("/{id}/diagnostics/{bundleID}", diagnosticsH.Download)
 				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Put("/{id}/config", proxyH.AssignConfig)
+				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Put("/{id}/labels", proxyH.SetLabels)
 				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Delete("/{id}", proxyH.Delete)
+
+				// Sync credentials
+				r.Group(func(r chi.Router) {
+					r.Use(RequireRole(domain.RoleRoot, domain.RoleAdmin))
+					r.Get("/{id}/credentials", syncAuthH.Credentials)
+					r.Post("/{id}/credentials/rotate", syncAuthH.Rotate)
+					r.Delete("/{id}/credentials", syncAuthH.Revoke)
+				})
+			})
+
+			// Proxy groups (label selectors configs can be assigned to)
+			r.Route("/groups", func(r chi.Router) {
+				r.Get("/", groupH.List)
+				r.Get("/{id}/proxies", groupH.Proxies)
+				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Post("/", groupH.Create)
+				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Put("/{id}", groupH.Update)
+				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Delete("/{id}", groupH.Delete)
+			})
+
+			// Rule sets (versioned domain/CIDR lists referenced by configs)
+			r.Route("/rule-sets", func(r chi.Router) {
+				r.Get("/", ruleSetH.List)
+				r.Get("/{id}", ruleSetH.Get)
+				r.Get("/{id}/versions", ruleSetH.Versions)
+				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Post("/", ruleSetH.Create)
+				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Put("/{id}", ruleSetH.Update)
+				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Delete("/{id}", ruleSetH.Delete)
+			})
+
+			// Feed sources (external lists staged as rule set updates)
+			r.Route("/feeds", func(r chi.Router) {
+				r.Get("/", feedH.List)
+				r.Get("/formats", feedH.Formats)
+				r.Get("/{id}", feedH.Get)
+				r.Get("/{id}/updates", feedH.Updates)
+				r.Group(func(r chi.Router) {
+					r.Use(RequireRole(domain.RoleRoot, domain.RoleAdmin))
+					r.Post("/", feedH.Create)
+					r.Put("/{id}", feedH.Update)
+					r.Delete("/{id}", feedH.Delete)
+					r.Post("/{id}/refresh", feedH.Refresh)
+					r.Post("/{id}/updates/{updateID}/apply", feedH.Apply)
+					r.Post("/{id}/updates/{updateID}/discard", feedH.Discard)
+				})
+			})
+
+			// Enrollment tokens
+			r.Route("/enrollment-tokens", func(r chi.Router) {
+				r.Use(RequireRole(domain.RoleRoot, domain.RoleAdmin))
+				r.Get("/", syncAuthH.ListTokens)
+				r.Post("/", syncAuthH.CreateToken)
+				r.Delete("/{id}", syncAuthH.RevokeToken)
+			})
+
+			// Stats
+			r.Get("/stats", statsH.Fleet)
+
+			// Alerts
+			r.Route("/alerts", func(r chi.Router) {
+				r.Get("/", alertH.List)
+				r.Get("/rules", alertH.ListRules)
+				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Post("/rules", alertH.CreateRule)
+				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Put("/rules/{id}", alertH.UpdateRule)
+				r.With(RequireRole(domain.RoleRoot, domain.RoleAdmin)).Delete("/rules/{id}", alertH.DeleteRule)
+			})
+
+			// Webhooks
+			r.Route("/webhooks", func(r chi.Router) {
+				r.Use(RequireRole(domain.RoleRoot, domain.RoleAdmin))
+				r.Get("/", webhookH.List)
+				r.Post("/", webhookH.Create)
+				r.Put("/{id}", webhookH.Update)
+				r.Delete("/{id}", webhookH.Delete)
+				r.Get("/{id}/deliveries", webhookH.Deliveries)
+			})
+
+			// Access logs shipped by the proxies
+			r.Route("/access-logs", func(r chi.Router) {
+				r.Use(RequireRole(domain.RoleRoot, domain.RoleAdmin))
+				r.Get("/", accessLogH.List)
 			})
 
 			// Audit
@@ -128,3 +291,19 @@ func NewRouter(pool *pgxpool.Pool, rdb *redis.Client, cfg *config.Config) http.H
 
 	return r
 }
+
+// newMailSender returns the SMTP sender, or nil (email disabled) when no host is configured.
+func newMailSender(smtp config.SMTP) notify.Sender {
+	if smtp.Host == "" {
+		log.Println("SMTP_HOST not set, email notifications disabled")
+		return nil
+	}
+	return notify.NewSMTPSender(notify.SMTPConfig{
+		Host:     smtp.Host,
+		Port:     smtp.Port,
+		Username: smtp.Username,
+		Password: smtp.Password,
+		From:     smtp.From,
+		TLSMode:  smtp.TLSMode,
+	})
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/service"
+)
+
+type RuleSetHandler struct {
+	ruleSetSvc *service.RuleSetService
+}
+
+func NewRuleSetHandler(ruleSetSvc *service.RuleSetService) *RuleSetHandler {
+	return &RuleSetHandler{ruleSetSvc: ruleSetSvc}
+}
+
+func (h *RuleSetHandler) List(w http.ResponseWriter, r *http.Request) {
+	sets, err := h.ruleSetSvc.List(r.Context())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"data": sets})
+}
+
+// Get returns a rule set with its current rules or, with ?version=N, the rules
+// of that version.
+func (h *RuleSetHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid rule set ID")
+		return
+	}
+
+	if v := r.URL.Query().Get("version"); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil || version < 1 {
+			respondError(w, http.StatusBadRequest, "bad_request", "Invalid version")
+			return
+		}
+		rsv, err := h.ruleSetSvc.GetVersion(r.Context(), id, version)
+		if err != nil {
+			respondDomainError(w, err)
+			return
+		}
+		respondJSON(w, http.StatusOK, rsv)
+		return
+	}
+
+	rs, err := h.ruleSetSvc.Get(r.Context(), id)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, rs)
+}
+
+func (h *RuleSetHandler) Versions(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid rule set ID")
+		return
+	}
+
+	versions, err := h.ruleSetSvc.ListVersions(r.Context(), id)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"data": versions})
+}
+
+func (h *RuleSetHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req service.RuleSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	rs, err := h.ruleSetSvc.Create(r.Context(), req, getUserID(r.Context()), clientIP(r), r.UserAgent())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, rs)
+}
+
+func (h *RuleSetHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid rule set ID")
+		return
+	}
+
+	var req service.RuleSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	result, err := h.ruleSetSvc.Update(r.Context(), id, req, getUserID(r.Context()), clientIP(r), r.UserAgent())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+func (h *RuleSetHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid rule set ID")
+		return
+	}
+
+	if err := h.ruleSetSvc.Delete(r.Context(), id, getUserID(r.Context()), clientIP(r), r.UserAgent()); err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
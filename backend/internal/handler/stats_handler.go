@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/service"
+)
+
+type StatsHandler struct {
+	statsSvc *service.StatsService
+}
+
+func NewStatsHandler(statsSvc *service.StatsService) *StatsHandler {
+	return &StatsHandler{statsSvc: statsSvc}
+}
+
+// ProxySeries returns the stats time series of a single proxy.
+func (h *StatsHandler) ProxySeries(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid proxy ID")
+		return
+	}
+
+	rng, err := parseStatsRange(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	resp, err := h.statsSvc.ProxySeries(r.Context(), id, rng)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// Fleet returns stats series grouped by proxy, config or for the whole fleet.
+func (h *StatsHandler) Fleet(w http.ResponseWriter, r *http.Request) {
+	rng, err := parseStatsRange(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	groupBy := domain.StatsGroupBy(r.URL.Query().Get("group_by"))
+
+	resp, err := h.statsSvc.FleetSeries(r.Context(), groupBy, rng)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// parseStatsRange reads from/to (RFC3339) and step from the query string.
+// Defaults to the last hour; when step is omitted the range is split in ~60 points.
+func parseStatsRange(r *http.Request) (service.StatsRange, error) {
+	now := time.Now().UTC()
+	rng := service.StatsRange{From: now.Add(-time.Hour), To: now}
+
+	if f := r.URL.Query().Get("from"); f != "" {
+		t, err := time.Parse(time.RFC3339, f)
+		if err != nil {
+			return rng, fmt.Errorf("invalid 'from', expected RFC3339")
+		}
+		rng.From = t
+	}
+	if t := r.URL.Query().Get("to"); t != "" {
+		parsed, err := time.Parse(time.RFC3339, t)
+		if err != nil {
+			return rng, fmt.Errorf("invalid 'to', expected RFC3339")
+		}
+		rng.To = parsed
+	}
+
+	if st := r.URL.Query().Get("step"); st != "" {
+		step, err := parseStep(st)
+		if err != nil {
+			return rng, fmt.Errorf("invalid 'step', use e.g. 30s, 5m, 1h or 1d")
+		}
+		rng.Step = step
+	} else {
+		rng.Step = (rng.To.Sub(rng.From) / 60).Truncate(time.Second)
+		if rng.Step < time.Minute {
+			rng.Step = time.Minute
+		}
+	}
+
+	return rng, nil
+}
+
+// parseStep accepts Go durations plus a "d" suffix for days.
+func parseStep(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid step %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
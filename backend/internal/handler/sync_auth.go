@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/service"
+)
+
+const (
+	ctxSyncProxy  contextKey = "sync_proxy"
+	ctxClientCert contextKey = "client_cert"
+)
+
+// maxSyncBody bounds the body read for signature verification.
+const maxSyncBody = 10 << 20
+
+func getSyncProxy(ctx context.Context) *domain.Proxy {
+	if v, ok := ctx.Value(ctxSyncProxy).(*domain.Proxy); ok {
+		return v
+	}
+	return nil
+}
+
+func getClientCert(ctx context.Context) *x509.Certificate {
+	if v, ok := ctx.Value(ctxClientCert).(*x509.Certificate); ok {
+		return v
+	}
+	return nil
+}
+
+// certFingerprint is the hex SHA-256 of the DER certificate.
+func certFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// certMatchesHostname accepts the hostname as the certificate CN or one of its SANs.
+func certMatchesHostname(cert *x509.Certificate, hostname string) bool {
+	return strings.EqualFold(cert.Subject.CommonName, hostname) || cert.VerifyHostname(hostname) == nil
+}
+
+// syncHostnameAllowed checks that a signed request only acts on its own proxy
+// and that a client certificate, when presented, was issued to that hostname.
+// Unsigned requests (allowed when sync auth is optional) are further checked by
+// SyncHandler.syncRequestAllowed.
+func syncHostnameAllowed(r *http.Request, hostname string) bool {
+	if proxy := getSyncProxy(r.Context()); proxy != nil && proxy.Hostname != hostname {
+		return false
+	}
+	if cert := getClientCert(r.Context()); cert != nil && !certMatchesHostname(cert, hostname) {
+		return false
+	}
+	return true
+}
+
+// clientCertPinned checks the request's client certificate against the
+// fingerprint stored for the proxy at registration. Proxies registered without
+// a certificate are not pinned.
+func clientCertPinned(r *http.Request, proxy *domain.Proxy) bool {
+	if proxy.ClientCertFingerprint == nil || *proxy.ClientCertFingerprint == "" {
+		return true
+	}
+	cert := getClientCert(r.Context())
+	return cert != nil && certFingerprint(cert) == *proxy.ClientCertFingerprint
+}
+
+// SyncMTLSMiddleware puts the verified client certificate in the context.
+// With required set, requests without one are rejected.
+func SyncMTLSMiddleware(required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+				if required {
+					respondError(w, http.StatusUnauthorized, "unauthorized", "Verified client certificate required")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cert := r.TLS.VerifiedChains[0][0]
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxClientCert, cert)))
+		})
+	}
+}
+
+// SyncAuthMiddleware verifies the HMAC signature helpers send in X-Proxy-ID,
+// X-Sync-Timestamp, X-Sync-Nonce and X-Sync-Signature and puts the proxy in the context.
+// A proxy registered with a client certificate must keep presenting it.
+// Unsigned requests pass through unless required is set.
+func SyncAuthMiddleware(authSvc *service.SyncAuthService, required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxyID := r.Header.Get("X-Proxy-ID")
+			if proxyID == "" {
+				if required {
+					respondError(w, http.StatusUnauthorized, "unauthorized", "Missing sync signature")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSyncBody))
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			proxy, err := authSvc.Authenticate(r.Context(), proxyID,
+				r.Header.Get("X-Sync-Timestamp"), r.Header.Get("X-Sync-Nonce"), r.Header.Get("X-Sync-Signature"),
+				r.Method, r.URL.RequestURI(), body)
+			if err != nil {
+				respondDomainError(w, err)
+				return
+			}
+			if !clientCertPinned(r, proxy) {
+				respondError(w, http.StatusUnauthorized, "unauthorized", "Client certificate does not match the one registered for this proxy")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxSyncProxy, proxy)))
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/service"
+)
+
+type SyncAuthHandler struct {
+	syncAuthSvc *service.SyncAuthService
+}
+
+func NewSyncAuthHandler(syncAuthSvc *service.SyncAuthService) *SyncAuthHandler {
+	return &SyncAuthHandler{syncAuthSvc: syncAuthSvc}
+}
+
+func (h *SyncAuthHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.syncAuthSvc.ListTokens(r.Context())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"data": tokens})
+}
+
+func (h *SyncAuthHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req service.EnrollmentTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	token, err := h.syncAuthSvc.CreateToken(r.Context(), req, getUserID(r.Context()), clientIP(r), r.UserAgent())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, token)
+}
+
+func (h *SyncAuthHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid token ID")
+		return
+	}
+
+	if err := h.syncAuthSvc.RevokeToken(r.Context(), id, getUserID(r.Context()), clientIP(r), r.UserAgent()); err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SyncAuthHandler) Credentials(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid proxy ID")
+		return
+	}
+
+	cred, err := h.syncAuthSvc.Credentials(r.Context(), id)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, cred)
+}
+
+func (h *SyncAuthHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid proxy ID")
+		return
+	}
+
+	cred, err := h.syncAuthSvc.Rotate(r.Context(), id, getUserID(r.Context()), clientIP(r), r.UserAgent())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, cred)
+}
+
+func (h *SyncAuthHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid proxy ID")
+		return
+	}
+
+	if err := h.syncAuthSvc.Revoke(r.Context(), id, getUserID(r.Context()), clientIP(r), r.UserAgent()); err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,18 +2,51 @@ package handler
 
 import (
 	"encoding/json"
+	"io"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/ats-proxy/proxy-manager/backend/internal/service"
 )
 
 type SyncHandler struct {
-	syncSvc *service.SyncService
+	syncSvc     *service.SyncService
+	syncAuthSvc *service.SyncAuthService
 }
 
-func NewSyncHandler(syncSvc *service.SyncService) *SyncHandler {
-	return &SyncHandler{syncSvc: syncSvc}
+func NewSyncHandler(syncSvc *service.SyncService, syncAuthSvc *service.SyncAuthService) *SyncHandler {
+	return &SyncHandler{syncSvc: syncSvc, syncAuthSvc: syncAuthSvc}
+}
+
+func respondHostnameMismatch(w http.ResponseWriter) {
+	respondError(w, http.StatusForbidden, "forbidden", "Hostname does not match the proxy credentials or client certificate")
+}
+
+// syncRequestAllowed applies syncHostnameAllowed and, for an unsigned request,
+// refuses a proxy that already has sync credentials: optional sync auth only
+// lets through proxies that have not enrolled yet. It writes the error response.
+func (h *SyncHandler) syncRequestAllowed(w http.ResponseWriter, r *http.Request, hostname string) bool {
+	if !syncHostnameAllowed(r, hostname) {
+		respondHostnameMismatch(w)
+		return false
+	}
+	if getSyncProxy(r.Context()) != nil {
+		return true
+	}
+	ok, err := h.syncAuthSvc.AcceptsUnsigned(r.Context(), hostname)
+	if err != nil {
+		respondDomainError(w, err)
+		return false
+	}
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "This proxy is enrolled; requests must be signed with its credentials")
+		return false
+	}
+	return true
 }
 
 func (h *SyncHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -23,8 +56,17 @@ func (h *SyncHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !syncHostnameAllowed(r, req.Hostname) {
+		respondHostnameMismatch(w)
+		return
+	}
+
 	// Extract client IP
 	req.RemoteIP = extractIP(r)
+	req.Authenticated = getSyncProxy(r.Context())
+	if cert := getClientCert(r.Context()); cert != nil {
+		req.ClientCertFingerprint = certFingerprint(cert)
+	}
 
 	resp, err := h.syncSvc.Register(r.Context(), req)
 	if err != nil {
@@ -67,16 +109,70 @@ func (h *SyncHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "bad_request", "hostname query param required")
 		return
 	}
+	if !h.syncRequestAllowed(w, r, hostname) {
+		return
+	}
 
-	resp, err := h.syncSvc.GetConfig(r.Context(), hostname, hash)
+	// Long-poll: ?wait=25s (or seconds) holds the request until the config changes
+	wait, err := parseSyncWait(r.URL.Query().Get("wait"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid wait parameter")
+		return
+	}
+	if wait > 0 {
+		// The server WriteTimeout is shorter than the longest wait
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(service.MaxSyncWait + 10*time.Second))
+	}
+
+	// Commands the helper got in its previous sync: ?received=<id>,<id>
+	var received []uuid.UUID
+	if v := r.URL.Query().Get("received"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			id, err := uuid.Parse(s)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "bad_request", "Invalid received command id")
+				return
+			}
+			received = append(received, id)
+		}
+	}
+
+	resp, err := h.syncSvc.WaitForConfig(r.Context(), hostname, hash, received, wait)
 	if err != nil {
 		respondDomainError(w, err)
 		return
 	}
 
+	// Hand a pending rotated secret only to the proxy that proved its identity
+	if proxy := getSyncProxy(r.Context()); proxy != nil {
+		resp.RotateSecret = h.syncAuthSvc.PendingSecret(r.Context(), proxy.ID)
+	}
+
 	respondJSON(w, http.StatusOK, resp)
 }
 
+// parseSyncWait accepts a Go duration ("25s") or a number of seconds.
+func parseSyncWait(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// SigningKey returns the Ed25519 public key config bundles are signed with.
+func (h *SyncHandler) SigningKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.syncSvc.SigningKey(r.Context())
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, key)
+}
+
 func (h *SyncHandler) Ack(w http.ResponseWriter, r *http.Request) {
 	var req service.AckRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -84,6 +180,10 @@ func (h *SyncHandler) Ack(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.syncRequestAllowed(w, r, req.Hostname) {
+		return
+	}
+
 	if err := h.syncSvc.Ack(r.Context(), req); err != nil {
 		respondDomainError(w, err)
 		return
@@ -99,6 +199,10 @@ func (h *SyncHandler) Stats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.syncRequestAllowed(w, r, req.Hostname) {
+		return
+	}
+
 	if err := h.syncSvc.Stats(r.Context(), req); err != nil {
 		respondDomainError(w, err)
 		return
@@ -107,6 +211,89 @@ func (h *SyncHandler) Stats(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]bool{"received": true})
 }
 
+func (h *SyncHandler) Drift(w http.ResponseWriter, r *http.Request) {
+	var req service.SyncDriftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	if !h.syncRequestAllowed(w, r, req.Hostname) {
+		return
+	}
+
+	drift, err := h.syncSvc.Drift(r.Context(), req)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, drift)
+}
+
+func (h *SyncHandler) CommandResult(w http.ResponseWriter, r *http.Request) {
+	var req service.CommandResultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	if !h.syncRequestAllowed(w, r, req.Hostname) {
+		return
+	}
+
+	cmd, err := h.syncSvc.CommandResult(r.Context(), req)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, cmd)
+}
+
+// Diagnostics receives a diagnostics tar.gz as the raw body; hostname and the
+// optional command_id come in the query string.
+func (h *SyncHandler) Diagnostics(w http.ResponseWriter, r *http.Request) {
+	hostname := r.URL.Query().Get("hostname")
+	if !h.syncRequestAllowed(w, r, hostname) {
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, service.MaxDiagnosticsSize+1))
+	if err != nil {
+		respondError(w, http.StatusRequestEntityTooLarge, "bad_request", "Bundle too large")
+		return
+	}
+
+	bundle, err := h.syncSvc.Diagnostics(r.Context(), hostname, r.URL.Query().Get("command_id"), data)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, bundle)
+}
+
+func (h *SyncHandler) AccessLogs(w http.ResponseWriter, r *http.Request) {
+	var req service.SyncAccessLogsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+
+	if !h.syncRequestAllowed(w, r, req.Hostname) {
+		return
+	}
+
+	result, err := h.syncSvc.AccessLogs(r.Context(), req)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
 func (h *SyncHandler) Logs(w http.ResponseWriter, r *http.Request) {
 	var req service.SyncLogsRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -114,6 +301,10 @@ func (h *SyncHandler) Logs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.syncRequestAllowed(w, r, req.Hostname) {
+		return
+	}
+
 	resp, err := h.syncSvc.Logs(r.Context(), req)
 	if err != nil {
 		respondDomainError(w, err)
@@ -221,6 +221,43 @@ func columnExists(ctx context.Context, pool *pgxpool.Pool, table, column string)
 	return exists, nil
 }
 
+func indexExists(ctx context.Context, pool *pgxpool.Pool, name string) (bool, error) {
+	var exists bool
+	err := pool.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM pg_indexes WHERE schemaname='public' AND indexname=$1)",
+		name,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("migrate: check index %s: %w", name, err)
+	}
+	return exists, nil
+}
+
+// foreignKeyRestricts reports whether a foreign key constraint is ON DELETE RESTRICT.
+func foreignKeyRestricts(ctx context.Context, pool *pgxpool.Pool, name string) (bool, error) {
+	var restricts bool
+	err := pool.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM pg_constraint WHERE contype='f' AND conname=$1 AND confdeltype='r')",
+		name,
+	).Scan(&restricts)
+	if err != nil {
+		return false, fmt.Errorf("migrate: check foreign key %s: %w", name, err)
+	}
+	return restricts, nil
+}
+
+func enumValueExists(ctx context.Context, pool *pgxpool.Pool, typ, value string) (bool, error) {
+	var exists bool
+	err := pool.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM pg_enum e JOIN pg_type t ON t.oid = e.enumtypid WHERE t.typname=$1 AND e.enumlabel=$2)",
+		typ, value,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("migrate: check enum value %s.%s: %w", typ, value, err)
+	}
+	return exists, nil
+}
+
 // bootstrapExistingDB detects which migrations have already been applied
 // by checking for their footprint (specific tables/columns) and records them.
 func bootstrapExistingDB(ctx context.Context, pool *pgxpool.Pool, migrations []migration) error {
@@ -236,6 +273,33 @@ func bootstrapExistingDB(ctx context.Context, pool *pgxpool.Pool, migrations []m
 		{3, func() (bool, error) { return tableExists(ctx, pool, "client_acl_rules") }},
 		{4, func() (bool, error) { return columnExists(ctx, pool, "proxies", "registered_ip") }},
 		{5, func() (bool, error) { return columnExists(ctx, pool, "configs", "default_action") }},
+		{6, func() (bool, error) { return columnExists(ctx, pool, "proxy_stats", "total_requests_delta") }},
+		{7, func() (bool, error) { return tableExists(ctx, pool, "proxy_stats_1d") }},
+		{8, func() (bool, error) { return tableExists(ctx, pool, "alert_rules") }},
+		{9, func() (bool, error) { return tableExists(ctx, pool, "webhooks") }},
+		{10, func() (bool, error) { return tableExists(ctx, pool, "notification_subscriptions") }},
+		{11, func() (bool, error) { return tableExists(ctx, pool, "enrollment_tokens") }},
+		{12, func() (bool, error) { return columnExists(ctx, pool, "proxies", "client_cert_fingerprint") }},
+		{13, func() (bool, error) { return tableExists(ctx, pool, "signing_keys") }},
+		{14, func() (bool, error) { return tableExists(ctx, pool, "proxy_drift") }},
+		{15, func() (bool, error) { return tableExists(ctx, pool, "config_records") }},
+		{16, func() (bool, error) { return tableExists(ctx, pool, "proxy_commands") }},
+		{17, func() (bool, error) { return tableExists(ctx, pool, "proxy_diagnostics") }},
+		{18, func() (bool, error) { return tableExists(ctx, pool, "access_logs") }},
+		{19, func() (bool, error) { return columnExists(ctx, pool, "proxy_logs", "tag") }},
+		{20, func() (bool, error) { return tableExists(ctx, pool, "config_groups") }},
+		{21, func() (bool, error) { return tableExists(ctx, pool, "config_overlays") }},
+		{22, func() (bool, error) { return tableExists(ctx, pool, "config_rule_sets") }},
+		{23, func() (bool, error) { return tableExists(ctx, pool, "feed_sources") }},
+		{24, func() (bool, error) { return enumValueExists(ctx, pool, "rule_action", "deny") }},
+		{25, func() (bool, error) { return columnExists(ctx, pool, "client_acl_rules", "methods") }},
+		{26, func() (bool, error) { return columnExists(ctx, pool, "configs", "connect_ports") }},
+		{27, func() (bool, error) { return columnExists(ctx, pool, "domain_rules", "tls_policy") }},
+		{28, func() (bool, error) { return tableExists(ctx, pool, "stats_rollup_watermarks") }},
+		{29, func() (bool, error) { return columnExists(ctx, pool, "enrollment_tokens", "group_id") }},
+		{30, func() (bool, error) { return indexExists(ctx, pool, "idx_access_logs_host_reverse") }},
+		{31, func() (bool, error) { return columnExists(ctx, pool, "proxies", "helper_labels") }},
+		{32, func() (bool, error) { return foreignKeyRestricts(ctx, pool, "config_overlays_group_id_fkey") }},
 	}
 
 	// Build a filename lookup from loaded migrations
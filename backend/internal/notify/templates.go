@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// Template names, one per notification kind.
+const (
+	TemplateConfigSubmitted = "config_submitted"
+	TemplateConfigApproved  = "config_approved"
+	TemplateConfigRejected  = "config_rejected"
+	TemplateDeployFailed    = "deploy_failed"
+)
+
+// Each template defines a "subject" and a "body" block.
+var templateSources = map[string]string{
+	TemplateConfigSubmitted: `{{define "subject"}}[ATS Proxy Manager] Config "{{.ConfigName}}" aguardando aprovação{{end}}
+{{define "body"}}A config "{{.ConfigName}}" (versão {{.Version}}) foi enviada para aprovação por {{.Actor}}.
+
+Revise em: {{.URL}}
+{{end}}`,
+
+	TemplateConfigApproved: `{{define "subject"}}[ATS Proxy Manager] Config "{{.ConfigName}}" aprovada{{end}}
+{{define "body"}}A config "{{.ConfigName}}" (versão {{.Version}}) foi aprovada por {{.Actor}} e está ativa.
+
+Detalhes: {{.URL}}
+{{end}}`,
+
+	TemplateConfigRejected: `{{define "subject"}}[ATS Proxy Manager] Config "{{.ConfigName}}" rejeitada{{end}}
+{{define "body"}}A config "{{.ConfigName}}" (versão {{.Version}}) foi rejeitada por {{.Actor}} e voltou para rascunho.
+{{if .Reason}}
+Motivo: {{.Reason}}
+{{end}}
+Detalhes: {{.URL}}
+{{end}}`,
+
+	TemplateDeployFailed: `{{define "subject"}}[ATS Proxy Manager] Falha ao aplicar config em {{.Hostname}}{{end}}
+{{define "body"}}O proxy {{.Hostname}} não conseguiu aplicar a config (hash {{.Hash}}).
+
+Erro: {{.Reason}}
+
+Detalhes: {{.URL}}
+{{end}}`,
+}
+
+// TemplateData is the data available to every template.
+type TemplateData struct {
+	ConfigName string
+	Version    int
+	Actor      string
+	Reason     string
+	Hostname   string
+	Hash       string
+	URL        string
+}
+
+// Templates renders notification subjects and bodies.
+type Templates struct {
+	set map[string]*template.Template
+}
+
+// DefaultTemplates parses the built-in templates.
+func DefaultTemplates() *Templates {
+	t := &Templates{set: make(map[string]*template.Template)}
+	for name, src := range templateSources {
+		t.set[name] = template.Must(template.New(name).Parse(src))
+	}
+	return t
+}
+
+// Render returns the subject and body of the named template.
+func (t *Templates) Render(name string, data TemplateData) (subject, body string, err error) {
+	tpl, ok := t.set[name]
+	if !ok {
+		return "", "", fmt.Errorf("unknown template %q", name)
+	}
+
+	var sb, bb bytes.Buffer
+	if err := tpl.ExecuteTemplate(&sb, "subject", data); err != nil {
+		return "", "", fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := tpl.ExecuteTemplate(&bb, "body", data); err != nil {
+		return "", "", fmt.Errorf("render %s body: %w", name, err)
+	}
+	return sb.String(), bb.String(), nil
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type AccessLogRepo struct {
+	db DBTX
+}
+
+func NewAccessLogRepo(db DBTX) *AccessLogRepo {
+	return &AccessLogRepo{db: db}
+}
+
+// InsertBatch stores the entries of one proxy in a single statement.
+func (r *AccessLogRepo) InsertBatch(ctx context.Context, proxyID uuid.UUID, entries []domain.AccessLog) (int64, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	n := len(entries)
+	ts := make([]time.Time, n)
+	clientIPs := make([]string, n)
+	usernames := make([]*string, n)
+	methods := make([]string, n)
+	urls := make([]string, n)
+	hosts := make([]string, n)
+	statuses := make([]int16, n)
+	sizes := make([]int64, n)
+	durations := make([]*int32, n)
+	routes := make([]*string, n)
+	upstreams := make([]*string, n)
+	userAgents := make([]*string, n)
+	for i, e := range entries {
+		ts[i] = e.Timestamp
+		clientIPs[i] = e.ClientIP
+		usernames[i] = e.Username
+		methods[i] = e.Method
+		urls[i] = e.URL
+		hosts[i] = e.Host
+		statuses[i] = int16(e.Status)
+		sizes[i] = e.Bytes
+		if e.DurationMs != nil {
+			d := int32(*e.DurationMs)
+			durations[i] = &d
+		}
+		routes[i] = e.Route
+		upstreams[i] = e.Upstream
+		userAgents[i] = e.UserAgent
+	}
+
+	tag, err := r.db.Exec(ctx,
+		`INSERT INTO access_logs (ts, proxy_id, client_ip, username, method, url, host, status, bytes, duration_ms, route, upstream, user_agent)
+		 SELECT t.ts, $1, t.client_ip::inet, t.username, t.method, t.url, t.host, t.status, t.bytes, t.duration_ms, t.route, t.upstream, t.user_agent
+		 FROM unnest($2::timestamptz[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[],
+		             $8::smallint[], $9::bigint[], $10::integer[], $11::text[], $12::text[], $13::text[])
+		      AS t(ts, client_ip, username, method, url, host, status, bytes, duration_ms, route, upstream, user_agent)`,
+		proxyID, ts, clientIPs, usernames, methods, urls, hosts, statuses, sizes, durations, routes, upstreams, userAgents,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("insert access logs: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// AccessLogFilter narrows a search. From and To are required so the query only
+// touches the partitions in range.
+type AccessLogFilter struct {
+	ProxyID   *uuid.UUID
+	ClientIP  *string // address or CIDR
+	Host      *string // the host and its subdomains
+	Username  *string
+	StatusMin *int
+	StatusMax *int
+	From      time.Time
+	To        time.Time
+}
+
+func (r *AccessLogRepo) Search(ctx context.Context, f AccessLogFilter, limit, offset int) ([]domain.AccessLog, int, error) {
+	where := " WHERE a.ts >= $1 AND a.ts <= $2"
+	args := []interface{}{f.From, f.To}
+	argIdx := 3
+
+	if f.ProxyID != nil {
+		where += fmt.Sprintf(" AND a.proxy_id = $%d", argIdx)
+		args = append(args, *f.ProxyID)
+		argIdx++
+	}
+	if f.ClientIP != nil {
+		where += fmt.Sprintf(" AND a.client_ip <<= $%d::inet", argIdx)
+		args = append(args, *f.ClientIP)
+		argIdx++
+	}
+	if f.Host != nil {
+		// Subdomains match the reversed host by prefix, served by idx_access_logs_host_reverse
+		where += fmt.Sprintf(" AND (a.host = $%d OR reverse(a.host) LIKE $%d)", argIdx, argIdx+1)
+		args = append(args, *f.Host, escapeLike(reverseString(*f.Host))+".%")
+		argIdx += 2
+	}
+	if f.Username != nil {
+		where += fmt.Sprintf(" AND a.username = $%d", argIdx)
+		args = append(args, *f.Username)
+		argIdx++
+	}
+	if f.StatusMin != nil {
+		where += fmt.Sprintf(" AND a.status >= $%d", argIdx)
+		args = append(args, *f.StatusMin)
+		argIdx++
+	}
+	if f.StatusMax != nil {
+		where += fmt.Sprintf(" AND a.status <= $%d", argIdx)
+		args = append(args, *f.StatusMax)
+		argIdx++
+	}
+
+	var total int
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM access_logs a"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count access logs: %w", err)
+	}
+
+	query := `SELECT a.id, a.ts, a.proxy_id, COALESCE(p.hostname, ''), host(a.client_ip), a.username, a.method, a.url, a.host,
+	                 a.status, a.bytes, a.duration_ms, a.route, a.upstream, a.user_agent
+	          FROM access_logs a LEFT JOIN proxies p ON p.id = a.proxy_id` + where +
+		fmt.Sprintf(` ORDER BY a.ts DESC, a.id DESC LIMIT $%d OFFSET $%d`, argIdx, argIdx+1)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("search access logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []domain.AccessLog
+	for rows.Next() {
+		var l domain.AccessLog
+		var status int16
+		var duration *int32
+		if err := rows.Scan(&l.ID, &l.Timestamp, &l.ProxyID, &l.Hostname, &l.ClientIP, &l.Username, &l.Method, &l.URL, &l.Host,
+			&status, &l.Bytes, &duration, &l.Route, &l.Upstream, &l.UserAgent); err != nil {
+			return nil, 0, fmt.Errorf("scan access log: %w", err)
+		}
+		l.Status = int(status)
+		if duration != nil {
+			d := int(*duration)
+			l.DurationMs = &d
+		}
+		logs = append(logs, l)
+	}
+	return logs, total, nil
+}
+
+// ========== Partitions ==========
+
+const accessLogPartitionLayout = "20060102"
+
+var accessLogPartitionName = regexp.MustCompile(`^access_logs_p(\d{8})$`)
+
+// EnsurePartitions creates the daily partitions (UTC) from day through the
+// following days.
+func (r *AccessLogRepo) EnsurePartitions(ctx context.Context, day time.Time, days int) error {
+	start := day.UTC().Truncate(24 * time.Hour)
+	for i := 0; i < days; i++ {
+		from := start.AddDate(0, 0, i)
+		to := from.AddDate(0, 0, 1)
+		name := "access_logs_p" + from.Format(accessLogPartitionLayout)
+		_, err := r.db.Exec(ctx, fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s PARTITION OF access_logs FOR VALUES FROM ('%s') TO ('%s')`,
+			name, from.Format(time.RFC3339), to.Format(time.RFC3339),
+		))
+		if err != nil {
+			return fmt.Errorf("create access log partition %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// DropPartitionsBefore drops the daily partitions that end before cutoff and
+// deletes older rows that landed in the default partition. Returns the names
+// of the dropped partitions.
+func (r *AccessLogRepo) DropPartitionsBefore(ctx context.Context, cutoff time.Time) ([]string, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT c.relname
+		 FROM pg_inherits i
+		 JOIN pg_class c ON c.oid = i.inhrelid
+		 JOIN pg_class p ON p.oid = i.inhparent
+		 WHERE p.relname = 'access_logs'`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list access log partitions: %w", err)
+	}
+	var expired []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan access log partition: %w", err)
+		}
+		m := accessLogPartitionName.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		day, err := time.Parse(accessLogPartitionLayout, m[1])
+		if err != nil {
+			continue
+		}
+		if !day.AddDate(0, 0, 1).After(cutoff) {
+			expired = append(expired, name)
+		}
+	}
+	rows.Close()
+
+	for _, name := range expired {
+		if _, err := r.db.Exec(ctx, "DROP TABLE IF EXISTS "+name); err != nil {
+			return nil, fmt.Errorf("drop access log partition %s: %w", name, err)
+		}
+	}
+
+	if _, err := r.db.Exec(ctx, `DELETE FROM access_logs_default WHERE ts < $1`, cutoff); err != nil {
+		return expired, fmt.Errorf("cleanup default access log partition: %w", err)
+	}
+	return expired, nil
+}
+
+// likeEscaper escapes the LIKE wildcards (and the escape character itself)
+// so a value is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+func reverseString(s string) string {
+	r := []rune(s)
+	slices.Reverse(r)
+	return string(r)
+}
@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type AlertRepo struct {
+	db DBTX
+}
+
+func NewAlertRepo(db DBTX) *AlertRepo {
+	return &AlertRepo{db: db}
+}
+
+const alertColumns = `a.id, a.rule_id, r.name, r.type, a.proxy_id, p.hostname, a.subject, a.status, a.message, a.value,
+	a.started_at, a.fired_at, a.resolved_at, a.last_evaluated_at`
+
+const alertFrom = ` FROM alerts a
+	JOIN alert_rules r ON r.id = a.rule_id
+	LEFT JOIN proxies p ON p.id = a.proxy_id`
+
+func scanAlert(row pgx.Row, a *domain.Alert) error {
+	return row.Scan(&a.ID, &a.RuleID, &a.RuleName, &a.RuleType, &a.ProxyID, &a.ProxyHostname, &a.Subject, &a.Status,
+		&a.Message, &a.Value, &a.StartedAt, &a.FiredAt, &a.ResolvedAt, &a.LastEvaluatedAt)
+}
+
+// ListOpen returns every pending or firing alert.
+func (r *AlertRepo) ListOpen(ctx context.Context) ([]domain.Alert, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+alertColumns+alertFrom+` WHERE a.status IN ('pending', 'firing')`)
+	if err != nil {
+		return nil, fmt.Errorf("list open alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []domain.Alert
+	for rows.Next() {
+		var a domain.Alert
+		if err := scanAlert(rows, &a); err != nil {
+			return nil, fmt.Errorf("scan alert: %w", err)
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+type AlertFilter struct {
+	Status  *domain.AlertStatus
+	RuleID  *uuid.UUID
+	ProxyID *uuid.UUID
+}
+
+func (r *AlertRepo) List(ctx context.Context, f AlertFilter, limit, offset int) ([]domain.Alert, int, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	argIdx := 1
+
+	if f.Status != nil {
+		where += fmt.Sprintf(" AND a.status = $%d", argIdx)
+		args = append(args, *f.Status)
+		argIdx++
+	}
+	if f.RuleID != nil {
+		where += fmt.Sprintf(" AND a.rule_id = $%d", argIdx)
+		args = append(args, *f.RuleID)
+		argIdx++
+	}
+	if f.ProxyID != nil {
+		where += fmt.Sprintf(" AND a.proxy_id = $%d", argIdx)
+		args = append(args, *f.ProxyID)
+		argIdx++
+	}
+
+	var total int
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*)"+alertFrom+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count alerts: %w", err)
+	}
+
+	query := `SELECT ` + alertColumns + alertFrom + where +
+		fmt.Sprintf(` ORDER BY a.started_at DESC LIMIT $%d OFFSET $%d`, argIdx, argIdx+1)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []domain.Alert
+	for rows.Next() {
+		var a domain.Alert
+		if err := scanAlert(rows, &a); err != nil {
+			return nil, 0, fmt.Errorf("scan alert: %w", err)
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, total, nil
+}
+
+func (r *AlertRepo) Create(ctx context.Context, a *domain.Alert) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO alerts (rule_id, proxy_id, subject, status, message, value, fired_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $4 = 'firing' THEN NOW() END)
+		 RETURNING id, started_at, fired_at, last_evaluated_at`,
+		a.RuleID, a.ProxyID, a.Subject, a.Status, a.Message, a.Value,
+	).Scan(&a.ID, &a.StartedAt, &a.FiredAt, &a.LastEvaluatedAt)
+	if err != nil {
+		return fmt.Errorf("create alert: %w", err)
+	}
+	return nil
+}
+
+// Refresh records a new evaluation of an open alert, moving it to status.
+// fired_at is set the first time the alert reaches firing.
+func (r *AlertRepo) Refresh(ctx context.Context, id uuid.UUID, status domain.AlertStatus, message *string, value *float64) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE alerts
+		 SET status = $1, message = $2, value = $3, last_evaluated_at = NOW(),
+		     fired_at = CASE WHEN $1 = 'firing' AND fired_at IS NULL THEN NOW() ELSE fired_at END
+		 WHERE id = $4`,
+		status, message, value, id,
+	)
+	if err != nil {
+		return fmt.Errorf("refresh alert: %w", err)
+	}
+	return nil
+}
+
+func (r *AlertRepo) Resolve(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE alerts SET status = 'resolved', resolved_at = NOW(), last_evaluated_at = NOW() WHERE id = $1`, id,
+	)
+	if err != nil {
+		return fmt.Errorf("resolve alert: %w", err)
+	}
+	return nil
+}
+
+// Delete removes an alert; used for pending alerts whose condition cleared before firing.
+func (r *AlertRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM alerts WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete alert: %w", err)
+	}
+	return nil
+}
+
+// CleanupResolved deletes resolved alerts older than the given retention.
+func (r *AlertRepo) CleanupResolved(ctx context.Context, retention time.Duration) (int64, error) {
+	tag, err := r.db.Exec(ctx,
+		`DELETE FROM alerts WHERE status = 'resolved' AND resolved_at < $1`, time.Now().Add(-retention),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup resolved alerts: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ========== Conditions ==========
+
+// AlertCandidate is one (proxy, subject) pair currently matching a rule condition.
+type AlertCandidate struct {
+	ProxyID  uuid.UUID
+	Hostname string
+	Subject  string
+	Value    *float64
+	Message  string
+}
+
+func (r *AlertRepo) queryCandidates(ctx context.Context, query string, args ...interface{}) ([]AlertCandidate, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query alert candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var out []AlertCandidate
+	for rows.Next() {
+		var c AlertCandidate
+		if err := rows.Scan(&c.ProxyID, &c.Hostname, &c.Subject, &c.Value, &c.Message); err != nil {
+			return nil, fmt.Errorf("scan alert candidate: %w", err)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// OfflineProxies returns proxies currently marked offline, with minutes since last seen.
+func (r *AlertRepo) OfflineProxies(ctx context.Context) ([]AlertCandidate, error) {
+	return r.queryCandidates(ctx,
+		`SELECT id, hostname, '',
+		        EXTRACT(EPOCH FROM NOW() - last_seen)::float8 / 60,
+		        'proxy offline since ' || COALESCE(to_char(last_seen AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), 'registration')
+		 FROM proxies WHERE is_online = FALSE`)
+}
+
+// ConfigMismatches returns online proxies whose applied config hash differs from
+// the hash expected for their active config, assigned directly or through a
+// group. With overlays the expected hash is the one of the merged files last
+// handed to the proxy (target_config_hash), as in the proxy list.
+func (r *AlertRepo) ConfigMismatches(ctx context.Context) ([]AlertCandidate, error) {
+	return r.queryCandidates(ctx,
+		`SELECT m.id, m.hostname, '', NULL::float8,
+		        'applied hash ' || COALESCE(m.current_config_hash, 'none') || ', expected ' || m.expected
+		 FROM (
+		     SELECT p.id, p.hostname, p.current_config_hash,
+		            CASE WHEN p.target_config_id = c.id THEN p.target_config_hash ELSE c.config_hash END AS expected
+		     FROM proxies p
+		     JOIN (`+activeConfigByProxy+`) a ON a.proxy_id = p.id
+		     JOIN configs c ON c.id = a.config_id
+		     WHERE p.is_online = TRUE
+		 ) m
+		 WHERE m.expected IS NOT NULL
+		   AND m.current_config_hash IS DISTINCT FROM m.expected`)
+}
+
+// ErrorRates returns proxies whose 5xx responses exceeded thresholdPct percent
+// of their requests over the trailing window.
+func (r *AlertRepo) ErrorRates(ctx context.Context, window time.Duration, thresholdPct float64) ([]AlertCandidate, error) {
+	return r.queryCandidates(ctx,
+		`SELECT p.id, p.hostname, '', e.rate,
+		        format('5xx rate %s%% over the last %s minutes', round(e.rate::numeric, 2), $2::int)
+		 FROM (
+		     SELECT proxy_id,
+		            100.0 * SUM(responses_5xx_delta)::float8 / NULLIF(SUM(total_requests_delta), 0) AS rate
+		     FROM proxy_stats
+		     WHERE collected_at > $1
+		     GROUP BY proxy_id
+		 ) e
+		 JOIN proxies p ON p.id = e.proxy_id
+		 WHERE e.rate > $3`,
+		time.Now().Add(-window), int(window.Minutes()), thresholdPct)
+}
+
+// FailedAcks returns proxies whose last config apply was acknowledged with an error.
+func (r *AlertRepo) FailedAcks(ctx context.Context) ([]AlertCandidate, error) {
+	return r.queryCandidates(ctx,
+		`SELECT id, hostname, '', NULL::float8, COALESCE(last_ack_message, 'config apply failed')
+		 FROM proxies WHERE last_ack_status = 'error'`)
+}
+
+// DriftedProxies returns online proxies whose files differ from the applied bundle.
+func (r *AlertRepo) DriftedProxies(ctx context.Context) ([]AlertCandidate, error) {
+	return r.queryCandidates(ctx,
+		`SELECT p.id, p.hostname, '', NULL::float8,
+		        'files changed on disk: ' || array_to_string(d.files, ', ')
+		 FROM proxy_drift d
+		 JOIN proxies p ON p.id = d.proxy_id
+		 WHERE d.drifted = TRUE AND p.is_online = TRUE`)
+}
+
+// ParentsDown returns parent proxies reported unreachable by online proxies.
+func (r *AlertRepo) ParentsDown(ctx context.Context) ([]AlertCandidate, error) {
+	return r.queryCandidates(ctx,
+		`SELECT p.id, p.hostname, s.address || ':' || s.port, NULL::float8,
+		        'parent ' || s.address || ':' || s.port || ' unreachable'
+		 FROM proxy_parent_status s
+		 JOIN proxies p ON p.id = s.proxy_id
+		 WHERE s.is_up = FALSE AND p.is_online = TRUE`)
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type AlertRuleRepo struct {
+	db DBTX
+}
+
+func NewAlertRuleRepo(db DBTX) *AlertRuleRepo {
+	return &AlertRuleRepo{db: db}
+}
+
+const alertRuleColumns = `id, name, type, threshold, duration_minutes, enabled, created_by, created_at, updated_at`
+
+func scanAlertRule(row pgx.Row, a *domain.AlertRule) error {
+	return row.Scan(&a.ID, &a.Name, &a.Type, &a.Threshold, &a.DurationMinutes, &a.Enabled, &a.CreatedBy, &a.CreatedAt, &a.UpdatedAt)
+}
+
+func (r *AlertRuleRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.AlertRule, error) {
+	var a domain.AlertRule
+	err := scanAlertRule(r.db.QueryRow(ctx, `SELECT `+alertRuleColumns+` FROM alert_rules WHERE id = $1`, id), &a)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get alert rule: %w", err)
+	}
+	return &a, nil
+}
+
+// List returns all rules, or only enabled ones when enabledOnly is set.
+func (r *AlertRuleRepo) List(ctx context.Context, enabledOnly bool) ([]domain.AlertRule, error) {
+	query := `SELECT ` + alertRuleColumns + ` FROM alert_rules`
+	if enabledOnly {
+		query += ` WHERE enabled = TRUE`
+	}
+	query += ` ORDER BY name`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []domain.AlertRule
+	for rows.Next() {
+		var a domain.AlertRule
+		if err := scanAlertRule(rows, &a); err != nil {
+			return nil, fmt.Errorf("scan alert rule: %w", err)
+		}
+		rules = append(rules, a)
+	}
+	return rules, nil
+}
+
+func (r *AlertRuleRepo) Create(ctx context.Context, a *domain.AlertRule) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO alert_rules (name, type, threshold, duration_minutes, enabled, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, created_at, updated_at`,
+		a.Name, a.Type, a.Threshold, a.DurationMinutes, a.Enabled, a.CreatedBy,
+	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create alert rule: %w", err)
+	}
+	return nil
+}
+
+func (r *AlertRuleRepo) Update(ctx context.Context, a *domain.AlertRule) error {
+	err := r.db.QueryRow(ctx,
+		`UPDATE alert_rules
+		 SET name = $1, type = $2, threshold = $3, duration_minutes = $4, enabled = $5, updated_at = NOW()
+		 WHERE id = $6
+		 RETURNING updated_at`,
+		a.Name, a.Type, a.Threshold, a.DurationMinutes, a.Enabled, a.ID,
+	).Scan(&a.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("update alert rule: %w", err)
+	}
+	return nil
+}
+
+func (r *AlertRuleRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM alert_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete alert rule: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
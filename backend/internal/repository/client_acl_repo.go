@@ -18,7 +18,7 @@ func NewClientACLRepo(db DBTX) *ClientACLRepo {
 
 func (r *ClientACLRepo) ListByConfig(ctx context.Context, configID uuid.UUID) ([]domain.ClientACLRule, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, config_id, cidr, action, priority, created_at
+		`SELECT id, config_id, cidr, action, apply, methods, priority, created_at
 		 FROM client_acl_rules WHERE config_id = $1 ORDER BY priority`, configID,
 	)
 	if err != nil {
@@ -29,7 +29,7 @@ func (r *ClientACLRepo) ListByConfig(ctx context.Context, configID uuid.UUID) ([
 	var rules []domain.ClientACLRule
 	for rows.Next() {
 		var r domain.ClientACLRule
-		if err := rows.Scan(&r.ID, &r.ConfigID, &r.CIDR, &r.Action, &r.Priority, &r.CreatedAt); err != nil {
+		if err := rows.Scan(&r.ID, &r.ConfigID, &r.CIDR, &r.Action, &r.Apply, &r.Methods, &r.Priority, &r.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scan client acl rule: %w", err)
 		}
 		rules = append(rules, r)
@@ -38,11 +38,17 @@ func (r *ClientACLRepo) ListByConfig(ctx context.Context, configID uuid.UUID) ([
 }
 
 func (r *ClientACLRepo) Create(ctx context.Context, rule *domain.ClientACLRule) error {
+	if rule.Apply == "" {
+		rule.Apply = domain.ACLApplyIn
+	}
+	if rule.Methods == nil {
+		rule.Methods = []string{}
+	}
 	err := r.db.QueryRow(ctx,
-		`INSERT INTO client_acl_rules (config_id, cidr, action, priority)
-		 VALUES ($1, $2, $3, $4)
+		`INSERT INTO client_acl_rules (config_id, cidr, action, apply, methods, priority)
+		 VALUES ($1, $2, $3, $4, $5, $6)
 		 RETURNING id, created_at`,
-		rule.ConfigID, rule.CIDR, rule.Action, rule.Priority,
+		rule.ConfigID, rule.CIDR, rule.Action, rule.Apply, rule.Methods, rule.Priority,
 	).Scan(&rule.ID, &rule.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("create client acl rule: %w", err)
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type ConfigGroupRepo struct {
+	db DBTX
+}
+
+func NewConfigGroupRepo(db DBTX) *ConfigGroupRepo {
+	return &ConfigGroupRepo{db: db}
+}
+
+func (r *ConfigGroupRepo) Assign(ctx context.Context, configID, groupID, userID uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO config_groups (config_id, group_id, assigned_by)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (config_id, group_id) DO NOTHING`,
+		configID, groupID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("assign group to config: %w", err)
+	}
+	return nil
+}
+
+func (r *ConfigGroupRepo) DeleteByConfig(ctx context.Context, configID uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		`DELETE FROM config_groups WHERE config_id = $1`, configID,
+	)
+	return err
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type ConfigOverlayRepo struct {
+	db DBTX
+}
+
+func NewConfigOverlayRepo(db DBTX) *ConfigOverlayRepo {
+	return &ConfigOverlayRepo{db: db}
+}
+
+const configOverlayColumns = `o.id, o.config_id, o.hostname, o.group_id, g.name, o.description,
+	o.domains, o.ip_ranges, o.client_acl, o.created_at`
+
+func scanConfigOverlay(row pgx.Row, o *domain.ConfigOverlay) error {
+	return row.Scan(&o.ID, &o.ConfigID, &o.Hostname, &o.GroupID, &o.GroupName, &o.Description,
+		&o.Domains, &o.IPRanges, &o.ClientACL, &o.CreatedAt)
+}
+
+// ListByConfig returns the overlays of a config, the per-proxy ones first.
+func (r *ConfigOverlayRepo) ListByConfig(ctx context.Context, configID uuid.UUID) ([]domain.ConfigOverlay, error) {
+	return r.list(ctx,
+		`SELECT `+configOverlayColumns+`
+		 FROM config_overlays o
+		 LEFT JOIN proxy_groups g ON o.group_id = g.id
+		 WHERE o.config_id = $1
+		 ORDER BY o.hostname IS NULL, o.hostname, g.priority DESC, g.name`, configID)
+}
+
+// ListForProxy returns the overlays of a config that apply to a proxy, in
+// merge order: the proxy's own overlay, then the overlays of the groups whose
+// selector matches its labels, highest group priority first (then by name).
+func (r *ConfigOverlayRepo) ListForProxy(ctx context.Context, configID uuid.UUID, hostname string) ([]domain.ConfigOverlay, error) {
+	return r.list(ctx,
+		`SELECT `+configOverlayColumns+`
+		 FROM config_overlays o
+		 LEFT JOIN proxy_groups g ON o.group_id = g.id
+		 WHERE o.config_id = $1
+		   AND (o.hostname = $2
+		        OR g.selector <@ (SELECT effective_labels FROM proxies WHERE hostname = $2))
+		 ORDER BY o.hostname IS NULL, g.priority DESC, g.name`, configID, hostname)
+}
+
+// ConfigNamesForGroup returns the names of the configs with an overlay for a group.
+func (r *ConfigOverlayRepo) ConfigNamesForGroup(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT c.name FROM config_overlays o JOIN configs c ON c.id = o.config_id
+		 WHERE o.group_id = $1 ORDER BY c.name`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("list configs with group overlays: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan config name: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func (r *ConfigOverlayRepo) ExistsForConfig(ctx context.Context, configID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM config_overlays WHERE config_id = $1)`, configID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check config overlays: %w", err)
+	}
+	return exists, nil
+}
+
+func (r *ConfigOverlayRepo) list(ctx context.Context, query string, args ...interface{}) ([]domain.ConfigOverlay, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list config overlays: %w", err)
+	}
+	defer rows.Close()
+
+	var overlays []domain.ConfigOverlay
+	for rows.Next() {
+		var o domain.ConfigOverlay
+		if err := scanConfigOverlay(rows, &o); err != nil {
+			return nil, fmt.Errorf("scan config overlay: %w", err)
+		}
+		overlays = append(overlays, o)
+	}
+	return overlays, nil
+}
+
+func (r *ConfigOverlayRepo) Create(ctx context.Context, o *domain.ConfigOverlay) error {
+	if o.Domains == nil {
+		o.Domains = []domain.OverlayDomainRule{}
+	}
+	if o.IPRanges == nil {
+		o.IPRanges = []domain.OverlayIPRule{}
+	}
+	if o.ClientACL == nil {
+		o.ClientACL = []domain.OverlayACLRule{}
+	}
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO config_overlays (config_id, hostname, group_id, description, domains, ip_ranges, client_acl)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id, created_at`,
+		o.ConfigID, o.Hostname, o.GroupID, o.Description, o.Domains, o.IPRanges, o.ClientACL,
+	).Scan(&o.ID, &o.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create config overlay: %w", err)
+	}
+	return nil
+}
+
+func (r *ConfigOverlayRepo) DeleteByConfig(ctx context.Context, configID uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		`DELETE FROM config_overlays WHERE config_id = $1`, configID,
+	)
+	return err
+}
@@ -40,12 +40,14 @@ func (r *ConfigProxyRepo) ListByConfig(ctx context.Context, configID uuid.UUID)
 	return proxies, nil
 }
 
-func (r *ConfigProxyRepo) Assign(ctx context.Context, configID, proxyID, userID uuid.UUID) error {
+// Assign adds the proxy to the config. assignedBy is nil when the assignment
+// comes from an enrollment token whose creator is unknown.
+func (r *ConfigProxyRepo) Assign(ctx context.Context, configID, proxyID uuid.UUID, assignedBy *uuid.UUID) error {
 	_, err := r.db.Exec(ctx,
 		`INSERT INTO config_proxies (config_id, proxy_id, assigned_by)
 		 VALUES ($1, $2, $3)
 		 ON CONFLICT (config_id, proxy_id) DO NOTHING`,
-		configID, proxyID, userID,
+		configID, proxyID, assignedBy,
 	)
 	if err != nil {
 		return fmt.Errorf("assign proxy to config: %w", err)
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type ConfigRecordRepo struct {
+	db DBTX
+}
+
+func NewConfigRecordRepo(db DBTX) *ConfigRecordRepo {
+	return &ConfigRecordRepo{db: db}
+}
+
+func (r *ConfigRecordRepo) ListByConfig(ctx context.Context, configID uuid.UUID) ([]domain.RecordOverride, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, config_id, name, value, created_at
+		 FROM config_records WHERE config_id = $1 ORDER BY name`, configID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list config records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []domain.RecordOverride
+	for rows.Next() {
+		var rec domain.RecordOverride
+		if err := rows.Scan(&rec.ID, &rec.ConfigID, &rec.Name, &rec.Value, &rec.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan config record: %w", err)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func (r *ConfigRecordRepo) Create(ctx context.Context, rec *domain.RecordOverride) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO config_records (config_id, name, value)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, created_at`,
+		rec.ConfigID, rec.Name, rec.Value,
+	).Scan(&rec.ID, &rec.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create config record: %w", err)
+	}
+	return nil
+}
+
+func (r *ConfigRecordRepo) DeleteByConfig(ctx context.Context, configID uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		`DELETE FROM config_records WHERE config_id = $1`, configID,
+	)
+	return err
+}
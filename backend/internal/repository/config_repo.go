@@ -23,11 +23,11 @@ func (r *ConfigRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Config,
 	err := r.db.QueryRow(ctx,
 		`SELECT id, name, description, status, version,
 		        created_by, created_at, modified_by, modified_at,
-		        submitted_by, submitted_at, approved_by, approved_at, config_hash, default_action
+		        submitted_by, submitted_at, approved_by, approved_at, config_hash, default_action, connect_ports
 		 FROM configs WHERE id = $1`, id,
 	).Scan(&c.ID, &c.Name, &c.Description, &c.Status, &c.Version,
 		&c.CreatedBy, &c.CreatedAt, &c.ModifiedBy, &c.ModifiedAt,
-		&c.SubmittedBy, &c.SubmittedAt, &c.ApprovedBy, &c.ApprovedAt, &c.ConfigHash, &c.DefaultAction)
+		&c.SubmittedBy, &c.SubmittedAt, &c.ApprovedBy, &c.ApprovedAt, &c.ConfigHash, &c.DefaultAction, &c.ConnectPorts)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, domain.ErrNotFound
 	}
@@ -43,7 +43,7 @@ func (r *ConfigRepo) List(ctx context.Context, status *domain.ConfigStatus, limi
 	listQuery := `SELECT c.id, c.name, c.description, c.status, c.version,
 	              c.created_by, c.created_at, c.modified_by, c.modified_at,
 	              c.submitted_by, c.submitted_at, c.approved_by, c.approved_at, c.config_hash,
-	              c.default_action, COUNT(cp.proxy_id) AS proxy_count
+	              c.default_action, c.connect_ports, COUNT(cp.proxy_id) AS proxy_count
 	              FROM configs c
 	              LEFT JOIN config_proxies cp ON c.id = cp.config_id`
 
@@ -77,7 +77,7 @@ func (r *ConfigRepo) List(ctx context.Context, status *domain.ConfigStatus, limi
 		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.Status, &c.Version,
 			&c.CreatedBy, &c.CreatedAt, &c.ModifiedBy, &c.ModifiedAt,
 			&c.SubmittedBy, &c.SubmittedAt, &c.ApprovedBy, &c.ApprovedAt, &c.ConfigHash,
-			&c.DefaultAction, &c.ProxyCount); err != nil {
+			&c.DefaultAction, &c.ConnectPorts, &c.ProxyCount); err != nil {
 			return nil, 0, fmt.Errorf("scan config: %w", err)
 		}
 		configs = append(configs, c)
@@ -99,10 +99,10 @@ func (r *ConfigRepo) Delete(ctx context.Context, id uuid.UUID) error {
 
 func (r *ConfigRepo) Create(ctx context.Context, c *domain.Config) error {
 	err := r.db.QueryRow(ctx,
-		`INSERT INTO configs (name, description, status, default_action, created_by, modified_by)
-		 VALUES ($1, $2, $3, $4, $5, $5)
+		`INSERT INTO configs (name, description, status, default_action, connect_ports, created_by, modified_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $6)
 		 RETURNING id, version, created_at, modified_at`,
-		c.Name, c.Description, domain.StatusDraft, c.DefaultAction, c.CreatedBy,
+		c.Name, c.Description, domain.StatusDraft, c.DefaultAction, c.ConnectPorts, c.CreatedBy,
 	).Scan(&c.ID, &c.Version, &c.CreatedAt, &c.ModifiedAt)
 	if err != nil {
 		return fmt.Errorf("create config: %w", err)
@@ -113,10 +113,10 @@ func (r *ConfigRepo) Create(ctx context.Context, c *domain.Config) error {
 
 func (r *ConfigRepo) CreateWithVersion(ctx context.Context, c *domain.Config, version int) error {
 	err := r.db.QueryRow(ctx,
-		`INSERT INTO configs (name, description, status, version, default_action, created_by, modified_by)
-		 VALUES ($1, $2, $3, $4, $5, $6, $6)
+		`INSERT INTO configs (name, description, status, version, default_action, connect_ports, created_by, modified_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
 		 RETURNING id, created_at, modified_at`,
-		c.Name, c.Description, domain.StatusDraft, version, c.DefaultAction, c.CreatedBy,
+		c.Name, c.Description, domain.StatusDraft, version, c.DefaultAction, c.ConnectPorts, c.CreatedBy,
 	).Scan(&c.ID, &c.CreatedAt, &c.ModifiedAt)
 	if err != nil {
 		return fmt.Errorf("create config with version: %w", err)
@@ -128,9 +128,10 @@ func (r *ConfigRepo) CreateWithVersion(ctx context.Context, c *domain.Config, ve
 
 func (r *ConfigRepo) Update(ctx context.Context, c *domain.Config) error {
 	tag, err := r.db.Exec(ctx,
-		`UPDATE configs SET name = $1, description = $2, default_action = $3, modified_by = $4, modified_at = NOW()
-		 WHERE id = $5 AND status = 'draft'`,
-		c.Name, c.Description, c.DefaultAction, c.ModifiedBy, c.ID,
+		`UPDATE configs SET name = $1, description = $2, default_action = $3, connect_ports = $4,
+		        modified_by = $5, modified_at = NOW()
+		 WHERE id = $6 AND status = 'draft'`,
+		c.Name, c.Description, c.DefaultAction, c.ConnectPorts, c.ModifiedBy, c.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("update config: %w", err)
@@ -194,20 +195,82 @@ func (r *ConfigRepo) UpdateHash(ctx context.Context, id uuid.UUID, hash string)
 	return err
 }
 
+// HasDraft reports whether a config with the given name has a draft version.
+func (r *ConfigRepo) HasDraft(ctx context.Context, name string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM configs WHERE name = $1 AND status = $2)`, name, domain.StatusDraft,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check config draft: %w", err)
+	}
+	return exists, nil
+}
+
+// activeConfigByProxy selects (proxy_id, config_id) for every proxy with an
+// active config, resolved like GetActiveForProxy: a direct assignment wins
+// over groups, then the group with the highest priority (then by name).
+const activeConfigByProxy = `SELECT DISTINCT ON (a.proxy_id) a.proxy_id, a.config_id
+	FROM (
+	  SELECT cp.proxy_id, cp.config_id, TRUE AS direct, 0 AS priority, '' AS group_name
+	  FROM config_proxies cp
+	  UNION ALL
+	  SELECT p.id, cg.config_id, FALSE, g.priority, g.name
+	  FROM config_groups cg
+	  JOIN proxy_groups g ON cg.group_id = g.id
+	  JOIN proxies p ON p.effective_labels @> g.selector
+	) a
+	JOIN configs c ON c.id = a.config_id AND c.status = 'active'
+	ORDER BY a.proxy_id, a.direct DESC, a.priority DESC, a.group_name`
+
+// NamesByIDs returns the names of the given configs; unknown IDs are left out.
+func (r *ConfigRepo) NamesByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, name FROM configs WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list config names: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[uuid.UUID]string, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("scan config name: %w", err)
+		}
+		names[id] = name
+	}
+	return names, rows.Err()
+}
+
+// GetActiveForProxy returns the active config of a proxy. A config assigned to
+// the proxy directly wins over the ones assigned to groups matching its labels;
+// among those, the group with the highest priority wins (then by name).
 func (r *ConfigRepo) GetActiveForProxy(ctx context.Context, hostname string) (*domain.Config, error) {
 	var c domain.Config
 	err := r.db.QueryRow(ctx,
 		`SELECT c.id, c.name, c.description, c.status, c.version,
 		        c.created_by, c.created_at, c.modified_by, c.modified_at,
-		        c.submitted_by, c.submitted_at, c.approved_by, c.approved_at, c.config_hash, c.default_action
+		        c.submitted_by, c.submitted_at, c.approved_by, c.approved_at, c.config_hash, c.default_action, c.connect_ports
 		 FROM configs c
-		 JOIN config_proxies cp ON c.id = cp.config_id
-		 JOIN proxies p ON cp.proxy_id = p.id
-		 WHERE p.hostname = $1 AND c.status = 'active'
+		 JOIN (
+		   SELECT cp.config_id, TRUE AS direct, 0 AS priority, '' AS group_name
+		   FROM config_proxies cp
+		   JOIN proxies p ON cp.proxy_id = p.id
+		   WHERE p.hostname = $1
+		   UNION ALL
+		   SELECT cg.config_id, FALSE, g.priority, g.name
+		   FROM config_groups cg
+		   JOIN proxy_groups g ON cg.group_id = g.id
+		   JOIN proxies p ON p.effective_labels @> g.selector
+		   WHERE p.hostname = $1
+		 ) a ON c.id = a.config_id
+		 WHERE c.status = 'active'
+		 ORDER BY a.direct DESC, a.priority DESC, a.group_name
 		 LIMIT 1`, hostname,
 	).Scan(&c.ID, &c.Name, &c.Description, &c.Status, &c.Version,
 		&c.CreatedBy, &c.CreatedAt, &c.ModifiedBy, &c.ModifiedAt,
-		&c.SubmittedBy, &c.SubmittedAt, &c.ApprovedBy, &c.ApprovedAt, &c.ConfigHash, &c.DefaultAction)
+		&c.SubmittedBy, &c.SubmittedAt, &c.ApprovedBy, &c.ApprovedAt, &c.ConfigHash, &c.DefaultAction, &c.ConnectPorts)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, domain.ErrNotFound
 	}
@@ -217,17 +280,22 @@ func (r *ConfigRepo) GetActiveForProxy(ctx context.Context, hostname string) (*d
 	return &c, nil
 }
 
-// DeactivateOthers sets other active configs that share proxies with the given config to 'approved'.
+// DeactivateOthers sets other active configs that share proxies or groups with the given config to 'approved'.
 func (r *ConfigRepo) DeactivateOthers(ctx context.Context, activeID uuid.UUID) error {
 	_, err := r.db.Exec(ctx,
 		`UPDATE configs SET status = 'approved'
 		 WHERE status = 'active' AND id != $1
-		   AND id IN (
+		   AND (id IN (
 		     SELECT cp2.config_id FROM config_proxies cp2
 		     WHERE cp2.proxy_id IN (
 		       SELECT cp1.proxy_id FROM config_proxies cp1 WHERE cp1.config_id = $1
 		     )
-		   )`,
+		   ) OR id IN (
+		     SELECT cg2.config_id FROM config_groups cg2
+		     WHERE cg2.group_id IN (
+		       SELECT cg1.group_id FROM config_groups cg1 WHERE cg1.config_id = $1
+		     )
+		   ))`,
 		activeID,
 	)
 	return err
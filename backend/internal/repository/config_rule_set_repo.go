@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type ConfigRuleSetRepo struct {
+	db DBTX
+}
+
+func NewConfigRuleSetRepo(db DBTX) *ConfigRuleSetRepo {
+	return &ConfigRuleSetRepo{db: db}
+}
+
+// RuleSetContent is the content of the rule set version a config references.
+type RuleSetContent struct {
+	Priority int
+	Domains  []domain.RuleSetDomain
+	IPRanges []domain.RuleSetCIDR
+}
+
+func (r *ConfigRuleSetRepo) ListByConfig(ctx context.Context, configID uuid.UUID) ([]domain.ConfigRuleSet, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT crs.rule_set_id, rs.name, crs.version, rs.version, crs.priority
+		 FROM config_rule_sets crs
+		 JOIN rule_sets rs ON crs.rule_set_id = rs.id
+		 WHERE crs.config_id = $1
+		 ORDER BY crs.priority, rs.name`, configID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list config rule sets: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []domain.ConfigRuleSet
+	for rows.Next() {
+		var ref domain.ConfigRuleSet
+		if err := rows.Scan(&ref.RuleSetID, &ref.Name, &ref.Version, &ref.LatestVersion, &ref.Priority); err != nil {
+			return nil, fmt.Errorf("scan config rule set: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// ListContentByConfig returns the rules of the rule set versions a config
+// references, ordered by priority (then rule set name).
+func (r *ConfigRuleSetRepo) ListContentByConfig(ctx context.Context, configID uuid.UUID) ([]RuleSetContent, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT crs.priority, v.domains, v.ip_ranges
+		 FROM config_rule_sets crs
+		 JOIN rule_set_versions v ON v.rule_set_id = crs.rule_set_id AND v.version = crs.version
+		 JOIN rule_sets rs ON crs.rule_set_id = rs.id
+		 WHERE crs.config_id = $1
+		 ORDER BY crs.priority, rs.name`, configID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list config rule set content: %w", err)
+	}
+	defer rows.Close()
+
+	var contents []RuleSetContent
+	for rows.Next() {
+		var c RuleSetContent
+		if err := rows.Scan(&c.Priority, &c.Domains, &c.IPRanges); err != nil {
+			return nil, fmt.Errorf("scan config rule set content: %w", err)
+		}
+		contents = append(contents, c)
+	}
+	return contents, nil
+}
+
+func (r *ConfigRuleSetRepo) Assign(ctx context.Context, configID uuid.UUID, ref domain.ConfigRuleSet) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO config_rule_sets (config_id, rule_set_id, version, priority)
+		 VALUES ($1, $2, $3, $4)`,
+		configID, ref.RuleSetID, ref.Version, ref.Priority,
+	)
+	if err != nil {
+		return fmt.Errorf("assign rule set to config: %w", err)
+	}
+	return nil
+}
+
+func (r *ConfigRuleSetRepo) DeleteByConfig(ctx context.Context, configID uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		`DELETE FROM config_rule_sets WHERE config_id = $1`, configID,
+	)
+	return err
+}
+
+// ListConfigsBehind returns the configs that reference a rule set at a version
+// older than the given one, newest version of each config name first.
+func (r *ConfigRuleSetRepo) ListConfigsBehind(ctx context.Context, ruleSetID uuid.UUID, version int) ([]domain.Config, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT c.id, c.name, c.status, c.version
+		 FROM configs c
+		 JOIN config_rule_sets crs ON crs.config_id = c.id
+		 WHERE crs.rule_set_id = $1 AND crs.version < $2
+		 ORDER BY c.name, c.version DESC`, ruleSetID, version,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list configs behind rule set: %w", err)
+	}
+	defer rows.Close()
+
+	var configs []domain.Config
+	for rows.Next() {
+		var c domain.Config
+		if err := rows.Scan(&c.ID, &c.Name, &c.Status, &c.Version); err != nil {
+			return nil, fmt.Errorf("scan config: %w", err)
+		}
+		configs = append(configs, c)
+	}
+	return configs, nil
+}
+
+// UpgradeDrafts moves the draft configs referencing a rule set to the given
+// version. Configs in any other status keep the version they were submitted
+// or approved with.
+func (r *ConfigRuleSetRepo) UpgradeDrafts(ctx context.Context, ruleSetID uuid.UUID, version int) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE config_rule_sets crs SET version = $2
+		 FROM configs c
+		 WHERE c.id = crs.config_id AND c.status = 'draft'
+		   AND crs.rule_set_id = $1 AND crs.version < $2`, ruleSetID, version,
+	)
+	if err != nil {
+		return fmt.Errorf("upgrade draft rule sets: %w", err)
+	}
+	return nil
+}
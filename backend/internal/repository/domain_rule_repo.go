@@ -18,7 +18,7 @@ func NewDomainRuleRepo(db DBTX) *DomainRuleRepo {
 
 func (r *DomainRuleRepo) ListByConfig(ctx context.Context, configID uuid.UUID) ([]domain.DomainRule, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, config_id, domain, action, priority, created_at
+		`SELECT id, config_id, domain, action, priority, tls_policy, created_at
 		 FROM domain_rules WHERE config_id = $1 ORDER BY priority`, configID,
 	)
 	if err != nil {
@@ -29,7 +29,7 @@ func (r *DomainRuleRepo) ListByConfig(ctx context.Context, configID uuid.UUID) (
 	var rules []domain.DomainRule
 	for rows.Next() {
 		var dr domain.DomainRule
-		if err := rows.Scan(&dr.ID, &dr.ConfigID, &dr.Domain, &dr.Action, &dr.Priority, &dr.CreatedAt); err != nil {
+		if err := rows.Scan(&dr.ID, &dr.ConfigID, &dr.Domain, &dr.Action, &dr.Priority, &dr.TLS, &dr.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scan domain rule: %w", err)
 		}
 		rules = append(rules, dr)
@@ -39,10 +39,10 @@ func (r *DomainRuleRepo) ListByConfig(ctx context.Context, configID uuid.UUID) (
 
 func (r *DomainRuleRepo) Create(ctx context.Context, dr *domain.DomainRule) error {
 	err := r.db.QueryRow(ctx,
-		`INSERT INTO domain_rules (config_id, domain, action, priority)
-		 VALUES ($1, $2, $3, $4)
+		`INSERT INTO domain_rules (config_id, domain, action, priority, tls_policy)
+		 VALUES ($1, $2, $3, $4, $5)
 		 RETURNING id, created_at`,
-		dr.ConfigID, dr.Domain, dr.Action, dr.Priority,
+		dr.ConfigID, dr.Domain, dr.Action, dr.Priority, dr.TLS,
 	).Scan(&dr.ID, &dr.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("create domain rule: %w", err)
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type EnrollmentTokenRepo struct {
+	db DBTX
+}
+
+func NewEnrollmentTokenRepo(db DBTX) *EnrollmentTokenRepo {
+	return &EnrollmentTokenRepo{db: db}
+}
+
+const enrollmentTokenColumns = `id, name, config_id, group_id, max_uses, use_count, expires_at, revoked_at, last_used_at, created_by, created_at`
+
+func scanEnrollmentToken(row pgx.Row, t *domain.EnrollmentToken) error {
+	return row.Scan(&t.ID, &t.Name, &t.ConfigID, &t.GroupID, &t.MaxUses, &t.UseCount, &t.ExpiresAt, &t.RevokedAt, &t.LastUsedAt, &t.CreatedBy, &t.CreatedAt)
+}
+
+func (r *EnrollmentTokenRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.EnrollmentToken, error) {
+	var t domain.EnrollmentToken
+	err := scanEnrollmentToken(r.db.QueryRow(ctx, `SELECT `+enrollmentTokenColumns+` FROM enrollment_tokens WHERE id = $1`, id), &t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get enrollment token: %w", err)
+	}
+	return &t, nil
+}
+
+func (r *EnrollmentTokenRepo) List(ctx context.Context) ([]domain.EnrollmentToken, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+enrollmentTokenColumns+` FROM enrollment_tokens ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("list enrollment tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []domain.EnrollmentToken
+	for rows.Next() {
+		var t domain.EnrollmentToken
+		if err := scanEnrollmentToken(rows, &t); err != nil {
+			return nil, fmt.Errorf("scan enrollment token: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+func (r *EnrollmentTokenRepo) Create(ctx context.Context, t *domain.EnrollmentToken, tokenHash string) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO enrollment_tokens (name, token_hash, config_id, group_id, max_uses, expires_at, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id, use_count, created_at`,
+		t.Name, tokenHash, t.ConfigID, t.GroupID, t.MaxUses, t.ExpiresAt, t.CreatedBy,
+	).Scan(&t.ID, &t.UseCount, &t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create enrollment token: %w", err)
+	}
+	return nil
+}
+
+// Consume counts one use of the token with the given hash. It returns
+// ErrNotFound when the token does not exist, is revoked, expired or used up.
+func (r *EnrollmentTokenRepo) Consume(ctx context.Context, tokenHash string) (*domain.EnrollmentToken, error) {
+	var t domain.EnrollmentToken
+	err := scanEnrollmentToken(r.db.QueryRow(ctx,
+		`UPDATE enrollment_tokens
+		 SET use_count = use_count + 1, last_used_at = NOW()
+		 WHERE token_hash = $1
+		   AND revoked_at IS NULL
+		   AND (expires_at IS NULL OR expires_at > NOW())
+		   AND (max_uses IS NULL OR use_count < max_uses)
+		 RETURNING `+enrollmentTokenColumns,
+		tokenHash,
+	), &t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("consume enrollment token: %w", err)
+	}
+	return &t, nil
+}
+
+func (r *EnrollmentTokenRepo) Revoke(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE enrollment_tokens SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("revoke enrollment token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
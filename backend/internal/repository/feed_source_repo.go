@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type FeedSourceRepo struct {
+	db DBTX
+}
+
+func NewFeedSourceRepo(db DBTX) *FeedSourceRepo {
+	return &FeedSourceRepo{db: db}
+}
+
+const feedSourceColumns = `f.id, f.name, f.rule_set_id, rs.name, f.source, f.format, f.action,
+	f.interval_minutes, f.enabled, f.last_fetched_at, f.last_status, f.last_error,
+	(SELECT u.id FROM feed_updates u WHERE u.feed_id = f.id AND u.status = 'pending'),
+	f.created_by, f.created_at, f.updated_at
+	FROM feed_sources f
+	JOIN rule_sets rs ON f.rule_set_id = rs.id`
+
+func scanFeedSource(row pgx.Row, f *domain.FeedSource) error {
+	return row.Scan(&f.ID, &f.Name, &f.RuleSetID, &f.RuleSetName, &f.Source, &f.Format, &f.Action,
+		&f.IntervalMinutes, &f.Enabled, &f.LastFetchedAt, &f.LastStatus, &f.LastError,
+		&f.PendingUpdateID, &f.CreatedBy, &f.CreatedAt, &f.UpdatedAt)
+}
+
+func (r *FeedSourceRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.FeedSource, error) {
+	var f domain.FeedSource
+	err := scanFeedSource(r.db.QueryRow(ctx, `SELECT `+feedSourceColumns+` WHERE f.id = $1`, id), &f)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get feed source: %w", err)
+	}
+	return &f, nil
+}
+
+func (r *FeedSourceRepo) GetByName(ctx context.Context, name string) (*domain.FeedSource, error) {
+	var f domain.FeedSource
+	err := scanFeedSource(r.db.QueryRow(ctx, `SELECT `+feedSourceColumns+` WHERE f.name = $1`, name), &f)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get feed source by name: %w", err)
+	}
+	return &f, nil
+}
+
+// GetByRuleSet returns the feed that fills a rule set.
+func (r *FeedSourceRepo) GetByRuleSet(ctx context.Context, ruleSetID uuid.UUID) (*domain.FeedSource, error) {
+	var f domain.FeedSource
+	err := scanFeedSource(r.db.QueryRow(ctx, `SELECT `+feedSourceColumns+` WHERE f.rule_set_id = $1`, ruleSetID), &f)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get feed source by rule set: %w", err)
+	}
+	return &f, nil
+}
+
+func (r *FeedSourceRepo) List(ctx context.Context) ([]domain.FeedSource, error) {
+	return r.list(ctx, `SELECT `+feedSourceColumns+` ORDER BY f.name`)
+}
+
+// ListDue returns the enabled feeds never fetched or last fetched at least
+// their interval ago.
+func (r *FeedSourceRepo) ListDue(ctx context.Context) ([]domain.FeedSource, error) {
+	return r.list(ctx,
+		`SELECT `+feedSourceColumns+`
+		 WHERE f.enabled
+		   AND (f.last_fetched_at IS NULL
+		        OR f.last_fetched_at <= NOW() - make_interval(mins => f.interval_minutes))
+		 ORDER BY f.last_fetched_at NULLS FIRST`)
+}
+
+func (r *FeedSourceRepo) list(ctx context.Context, query string, args ...interface{}) ([]domain.FeedSource, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list feed sources: %w", err)
+	}
+	defer rows.Close()
+
+	var feeds []domain.FeedSource
+	for rows.Next() {
+		var f domain.FeedSource
+		if err := scanFeedSource(rows, &f); err != nil {
+			return nil, fmt.Errorf("scan feed source: %w", err)
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, nil
+}
+
+func (r *FeedSourceRepo) Create(ctx context.Context, f *domain.FeedSource) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO feed_sources (name, rule_set_id, source, format, action, interval_minutes, enabled, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING id, created_at, updated_at`,
+		f.Name, f.RuleSetID, f.Source, f.Format, f.Action, f.IntervalMinutes, f.Enabled, f.CreatedBy,
+	).Scan(&f.ID, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create feed source: %w", err)
+	}
+	return nil
+}
+
+func (r *FeedSourceRepo) Update(ctx context.Context, f *domain.FeedSource) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE feed_sources
+		 SET name = $1, source = $2, format = $3, action = $4, interval_minutes = $5, enabled = $6, updated_at = NOW()
+		 WHERE id = $7`,
+		f.Name, f.Source, f.Format, f.Action, f.IntervalMinutes, f.Enabled, f.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update feed source: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// SetFetchResult records the outcome of a fetch.
+func (r *FeedSourceRepo) SetFetchResult(ctx context.Context, id uuid.UUID, status string, fetchErr *string) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE feed_sources SET last_fetched_at = NOW(), last_status = $1, last_error = $2 WHERE id = $3`,
+		status, fetchErr, id,
+	)
+	if err != nil {
+		return fmt.Errorf("set feed fetch result: %w", err)
+	}
+	return nil
+}
+
+func (r *FeedSourceRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM feed_sources WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete feed source: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
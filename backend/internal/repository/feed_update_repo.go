@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type FeedUpdateRepo struct {
+	db DBTX
+}
+
+func NewFeedUpdateRepo(db DBTX) *FeedUpdateRepo {
+	return &FeedUpdateRepo{db: db}
+}
+
+const feedUpdateColumns = `id, feed_id, status, base_version, domains, ip_ranges, added, removed,
+	skipped, fetched_at, reviewed_by, reviewed_at, applied_version`
+
+func scanFeedUpdate(row pgx.Row, u *domain.FeedUpdate) error {
+	return row.Scan(&u.ID, &u.FeedID, &u.Status, &u.BaseVersion, &u.Domains, &u.IPRanges, &u.Added, &u.Removed,
+		&u.Skipped, &u.FetchedAt, &u.ReviewedBy, &u.ReviewedAt, &u.AppliedVersion)
+}
+
+func (r *FeedUpdateRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.FeedUpdate, error) {
+	var u domain.FeedUpdate
+	err := scanFeedUpdate(r.db.QueryRow(ctx, `SELECT `+feedUpdateColumns+` FROM feed_updates WHERE id = $1`, id), &u)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get feed update: %w", err)
+	}
+	return &u, nil
+}
+
+// GetByIDForUpdate reads an update and locks its row until the transaction
+// ends, so only one review of it can go ahead.
+func (r *FeedUpdateRepo) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.FeedUpdate, error) {
+	var u domain.FeedUpdate
+	err := scanFeedUpdate(r.db.QueryRow(ctx, `SELECT `+feedUpdateColumns+` FROM feed_updates WHERE id = $1 FOR UPDATE`, id), &u)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get feed update: %w", err)
+	}
+	return &u, nil
+}
+
+// ListByFeed returns the latest updates of a feed, newest first.
+func (r *FeedUpdateRepo) ListByFeed(ctx context.Context, feedID uuid.UUID, limit int) ([]domain.FeedUpdate, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+feedUpdateColumns+` FROM feed_updates
+		 WHERE feed_id = $1 ORDER BY fetched_at DESC LIMIT $2`, feedID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list feed updates: %w", err)
+	}
+	defer rows.Close()
+
+	var updates []domain.FeedUpdate
+	for rows.Next() {
+		var u domain.FeedUpdate
+		if err := scanFeedUpdate(rows, &u); err != nil {
+			return nil, fmt.Errorf("scan feed update: %w", err)
+		}
+		updates = append(updates, u)
+	}
+	return updates, nil
+}
+
+// SavePending stores the pending update of a feed, replacing the previous
+// pending one if any.
+func (r *FeedUpdateRepo) SavePending(ctx context.Context, u *domain.FeedUpdate) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO feed_updates (feed_id, base_version, domains, ip_ranges, added, removed, skipped)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (feed_id) WHERE status = 'pending' DO UPDATE
+		 SET base_version = EXCLUDED.base_version, domains = EXCLUDED.domains, ip_ranges = EXCLUDED.ip_ranges,
+		     added = EXCLUDED.added, removed = EXCLUDED.removed, skipped = EXCLUDED.skipped, fetched_at = NOW()
+		 RETURNING id, status, fetched_at`,
+		u.FeedID, u.BaseVersion, u.Domains, u.IPRanges, u.Added, u.Removed, u.Skipped,
+	).Scan(&u.ID, &u.Status, &u.FetchedAt)
+	if err != nil {
+		return fmt.Errorf("save pending feed update: %w", err)
+	}
+	return nil
+}
+
+// DropPending deletes the pending update of a feed, once the rule set already
+// has the feed's content.
+func (r *FeedUpdateRepo) DropPending(ctx context.Context, feedID uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		`DELETE FROM feed_updates WHERE feed_id = $1 AND status = 'pending'`, feedID,
+	)
+	if err != nil {
+		return fmt.Errorf("drop pending feed update: %w", err)
+	}
+	return nil
+}
+
+// Review closes a pending update as applied (with the rule set version it
+// became) or discarded.
+func (r *FeedUpdateRepo) Review(ctx context.Context, id uuid.UUID, status domain.FeedUpdateStatus, userID uuid.UUID, appliedVersion *int) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE feed_updates SET status = $1, reviewed_by = $2, reviewed_at = NOW(), applied_version = $3
+		 WHERE id = $4 AND status = 'pending'`,
+		status, userID, appliedVersion, id,
+	)
+	if err != nil {
+		return fmt.Errorf("review feed update: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrInvalidStatus
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type NotificationSubscriptionRepo struct {
+	db DBTX
+}
+
+func NewNotificationSubscriptionRepo(db DBTX) *NotificationSubscriptionRepo {
+	return &NotificationSubscriptionRepo{db: db}
+}
+
+func (r *NotificationSubscriptionRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.WebhookEvent, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT event FROM notification_subscriptions WHERE user_id = $1 ORDER BY event`, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list notification subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var events []domain.WebhookEvent
+	for rows.Next() {
+		var e domain.WebhookEvent
+		if err := rows.Scan(&e); err != nil {
+			return nil, fmt.Errorf("scan notification subscription: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// Replace sets the exact list of events the user is subscribed to.
+func (r *NotificationSubscriptionRepo) Replace(ctx context.Context, userID uuid.UUID, events []domain.WebhookEvent) error {
+	names := make([]string, 0, len(events))
+	for _, e := range events {
+		names = append(names, string(e))
+	}
+
+	_, err := r.db.Exec(ctx,
+		`WITH removed AS (
+		     DELETE FROM notification_subscriptions WHERE user_id = $1 AND event <> ALL($2::text[])
+		 )
+		 INSERT INTO notification_subscriptions (user_id, event)
+		 SELECT $1, e FROM unnest($2::text[]) AS e
+		 ON CONFLICT DO NOTHING`,
+		userID, names,
+	)
+	if err != nil {
+		return fmt.Errorf("replace notification subscriptions: %w", err)
+	}
+	return nil
+}
+
+// ListSubscribers returns the active users subscribed to the event.
+func (r *NotificationSubscriptionRepo) ListSubscribers(ctx context.Context, event domain.WebhookEvent) ([]domain.User, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT u.id, u.username, u.email, u.role
+		 FROM notification_subscriptions s
+		 JOIN users u ON u.id = s.user_id
+		 WHERE s.event = $1 AND u.is_active = TRUE`, event,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list notification subscribers: %w", err)
+	}
+	defer rows.Close()
+
+	var users []domain.User
+	for rows.Next() {
+		var u domain.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.Role); err != nil {
+			return nil, fmt.Errorf("scan notification subscriber: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type ProxyCommandRepo struct {
+	db DBTX
+}
+
+func NewProxyCommandRepo(db DBTX) *ProxyCommandRepo {
+	return &ProxyCommandRepo{db: db}
+}
+
+const proxyCommandColumns = `id, proxy_id, type, args, status, timeout_seconds, output, error,
+	created_by, created_at, expires_at, delivered_at, finished_at`
+
+func scanProxyCommand(row pgx.Row) (*domain.ProxyCommand, error) {
+	var c domain.ProxyCommand
+	var args []byte
+	err := row.Scan(&c.ID, &c.ProxyID, &c.Type, &args, &c.Status, &c.TimeoutSeconds, &c.Output, &c.Error,
+		&c.CreatedBy, &c.CreatedAt, &c.ExpiresAt, &c.DeliveredAt, &c.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &c.Args); err != nil {
+			return nil, fmt.Errorf("decode command args: %w", err)
+		}
+	}
+	return &c, nil
+}
+
+func (r *ProxyCommandRepo) Create(ctx context.Context, c *domain.ProxyCommand) error {
+	args := c.Args
+	if args == nil {
+		args = map[string]string{}
+	}
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("encode command args: %w", err)
+	}
+	err = r.db.QueryRow(ctx,
+		`INSERT INTO proxy_commands (proxy_id, type, args, timeout_seconds, created_by, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, status, created_at`,
+		c.ProxyID, c.Type, raw, c.TimeoutSeconds, c.CreatedBy, c.ExpiresAt,
+	).Scan(&c.ID, &c.Status, &c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create proxy command: %w", err)
+	}
+	return nil
+}
+
+func (r *ProxyCommandRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.ProxyCommand, error) {
+	c, err := scanProxyCommand(r.db.QueryRow(ctx,
+		`SELECT `+proxyCommandColumns+` FROM proxy_commands WHERE id = $1`, id,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get proxy command: %w", err)
+	}
+	return c, nil
+}
+
+// ListByProxy returns the command history of a proxy, newest first.
+func (r *ProxyCommandRepo) ListByProxy(ctx context.Context, proxyID uuid.UUID, limit int) ([]domain.ProxyCommand, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+proxyCommandColumns+` FROM proxy_commands
+		 WHERE proxy_id = $1 ORDER BY created_at DESC LIMIT $2`, proxyID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list proxy commands: %w", err)
+	}
+	defer rows.Close()
+
+	var commands []domain.ProxyCommand
+	for rows.Next() {
+		c, err := scanProxyCommand(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan proxy command: %w", err)
+		}
+		commands = append(commands, *c)
+	}
+	return commands, nil
+}
+
+// ListPending returns the pending, unexpired commands of a proxy, oldest
+// first. They stay pending until the helper reports them received, so a sync
+// response that never reaches the helper does not lose them.
+func (r *ProxyCommandRepo) ListPending(ctx context.Context, proxyID uuid.UUID) ([]domain.ProxyCommand, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+proxyCommandColumns+` FROM proxy_commands
+		 WHERE proxy_id = $1 AND status = 'pending' AND expires_at > NOW()`, proxyID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list pending proxy commands: %w", err)
+	}
+	defer rows.Close()
+
+	var commands []domain.ProxyCommand
+	for rows.Next() {
+		c, err := scanProxyCommand(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan proxy command: %w", err)
+		}
+		commands = append(commands, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list pending proxy commands: %w", err)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].CreatedAt.Before(commands[j].CreatedAt) })
+	return commands, nil
+}
+
+// MarkDelivered marks the given pending commands of a proxy as delivered.
+// IDs of other proxies or of commands no longer pending are ignored.
+func (r *ProxyCommandRepo) MarkDelivered(ctx context.Context, proxyID uuid.UUID, ids []uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE proxy_commands SET status = 'delivered', delivered_at = NOW()
+		 WHERE proxy_id = $1 AND id = ANY($2) AND status = 'pending'`, proxyID, ids,
+	)
+	if err != nil {
+		return fmt.Errorf("mark proxy commands delivered: %w", err)
+	}
+	return nil
+}
+
+// Complete stores the result of a command. A result can arrive before the
+// helper reports the command received, so pending commands are accepted too.
+// Commands of another proxy, or already finished ones, return domain.ErrNotFound.
+func (r *ProxyCommandRepo) Complete(ctx context.Context, id, proxyID uuid.UUID, status domain.CommandStatus, output, errMsg *string) (*domain.ProxyCommand, error) {
+	c, err := scanProxyCommand(r.db.QueryRow(ctx,
+		`UPDATE proxy_commands SET status = $3, output = $4, error = $5,
+		     delivered_at = COALESCE(delivered_at, NOW()), finished_at = NOW()
+		 WHERE id = $1 AND proxy_id = $2 AND status IN ('pending', 'delivered')
+		 RETURNING `+proxyCommandColumns,
+		id, proxyID, status, output, errMsg,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("complete proxy command: %w", err)
+	}
+	return c, nil
+}
+
+// Cancel cancels a command the helper has not picked up yet.
+func (r *ProxyCommandRepo) Cancel(ctx context.Context, id, proxyID uuid.UUID) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE proxy_commands SET status = 'cancelled', finished_at = NOW()
+		 WHERE id = $1 AND proxy_id = $2 AND status = 'pending'`, id, proxyID,
+	)
+	if err != nil {
+		return fmt.Errorf("cancel proxy command: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// ExpireStale closes commands that will never report: pending ones past their
+// expiry (the proxy did not sync) and delivered ones whose result is overdue
+// by grace after their timeout.
+func (r *ProxyCommandRepo) ExpireStale(ctx context.Context, grace time.Duration) (int64, error) {
+	expired, err := r.db.Exec(ctx,
+		`UPDATE proxy_commands SET status = 'expired', finished_at = NOW()
+		 WHERE status = 'pending' AND expires_at <= NOW()`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("expire proxy commands: %w", err)
+	}
+	timedOut, err := r.db.Exec(ctx,
+		`UPDATE proxy_commands SET status = 'timed_out', error = 'no result from helper', finished_at = NOW()
+		 WHERE status = 'delivered'
+		   AND delivered_at + make_interval(secs => timeout_seconds + $1) <= NOW()`,
+		int(grace.Seconds()),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("time out proxy commands: %w", err)
+	}
+	return expired.RowsAffected() + timedOut.RowsAffected(), nil
+}
+
+// CleanupOld prunes finished commands older than retention from the history.
+func (r *ProxyCommandRepo) CleanupOld(ctx context.Context, retention time.Duration) (int64, error) {
+	tag, err := r.db.Exec(ctx,
+		`DELETE FROM proxy_commands WHERE finished_at < $1`, time.Now().Add(-retention),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup proxy commands: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type ProxyCredentialRepo struct {
+	db DBTX
+}
+
+func NewProxyCredentialRepo(db DBTX) *ProxyCredentialRepo {
+	return &ProxyCredentialRepo{db: db}
+}
+
+func (r *ProxyCredentialRepo) Get(ctx context.Context, proxyID uuid.UUID) (*domain.ProxyCredential, error) {
+	var c domain.ProxyCredential
+	err := r.db.QueryRow(ctx,
+		`SELECT proxy_id, secret, next_secret, enrollment_token_id, issued_at, rotated_at, last_used_at
+		 FROM proxy_credentials WHERE proxy_id = $1`, proxyID,
+	).Scan(&c.ProxyID, &c.Secret, &c.NextSecret, &c.EnrollmentTokenID, &c.IssuedAt, &c.RotatedAt, &c.LastUsedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get proxy credential: %w", err)
+	}
+	return &c, nil
+}
+
+// Issue replaces the proxy credential with a fresh secret, dropping any pending rotation.
+func (r *ProxyCredentialRepo) Issue(ctx context.Context, proxyID uuid.UUID, secret string, tokenID *uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO proxy_credentials (proxy_id, secret, enrollment_token_id)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (proxy_id) DO UPDATE
+		 SET secret = EXCLUDED.secret, next_secret = NULL,
+		     enrollment_token_id = EXCLUDED.enrollment_token_id,
+		     issued_at = NOW(), rotated_at = NULL, last_used_at = NULL`,
+		proxyID, secret, tokenID)
+	if err != nil {
+		return fmt.Errorf("issue proxy credential: %w", err)
+	}
+	return nil
+}
+
+// SetNext stores the secret the proxy should switch to.
+func (r *ProxyCredentialRepo) SetNext(ctx context.Context, proxyID uuid.UUID, secret string) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE proxy_credentials SET next_secret = $2, rotated_at = NOW() WHERE proxy_id = $1`,
+		proxyID, secret)
+	if err != nil {
+		return fmt.Errorf("rotate proxy credential: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// Promote makes the pending secret the current one, once the proxy has used it.
+func (r *ProxyCredentialRepo) Promote(ctx context.Context, proxyID uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE proxy_credentials SET secret = next_secret, next_secret = NULL
+		 WHERE proxy_id = $1 AND next_secret IS NOT NULL`, proxyID)
+	if err != nil {
+		return fmt.Errorf("promote proxy credential: %w", err)
+	}
+	return nil
+}
+
+func (r *ProxyCredentialRepo) Touch(ctx context.Context, proxyID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE proxy_credentials SET last_used_at = NOW() WHERE proxy_id = $1`, proxyID)
+	if err != nil {
+		return fmt.Errorf("touch proxy credential: %w", err)
+	}
+	return nil
+}
+
+func (r *ProxyCredentialRepo) Delete(ctx context.Context, proxyID uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM proxy_credentials WHERE proxy_id = $1`, proxyID)
+	if err != nil {
+		return fmt.Errorf("delete proxy credential: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
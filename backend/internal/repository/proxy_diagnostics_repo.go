@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type ProxyDiagnosticsRepo struct {
+	db DBTX
+}
+
+func NewProxyDiagnosticsRepo(db DBTX) *ProxyDiagnosticsRepo {
+	return &ProxyDiagnosticsRepo{db: db}
+}
+
+func (r *ProxyDiagnosticsRepo) Create(ctx context.Context, d *domain.DiagnosticsBundle, data []byte) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO proxy_diagnostics (proxy_id, command_id, size_bytes, sha256, data, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, created_at`,
+		d.ProxyID, d.CommandID, d.SizeBytes, d.SHA256, data, d.ExpiresAt,
+	).Scan(&d.ID, &d.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create diagnostics bundle: %w", err)
+	}
+	return nil
+}
+
+// ListByProxy returns the unexpired bundles of a proxy, newest first, without content.
+func (r *ProxyDiagnosticsRepo) ListByProxy(ctx context.Context, proxyID uuid.UUID) ([]domain.DiagnosticsBundle, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, proxy_id, command_id, size_bytes, sha256, created_at, expires_at
+		 FROM proxy_diagnostics
+		 WHERE proxy_id = $1 AND expires_at > NOW()
+		 ORDER BY created_at DESC`, proxyID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list diagnostics bundles: %w", err)
+	}
+	defer rows.Close()
+
+	var bundles []domain.DiagnosticsBundle
+	for rows.Next() {
+		var d domain.DiagnosticsBundle
+		if err := rows.Scan(&d.ID, &d.ProxyID, &d.CommandID, &d.SizeBytes, &d.SHA256, &d.CreatedAt, &d.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("scan diagnostics bundle: %w", err)
+		}
+		bundles = append(bundles, d)
+	}
+	return bundles, nil
+}
+
+// GetWithData returns an unexpired bundle of the proxy with its content.
+func (r *ProxyDiagnosticsRepo) GetWithData(ctx context.Context, id, proxyID uuid.UUID) (*domain.DiagnosticsBundle, []byte, error) {
+	var d domain.DiagnosticsBundle
+	var data []byte
+	err := r.db.QueryRow(ctx,
+		`SELECT id, proxy_id, command_id, size_bytes, sha256, created_at, expires_at, data
+		 FROM proxy_diagnostics
+		 WHERE id = $1 AND proxy_id = $2 AND expires_at > NOW()`, id, proxyID,
+	).Scan(&d.ID, &d.ProxyID, &d.CommandID, &d.SizeBytes, &d.SHA256, &d.CreatedAt, &d.ExpiresAt, &data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("get diagnostics bundle: %w", err)
+	}
+	return &d, data, nil
+}
+
+func (r *ProxyDiagnosticsRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM proxy_diagnostics WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired diagnostics bundles: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type ProxyDriftRepo struct {
+	db DBTX
+}
+
+func NewProxyDriftRepo(db DBTX) *ProxyDriftRepo {
+	return &ProxyDriftRepo{db: db}
+}
+
+// Upsert stores the latest drift check for a proxy. detected_at marks when the
+// current drift started: it is kept while the proxy stays drifted and cleared
+// once the files match again.
+func (r *ProxyDriftRepo) Upsert(ctx context.Context, d *domain.ProxyDrift) error {
+	files := d.Files
+	if files == nil {
+		files = []string{}
+	}
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO proxy_drift (proxy_id, drifted, expected_hash, actual_hash, files, remediated, detected_at, checked_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $2 THEN NOW() END, NOW())
+		 ON CONFLICT (proxy_id) DO UPDATE
+		 SET drifted = EXCLUDED.drifted,
+		     expected_hash = EXCLUDED.expected_hash,
+		     actual_hash = EXCLUDED.actual_hash,
+		     files = EXCLUDED.files,
+		     remediated = EXCLUDED.remediated,
+		     detected_at = CASE
+		         WHEN NOT EXCLUDED.drifted THEN NULL
+		         WHEN proxy_drift.drifted THEN proxy_drift.detected_at
+		         ELSE NOW()
+		     END,
+		     checked_at = EXCLUDED.checked_at
+		 RETURNING detected_at, checked_at`,
+		d.ProxyID, d.Drifted, d.ExpectedHash, d.ActualHash, files, d.Remediated,
+	).Scan(&d.DetectedAt, &d.CheckedAt)
+	if err != nil {
+		return fmt.Errorf("upsert proxy drift: %w", err)
+	}
+	d.Files = files
+	return nil
+}
+
+// List returns the latest drift check of every proxy, keyed by proxy ID.
+func (r *ProxyDriftRepo) List(ctx context.Context) (map[uuid.UUID]*domain.ProxyDrift, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT proxy_id, drifted, expected_hash, actual_hash, files, remediated, detected_at, checked_at
+		 FROM proxy_drift`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list proxy drift: %w", err)
+	}
+	defer rows.Close()
+
+	drift := make(map[uuid.UUID]*domain.ProxyDrift)
+	for rows.Next() {
+		var d domain.ProxyDrift
+		if err := rows.Scan(&d.ProxyID, &d.Drifted, &d.ExpectedHash, &d.ActualHash, &d.Files, &d.Remediated, &d.DetectedAt, &d.CheckedAt); err != nil {
+			return nil, fmt.Errorf("scan proxy drift: %w", err)
+		}
+		drift[d.ProxyID] = &d
+	}
+	return drift, rows.Err()
+}
+
+func (r *ProxyDriftRepo) GetByProxy(ctx context.Context, proxyID uuid.UUID) (*domain.ProxyDrift, error) {
+	var d domain.ProxyDrift
+	err := r.db.QueryRow(ctx,
+		`SELECT proxy_id, drifted, expected_hash, actual_hash, files, remediated, detected_at, checked_at
+		 FROM proxy_drift WHERE proxy_id = $1`, proxyID,
+	).Scan(&d.ProxyID, &d.Drifted, &d.ExpectedHash, &d.ActualHash, &d.Files, &d.Remediated, &d.DetectedAt, &d.CheckedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get proxy drift: %w", err)
+	}
+	return &d, nil
+}
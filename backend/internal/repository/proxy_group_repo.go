@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type ProxyGroupRepo struct {
+	db DBTX
+}
+
+func NewProxyGroupRepo(db DBTX) *ProxyGroupRepo {
+	return &ProxyGroupRepo{db: db}
+}
+
+const proxyGroupColumns = `g.id, g.name, g.description, g.selector, g.priority,
+	(SELECT COUNT(*) FROM proxies p WHERE p.effective_labels @> g.selector),
+	g.created_by, g.created_at, g.updated_at`
+
+func scanProxyGroup(row pgx.Row, g *domain.ProxyGroup) error {
+	return row.Scan(&g.ID, &g.Name, &g.Description, &g.Selector, &g.Priority, &g.ProxyCount, &g.CreatedBy, &g.CreatedAt, &g.UpdatedAt)
+}
+
+func (r *ProxyGroupRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.ProxyGroup, error) {
+	var g domain.ProxyGroup
+	err := scanProxyGroup(r.db.QueryRow(ctx, `SELECT `+proxyGroupColumns+` FROM proxy_groups g WHERE g.id = $1`, id), &g)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get proxy group: %w", err)
+	}
+	return &g, nil
+}
+
+func (r *ProxyGroupRepo) GetByName(ctx context.Context, name string) (*domain.ProxyGroup, error) {
+	var g domain.ProxyGroup
+	err := scanProxyGroup(r.db.QueryRow(ctx, `SELECT `+proxyGroupColumns+` FROM proxy_groups g WHERE g.name = $1`, name), &g)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get proxy group by name: %w", err)
+	}
+	return &g, nil
+}
+
+func (r *ProxyGroupRepo) List(ctx context.Context) ([]domain.ProxyGroup, error) {
+	return r.list(ctx, `SELECT `+proxyGroupColumns+` FROM proxy_groups g ORDER BY g.priority DESC, g.name`)
+}
+
+// ListByConfig returns the groups a config is assigned to.
+func (r *ProxyGroupRepo) ListByConfig(ctx context.Context, configID uuid.UUID) ([]domain.ProxyGroup, error) {
+	return r.list(ctx,
+		`SELECT `+proxyGroupColumns+`
+		 FROM proxy_groups g
+		 JOIN config_groups cg ON g.id = cg.group_id
+		 WHERE cg.config_id = $1
+		 ORDER BY g.priority DESC, g.name`, configID)
+}
+
+func (r *ProxyGroupRepo) list(ctx context.Context, query string, args ...interface{}) ([]domain.ProxyGroup, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list proxy groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []domain.ProxyGroup
+	for rows.Next() {
+		var g domain.ProxyGroup
+		if err := scanProxyGroup(rows, &g); err != nil {
+			return nil, fmt.Errorf("scan proxy group: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+func (r *ProxyGroupRepo) Create(ctx context.Context, g *domain.ProxyGroup) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO proxy_groups (name, description, selector, priority, created_by)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, created_at, updated_at`,
+		g.Name, g.Description, labelsOrEmpty(g.Selector), g.Priority, g.CreatedBy,
+	).Scan(&g.ID, &g.CreatedAt, &g.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create proxy group: %w", err)
+	}
+	return nil
+}
+
+func (r *ProxyGroupRepo) Update(ctx context.Context, g *domain.ProxyGroup) error {
+	err := r.db.QueryRow(ctx,
+		`UPDATE proxy_groups
+		 SET name = $1, description = $2, selector = $3, priority = $4, updated_at = NOW()
+		 WHERE id = $5
+		 RETURNING updated_at`,
+		g.Name, g.Description, labelsOrEmpty(g.Selector), g.Priority, g.ID,
+	).Scan(&g.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("update proxy group: %w", err)
+	}
+	return nil
+}
+
+func (r *ProxyGroupRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM proxy_groups WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete proxy group: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
@@ -16,12 +17,18 @@ func NewProxyLogsRepo(db DBTX) *ProxyLogsRepo {
 	return &ProxyLogsRepo{db: db}
 }
 
+// Create stores a log line. A zero CapturedAt is stored as the insert time.
 func (r *ProxyLogsRepo) Create(ctx context.Context, l *domain.ProxyLog) error {
+	var capturedAt *time.Time
+	if !l.CapturedAt.IsZero() {
+		capturedAt = &l.CapturedAt
+	}
+
 	err := r.db.QueryRow(ctx,
-		`INSERT INTO proxy_logs (proxy_id, log_level, message)
-		 VALUES ($1, $2, $3)
+		`INSERT INTO proxy_logs (proxy_id, captured_at, log_level, tag, message)
+		 VALUES ($1, COALESCE($2, NOW()), $3, $4, $5)
 		 RETURNING id, captured_at, expires_at`,
-		l.ProxyID, l.LogLevel, l.Message,
+		l.ProxyID, capturedAt, l.LogLevel, l.Tag, l.Message,
 	).Scan(&l.ID, &l.CapturedAt, &l.ExpiresAt)
 	if err != nil {
 		return fmt.Errorf("create proxy log: %w", err)
@@ -41,7 +48,7 @@ func (r *ProxyLogsRepo) BulkCreate(ctx context.Context, proxyID uuid.UUID, logs
 
 func (r *ProxyLogsRepo) ListByProxy(ctx context.Context, proxyID uuid.UUID) ([]domain.ProxyLog, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, proxy_id, captured_at, log_level, message, expires_at
+		`SELECT id, proxy_id, captured_at, log_level, tag, message, expires_at
 		 FROM proxy_logs WHERE proxy_id = $1 AND expires_at > NOW()
 		 ORDER BY captured_at DESC`, proxyID,
 	)
@@ -53,7 +60,7 @@ func (r *ProxyLogsRepo) ListByProxy(ctx context.Context, proxyID uuid.UUID) ([]d
 	var logs []domain.ProxyLog
 	for rows.Next() {
 		var l domain.ProxyLog
-		if err := rows.Scan(&l.ID, &l.ProxyID, &l.CapturedAt, &l.LogLevel, &l.Message, &l.ExpiresAt); err != nil {
+		if err := rows.Scan(&l.ID, &l.ProxyID, &l.CapturedAt, &l.LogLevel, &l.Tag, &l.Message, &l.ExpiresAt); err != nil {
 			return nil, fmt.Errorf("scan proxy log: %w", err)
 		}
 		logs = append(logs, l)
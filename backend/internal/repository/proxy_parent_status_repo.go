@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type ProxyParentStatusRepo struct {
+	db DBTX
+}
+
+func NewProxyParentStatusRepo(db DBTX) *ProxyParentStatusRepo {
+	return &ProxyParentStatusRepo{db: db}
+}
+
+// Replace stores the parent health reported by a proxy, dropping parents it no
+// longer reports (removed from its parent.config).
+func (r *ProxyParentStatusRepo) Replace(ctx context.Context, proxyID uuid.UUID, statuses []domain.ProxyParentStatus) error {
+	addresses := make([]string, 0, len(statuses))
+	ports := make([]int32, 0, len(statuses))
+	ups := make([]bool, 0, len(statuses))
+	for _, s := range statuses {
+		addresses = append(addresses, s.Address)
+		ports = append(ports, int32(s.Port))
+		ups = append(ups, s.IsUp)
+	}
+
+	_, err := r.db.Exec(ctx,
+		`DELETE FROM proxy_parent_status s
+		 WHERE s.proxy_id = $1
+		   AND NOT EXISTS (
+		       SELECT 1 FROM unnest($2::text[], $3::int[]) AS n(address, port)
+		       WHERE n.address = s.address AND n.port = s.port)`,
+		proxyID, addresses, ports,
+	)
+	if err != nil {
+		return fmt.Errorf("prune parent status: %w", err)
+	}
+
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	_, err = r.db.Exec(ctx,
+		`INSERT INTO proxy_parent_status (proxy_id, address, port, is_up, checked_at)
+		 SELECT $1, n.address, n.port, n.is_up, NOW()
+		 FROM unnest($2::text[], $3::int[], $4::bool[]) AS n(address, port, is_up)
+		 ON CONFLICT (proxy_id, address, port) DO UPDATE
+		 SET is_up = EXCLUDED.is_up, checked_at = EXCLUDED.checked_at`,
+		proxyID, addresses, ports, ups,
+	)
+	if err != nil {
+		return fmt.Errorf("upsert parent status: %w", err)
+	}
+	return nil
+}
+
+func (r *ProxyParentStatusRepo) ListByProxy(ctx context.Context, proxyID uuid.UUID) ([]domain.ProxyParentStatus, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT proxy_id, address, port, is_up, checked_at
+		 FROM proxy_parent_status WHERE proxy_id = $1
+		 ORDER BY address, port`, proxyID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list parent status: %w", err)
+	}
+	defer rows.Close()
+
+	var statuses []domain.ProxyParentStatus
+	for rows.Next() {
+		var s domain.ProxyParentStatus
+		if err := rows.Scan(&s.ProxyID, &s.Address, &s.Port, &s.IsUp, &s.CheckedAt); err != nil {
+			return nil, fmt.Errorf("scan parent status: %w", err)
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
@@ -22,9 +22,11 @@ func NewProxyRepo(db DBTX) *ProxyRepo {
 func (r *ProxyRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Proxy, error) {
 	var p domain.Proxy
 	err := r.db.QueryRow(ctx,
-		`SELECT id, hostname, config_id, is_online, last_seen, current_config_hash, registered_at, registered_ip, capture_logs_until
+		`SELECT id, hostname, config_id, is_online, last_seen, current_config_hash, registered_at, registered_ip, capture_logs_until, capture_debug_tags,
+		        last_ack_status, last_ack_message, last_ack_at, client_cert_fingerprint, labels, helper_labels, target_config_id, target_config_hash
 		 FROM proxies WHERE id = $1`, id,
-	).Scan(&p.ID, &p.Hostname, &p.ConfigID, &p.IsOnline, &p.LastSeen, &p.CurrentConfigHash, &p.RegisteredAt, &p.RegisteredIP, &p.CaptureLogsUntil)
+	).Scan(&p.ID, &p.Hostname, &p.ConfigID, &p.IsOnline, &p.LastSeen, &p.CurrentConfigHash, &p.RegisteredAt, &p.RegisteredIP, &p.CaptureLogsUntil, &p.CaptureDebugTags,
+		&p.LastAckStatus, &p.LastAckMessage, &p.LastAckAt, &p.ClientCertFingerprint, &p.Labels, &p.HelperLabels, &p.TargetConfigID, &p.TargetConfigHash)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, domain.ErrNotFound
 	}
@@ -34,12 +36,34 @@ func (r *ProxyRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Proxy, e
 	return &p, nil
 }
 
+// HostnamesByIDs returns the hostnames of the given proxies; unknown IDs are left out.
+func (r *ProxyRepo) HostnamesByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, hostname FROM proxies WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list proxy hostnames: %w", err)
+	}
+	defer rows.Close()
+
+	hostnames := make(map[uuid.UUID]string, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var hostname string
+		if err := rows.Scan(&id, &hostname); err != nil {
+			return nil, fmt.Errorf("scan proxy hostname: %w", err)
+		}
+		hostnames[id] = hostname
+	}
+	return hostnames, rows.Err()
+}
+
 func (r *ProxyRepo) GetByHostname(ctx context.Context, hostname string) (*domain.Proxy, error) {
 	var p domain.Proxy
 	err := r.db.QueryRow(ctx,
-		`SELECT id, hostname, config_id, is_online, last_seen, current_config_hash, registered_at, registered_ip, capture_logs_until
+		`SELECT id, hostname, config_id, is_online, last_seen, current_config_hash, registered_at, registered_ip, capture_logs_until, capture_debug_tags,
+		        last_ack_status, last_ack_message, last_ack_at, client_cert_fingerprint, labels, helper_labels, target_config_id, target_config_hash
 		 FROM proxies WHERE hostname = $1`, hostname,
-	).Scan(&p.ID, &p.Hostname, &p.ConfigID, &p.IsOnline, &p.LastSeen, &p.CurrentConfigHash, &p.RegisteredAt, &p.RegisteredIP, &p.CaptureLogsUntil)
+	).Scan(&p.ID, &p.Hostname, &p.ConfigID, &p.IsOnline, &p.LastSeen, &p.CurrentConfigHash, &p.RegisteredAt, &p.RegisteredIP, &p.CaptureLogsUntil, &p.CaptureDebugTags,
+		&p.LastAckStatus, &p.LastAckMessage, &p.LastAckAt, &p.ClientCertFingerprint, &p.Labels, &p.HelperLabels, &p.TargetConfigID, &p.TargetConfigHash)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, domain.ErrNotFound
 	}
@@ -51,7 +75,8 @@ func (r *ProxyRepo) GetByHostname(ctx context.Context, hostname string) (*domain
 
 func (r *ProxyRepo) List(ctx context.Context) ([]domain.Proxy, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, hostname, config_id, is_online, last_seen, current_config_hash, registered_at, registered_ip, capture_logs_until
+		`SELECT id, hostname, config_id, is_online, last_seen, current_config_hash, registered_at, registered_ip, capture_logs_until, capture_debug_tags,
+		        last_ack_status, last_ack_message, last_ack_at, client_cert_fingerprint, labels, helper_labels, target_config_id, target_config_hash
 		 FROM proxies ORDER BY hostname`,
 	)
 	if err != nil {
@@ -62,7 +87,8 @@ func (r *ProxyRepo) List(ctx context.Context) ([]domain.Proxy, error) {
 	var proxies []domain.Proxy
 	for rows.Next() {
 		var p domain.Proxy
-		if err := rows.Scan(&p.ID, &p.Hostname, &p.ConfigID, &p.IsOnline, &p.LastSeen, &p.CurrentConfigHash, &p.RegisteredAt, &p.RegisteredIP, &p.CaptureLogsUntil); err != nil {
+		if err := rows.Scan(&p.ID, &p.Hostname, &p.ConfigID, &p.IsOnline, &p.LastSeen, &p.CurrentConfigHash, &p.RegisteredAt, &p.RegisteredIP, &p.CaptureLogsUntil, &p.CaptureDebugTags,
+			&p.LastAckStatus, &p.LastAckMessage, &p.LastAckAt, &p.ClientCertFingerprint, &p.Labels, &p.HelperLabels, &p.TargetConfigID, &p.TargetConfigHash); err != nil {
 			return nil, fmt.Errorf("scan proxy: %w", err)
 		}
 		proxies = append(proxies, p)
@@ -72,10 +98,10 @@ func (r *ProxyRepo) List(ctx context.Context) ([]domain.Proxy, error) {
 
 func (r *ProxyRepo) Create(ctx context.Context, p *domain.Proxy) error {
 	err := r.db.QueryRow(ctx,
-		`INSERT INTO proxies (hostname, config_id, registered_ip)
-		 VALUES ($1, $2, $3)
+		`INSERT INTO proxies (hostname, config_id, registered_ip, labels, helper_labels)
+		 VALUES ($1, $2, $3, $4, $5)
 		 RETURNING id, is_online, registered_at`,
-		p.Hostname, p.ConfigID, p.RegisteredIP,
+		p.Hostname, p.ConfigID, p.RegisteredIP, labelsOrEmpty(p.Labels), labelsOrEmpty(p.HelperLabels),
 	).Scan(&p.ID, &p.IsOnline, &p.RegisteredAt)
 	if err != nil {
 		return fmt.Errorf("create proxy: %w", err)
@@ -98,6 +124,15 @@ func (r *ProxyRepo) UpdateLastSeen(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+// SetTargetHash records the hash of the files handed to a proxy for a config;
+// nil configID and hash clear it.
+func (r *ProxyRepo) SetTargetHash(ctx context.Context, id uuid.UUID, configID *uuid.UUID, hash *string) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE proxies SET target_config_id = $1, target_config_hash = $2 WHERE id = $3`, configID, hash, id,
+	)
+	return err
+}
+
 func (r *ProxyRepo) UpdateConfigHash(ctx context.Context, id uuid.UUID, hash string) error {
 	_, err := r.db.Exec(ctx,
 		`UPDATE proxies SET current_config_hash = $1, last_seen = NOW(), is_online = true WHERE id = $2`, hash, id,
@@ -105,23 +140,121 @@ func (r *ProxyRepo) UpdateConfigHash(ctx context.Context, id uuid.UUID, hash str
 	return err
 }
 
-func (r *ProxyRepo) SetCaptureLogsUntil(ctx context.Context, id uuid.UUID, until time.Time) error {
+func (r *ProxyRepo) UpdateAckResult(ctx context.Context, id uuid.UUID, status, message string) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE proxies SET last_ack_status = $1, last_ack_message = NULLIF($2, ''), last_ack_at = NOW() WHERE id = $3`,
+		status, message, id,
+	)
+	return err
+}
+
+func (r *ProxyRepo) UpdateClientCertFingerprint(ctx context.Context, id uuid.UUID, fingerprint string) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE proxies SET client_cert_fingerprint = $1 WHERE id = $2`, fingerprint, id,
+	)
+	return err
+}
+
+func (r *ProxyRepo) SetCaptureLogsUntil(ctx context.Context, id uuid.UUID, until time.Time, debugTags []string) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE proxies SET capture_logs_until = $1, capture_debug_tags = $2 WHERE id = $3`, until, debugTags, id,
+	)
+	return err
+}
+
+// SetLabels replaces the labels an admin set on a proxy.
+func (r *ProxyRepo) SetLabels(ctx context.Context, id uuid.UUID, labels map[string]string) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE proxies SET labels = $1 WHERE id = $2`, labelsOrEmpty(labels), id,
+	)
+	if err != nil {
+		return fmt.Errorf("set proxy labels: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// SetHelperLabels replaces the labels the helper of a proxy reported.
+func (r *ProxyRepo) SetHelperLabels(ctx context.Context, id uuid.UUID, labels map[string]string) error {
 	_, err := r.db.Exec(ctx,
-		`UPDATE proxies SET capture_logs_until = $1 WHERE id = $2`, until, id,
+		`UPDATE proxies SET helper_labels = $1 WHERE id = $2`, labelsOrEmpty(labels), id,
 	)
 	return err
 }
 
-func (r *ProxyRepo) MarkOfflineStale(ctx context.Context) error {
+// MergeLabels sets the given labels on a proxy, keeping the ones it already
+// has under other keys.
+func (r *ProxyRepo) MergeLabels(ctx context.Context, id uuid.UUID, labels map[string]string) error {
 	_, err := r.db.Exec(ctx,
-		`UPDATE proxies SET is_online = FALSE WHERE last_seen < NOW() - INTERVAL '2 minutes' AND is_online = TRUE`,
+		`UPDATE proxies SET labels = labels || $1::jsonb WHERE id = $2`, labelsOrEmpty(labels), id,
 	)
 	return err
 }
 
+// ListBySelector returns the proxies whose effective labels (helper labels
+// overridden by admin labels) include every label of the selector.
+func (r *ProxyRepo) ListBySelector(ctx context.Context, selector map[string]string) ([]domain.Proxy, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, hostname, is_online, last_seen, current_config_hash, registered_at, labels, helper_labels
+		 FROM proxies WHERE effective_labels @> $1::jsonb ORDER BY hostname`, labelsOrEmpty(selector),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list proxies by selector: %w", err)
+	}
+	defer rows.Close()
+
+	var proxies []domain.Proxy
+	for rows.Next() {
+		var p domain.Proxy
+		if err := rows.Scan(&p.ID, &p.Hostname, &p.IsOnline, &p.LastSeen, &p.CurrentConfigHash, &p.RegisteredAt, &p.Labels, &p.HelperLabels); err != nil {
+			return nil, fmt.Errorf("scan proxy: %w", err)
+		}
+		proxies = append(proxies, p)
+	}
+	return proxies, nil
+}
+
+// labelsOrEmpty keeps a nil map from being written as SQL NULL.
+func labelsOrEmpty(labels map[string]string) map[string]string {
+	if labels == nil {
+		return map[string]string{}
+	}
+	return labels
+}
+
+// MarkOfflineStale marks proxies not seen in 2 minutes as offline and returns the ones it changed.
+func (r *ProxyRepo) MarkOfflineStale(ctx context.Context) ([]domain.Proxy, error) {
+	rows, err := r.db.Query(ctx,
+		`UPDATE proxies SET is_online = FALSE
+		 WHERE last_seen < NOW() - INTERVAL '2 minutes' AND is_online = TRUE
+		 RETURNING id, hostname, last_seen`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("mark offline stale proxies: %w", err)
+	}
+	defer rows.Close()
+
+	var proxies []domain.Proxy
+	for rows.Next() {
+		var p domain.Proxy
+		if err := rows.Scan(&p.ID, &p.Hostname, &p.LastSeen); err != nil {
+			return nil, fmt.Errorf("scan proxy: %w", err)
+		}
+		proxies = append(proxies, p)
+	}
+	return proxies, nil
+}
+
+// DeleteOfflineStale removes proxies offline for more than 4 hours. Enrolled
+// proxies (with sync credentials) are kept, so their helper can reconnect
+// after a longer outage; an admin removes them explicitly.
 func (r *ProxyRepo) DeleteOfflineStale(ctx context.Context) (int64, error) {
 	tag, err := r.db.Exec(ctx,
-		`DELETE FROM proxies WHERE is_online = FALSE AND last_seen < NOW() - INTERVAL '4 hours'`,
+		`DELETE FROM proxies p
+		 WHERE p.is_online = FALSE AND p.last_seen < NOW() - INTERVAL '4 hours'
+		   AND NOT EXISTS (SELECT 1 FROM proxy_credentials c WHERE c.proxy_id = p.id)`,
 	)
 	if err != nil {
 		return 0, fmt.Errorf("delete offline stale proxies: %w", err)
@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
 )
 
@@ -16,17 +18,64 @@ func NewProxyStatsRepo(db DBTX) *ProxyStatsRepo {
 	return &ProxyStatsRepo{db: db}
 }
 
+// proxyStatColumns lists raw and delta columns in the order scanned by scanProxyStat.
+const proxyStatColumns = `id, proxy_id, collected_at,
+	active_connections, total_connections, cache_hits, cache_misses, errors,
+	COALESCE(total_requests, 0), COALESCE(connect_requests, 0),
+	COALESCE(responses_2xx, 0), COALESCE(responses_3xx, 0), COALESCE(responses_4xx, 0), COALESCE(responses_5xx, 0),
+	COALESCE(err_connect_fail, 0), COALESCE(err_client_abort, 0), COALESCE(broken_server_conns, 0),
+	COALESCE(bytes_in, 0), COALESCE(bytes_out, 0),
+	COALESCE(interval_seconds, 0), COALESCE(counter_reset, FALSE),
+	COALESCE(total_connections_delta, 0), COALESCE(cache_hits_delta, 0), COALESCE(cache_misses_delta, 0),
+	COALESCE(errors_delta, 0), COALESCE(total_requests_delta, 0), COALESCE(connect_requests_delta, 0),
+	COALESCE(responses_2xx_delta, 0), COALESCE(responses_3xx_delta, 0),
+	COALESCE(responses_4xx_delta, 0), COALESCE(responses_5xx_delta, 0),
+	COALESCE(err_connect_fail_delta, 0), COALESCE(err_client_abort_delta, 0), COALESCE(broken_server_conns_delta, 0),
+	COALESCE(bytes_in_delta, 0), COALESCE(bytes_out_delta, 0)`
+
+func scanProxyStat(row pgx.Row, s *domain.ProxyStat) error {
+	return row.Scan(
+		&s.ID, &s.ProxyID, &s.CollectedAt,
+		&s.ActiveConnections, &s.TotalConnections, &s.CacheHits, &s.CacheMisses, &s.Errors,
+		&s.TotalRequests, &s.ConnectRequests,
+		&s.Responses2xx, &s.Responses3xx, &s.Responses4xx, &s.Responses5xx,
+		&s.ErrConnectFail, &s.ErrClientAbort, &s.BrokenServerConns,
+		&s.BytesIn, &s.BytesOut,
+		&s.Delta.IntervalSeconds, &s.Delta.CounterReset,
+		&s.Delta.TotalConnections, &s.Delta.CacheHits, &s.Delta.CacheMisses,
+		&s.Delta.Errors, &s.Delta.TotalRequests, &s.Delta.ConnectRequests,
+		&s.Delta.Responses2xx, &s.Delta.Responses3xx,
+		&s.Delta.Responses4xx, &s.Delta.Responses5xx,
+		&s.Delta.ErrConnectFail, &s.Delta.ErrClientAbort, &s.Delta.BrokenServerConns,
+		&s.Delta.BytesIn, &s.Delta.BytesOut,
+	)
+}
+
 func (r *ProxyStatsRepo) Create(ctx context.Context, s *domain.ProxyStat) error {
+	d := s.Delta
 	err := r.db.QueryRow(ctx,
 		`INSERT INTO proxy_stats (
 			proxy_id, active_connections, total_connections, cache_hits, cache_misses, errors,
 			total_requests, connect_requests, responses_2xx, responses_3xx, responses_4xx, responses_5xx,
-			err_connect_fail, err_client_abort, broken_server_conns, bytes_in, bytes_out
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17)
+			err_connect_fail, err_client_abort, broken_server_conns, bytes_in, bytes_out,
+			interval_seconds, counter_reset,
+			total_connections_delta, cache_hits_delta, cache_misses_delta, errors_delta,
+			total_requests_delta, connect_requests_delta,
+			responses_2xx_delta, responses_3xx_delta, responses_4xx_delta, responses_5xx_delta,
+			err_connect_fail_delta, err_client_abort_delta, broken_server_conns_delta,
+			bytes_in_delta, bytes_out_delta
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,
+		          $18,$19,$20,$21,$22,$23,$24,$25,$26,$27,$28,$29,$30,$31,$32,$33,$34)
 		 RETURNING id, collected_at`,
 		s.ProxyID, s.ActiveConnections, s.TotalConnections, s.CacheHits, s.CacheMisses, s.Errors,
 		s.TotalRequests, s.ConnectRequests, s.Responses2xx, s.Responses3xx, s.Responses4xx, s.Responses5xx,
 		s.ErrConnectFail, s.ErrClientAbort, s.BrokenServerConns, s.BytesIn, s.BytesOut,
+		d.IntervalSeconds, d.CounterReset,
+		d.TotalConnections, d.CacheHits, d.CacheMisses, d.Errors,
+		d.TotalRequests, d.ConnectRequests,
+		d.Responses2xx, d.Responses3xx, d.Responses4xx, d.Responses5xx,
+		d.ErrConnectFail, d.ErrClientAbort, d.BrokenServerConns,
+		d.BytesIn, d.BytesOut,
 	).Scan(&s.ID, &s.CollectedAt)
 	if err != nil {
 		return fmt.Errorf("create proxy stat: %w", err)
@@ -34,14 +83,26 @@ func (r *ProxyStatsRepo) Create(ctx context.Context, s *domain.ProxyStat) error
 	return nil
 }
 
+// GetLatest returns the most recent sample for a proxy, used as the baseline for deltas.
+func (r *ProxyStatsRepo) GetLatest(ctx context.Context, proxyID uuid.UUID) (*domain.ProxyStat, error) {
+	var s domain.ProxyStat
+	err := scanProxyStat(r.db.QueryRow(ctx,
+		`SELECT `+proxyStatColumns+`
+		 FROM proxy_stats WHERE proxy_id = $1
+		 ORDER BY collected_at DESC LIMIT 1`, proxyID,
+	), &s)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get latest proxy stat: %w", err)
+	}
+	return &s, nil
+}
+
 func (r *ProxyStatsRepo) ListByProxy(ctx context.Context, proxyID uuid.UUID, limit int) ([]domain.ProxyStat, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, proxy_id, collected_at,
-			active_connections, total_connections, cache_hits, cache_misses, errors,
-			COALESCE(total_requests, 0), COALESCE(connect_requests, 0),
-			COALESCE(responses_2xx, 0), COALESCE(responses_3xx, 0), COALESCE(responses_4xx, 0), COALESCE(responses_5xx, 0),
-			COALESCE(err_connect_fail, 0), COALESCE(err_client_abort, 0), COALESCE(broken_server_conns, 0),
-			COALESCE(bytes_in, 0), COALESCE(bytes_out, 0)
+		`SELECT `+proxyStatColumns+`
 		 FROM proxy_stats WHERE proxy_id = $1
 		 ORDER BY collected_at DESC LIMIT $2`, proxyID, limit,
 	)
@@ -53,14 +114,7 @@ func (r *ProxyStatsRepo) ListByProxy(ctx context.Context, proxyID uuid.UUID, lim
 	var stats []domain.ProxyStat
 	for rows.Next() {
 		var s domain.ProxyStat
-		if err := rows.Scan(
-			&s.ID, &s.ProxyID, &s.CollectedAt,
-			&s.ActiveConnections, &s.TotalConnections, &s.CacheHits, &s.CacheMisses, &s.Errors,
-			&s.TotalRequests, &s.ConnectRequests,
-			&s.Responses2xx, &s.Responses3xx, &s.Responses4xx, &s.Responses5xx,
-			&s.ErrConnectFail, &s.ErrClientAbort, &s.BrokenServerConns,
-			&s.BytesIn, &s.BytesOut,
-		); err != nil {
+		if err := scanProxyStat(rows, &s); err != nil {
 			return nil, fmt.Errorf("scan proxy stat: %w", err)
 		}
 		stats = append(stats, s)
@@ -68,25 +122,44 @@ func (r *ProxyStatsRepo) ListByProxy(ctx context.Context, proxyID uuid.UUID, lim
 	return stats, nil
 }
 
+// ListByProxyAggregated groups samples per minute. Raw counters carry the last
+// cumulative value seen in the minute; Delta carries the traffic of the minute.
 func (r *ProxyStatsRepo) ListByProxyAggregated(ctx context.Context, proxyID uuid.UUID, limit int) ([]domain.ProxyStat, error) {
 	rows, err := r.db.Query(ctx,
 		`SELECT MIN(id::text)::uuid, proxy_id, date_trunc('minute', collected_at) AS minute,
 			MAX(active_connections)::int,
-			SUM(total_connections)::bigint,
-			SUM(cache_hits)::bigint,
-			SUM(cache_misses)::bigint,
-			SUM(errors)::int,
-			SUM(COALESCE(total_requests, 0))::bigint,
-			SUM(COALESCE(connect_requests, 0))::bigint,
-			SUM(COALESCE(responses_2xx, 0))::bigint,
-			SUM(COALESCE(responses_3xx, 0))::bigint,
-			SUM(COALESCE(responses_4xx, 0))::bigint,
-			SUM(COALESCE(responses_5xx, 0))::bigint,
-			SUM(COALESCE(err_connect_fail, 0))::int,
-			SUM(COALESCE(err_client_abort, 0))::int,
-			SUM(COALESCE(broken_server_conns, 0))::int,
-			SUM(COALESCE(bytes_in, 0))::bigint,
-			SUM(COALESCE(bytes_out, 0))::bigint
+			MAX(total_connections)::bigint,
+			MAX(cache_hits)::bigint,
+			MAX(cache_misses)::bigint,
+			MAX(errors)::int,
+			MAX(COALESCE(total_requests, 0))::bigint,
+			MAX(COALESCE(connect_requests, 0))::bigint,
+			MAX(COALESCE(responses_2xx, 0))::bigint,
+			MAX(COALESCE(responses_3xx, 0))::bigint,
+			MAX(COALESCE(responses_4xx, 0))::bigint,
+			MAX(COALESCE(responses_5xx, 0))::bigint,
+			MAX(COALESCE(err_connect_fail, 0))::int,
+			MAX(COALESCE(err_client_abort, 0))::int,
+			MAX(COALESCE(broken_server_conns, 0))::int,
+			MAX(COALESCE(bytes_in, 0))::bigint,
+			MAX(COALESCE(bytes_out, 0))::bigint,
+			SUM(COALESCE(interval_seconds, 0))::float8,
+			BOOL_OR(COALESCE(counter_reset, FALSE)),
+			SUM(COALESCE(total_connections_delta, 0))::bigint,
+			SUM(COALESCE(cache_hits_delta, 0))::bigint,
+			SUM(COALESCE(cache_misses_delta, 0))::bigint,
+			SUM(COALESCE(errors_delta, 0))::bigint,
+			SUM(COALESCE(total_requests_delta, 0))::bigint,
+			SUM(COALESCE(connect_requests_delta, 0))::bigint,
+			SUM(COALESCE(responses_2xx_delta, 0))::bigint,
+			SUM(COALESCE(responses_3xx_delta, 0))::bigint,
+			SUM(COALESCE(responses_4xx_delta, 0))::bigint,
+			SUM(COALESCE(responses_5xx_delta, 0))::bigint,
+			SUM(COALESCE(err_connect_fail_delta, 0))::bigint,
+			SUM(COALESCE(err_client_abort_delta, 0))::bigint,
+			SUM(COALESCE(broken_server_conns_delta, 0))::bigint,
+			SUM(COALESCE(bytes_in_delta, 0))::bigint,
+			SUM(COALESCE(bytes_out_delta, 0))::bigint
 		 FROM proxy_stats WHERE proxy_id = $1
 		 GROUP BY proxy_id, date_trunc('minute', collected_at)
 		 ORDER BY minute DESC LIMIT $2`, proxyID, limit,
@@ -99,14 +172,7 @@ func (r *ProxyStatsRepo) ListByProxyAggregated(ctx context.Context, proxyID uuid
 	var stats []domain.ProxyStat
 	for rows.Next() {
 		var s domain.ProxyStat
-		if err := rows.Scan(
-			&s.ID, &s.ProxyID, &s.CollectedAt,
-			&s.ActiveConnections, &s.TotalConnections, &s.CacheHits, &s.CacheMisses, &s.Errors,
-			&s.TotalRequests, &s.ConnectRequests,
-			&s.Responses2xx, &s.Responses3xx, &s.Responses4xx, &s.Responses5xx,
-			&s.ErrConnectFail, &s.ErrClientAbort, &s.BrokenServerConns,
-			&s.BytesIn, &s.BytesOut,
-		); err != nil {
+		if err := scanProxyStat(rows, &s); err != nil {
 			return nil, fmt.Errorf("scan proxy stat aggregated: %w", err)
 		}
 		stats = append(stats, s)
@@ -129,22 +195,24 @@ type ProxyStatsSummary struct {
 	BytesOut1h      int64 `json:"bytes_out_1h"`
 }
 
+// SummaryForProxy sums the per-interval deltas of the last hour; summing the
+// raw cumulative counters would count the same traffic once per sample.
 func (r *ProxyStatsRepo) SummaryForProxy(ctx context.Context, proxyID uuid.UUID) (*ProxyStatsSummary, error) {
 	var s ProxyStatsSummary
 	var cacheHits, cacheMisses int64
 	err := r.db.QueryRow(ctx,
 		`SELECT
 		   COALESCE((SELECT active_connections FROM proxy_stats WHERE proxy_id = $1 ORDER BY collected_at DESC LIMIT 1), 0),
-		   COALESCE(SUM(total_connections), 0),
-		   COALESCE(SUM(cache_hits), 0),
-		   COALESCE(SUM(cache_misses), 0),
-		   COALESCE(SUM(COALESCE(total_requests, 0)), 0),
-		   COALESCE(SUM(errors), 0),
-		   COALESCE(SUM(COALESCE(responses_2xx, 0)), 0),
-		   COALESCE(SUM(COALESCE(responses_4xx, 0)), 0),
-		   COALESCE(SUM(COALESCE(responses_5xx, 0)), 0),
-		   COALESCE(SUM(COALESCE(bytes_in, 0)), 0),
-		   COALESCE(SUM(COALESCE(bytes_out, 0)), 0)
+		   COALESCE(SUM(COALESCE(total_connections_delta, 0)), 0)::bigint,
+		   COALESCE(SUM(COALESCE(cache_hits_delta, 0)), 0)::bigint,
+		   COALESCE(SUM(COALESCE(cache_misses_delta, 0)), 0)::bigint,
+		   COALESCE(SUM(COALESCE(total_requests_delta, 0)), 0)::bigint,
+		   COALESCE(SUM(COALESCE(errors_delta, 0)), 0)::bigint,
+		   COALESCE(SUM(COALESCE(responses_2xx_delta, 0)), 0)::bigint,
+		   COALESCE(SUM(COALESCE(responses_4xx_delta, 0)), 0)::bigint,
+		   COALESCE(SUM(COALESCE(responses_5xx_delta, 0)), 0)::bigint,
+		   COALESCE(SUM(COALESCE(bytes_in_delta, 0)), 0)::bigint,
+		   COALESCE(SUM(COALESCE(bytes_out_delta, 0)), 0)::bigint
 		 FROM proxy_stats
 		 WHERE proxy_id = $1 AND collected_at > NOW() - INTERVAL '1 hour'`,
 		proxyID,
@@ -162,10 +230,3 @@ func (r *ProxyStatsRepo) SummaryForProxy(ctx context.Context, proxyID uuid.UUID)
 	}
 	return &s, nil
 }
-
-func (r *ProxyStatsRepo) CleanupOld(ctx context.Context) error {
-	_, err := r.db.Exec(ctx,
-		`DELETE FROM proxy_stats WHERE collected_at < NOW() - INTERVAL '7 days'`,
-	)
-	return err
-}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type RuleSetRepo struct {
+	db DBTX
+}
+
+func NewRuleSetRepo(db DBTX) *RuleSetRepo {
+	return &RuleSetRepo{db: db}
+}
+
+// ruleSetColumns reads a rule set with the content of its current version.
+const ruleSetColumns = `r.id, r.name, r.description, r.version, v.domains, v.ip_ranges,
+	(SELECT COUNT(*) FROM config_rule_sets c WHERE c.rule_set_id = r.id),
+	r.created_by, r.created_at, r.updated_at
+	FROM rule_sets r
+	JOIN rule_set_versions v ON v.rule_set_id = r.id AND v.version = r.version`
+
+func scanRuleSet(row pgx.Row, rs *domain.RuleSet) error {
+	return row.Scan(&rs.ID, &rs.Name, &rs.Description, &rs.Version, &rs.Domains, &rs.IPRanges,
+		&rs.ConfigCount, &rs.CreatedBy, &rs.CreatedAt, &rs.UpdatedAt)
+}
+
+func (r *RuleSetRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.RuleSet, error) {
+	var rs domain.RuleSet
+	err := scanRuleSet(r.db.QueryRow(ctx, `SELECT `+ruleSetColumns+` WHERE r.id = $1`, id), &rs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get rule set: %w", err)
+	}
+	return &rs, nil
+}
+
+// GetByIDForUpdate reads a rule set and locks its row until the transaction
+// ends, so its version cannot move under the caller.
+func (r *RuleSetRepo) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.RuleSet, error) {
+	var rs domain.RuleSet
+	err := scanRuleSet(r.db.QueryRow(ctx, `SELECT `+ruleSetColumns+` WHERE r.id = $1 FOR UPDATE OF r`, id), &rs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get rule set: %w", err)
+	}
+	return &rs, nil
+}
+
+func (r *RuleSetRepo) GetByName(ctx context.Context, name string) (*domain.RuleSet, error) {
+	var rs domain.RuleSet
+	err := scanRuleSet(r.db.QueryRow(ctx, `SELECT `+ruleSetColumns+` WHERE r.name = $1`, name), &rs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get rule set by name: %w", err)
+	}
+	return &rs, nil
+}
+
+func (r *RuleSetRepo) List(ctx context.Context) ([]domain.RuleSet, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+ruleSetColumns+` ORDER BY r.name`)
+	if err != nil {
+		return nil, fmt.Errorf("list rule sets: %w", err)
+	}
+	defer rows.Close()
+
+	var sets []domain.RuleSet
+	for rows.Next() {
+		var rs domain.RuleSet
+		if err := scanRuleSet(rows, &rs); err != nil {
+			return nil, fmt.Errorf("scan rule set: %w", err)
+		}
+		sets = append(sets, rs)
+	}
+	return sets, nil
+}
+
+// Create inserts a rule set at version 1; its content goes in with AddVersion.
+func (r *RuleSetRepo) Create(ctx context.Context, rs *domain.RuleSet) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO rule_sets (name, description, created_by)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, version, created_at, updated_at`,
+		rs.Name, rs.Description, rs.CreatedBy,
+	).Scan(&rs.ID, &rs.Version, &rs.CreatedAt, &rs.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create rule set: %w", err)
+	}
+	return nil
+}
+
+// Update saves name and description; with bump it also moves the rule set to
+// the next version, returned in rs.Version.
+func (r *RuleSetRepo) Update(ctx context.Context, rs *domain.RuleSet, bump bool) error {
+	err := r.db.QueryRow(ctx,
+		`UPDATE rule_sets
+		 SET name = $1, description = $2, updated_at = NOW(),
+		     version = CASE WHEN $3 THEN version + 1 ELSE version END
+		 WHERE id = $4
+		 RETURNING version, updated_at`,
+		rs.Name, rs.Description, bump, rs.ID,
+	).Scan(&rs.Version, &rs.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("update rule set: %w", err)
+	}
+	return nil
+}
+
+func (r *RuleSetRepo) AddVersion(ctx context.Context, v *domain.RuleSetVersion) error {
+	if v.Domains == nil {
+		v.Domains = []domain.RuleSetDomain{}
+	}
+	if v.IPRanges == nil {
+		v.IPRanges = []domain.RuleSetCIDR{}
+	}
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO rule_set_versions (rule_set_id, version, domains, ip_ranges, created_by)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING created_at`,
+		v.RuleSetID, v.Version, v.Domains, v.IPRanges, v.CreatedBy,
+	).Scan(&v.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("add rule set version: %w", err)
+	}
+	return nil
+}
+
+func (r *RuleSetRepo) GetVersion(ctx context.Context, id uuid.UUID, version int) (*domain.RuleSetVersion, error) {
+	var v domain.RuleSetVersion
+	err := r.db.QueryRow(ctx,
+		`SELECT rule_set_id, version, domains, ip_ranges, created_by, created_at
+		 FROM rule_set_versions WHERE rule_set_id = $1 AND version = $2`, id, version,
+	).Scan(&v.RuleSetID, &v.Version, &v.Domains, &v.IPRanges, &v.CreatedBy, &v.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get rule set version: %w", err)
+	}
+	return &v, nil
+}
+
+// ListVersions returns every version of a rule set, newest first.
+func (r *RuleSetRepo) ListVersions(ctx context.Context, id uuid.UUID) ([]domain.RuleSetVersion, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT rule_set_id, version, domains, ip_ranges, created_by, created_at
+		 FROM rule_set_versions WHERE rule_set_id = $1 ORDER BY version DESC`, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list rule set versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []domain.RuleSetVersion
+	for rows.Next() {
+		var v domain.RuleSetVersion
+		if err := rows.Scan(&v.RuleSetID, &v.Version, &v.Domains, &v.IPRanges, &v.CreatedBy, &v.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan rule set version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func (r *RuleSetRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM rule_sets WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete rule set: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
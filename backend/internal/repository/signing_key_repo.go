@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type SigningKeyRepo struct {
+	db DBTX
+}
+
+func NewSigningKeyRepo(db DBTX) *SigningKeyRepo {
+	return &SigningKeyRepo{db: db}
+}
+
+func (r *SigningKeyRepo) GetActive(ctx context.Context) (*domain.SigningKey, error) {
+	var k domain.SigningKey
+	err := r.db.QueryRow(ctx,
+		`SELECT id, algorithm, public_key, private_key, created_at, retired_at
+		 FROM signing_keys WHERE retired_at IS NULL`,
+	).Scan(&k.ID, &k.Algorithm, &k.PublicKey, &k.PrivateKey, &k.CreatedAt, &k.RetiredAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get active signing key: %w", err)
+	}
+	return &k, nil
+}
+
+// CreateIfNone stores the key as the active one unless another replica already
+// created one first. The caller should re-read the active key afterwards.
+func (r *SigningKeyRepo) CreateIfNone(ctx context.Context, k *domain.SigningKey) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO signing_keys (algorithm, public_key, private_key)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT DO NOTHING`,
+		k.Algorithm, k.PublicKey, k.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("create signing key: %w", err)
+	}
+	return nil
+}
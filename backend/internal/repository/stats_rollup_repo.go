@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+// statsCounterColumns are the counters carried by every rollup tier. In the
+// raw proxy_stats table they are stored with a "_delta" suffix.
+var statsCounterColumns = []string{
+	"total_connections", "cache_hits", "cache_misses", "errors",
+	"total_requests", "connect_requests",
+	"responses_2xx", "responses_3xx", "responses_4xx", "responses_5xx",
+	"err_connect_fail", "err_client_abort", "broken_server_conns",
+	"bytes_in", "bytes_out",
+}
+
+// statsTierTables maps each rollup tier to its table and bucket expression.
+var statsTierTables = map[domain.StatsTier]struct {
+	table  string
+	bucket string
+}{
+	domain.Tier5m:     {"proxy_stats_5m", "date_bin('5 minutes', %s, TIMESTAMPTZ '2000-01-01')"},
+	domain.TierHourly: {"proxy_stats_1h", "date_trunc('hour', %s)"},
+	domain.TierDaily:  {"proxy_stats_1d", "date_trunc('day', %s)"},
+}
+
+// StatsPoint is one bucket of a stats time series. Counters are the traffic
+// seen during the bucket (sum of deltas), not cumulative ATS values.
+type StatsPoint struct {
+	GroupKey             string    `json:"-"`
+	Timestamp            time.Time `json:"timestamp"`
+	Samples              int64     `json:"samples"`
+	ActiveConnectionsMax int64     `json:"active_connections_max"`
+	ActiveConnectionsAvg float64   `json:"active_connections_avg"`
+	IntervalSeconds      float64   `json:"interval_seconds"`
+
+	TotalConnections  int64 `json:"total_connections"`
+	CacheHits         int64 `json:"cache_hits"`
+	CacheMisses       int64 `json:"cache_misses"`
+	Errors            int64 `json:"errors"`
+	TotalRequests     int64 `json:"total_requests"`
+	ConnectRequests   int64 `json:"connect_requests"`
+	Responses2xx      int64 `json:"responses_2xx"`
+	Responses3xx      int64 `json:"responses_3xx"`
+	Responses4xx      int64 `json:"responses_4xx"`
+	Responses5xx      int64 `json:"responses_5xx"`
+	ErrConnectFail    int64 `json:"err_connect_fail"`
+	ErrClientAbort    int64 `json:"err_client_abort"`
+	BrokenServerConns int64 `json:"broken_server_conns"`
+	BytesIn           int64 `json:"bytes_in"`
+	BytesOut          int64 `json:"bytes_out"`
+
+	// Derived ratios (0..1): errors over requests and each status class over all responses
+	ErrorRate float64 `json:"error_rate"`
+	Ratio2xx  float64 `json:"ratio_2xx"`
+	Ratio4xx  float64 `json:"ratio_4xx"`
+	Ratio5xx  float64 `json:"ratio_5xx"`
+}
+
+// FillRatios computes the derived ratio fields from the counters.
+func (p *StatsPoint) FillRatios() {
+	if p.TotalRequests > 0 {
+		p.ErrorRate = float64(p.Errors) / float64(p.TotalRequests)
+	}
+	responses := p.Responses2xx + p.Responses3xx + p.Responses4xx + p.Responses5xx
+	if responses > 0 {
+		p.Ratio2xx = float64(p.Responses2xx) / float64(responses)
+		p.Ratio4xx = float64(p.Responses4xx) / float64(responses)
+		p.Ratio5xx = float64(p.Responses5xx) / float64(responses)
+	}
+}
+
+// counterPtrs returns pointers to the counters in statsCounterColumns order.
+func (p *StatsPoint) counterPtrs() []any {
+	return []any{
+		&p.TotalConnections, &p.CacheHits, &p.CacheMisses, &p.Errors,
+		&p.TotalRequests, &p.ConnectRequests,
+		&p.Responses2xx, &p.Responses3xx, &p.Responses4xx, &p.Responses5xx,
+		&p.ErrConnectFail, &p.ErrClientAbort, &p.BrokenServerConns,
+		&p.BytesIn, &p.BytesOut,
+	}
+}
+
+type StatsRollupRepo struct {
+	db DBTX
+}
+
+func NewStatsRollupRepo(db DBTX) *StatsRollupRepo {
+	return &StatsRollupRepo{db: db}
+}
+
+// Rollup recomputes every bucket of the given tier starting at the bucket
+// that contains since. Buckets are upserted, so re-running over a partially
+// filled bucket is safe. The 5m tier reads raw samples; coarser tiers read
+// the tier immediately below them.
+func (r *StatsRollupRepo) Rollup(ctx context.Context, tier domain.StatsTier, since time.Time) error {
+	dst, ok := statsTierTables[tier]
+	if !ok {
+		return fmt.Errorf("rollup: unknown tier %q", tier)
+	}
+
+	var source, timeCol, samples, activeMax, activeAvg string
+	sums := make([]string, 0, len(statsCounterColumns))
+
+	switch tier {
+	case domain.Tier5m:
+		source, timeCol = "proxy_stats", "collected_at"
+		samples = "COUNT(*)"
+		activeMax = "MAX(active_connections)"
+		activeAvg = "AVG(active_connections)"
+		for _, c := range statsCounterColumns {
+			sums = append(sums, fmt.Sprintf("SUM(COALESCE(%s_delta, 0))", c))
+		}
+	default:
+		below := domain.Tier5m
+		if tier == domain.TierDaily {
+			below = domain.TierHourly
+		}
+		source, timeCol = statsTierTables[below].table, "bucket"
+		samples = "SUM(samples)"
+		activeMax = "MAX(active_connections_max)"
+		activeAvg = "SUM(active_connections_avg * samples) / NULLIF(SUM(samples), 0)"
+		for _, c := range statsCounterColumns {
+			sums = append(sums, fmt.Sprintf("SUM(%s)", c))
+		}
+	}
+
+	bucketExpr := fmt.Sprintf(dst.bucket, timeCol)
+	updates := make([]string, 0, len(statsCounterColumns)+4)
+	for _, c := range append([]string{"samples", "active_connections_max", "active_connections_avg", "interval_seconds"}, statsCounterColumns...) {
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", c, c))
+	}
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (proxy_id, bucket, samples, active_connections_max, active_connections_avg, interval_seconds, %s)
+		 SELECT proxy_id, %s AS b, %s, %s, COALESCE(%s, 0), SUM(COALESCE(interval_seconds, 0)), %s
+		 FROM %s
+		 WHERE %s >= %s
+		 GROUP BY proxy_id, b
+		 ON CONFLICT (proxy_id, bucket) DO UPDATE SET %s`,
+		dst.table, strings.Join(statsCounterColumns, ", "),
+		bucketExpr, samples, activeMax, activeAvg, strings.Join(sums, ", "),
+		source,
+		timeCol, fmt.Sprintf(dst.bucket, "$1::timestamptz"),
+		strings.Join(updates, ", "),
+	)
+
+	if _, err := r.db.Exec(ctx, query, since); err != nil {
+		return fmt.Errorf("rollup %s: %w", tier, err)
+	}
+	return nil
+}
+
+// Watermark returns the time the tier was last rolled up to, nil if it never was.
+func (r *StatsRollupRepo) Watermark(ctx context.Context, tier domain.StatsTier) (*time.Time, error) {
+	var t time.Time
+	err := r.db.QueryRow(ctx,
+		`SELECT rolled_until FROM stats_rollup_watermarks WHERE tier = $1`, tier,
+	).Scan(&t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get rollup watermark: %w", err)
+	}
+	return &t, nil
+}
+
+// SetWatermark records that the tier has been rolled up to t.
+func (r *StatsRollupRepo) SetWatermark(ctx context.Context, tier domain.StatsTier, t time.Time) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO stats_rollup_watermarks (tier, rolled_until) VALUES ($1, $2)
+		 ON CONFLICT (tier) DO UPDATE SET rolled_until = EXCLUDED.rolled_until`, tier, t,
+	)
+	if err != nil {
+		return fmt.Errorf("set rollup watermark: %w", err)
+	}
+	return nil
+}
+
+// CleanupTier deletes rows of the given tier older than the retention window.
+func (r *StatsRollupRepo) CleanupTier(ctx context.Context, tier domain.StatsTier, retention time.Duration) (int64, error) {
+	table, timeCol := "proxy_stats", "collected_at"
+	if t, ok := statsTierTables[tier]; ok {
+		table, timeCol = t.table, "bucket"
+	} else if tier != domain.TierRaw {
+		return 0, fmt.Errorf("cleanup: unknown tier %q", tier)
+	}
+
+	tag, err := r.db.Exec(ctx,
+		fmt.Sprintf(`DELETE FROM %s WHERE %s < $1`, table, timeCol),
+		time.Now().Add(-retention),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup %s stats: %w", tier, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// StatsQuery selects a time series from one tier. With GroupBy unset or
+// "proxy" each proxy gets its own series; "config" groups proxies by their
+// active config and "fleet" merges everything into a single series.
+type StatsQuery struct {
+	Tier    domain.StatsTier
+	GroupBy domain.StatsGroupBy
+	ProxyID *uuid.UUID
+	From    time.Time
+	To      time.Time
+	Step    time.Duration
+}
+
+// Query returns points bucketed by q.Step from the table backing q.Tier.
+// Per-proxy aggregates are computed first and then summed across the group,
+// so connection gauges add up across proxies instead of taking a global max.
+func (r *StatsRollupRepo) Query(ctx context.Context, q StatsQuery) ([]StatsPoint, error) {
+	var table, timeCol, samples, activeMax, activeAvg string
+	sums := make([]string, 0, len(statsCounterColumns))
+
+	if q.Tier == domain.TierRaw {
+		table, timeCol = "proxy_stats", "collected_at"
+		samples = "COUNT(*)"
+		activeMax = "MAX(active_connections)"
+		activeAvg = "AVG(active_connections)"
+		for _, c := range statsCounterColumns {
+			sums = append(sums, fmt.Sprintf("COALESCE(SUM(%s_delta), 0)::bigint AS %s", c, c))
+		}
+	} else {
+		t, ok := statsTierTables[q.Tier]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown stats tier %q", domain.ErrBadRequest, q.Tier)
+		}
+		table, timeCol = t.table, "bucket"
+		samples = "SUM(samples)"
+		activeMax = "MAX(active_connections_max)"
+		activeAvg = "SUM(active_connections_avg * samples) / NULLIF(SUM(samples), 0)"
+		for _, c := range statsCounterColumns {
+			sums = append(sums, fmt.Sprintf("COALESCE(SUM(%s), 0)::bigint AS %s", c, c))
+		}
+	}
+
+	groupKey, join := "s.proxy_id::text", ""
+	switch q.GroupBy {
+	case domain.GroupByConfig:
+		groupKey = "COALESCE(a.config_id::text, '')"
+		join = ` LEFT JOIN (` + activeConfigByProxy + `) a ON a.proxy_id = s.proxy_id`
+	case domain.GroupByFleet:
+		groupKey = "'fleet'"
+	}
+
+	where := fmt.Sprintf(" WHERE s.%s >= $2 AND s.%s < $3", timeCol, timeCol)
+	args := []any{q.Step.Seconds(), q.From, q.To}
+	if q.ProxyID != nil {
+		where += " AND s.proxy_id = $4"
+		args = append(args, *q.ProxyID)
+	}
+
+	outerSums := make([]string, 0, len(statsCounterColumns))
+	for _, c := range statsCounterColumns {
+		outerSums = append(outerSums, fmt.Sprintf("SUM(%s)::bigint", c))
+	}
+
+	query := fmt.Sprintf(
+		`WITH per_proxy AS (
+		   SELECT %s AS group_key, s.proxy_id,
+		          date_bin(make_interval(secs => $1), s.%s, TIMESTAMPTZ '2000-01-01') AS ts,
+		          COALESCE(%s, 0)::bigint AS samples,
+		          COALESCE(%s, 0)::bigint AS active_max,
+		          COALESCE(%s, 0)::float8 AS active_avg,
+		          COALESCE(SUM(interval_seconds), 0)::float8 AS interval_seconds,
+		          %s
+		   FROM %s s%s%s
+		   GROUP BY group_key, s.proxy_id, ts
+		 )
+		 SELECT group_key, ts, SUM(samples)::bigint, SUM(active_max)::bigint, SUM(active_avg)::float8,
+		        MAX(interval_seconds)::float8, %s
+		 FROM per_proxy
+		 GROUP BY group_key, ts
+		 ORDER BY group_key, ts`,
+		groupKey, timeCol, samples, activeMax, activeAvg, strings.Join(sums, ", "),
+		table, join, where,
+		strings.Join(outerSums, ", "),
+	)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query %s stats: %w", q.Tier, err)
+	}
+	defer rows.Close()
+
+	var points []StatsPoint
+	for rows.Next() {
+		var p StatsPoint
+		dest := append([]any{&p.GroupKey, &p.Timestamp, &p.Samples, &p.ActiveConnectionsMax, &p.ActiveConnectionsAvg, &p.IntervalSeconds}, p.counterPtrs()...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scan stats point: %w", err)
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
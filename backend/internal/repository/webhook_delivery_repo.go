@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type WebhookDeliveryRepo struct {
+	db DBTX
+}
+
+func NewWebhookDeliveryRepo(db DBTX) *WebhookDeliveryRepo {
+	return &WebhookDeliveryRepo{db: db}
+}
+
+const webhookDeliveryColumns = `id, webhook_id, event, payload, status, attempts, next_attempt_at,
+	last_status_code, last_error, created_at, delivered_at`
+
+func scanWebhookDelivery(row pgx.Row, d *domain.WebhookDelivery) error {
+	return row.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+}
+
+func (r *WebhookDeliveryRepo) Create(ctx context.Context, d *domain.WebhookDelivery) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, status, attempts, next_attempt_at, created_at`,
+		d.WebhookID, d.Event, d.Payload,
+	).Scan(&d.ID, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ClaimDue leases up to limit pending deliveries whose next attempt is due by
+// pushing their next_attempt_at forward, so concurrent workers skip them.
+func (r *WebhookDeliveryRepo) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx,
+		`UPDATE webhook_deliveries SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		 WHERE id IN (
+		     SELECT id FROM webhook_deliveries
+		     WHERE status = 'pending' AND next_attempt_at <= NOW()
+		     ORDER BY next_attempt_at
+		     LIMIT $1
+		     FOR UPDATE SKIP LOCKED)
+		 RETURNING `+webhookDeliveryColumns,
+		limit, lease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var out []domain.WebhookDelivery
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// RecordAttempt stores the outcome of one delivery attempt. nextAttempt is
+// only used while status stays pending.
+func (r *WebhookDeliveryRepo) RecordAttempt(ctx context.Context, id uuid.UUID, status domain.DeliveryStatus, statusCode *int, lastErr *string, nextAttempt time.Time) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE webhook_deliveries
+		 SET status = $1, attempts = attempts + 1, last_status_code = $2, last_error = $3,
+		     next_attempt_at = CASE WHEN $1 = 'pending' THEN $4 ELSE NULL END,
+		     delivered_at = CASE WHEN $1 = 'success' THEN NOW() ELSE delivered_at END
+		 WHERE id = $5`,
+		status, statusCode, lastErr, nextAttempt, id,
+	)
+	if err != nil {
+		return fmt.Errorf("record webhook attempt: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookDeliveryRepo) ListByWebhook(ctx context.Context, webhookID uuid.UUID, limit, offset int) ([]domain.WebhookDelivery, int, error) {
+	var total int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1`, webhookID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count webhook deliveries: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx,
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		 WHERE webhook_id = $1
+		 ORDER BY created_at DESC LIMIT $2 OFFSET $3`,
+		webhookID, limit, offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var out []domain.WebhookDelivery
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, 0, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		out = append(out, d)
+	}
+	return out, total, nil
+}
+
+// CleanupOld deletes finished deliveries older than the retention.
+func (r *WebhookDeliveryRepo) CleanupOld(ctx context.Context, retention time.Duration) (int64, error) {
+	tag, err := r.db.Exec(ctx,
+		`DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < $1`, time.Now().Add(-retention),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+type WebhookRepo struct {
+	db DBTX
+}
+
+func NewWebhookRepo(db DBTX) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+const webhookColumns = `id, name, url, secret, events, enabled, created_by, created_at, updated_at`
+
+func scanWebhook(row pgx.Row, w *domain.Webhook) error {
+	var events []string
+	if err := row.Scan(&w.ID, &w.Name, &w.URL, &w.Secret, &events, &w.Enabled, &w.CreatedBy, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return err
+	}
+	w.Events = make([]domain.WebhookEvent, 0, len(events))
+	for _, e := range events {
+		w.Events = append(w.Events, domain.WebhookEvent(e))
+	}
+	return nil
+}
+
+func eventStrings(events []domain.WebhookEvent) []string {
+	out := make([]string, 0, len(events))
+	for _, e := range events {
+		out = append(out, string(e))
+	}
+	return out
+}
+
+func (r *WebhookRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Webhook, error) {
+	var w domain.Webhook
+	err := scanWebhook(r.db.QueryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id), &w)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get webhook: %w", err)
+	}
+	return &w, nil
+}
+
+func (r *WebhookRepo) List(ctx context.Context) ([]domain.Webhook, error) {
+	return r.list(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY name`)
+}
+
+// ListSubscribed returns enabled webhooks subscribed to the event.
+func (r *WebhookRepo) ListSubscribed(ctx context.Context, event domain.WebhookEvent) ([]domain.Webhook, error) {
+	return r.list(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE enabled = TRUE AND $1 = ANY(events)`, string(event))
+}
+
+func (r *WebhookRepo) list(ctx context.Context, query string, args ...interface{}) ([]domain.Webhook, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var hooks []domain.Webhook
+	for rows.Next() {
+		var w domain.Webhook
+		if err := scanWebhook(rows, &w); err != nil {
+			return nil, fmt.Errorf("scan webhook: %w", err)
+		}
+		hooks = append(hooks, w)
+	}
+	return hooks, nil
+}
+
+func (r *WebhookRepo) Create(ctx context.Context, w *domain.Webhook) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO webhooks (name, url, secret, events, enabled, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, created_at, updated_at`,
+		w.Name, w.URL, w.Secret, eventStrings(w.Events), w.Enabled, w.CreatedBy,
+	).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create webhook: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepo) Update(ctx context.Context, w *domain.Webhook) error {
+	err := r.db.QueryRow(ctx,
+		`UPDATE webhooks
+		 SET name = $1, url = $2, secret = $3, events = $4, enabled = $5, updated_at = NOW()
+		 WHERE id = $6
+		 RETURNING updated_at`,
+		w.Name, w.URL, w.Secret, eventStrings(w.Events), w.Enabled, w.ID,
+	).Scan(&w.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("update webhook: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
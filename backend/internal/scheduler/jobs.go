@@ -6,18 +6,28 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ats-proxy/proxy-manager/backend/internal/config"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/feed"
 	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+	"github.com/ats-proxy/proxy-manager/backend/internal/service"
 )
 
 type Scheduler struct {
-	pool   *pgxpool.Pool
-	stop   chan struct{}
+	pool               *pgxpool.Pool
+	retention          config.StatsRetention
+	accessLogRetention time.Duration
+	feedDir            string
+	stop               chan struct{}
 }
 
-func New(pool *pgxpool.Pool) *Scheduler {
+func New(pool *pgxpool.Pool, retention config.StatsRetention, accessLogRetention time.Duration, feedDir string) *Scheduler {
 	return &Scheduler{
-		pool: pool,
-		stop: make(chan struct{}),
+		pool:               pool,
+		retention:          retention,
+		accessLogRetention: accessLogRetention,
+		feedDir:            feedDir,
+		stop:               make(chan struct{}),
 	}
 }
 
@@ -26,6 +36,13 @@ func (s *Scheduler) Start() {
 	go s.runProxyCleanup()
 	go s.runLogCleanup()
 	go s.runStatsCleanup()
+	go s.runStatsRollup()
+	go s.runAlertEvaluation()
+	go s.runWebhookDelivery()
+	go s.runCommandExpiry()
+	go s.runDiagnosticsCleanup()
+	go s.runAccessLogPartitions()
+	go s.runFeedRefresh()
 	log.Println("Scheduler started")
 }
 
@@ -39,6 +56,8 @@ func (s *Scheduler) runProxyStatusCheck() {
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
+	webhookSvc := s.webhookService()
+
 	for {
 		select {
 		case <-s.stop:
@@ -46,9 +65,17 @@ func (s *Scheduler) runProxyStatusCheck() {
 		case <-ticker.C:
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			repo := repository.NewProxyRepo(s.pool)
-			if err := repo.MarkOfflineStale(ctx); err != nil {
+			offline, err := repo.MarkOfflineStale(ctx)
+			if err != nil {
 				log.Printf("Proxy status check error: %v", err)
 			}
+			for _, p := range offline {
+				webhookSvc.Publish(ctx, domain.EventProxyOffline, map[string]interface{}{
+					"proxy_id":  p.ID.String(),
+					"hostname":  p.Hostname,
+					"last_seen": p.LastSeen,
+				})
+			}
 			cancel()
 		}
 	}
@@ -97,22 +124,280 @@ func (s *Scheduler) runLogCleanup() {
 	}
 }
 
-// runStatsCleanup deletes stats older than 7 days, runs daily at 2am.
+// runStatsCleanup deletes stats past the retention of each tier, runs daily.
 func (s *Scheduler) runStatsCleanup() {
 	ticker := time.NewTicker(24 * time.Hour)
 	defer ticker.Stop()
 
+	tiers := []struct {
+		tier      domain.StatsTier
+		retention time.Duration
+	}{
+		{domain.TierRaw, s.retention.Raw},
+		{domain.Tier5m, s.retention.FiveMin},
+		{domain.TierHourly, s.retention.Hourly},
+		{domain.TierDaily, s.retention.Daily},
+	}
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			repo := repository.NewStatsRollupRepo(s.pool)
+			for _, t := range tiers {
+				count, err := repo.CleanupTier(ctx, t.tier, t.retention)
+				if err != nil {
+					log.Printf("Stats cleanup error (%s): %v", t.tier, err)
+				} else if count > 0 {
+					log.Printf("Stats cleanup: removed %d %s rows", count, t.tier)
+				}
+			}
+			cancel()
+		}
+	}
+}
+
+// runStatsRollup aggregates raw stats into the 5m, hourly and daily tiers every
+// 5 minutes. Each tier re-computes a trailing window so late samples and the
+// still-open bucket are picked up on the next run; after downtime it resumes
+// from the tier's watermark instead, so no bucket is skipped.
+func (s *Scheduler) runStatsRollup() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	windows := []struct {
+		tier     domain.StatsTier
+		lookback time.Duration
+	}{
+		{domain.Tier5m, 15 * time.Minute},
+		{domain.TierHourly, 2 * time.Hour},
+		{domain.TierDaily, 48 * time.Hour},
+	}
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			repo := repository.NewStatsRollupRepo(s.pool)
+			now := time.Now()
+			for _, w := range windows {
+				since := now.Add(-w.lookback)
+				mark, err := repo.Watermark(ctx, w.tier)
+				if err != nil {
+					log.Printf("Stats rollup error (%s): %v", w.tier, err)
+					break
+				}
+				if mark != nil && mark.Before(since) {
+					since = *mark
+				}
+				if err := repo.Rollup(ctx, w.tier, since); err != nil {
+					log.Printf("Stats rollup error (%s): %v", w.tier, err)
+					break // coarser tiers read from this one
+				}
+				if err := repo.SetWatermark(ctx, w.tier, now); err != nil {
+					log.Printf("Stats rollup error (%s): %v", w.tier, err)
+				}
+			}
+			cancel()
+		}
+	}
+}
+
+// runAlertEvaluation evaluates alert rules every minute and drops resolved
+// alerts older than 30 days once a day.
+func (s *Scheduler) runAlertEvaluation() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	alertSvc := service.NewAlertService(
+		repository.NewAlertRuleRepo(s.pool),
+		repository.NewAlertRepo(s.pool),
+		repository.NewAuditRepo(s.pool),
+		s.webhookService(),
+	)
+	lastCleanup := time.Now()
+
 	for {
 		select {
 		case <-s.stop:
 			return
 		case <-ticker.C:
 			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			repo := repository.NewProxyStatsRepo(s.pool)
-			if err := repo.CleanupOld(ctx); err != nil {
-				log.Printf("Stats cleanup error: %v", err)
+			if err := alertSvc.Evaluate(ctx); err != nil {
+				log.Printf("Alert evaluation error: %v", err)
+			}
+			if time.Since(lastCleanup) >= 24*time.Hour {
+				lastCleanup = time.Now()
+				count, err := repository.NewAlertRepo(s.pool).CleanupResolved(ctx, 30*24*time.Hour)
+				if err != nil {
+					log.Printf("Alert cleanup error: %v", err)
+				} else if count > 0 {
+					log.Printf("Alert cleanup: removed %d resolved alerts", count)
+				}
 			}
 			cancel()
 		}
 	}
 }
+
+// runWebhookDelivery sends due webhook deliveries every 10 seconds and drops
+// finished deliveries older than 30 days once a day.
+func (s *Scheduler) runWebhookDelivery() {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	webhookSvc := s.webhookService()
+	lastCleanup := time.Now()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			if err := webhookSvc.DeliverDue(ctx, 50); err != nil {
+				log.Printf("Webhook delivery error: %v", err)
+			}
+			if time.Since(lastCleanup) >= 24*time.Hour {
+				lastCleanup = time.Now()
+				count, err := repository.NewWebhookDeliveryRepo(s.pool).CleanupOld(ctx, 30*24*time.Hour)
+				if err != nil {
+					log.Printf("Webhook delivery cleanup error: %v", err)
+				} else if count > 0 {
+					log.Printf("Webhook delivery cleanup: removed %d deliveries", count)
+				}
+			}
+			cancel()
+		}
+	}
+}
+
+// runCommandExpiry closes proxy commands that will never report a result
+// every minute and drops finished commands older than 30 days once a day.
+func (s *Scheduler) runCommandExpiry() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			repo := repository.NewProxyCommandRepo(s.pool)
+			// Grace on top of the command timeout for the helper to post its result
+			if _, err := repo.ExpireStale(ctx, time.Minute); err != nil {
+				log.Printf("Command expiry error: %v", err)
+			}
+			if time.Since(lastCleanup) >= 24*time.Hour {
+				lastCleanup = time.Now()
+				count, err := repo.CleanupOld(ctx, 30*24*time.Hour)
+				if err != nil {
+					log.Printf("Command cleanup error: %v", err)
+				} else if count > 0 {
+					log.Printf("Command cleanup: removed %d commands", count)
+				}
+			}
+			cancel()
+		}
+	}
+}
+
+// runDiagnosticsCleanup deletes expired diagnostics bundles every hour.
+func (s *Scheduler) runDiagnosticsCleanup() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			count, err := repository.NewProxyDiagnosticsRepo(s.pool).DeleteExpired(ctx)
+			if err != nil {
+				log.Printf("Diagnostics cleanup error: %v", err)
+			} else if count > 0 {
+				log.Printf("Diagnostics cleanup: removed %d bundles", count)
+			}
+			cancel()
+		}
+	}
+}
+
+// runAccessLogPartitions keeps the daily access_logs partitions: creates today's
+// and the next two, and drops those past the retention. Runs at start and hourly.
+func (s *Scheduler) runAccessLogPartitions() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	maintain := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		repo := repository.NewAccessLogRepo(s.pool)
+		now := time.Now()
+		if err := repo.EnsurePartitions(ctx, now, 3); err != nil {
+			log.Printf("Access log partition error: %v", err)
+		}
+		dropped, err := repo.DropPartitionsBefore(ctx, now.Add(-s.accessLogRetention))
+		if err != nil {
+			log.Printf("Access log cleanup error: %v", err)
+		} else if len(dropped) > 0 {
+			log.Printf("Access log cleanup: dropped partitions %v", dropped)
+		}
+	}
+
+	maintain()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			maintain()
+		}
+	}
+}
+
+// runFeedRefresh fetches the feed sources whose interval has elapsed, every
+// minute. Changes are staged as feed updates for review, never applied.
+func (s *Scheduler) runFeedRefresh() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	feedSvc := service.NewFeedService(
+		repository.NewFeedSourceRepo(s.pool),
+		repository.NewFeedUpdateRepo(s.pool),
+		repository.NewRuleSetRepo(s.pool),
+		repository.NewAuditRepo(s.pool),
+		feed.NewSourceFetcher(nil, s.feedDir),
+		s.feedDir,
+	)
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			if err := feedSvc.RefreshDue(ctx); err != nil {
+				log.Printf("Feed refresh error: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+func (s *Scheduler) webhookService() *service.WebhookService {
+	return service.NewWebhookService(
+		repository.NewWebhookRepo(s.pool),
+		repository.NewWebhookDeliveryRepo(s.pool),
+		repository.NewAuditRepo(s.pool),
+	)
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+)
+
+const (
+	// MaxAccessLogBatch bounds the entries of one POST /sync/access-logs.
+	MaxAccessLogBatch = 5000
+	// maxAccessLogRange bounds the time range of a search.
+	maxAccessLogRange = 7 * 24 * time.Hour
+	// accessLogClockSkew is how far in the future an entry may be stamped.
+	accessLogClockSkew = 10 * time.Minute
+)
+
+type AccessLogService struct {
+	logs      *repository.AccessLogRepo
+	retention time.Duration
+}
+
+func NewAccessLogService(logs *repository.AccessLogRepo, retention time.Duration) *AccessLogService {
+	return &AccessLogService{logs: logs, retention: retention}
+}
+
+// AccessLogEntry mirrors helper's AccessLogEntry
+type AccessLogEntry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	ClientIP   string    `json:"client_ip"`
+	Username   string    `json:"username,omitempty"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	Host       string    `json:"host,omitempty"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMs *int      `json:"duration_ms,omitempty"`
+	Route      string    `json:"route,omitempty"`
+	Upstream   string    `json:"upstream,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+type IngestResult struct {
+	Accepted int `json:"accepted"`
+	Dropped  int `json:"dropped"`
+}
+
+// Ingest stores a batch shipped by a proxy. Entries that cannot be stored (bad
+// client IP or status, outside the retention window) are dropped and counted
+// rather than failing the batch, so one bad line does not block the tail.
+func (s *AccessLogService) Ingest(ctx context.Context, proxyID uuid.UUID, entries []AccessLogEntry) (*IngestResult, error) {
+	if len(entries) > MaxAccessLogBatch {
+		return nil, fmt.Errorf("%w: at most %d entries per batch", domain.ErrBadRequest, MaxAccessLogBatch)
+	}
+
+	now := time.Now()
+	oldest := now.Add(-s.retention)
+	newest := now.Add(accessLogClockSkew)
+
+	logs := make([]domain.AccessLog, 0, len(entries))
+	for _, e := range entries {
+		if e.Timestamp.Before(oldest) || e.Timestamp.After(newest) {
+			continue
+		}
+		ip := net.ParseIP(e.ClientIP)
+		if ip == nil || e.Status < 0 || e.Status > 999 {
+			continue
+		}
+		host := e.Host
+		if host == "" {
+			host = hostFromURL(e.URL)
+		}
+
+		logs = append(logs, domain.AccessLog{
+			Timestamp:  e.Timestamp,
+			ClientIP:   ip.String(),
+			Username:   optionalString(truncate(e.Username, 255)),
+			Method:     truncate(strings.ToUpper(e.Method), 16),
+			URL:        truncate(e.URL, 2048),
+			Host:       truncate(strings.ToLower(strings.TrimSuffix(host, ".")), 255),
+			Status:     e.Status,
+			Bytes:      e.Bytes,
+			DurationMs: e.DurationMs,
+			Route:      optionalString(truncate(e.Route, 32)),
+			Upstream:   optionalString(truncate(e.Upstream, 255)),
+			UserAgent:  optionalString(truncate(e.UserAgent, 512)),
+		})
+	}
+
+	if _, err := s.logs.InsertBatch(ctx, proxyID, logs); err != nil {
+		return nil, err
+	}
+	return &IngestResult{Accepted: len(logs), Dropped: len(entries) - len(logs)}, nil
+}
+
+// hostFromURL extracts the destination host of a request URL, including the
+// authority form CONNECT uses (host:port).
+func hostFromURL(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	if host, _, err := net.SplitHostPort(raw); err == nil {
+		return host
+	}
+	return raw
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+func optionalString(s string) *string {
+	if s == "" || s == "-" {
+		return nil
+	}
+	return &s
+}
+
+// AccessLogQuery is a search as received from the API; empty fields are not filtered.
+type AccessLogQuery struct {
+	ProxyID  string
+	ClientIP string
+	Host     string
+	Username string
+	Status   string // a code (404) or a class (4xx)
+	From     *time.Time
+	To       *time.Time
+}
+
+func (s *AccessLogService) Search(ctx context.Context, q AccessLogQuery, page, limit int) ([]domain.AccessLog, int, error) {
+	f, err := accessLogFilter(q, time.Now())
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.logs.Search(ctx, f, limit, (page-1)*limit)
+}
+
+func accessLogFilter(q AccessLogQuery, now time.Time) (repository.AccessLogFilter, error) {
+	var f repository.AccessLogFilter
+
+	f.To = now
+	if q.To != nil {
+		f.To = *q.To
+	}
+	f.From = f.To.Add(-time.Hour)
+	if q.From != nil {
+		f.From = *q.From
+	}
+	if !f.From.Before(f.To) {
+		return f, fmt.Errorf("%w: from must be before to", domain.ErrBadRequest)
+	}
+	if f.To.Sub(f.From) > maxAccessLogRange {
+		return f, fmt.Errorf("%w: time range is limited to %s", domain.ErrBadRequest, maxAccessLogRange)
+	}
+
+	if q.ProxyID != "" {
+		id, err := uuid.Parse(q.ProxyID)
+		if err != nil {
+			return f, fmt.Errorf("%w: invalid proxy_id", domain.ErrBadRequest)
+		}
+		f.ProxyID = &id
+	}
+
+	if q.ClientIP != "" {
+		cidr := q.ClientIP
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return f, fmt.Errorf("%w: client_ip must be an IP address or CIDR", domain.ErrBadRequest)
+			}
+			cidr = ip.String()
+		}
+		f.ClientIP = &cidr
+	}
+
+	if q.Host != "" {
+		host := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(q.Host, "*."), "."))
+		f.Host = &host
+	}
+	if q.Username != "" {
+		f.Username = &q.Username
+	}
+
+	if q.Status != "" {
+		min, max, err := parseStatusFilter(q.Status)
+		if err != nil {
+			return f, err
+		}
+		f.StatusMin, f.StatusMax = &min, &max
+	}
+
+	return f, nil
+}
+
+// parseStatusFilter accepts an exact code ("404") or a class ("4xx").
+func parseStatusFilter(s string) (int, int, error) {
+	s = strings.ToLower(s)
+	if len(s) == 3 && strings.HasSuffix(s, "xx") && s[0] >= '1' && s[0] <= '5' {
+		class := int(s[0]-'0') * 100
+		return class, class + 99, nil
+	}
+	code, err := strconv.Atoi(s)
+	if err != nil || code < 100 || code > 999 {
+		return 0, 0, fmt.Errorf("%w: status must be a code (404) or a class (4xx)", domain.ErrBadRequest)
+	}
+	return code, code, nil
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+)
+
+// errorRateWindow is the trailing window used to compute the 5xx rate of error_rate rules.
+const errorRateWindow = 5 * time.Minute
+
+type AlertService struct {
+	rules  *repository.AlertRuleRepo
+	alerts *repository.AlertRepo
+	audit  *repository.AuditRepo
+	events *WebhookService
+}
+
+func NewAlertService(rules *repository.AlertRuleRepo, alerts *repository.AlertRepo, audit *repository.AuditRepo, events *WebhookService) *AlertService {
+	return &AlertService{rules: rules, alerts: alerts, audit: audit, events: events}
+}
+
+// ========== Rules ==========
+
+func (s *AlertService) ListRules(ctx context.Context) ([]domain.AlertRule, error) {
+	rules, err := s.rules.List(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		rules = []domain.AlertRule{}
+	}
+	return rules, nil
+}
+
+type AlertRuleRequest struct {
+	Name            string               `json:"name"`
+	Type            domain.AlertRuleType `json:"type"`
+	Threshold       float64              `json:"threshold"`
+	DurationMinutes *int                 `json:"duration_minutes,omitempty"`
+	Enabled         *bool                `json:"enabled,omitempty"`
+}
+
+func validateAlertRule(rule *domain.AlertRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", domain.ErrBadRequest)
+	}
+	if !rule.Type.IsValid() {
+		return fmt.Errorf("%w: type must be one of proxy_offline, config_mismatch, error_rate, ack_failed, parent_down", domain.ErrBadRequest)
+	}
+	if rule.DurationMinutes < 0 {
+		return fmt.Errorf("%w: duration_minutes cannot be negative", domain.ErrBadRequest)
+	}
+	if rule.Type == domain.AlertErrorRate && (rule.Threshold <= 0 || rule.Threshold > 100) {
+		return fmt.Errorf("%w: error_rate threshold must be a percentage between 0 and 100", domain.ErrBadRequest)
+	}
+	return nil
+}
+
+func (s *AlertService) CreateRule(ctx context.Context, req AlertRuleRequest, userID uuid.UUID, ip, ua string) (*domain.AlertRule, error) {
+	rule := &domain.AlertRule{
+		Name:            req.Name,
+		Type:            req.Type,
+		Threshold:       req.Threshold,
+		DurationMinutes: 5,
+		Enabled:         true,
+		CreatedBy:       &userID,
+	}
+	if req.DurationMinutes != nil {
+		rule.DurationMinutes = *req.DurationMinutes
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if err := validateAlertRule(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.rules.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	s.logAudit(ctx, &userID, "alert_rule.create", &rule.ID, nil, jsonVal("name", rule.Name), ip, ua)
+	return rule, nil
+}
+
+func (s *AlertService) UpdateRule(ctx context.Context, id uuid.UUID, req AlertRuleRequest, userID uuid.UUID, ip, ua string) (*domain.AlertRule, error) {
+	rule, err := s.rules.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		rule.Name = req.Name
+	}
+	if req.Type != "" {
+		rule.Type = req.Type
+	}
+	if req.Threshold != 0 {
+		rule.Threshold = req.Threshold
+	}
+	if req.DurationMinutes != nil {
+		rule.DurationMinutes = *req.DurationMinutes
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if err := validateAlertRule(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.rules.Update(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	s.logAudit(ctx, &userID, "alert_rule.update", &id, nil, jsonVal("name", rule.Name), ip, ua)
+	return rule, nil
+}
+
+func (s *AlertService) DeleteRule(ctx context.Context, id, userID uuid.UUID, ip, ua string) error {
+	rule, err := s.rules.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.rules.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.logAudit(ctx, &userID, "alert_rule.delete", &id, jsonVal("name", rule.Name), nil, ip, ua)
+	return nil
+}
+
+// ========== Alerts ==========
+
+func (s *AlertService) List(ctx context.Context, filter repository.AlertFilter, page, limit int) ([]domain.Alert, int, error) {
+	offset := (page - 1) * limit
+	return s.alerts.List(ctx, filter, limit, offset)
+}
+
+// alertKey identifies an open alert: one per rule, proxy and subject.
+type alertKey struct {
+	ruleID  uuid.UUID
+	proxyID uuid.UUID
+	subject string
+}
+
+// Evaluate runs every enabled rule once. A matching condition opens a pending
+// alert which moves to firing after the rule duration; alerts whose condition
+// cleared are resolved (or dropped, if they never fired).
+func (s *AlertService) Evaluate(ctx context.Context) error {
+	rules, err := s.rules.List(ctx, true)
+	if err != nil {
+		return err
+	}
+	open, err := s.alerts.ListOpen(ctx)
+	if err != nil {
+		return err
+	}
+
+	openByKey := make(map[alertKey]domain.Alert, len(open))
+	for _, a := range open {
+		k := alertKey{ruleID: a.RuleID, subject: a.Subject}
+		if a.ProxyID != nil {
+			k.proxyID = *a.ProxyID
+		}
+		openByKey[k] = a
+	}
+
+	now := time.Now()
+	for _, rule := range rules {
+		candidates, err := s.candidates(ctx, rule)
+		if err != nil {
+			log.Printf("Alert rule %q evaluation error: %v", rule.Name, err)
+			// keep the open alerts of this rule untouched until it can be evaluated
+			for k := range openByKey {
+				if k.ruleID == rule.ID {
+					delete(openByKey, k)
+				}
+			}
+			continue
+		}
+
+		for _, c := range candidates {
+			k := alertKey{ruleID: rule.ID, proxyID: c.ProxyID, subject: c.Subject}
+			msg := fmt.Sprintf("%s: %s", c.Hostname, c.Message)
+			hold := time.Duration(rule.DurationMinutes) * time.Minute
+
+			existing, ok := openByKey[k]
+			if !ok {
+				status := domain.AlertPending
+				if hold == 0 {
+					status = domain.AlertFiring
+				}
+				proxyID := c.ProxyID
+				alert := &domain.Alert{
+					RuleID:  rule.ID,
+					ProxyID: &proxyID,
+					Subject: c.Subject,
+					Status:  status,
+					Message: &msg,
+					Value:   c.Value,
+				}
+				if err := s.alerts.Create(ctx, alert); err != nil {
+					log.Printf("Alert create error (%s): %v", rule.Name, err)
+					continue
+				}
+				if status == domain.AlertFiring {
+					s.fire(ctx, rule, alert, c)
+				}
+				continue
+			}
+			delete(openByKey, k)
+
+			status := existing.Status
+			if status == domain.AlertPending && now.Sub(existing.StartedAt) >= hold {
+				status = domain.AlertFiring
+			}
+			if err := s.alerts.Refresh(ctx, existing.ID, status, &msg, c.Value); err != nil {
+				log.Printf("Alert refresh error (%s): %v", rule.Name, err)
+				continue
+			}
+			if status != existing.Status {
+				existing.Status = status
+				existing.Message = &msg
+				existing.Value = c.Value
+				s.fire(ctx, rule, &existing, c)
+			}
+		}
+	}
+
+	// Whatever is left no longer matches (or its rule was disabled).
+	for _, a := range openByKey {
+		if a.Status == domain.AlertPending {
+			if err := s.alerts.Delete(ctx, a.ID); err != nil {
+				log.Printf("Alert delete error: %v", err)
+			}
+			continue
+		}
+		if err := s.alerts.Resolve(ctx, a.ID); err != nil {
+			log.Printf("Alert resolve error: %v", err)
+			continue
+		}
+		log.Printf("Alert resolved: [%s] %s", a.RuleName, alertSubject(a))
+	}
+
+	return nil
+}
+
+// fire logs an alert that just reached firing and notifies webhooks.
+func (s *AlertService) fire(ctx context.Context, rule domain.AlertRule, alert *domain.Alert, c repository.AlertCandidate) {
+	msg := ""
+	if alert.Message != nil {
+		msg = *alert.Message
+	}
+	log.Printf("Alert firing: [%s] %s", rule.Name, msg)
+
+	data := map[string]interface{}{
+		"alert_id":   alert.ID.String(),
+		"rule_id":    rule.ID.String(),
+		"rule_name":  rule.Name,
+		"rule_type":  rule.Type,
+		"proxy_id":   c.ProxyID.String(),
+		"hostname":   c.Hostname,
+		"message":    msg,
+		"started_at": alert.StartedAt,
+	}
+	if c.Subject != "" {
+		data["subject"] = c.Subject
+	}
+	if c.Value != nil {
+		data["value"] = *c.Value
+	}
+	s.events.Publish(ctx, domain.EventAlertFiring, data)
+}
+
+func (s *AlertService) candidates(ctx context.Context, rule domain.AlertRule) ([]repository.AlertCandidate, error) {
+	switch rule.Type {
+	case domain.AlertProxyOffline:
+		return s.alerts.OfflineProxies(ctx)
+	case domain.AlertConfigMismatch:
+		return s.alerts.ConfigMismatches(ctx)
+	case domain.AlertErrorRate:
+		return s.alerts.ErrorRates(ctx, errorRateWindow, rule.Threshold)
+	case domain.AlertAckFailed:
+		return s.alerts.FailedAcks(ctx)
+	case domain.AlertParentDown:
+		return s.alerts.ParentsDown(ctx)
+	case domain.AlertConfigDrift:
+		return s.alerts.DriftedProxies(ctx)
+	}
+	return nil, fmt.Errorf("unknown rule type %q", rule.Type)
+}
+
+func alertSubject(a domain.Alert) string {
+	s := ""
+	if a.ProxyHostname != nil {
+		s = *a.ProxyHostname
+	}
+	if a.Subject != "" {
+		s += " " + a.Subject
+	}
+	return s
+}
+
+func (s *AlertService) logAudit(ctx context.Context, userID *uuid.UUID, action string, entityID *uuid.UUID, oldVal, newVal []byte, ip, ua string) {
+	_ = s.audit.Create(ctx, &domain.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityType: "alert_rule",
+		EntityID:   entityID,
+		OldValue:   oldVal,
+		NewValue:   newVal,
+		IPAddress:  &ip,
+		UserAgent:  &ua,
+	})
+}
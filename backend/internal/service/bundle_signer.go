@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	stdsync "sync"
+
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+)
+
+// bundleSignatureVersion prefixes the signed message so the format can evolve.
+const bundleSignatureVersion = "ats-proxy-bundle/v1"
+
+// BundleSigner signs the config bundles served to helpers with the backend's
+// Ed25519 key. The key is generated on first use and shared by all replicas
+// through the database.
+type BundleSigner struct {
+	keys *repository.SigningKeyRepo
+
+	mu   stdsync.Mutex
+	key  *domain.SigningKey
+	priv ed25519.PrivateKey
+}
+
+func NewBundleSigner(keys *repository.SigningKeyRepo) *BundleSigner {
+	return &BundleSigner{keys: keys}
+}
+
+// BundleMessage is the byte string that is signed: the version line, the hash
+// line, then every file sorted by name as "<name>\n<length>\n<content>" and,
+// when set, the CONNECT ports as "connect_ports\n<length>\n<ports>".
+// The helper rebuilds the same message to verify the signature.
+func BundleMessage(hash string, files map[string]string, connectPorts string) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msg := []byte(bundleSignatureVersion + "\n" + hash + "\n")
+	for _, name := range names {
+		msg = append(msg, name+"\n"+strconv.Itoa(len(files[name]))+"\n"...)
+		msg = append(msg, files[name]...)
+	}
+	if connectPorts != "" {
+		msg = append(msg, "connect_ports\n"+strconv.Itoa(len(connectPorts))+"\n"+connectPorts...)
+	}
+	return msg
+}
+
+// Sign returns the base64 signature of the bundle and the ID of the key used.
+func (s *BundleSigner) Sign(ctx context.Context, hash string, files map[string]string, connectPorts string) (signature, keyID string, err error) {
+	key, priv, err := s.activeKey(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	sig := ed25519.Sign(priv, BundleMessage(hash, files, connectPorts))
+	return base64.StdEncoding.EncodeToString(sig), key.ID.String(), nil
+}
+
+// PublicKey returns the active key without its private part.
+func (s *BundleSigner) PublicKey(ctx context.Context) (*domain.SigningKey, error) {
+	key, _, err := s.activeKey(ctx)
+	return key, err
+}
+
+func (s *BundleSigner) activeKey(ctx context.Context) (*domain.SigningKey, ed25519.PrivateKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.key != nil {
+		return s.key, s.priv, nil
+	}
+
+	key, err := s.keys.GetActive(ctx)
+	if errors.Is(err, domain.ErrNotFound) {
+		pub, priv, genErr := ed25519.GenerateKey(rand.Reader)
+		if genErr != nil {
+			return nil, nil, fmt.Errorf("generate signing key: %w", genErr)
+		}
+		if err := s.keys.CreateIfNone(ctx, &domain.SigningKey{
+			Algorithm:  "ed25519",
+			PublicKey:  base64.StdEncoding.EncodeToString(pub),
+			PrivateKey: base64.StdEncoding.EncodeToString(priv),
+		}); err != nil {
+			return nil, nil, err
+		}
+		key, err = s.keys.GetActive(ctx)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(key.PrivateKey)
+	if err != nil || len(raw) != ed25519.PrivateKeySize {
+		return nil, nil, fmt.Errorf("invalid signing key %s", key.ID)
+	}
+
+	s.key = key
+	s.priv = ed25519.PrivateKey(raw)
+	return s.key, s.priv, nil
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+)
+
+const (
+	// commandTTL is how long a command waits for the proxy to sync before it expires.
+	commandTTL        = time.Hour
+	maxCommandTimeout = 600
+	maxCommandOutput  = 64 * 1024
+	commandHistory    = 50
+)
+
+// defaultCommandTimeouts are the helper-side timeouts, in seconds, used when
+// the request does not set one.
+var defaultCommandTimeouts = map[domain.CommandType]int{
+	domain.CommandResync:           60,
+	domain.CommandReload:           30,
+	domain.CommandRestart:          120,
+	domain.CommandDiagnostics:      120,
+	domain.CommandClearHostDB:      60,
+	domain.CommandConnectivityTest: 30,
+}
+
+type CommandService struct {
+	commands *repository.ProxyCommandRepo
+	proxies  *repository.ProxyRepo
+	audit    *repository.AuditRepo
+	pushes   *SyncNotifier
+}
+
+func NewCommandService(
+	commands *repository.ProxyCommandRepo,
+	proxies *repository.ProxyRepo,
+	audit *repository.AuditRepo,
+	pushes *SyncNotifier,
+) *CommandService {
+	return &CommandService{
+		commands: commands,
+		proxies:  proxies,
+		audit:    audit,
+		pushes:   pushes,
+	}
+}
+
+type EnqueueCommandRequest struct {
+	Type           domain.CommandType `json:"type"`
+	Args           map[string]string  `json:"args,omitempty"`
+	TimeoutSeconds int                `json:"timeout_seconds,omitempty"`
+}
+
+// SyncCommand mirrors helper's Command
+type SyncCommand struct {
+	ID             string            `json:"id"`
+	Type           string            `json:"type"`
+	Args           map[string]string `json:"args,omitempty"`
+	TimeoutSeconds int               `json:"timeout_seconds"`
+}
+
+func validateCommand(req *EnqueueCommandRequest) error {
+	if !req.Type.IsValid() {
+		return fmt.Errorf("%w: invalid command type '%s'", domain.ErrBadRequest, req.Type)
+	}
+
+	switch req.Type {
+	case domain.CommandConnectivityTest:
+		for k := range req.Args {
+			if k != "url" && k != "direct" {
+				return fmt.Errorf("%w: unknown argument '%s'", domain.ErrBadRequest, k)
+			}
+		}
+		u, err := url.Parse(req.Args["url"])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: connectivity_test needs an http or https url", domain.ErrBadRequest)
+		}
+		if d, ok := req.Args["direct"]; ok && d != "true" && d != "false" {
+			return fmt.Errorf("%w: direct must be true or false", domain.ErrBadRequest)
+		}
+	default:
+		if len(req.Args) > 0 {
+			return fmt.Errorf("%w: %s takes no arguments", domain.ErrBadRequest, req.Type)
+		}
+	}
+
+	if req.TimeoutSeconds == 0 {
+		req.TimeoutSeconds = defaultCommandTimeouts[req.Type]
+	}
+	if req.TimeoutSeconds < 1 || req.TimeoutSeconds > maxCommandTimeout {
+		return fmt.Errorf("%w: timeout_seconds must be between 1 and %d", domain.ErrBadRequest, maxCommandTimeout)
+	}
+	return nil
+}
+
+// Enqueue queues a command for the proxy and wakes its long-poll so the helper
+// picks it up right away.
+func (s *CommandService) Enqueue(ctx context.Context, proxyID uuid.UUID, req EnqueueCommandRequest, userID uuid.UUID, ip, ua string) (*domain.ProxyCommand, error) {
+	if err := validateCommand(&req); err != nil {
+		return nil, err
+	}
+	if _, err := s.proxies.GetByID(ctx, proxyID); err != nil {
+		return nil, err
+	}
+
+	cmd := &domain.ProxyCommand{
+		ProxyID:        proxyID,
+		Type:           req.Type,
+		Args:           req.Args,
+		TimeoutSeconds: req.TimeoutSeconds,
+		CreatedBy:      &userID,
+		ExpiresAt:      time.Now().Add(commandTTL),
+	}
+	if err := s.commands.Create(ctx, cmd); err != nil {
+		return nil, err
+	}
+	s.pushes.ProxyChanged(ctx, proxyID)
+
+	newVal, _ := json.Marshal(map[string]interface{}{
+		"command_id": cmd.ID,
+		"type":       cmd.Type,
+		"args":       cmd.Args,
+	})
+	_ = s.audit.Create(ctx, &domain.AuditLog{
+		UserID:     &userID,
+		Action:     "proxy.command",
+		EntityType: "proxy",
+		EntityID:   &proxyID,
+		IPAddress:  &ip,
+		UserAgent:  &ua,
+		NewValue:   newVal,
+	})
+
+	return cmd, nil
+}
+
+func (s *CommandService) List(ctx context.Context, proxyID uuid.UUID) ([]domain.ProxyCommand, error) {
+	if _, err := s.proxies.GetByID(ctx, proxyID); err != nil {
+		return nil, err
+	}
+	return s.commands.ListByProxy(ctx, proxyID, commandHistory)
+}
+
+// Cancel cancels a command that has not been delivered yet.
+func (s *CommandService) Cancel(ctx context.Context, proxyID, commandID uuid.UUID, userID uuid.UUID, ip, ua string) error {
+	if err := s.commands.Cancel(ctx, commandID, proxyID); err != nil {
+		return err
+	}
+
+	_ = s.audit.Create(ctx, &domain.AuditLog{
+		UserID:     &userID,
+		Action:     "proxy.command_cancel",
+		EntityType: "proxy",
+		EntityID:   &proxyID,
+		IPAddress:  &ip,
+		UserAgent:  &ua,
+		OldValue:   []byte(fmt.Sprintf(`{"command_id":%q}`, commandID.String())),
+	})
+	return nil
+}
+
+// Received marks the commands the helper got in its previous sync as delivered.
+func (s *CommandService) Received(ctx context.Context, proxyID uuid.UUID, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.commands.MarkDelivered(ctx, proxyID, ids)
+}
+
+// Pending lists the commands to hand to the helper. They are sent again on
+// every sync until the helper reports them received.
+func (s *CommandService) Pending(ctx context.Context, proxyID uuid.UUID) ([]SyncCommand, error) {
+	commands, err := s.commands.ListPending(ctx, proxyID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SyncCommand, 0, len(commands))
+	for _, c := range commands {
+		out = append(out, SyncCommand{
+			ID:             c.ID.String(),
+			Type:           string(c.Type),
+			Args:           c.Args,
+			TimeoutSeconds: c.TimeoutSeconds,
+		})
+	}
+	return out, nil
+}
+
+// Result stores what the helper reported for a delivered command.
+func (s *CommandService) Result(ctx context.Context, proxyID, commandID uuid.UUID, status domain.CommandStatus, output, errMsg string) (*domain.ProxyCommand, error) {
+	switch status {
+	case domain.CommandSucceeded, domain.CommandFailed, domain.CommandTimedOut:
+	default:
+		return nil, fmt.Errorf("%w: status must be succeeded, failed or timed_out", domain.ErrBadRequest)
+	}
+
+	return s.commands.Complete(ctx, commandID, proxyID, status, truncateOutput(output), truncateOutput(errMsg))
+}
+
+// truncateOutput caps what is stored per command; nil for an empty string.
+func truncateOutput(s string) *string {
+	if s == "" {
+		return nil
+	}
+	if len(s) > maxCommandOutput {
+		s = s[:maxCommandOutput] + "\n... (truncated)"
+	}
+	return &s
+}
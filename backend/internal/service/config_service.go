@@ -2,12 +2,12 @@ package service
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 
@@ -25,8 +25,16 @@ type ConfigService struct {
 	ipRanges     *repository.IPRangeRuleRepo
 	parents      *repository.ParentProxyRepo
 	clientACL    *repository.ClientACLRepo
+	records      *repository.ConfigRecordRepo
 	configProxy  *repository.ConfigProxyRepo
+	configGroups *repository.ConfigGroupRepo
+	groups       *repository.ProxyGroupRepo
+	overlays     *repository.ConfigOverlayRepo
+	ruleSets     *repository.ConfigRuleSetRepo
 	audit        *repository.AuditRepo
+	events       *WebhookService
+	notifier     *NotificationService
+	pushes       *SyncNotifier
 }
 
 func NewConfigService(
@@ -36,18 +44,34 @@ func NewConfigService(
 	ipRanges *repository.IPRangeRuleRepo,
 	parents *repository.ParentProxyRepo,
 	clientACL *repository.ClientACLRepo,
+	records *repository.ConfigRecordRepo,
 	configProxy *repository.ConfigProxyRepo,
+	configGroups *repository.ConfigGroupRepo,
+	groups *repository.ProxyGroupRepo,
+	overlays *repository.ConfigOverlayRepo,
+	ruleSets *repository.ConfigRuleSetRepo,
 	audit *repository.AuditRepo,
+	events *WebhookService,
+	notifier *NotificationService,
+	pushes *SyncNotifier,
 ) *ConfigService {
 	return &ConfigService{
-		pool:        pool,
-		configs:     configs,
-		domains:     domains,
-		ipRanges:    ipRanges,
-		parents:     parents,
-		clientACL:   clientACL,
-		configProxy: configProxy,
-		audit:       audit,
+		pool:         pool,
+		configs:      configs,
+		domains:      domains,
+		ipRanges:     ipRanges,
+		parents:      parents,
+		clientACL:    clientACL,
+		records:      records,
+		configProxy:  configProxy,
+		configGroups: configGroups,
+		groups:       groups,
+		overlays:     overlays,
+		ruleSets:     ruleSets,
+		audit:        audit,
+		events:       events,
+		notifier:     notifier,
+		pushes:       pushes,
 	}
 }
 
@@ -57,7 +81,11 @@ type ConfigDetail struct {
 	IPRanges      []domain.IPRangeRule   `json:"ip_ranges"`
 	ParentProxies []domain.ParentProxy   `json:"parent_proxies"`
 	ClientACL     []domain.ClientACLRule `json:"client_acl"`
+	Records       []domain.RecordOverride `json:"records"`
 	Proxies       []domain.Proxy         `json:"proxies"`
+	Groups        []domain.ProxyGroup    `json:"groups"`
+	Overlays      []domain.ConfigOverlay `json:"overlays"`
+	RuleSets      []domain.ConfigRuleSet `json:"rule_sets"`
 	ModifiedByUser  *UserResponse `json:"modified_by_user,omitempty"`
 	ApprovedByUser  *UserResponse `json:"approved_by_user,omitempty"`
 }
@@ -84,10 +112,26 @@ func (s *ConfigService) GetByID(ctx context.Context, id uuid.UUID) (*ConfigDetai
 	if err != nil {
 		return nil, err
 	}
+	records, err := s.records.ListByConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 	proxies, err := s.configProxy.ListByConfig(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	groups, err := s.groups.ListByConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	overlays, err := s.overlays.ListByConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ruleSets, err := s.ruleSets.ListByConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
 	if domains == nil {
 		domains = []domain.DomainRule{}
@@ -101,9 +145,21 @@ func (s *ConfigService) GetByID(ctx context.Context, id uuid.UUID) (*ConfigDetai
 	if clientACL == nil {
 		clientACL = []domain.ClientACLRule{}
 	}
+	if records == nil {
+		records = []domain.RecordOverride{}
+	}
 	if proxies == nil {
 		proxies = []domain.Proxy{}
 	}
+	if groups == nil {
+		groups = []domain.ProxyGroup{}
+	}
+	if overlays == nil {
+		overlays = []domain.ConfigOverlay{}
+	}
+	if ruleSets == nil {
+		ruleSets = []domain.ConfigRuleSet{}
+	}
 
 	return &ConfigDetail{
 		Config:        *cfg,
@@ -111,7 +167,11 @@ func (s *ConfigService) GetByID(ctx context.Context, id uuid.UUID) (*ConfigDetai
 		IPRanges:      ipRanges,
 		ParentProxies: parents,
 		ClientACL:     clientACL,
+		Records:       records,
 		Proxies:       proxies,
+		Groups:        groups,
+		Overlays:      overlays,
+		RuleSets:      ruleSets,
 	}, nil
 }
 
@@ -155,17 +215,24 @@ type CreateConfigRequest struct {
 	Name          string                    `json:"name"`
 	Description   *string                   `json:"description,omitempty"`
 	DefaultAction domain.RuleAction         `json:"default_action"`
+	ConnectPorts  *string                   `json:"connect_ports,omitempty"`
 	Domains       []DomainRuleInput         `json:"domains"`
 	IPRanges      []IPRangeRuleInput        `json:"ip_ranges"`
 	ParentProxies []ParentProxyInput        `json:"parent_proxies"`
 	ClientACL     []ClientACLInput          `json:"client_acl"`
+	Records       []RecordOverrideInput     `json:"records"`
 	ProxyIDs      []uuid.UUID               `json:"proxy_ids"`
+	GroupIDs      []uuid.UUID               `json:"group_ids"`
+	Overlays      []ConfigOverlayInput      `json:"overlays"`
+	RuleSets      []ConfigRuleSetInput      `json:"rule_sets"`
 }
 
 type DomainRuleInput struct {
 	Domain   string            `json:"domain"`
 	Action   domain.RuleAction `json:"action"`
 	Priority int               `json:"priority"`
+	// TLS is the sni.yaml policy of the domain; config rules only
+	TLS *domain.TLSPolicy `json:"tls,omitempty"`
 }
 
 type IPRangeRuleInput struct {
@@ -182,9 +249,38 @@ type ParentProxyInput struct {
 }
 
 type ClientACLInput struct {
-	CIDR     string           `json:"cidr"`
-	Action   domain.ACLAction `json:"action"`
-	Priority int              `json:"priority"`
+	CIDR   string           `json:"cidr"`
+	Action domain.ACLAction `json:"action"`
+	// Apply defaults to in (client address); out matches the destination
+	Apply domain.ACLApply `json:"apply,omitempty"`
+	// Methods limits the action to these HTTP methods; empty or ALL means all
+	Methods  []string `json:"methods,omitempty"`
+	Priority int      `json:"priority"`
+}
+
+// ConfigOverlayInput adds rules for one proxy (by hostname) or one group on top
+// of the config's own; see domain.ConfigOverlay.
+type ConfigOverlayInput struct {
+	Hostname    *string            `json:"hostname,omitempty"`
+	GroupID     *uuid.UUID         `json:"group_id,omitempty"`
+	Description *string            `json:"description,omitempty"`
+	Domains     []DomainRuleInput  `json:"domains"`
+	IPRanges    []IPRangeRuleInput `json:"ip_ranges"`
+	ClientACL   []ClientACLInput   `json:"client_acl"`
+}
+
+// ConfigRuleSetInput references a rule set version (0 for the current one);
+// its rules are expanded among the config's own at Priority.
+type ConfigRuleSetInput struct {
+	RuleSetID uuid.UUID `json:"rule_set_id"`
+	Version   int       `json:"version"`
+	Priority  int       `json:"priority"`
+}
+
+// RecordOverrideInput sets a records.yaml value (see domain.ManagedRecords).
+type RecordOverrideInput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
 var domainPattern = regexp.MustCompile(`^(\*\.)?[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)+$`)
@@ -196,46 +292,176 @@ func validateRules(req CreateConfigRequest) error {
 	if req.DefaultAction == "" {
 		req.DefaultAction = domain.ActionDirect
 	}
-	if !req.DefaultAction.IsValid() {
+	if req.DefaultAction != domain.ActionDirect && req.DefaultAction != domain.ActionParent {
 		errs = append(errs, fmt.Sprintf("default_action: '%s' is not valid, must be 'direct' or 'parent'", req.DefaultAction))
 	}
 
-	// Validate domain rules
-	for i, d := range req.Domains {
+	if req.ConnectPorts != nil {
+		if err := domain.ValidatePortList(*req.ConnectPorts); err != nil {
+			errs = append(errs, fmt.Sprintf("connect_ports: %v", err))
+		}
+	}
+
+	errs = append(errs, validateDomainRules("domains", req.Domains)...)
+	errs = append(errs, validateIPRangeRules("ip_ranges", req.IPRanges)...)
+	errs = append(errs, validateClientACL("client_acl", req.ClientACL)...)
+
+	// Validate overlays: one per proxy or group, each with some rule
+	seenTargets := make(map[string]bool, len(req.Overlays))
+	for i, o := range req.Overlays {
+		prefix := fmt.Sprintf("overlays[%d]", i)
+		target := ""
+		switch {
+		case (o.Hostname == nil) == (o.GroupID == nil):
+			errs = append(errs, fmt.Sprintf("%s: exactly one of hostname and group_id must be set", prefix))
+		case o.Hostname != nil && *o.Hostname == "":
+			errs = append(errs, fmt.Sprintf("%s: hostname cannot be empty", prefix))
+		case o.Hostname != nil:
+			target = "proxy " + *o.Hostname
+		default:
+			target = "group " + o.GroupID.String()
+		}
+		if target != "" {
+			if seenTargets[target] {
+				errs = append(errs, fmt.Sprintf("%s: %s has more than one overlay", prefix, target))
+			}
+			seenTargets[target] = true
+		}
+		if len(o.Domains)+len(o.IPRanges)+len(o.ClientACL) == 0 {
+			errs = append(errs, fmt.Sprintf("%s: overlay has no rules", prefix))
+		}
+		for j, d := range o.Domains {
+			if !d.TLS.IsEmpty() {
+				errs = append(errs, fmt.Sprintf("%s.domains[%d]: tls is only supported on the config's own domain rules", prefix, j))
+			}
+		}
+		errs = append(errs, validateDomainRules(prefix+".domains", o.Domains)...)
+		errs = append(errs, validateIPRangeRules(prefix+".ip_ranges", o.IPRanges)...)
+		errs = append(errs, validateClientACL(prefix+".client_acl", o.ClientACL)...)
+	}
+
+	// Validate rule set references
+	seenRuleSets := make(map[uuid.UUID]bool, len(req.RuleSets))
+	for i, ref := range req.RuleSets {
+		if ref.Version < 0 {
+			errs = append(errs, fmt.Sprintf("rule_sets[%d]: version %d is not valid", i, ref.Version))
+		}
+		if seenRuleSets[ref.RuleSetID] {
+			errs = append(errs, fmt.Sprintf("rule_sets[%d]: rule set %s is referenced more than once", i, ref.RuleSetID))
+		}
+		seenRuleSets[ref.RuleSetID] = true
+	}
+
+	// Validate records.yaml overrides
+	seenRecords := make(map[string]bool, len(req.Records))
+	for i, rec := range req.Records {
+		if err := domain.ValidateRecord(rec.Name, rec.Value); err != nil {
+			errs = append(errs, fmt.Sprintf("records[%d]: %v", i, err))
+			continue
+		}
+		if seenRecords[rec.Name] {
+			errs = append(errs, fmt.Sprintf("records[%d]: '%s' is set more than once", i, rec.Name))
+		}
+		if rec.Name == domain.ConnectPortsRecord && req.ConnectPorts != nil {
+			errs = append(errs, fmt.Sprintf("records[%d]: '%s' cannot be overridden together with connect_ports", i, rec.Name))
+		}
+		seenRecords[rec.Name] = true
+	}
+
+	// Validate parent proxies
+	for i, pp := range req.ParentProxies {
+		if pp.Address == "" {
+			errs = append(errs, fmt.Sprintf("parent_proxies[%d]: address cannot be empty", i))
+		} else if net.ParseIP(pp.Address) == nil {
+			errs = append(errs, fmt.Sprintf("parent_proxies[%d]: '%s' is not a valid IP address", i, pp.Address))
+		}
+		if pp.Port < 1024 || pp.Port > 65535 {
+			errs = append(errs, fmt.Sprintf("parent_proxies[%d]: port %d is out of range (1024-65535)", i, pp.Port))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %s", domain.ErrBadRequest, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// checkOverlayGroups rejects overlays whose group does not exist.
+func (s *ConfigService) checkOverlayGroups(ctx context.Context, overlays []ConfigOverlayInput) error {
+	for i, o := range overlays {
+		if o.GroupID == nil {
+			continue
+		}
+		if _, err := s.groups.GetByID(ctx, *o.GroupID); err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return fmt.Errorf("%w: overlays[%d]: group %s does not exist", domain.ErrBadRequest, i, *o.GroupID)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// validateDomainRules checks domain rules, reporting them as prefix[i].
+func validateDomainRules(prefix string, rules []DomainRuleInput) []string {
+	var errs []string
+	for i, d := range rules {
 		if d.Domain == "" {
-			errs = append(errs, fmt.Sprintf("domains[%d]: domain cannot be empty", i))
+			errs = append(errs, fmt.Sprintf("%s[%d]: domain cannot be empty", prefix, i))
 			continue
 		}
 		if d.Domain == "*." || d.Domain == "*" {
-			errs = append(errs, fmt.Sprintf("domains[%d]: '%s' total wildcard is not allowed", i, d.Domain))
+			errs = append(errs, fmt.Sprintf("%s[%d]: '%s' total wildcard is not allowed", prefix, i, d.Domain))
 			continue
 		}
 		if !domainPattern.MatchString(d.Domain) {
-			errs = append(errs, fmt.Sprintf("domains[%d]: '%s' is not a valid domain (use *.example.com or host.example.com)", i, d.Domain))
+			errs = append(errs, fmt.Sprintf("%s[%d]: '%s' is not a valid domain (use *.example.com or host.example.com)", prefix, i, d.Domain))
 		}
 		if !d.Action.IsValid() {
-			errs = append(errs, fmt.Sprintf("domains[%d]: action '%s' is not valid", i, d.Action))
+			errs = append(errs, fmt.Sprintf("%s[%d]: action '%s' is not valid", prefix, i, d.Action))
+		}
+		if d.TLS.IsEmpty() {
+			continue
+		}
+		switch {
+		case d.Action == domain.ActionDeny:
+			errs = append(errs, fmt.Sprintf("%s[%d]: tls cannot be set on a deny rule", prefix, i))
+		case d.TLS.ForwardRoute != "" && d.Action != domain.ActionDirect:
+			errs = append(errs, fmt.Sprintf("%s[%d]: tls.forward_route is only valid on direct rules", prefix, i))
+		default:
+			if err := d.TLS.Validate(); err != nil {
+				errs = append(errs, fmt.Sprintf("%s[%d]: tls: %v", prefix, i, err))
+			}
 		}
 	}
+	return errs
+}
 
-	// Validate IP range rules
-	for i, ir := range req.IPRanges {
+// validateIPRangeRules checks IP range rules, reporting them as prefix[i].
+func validateIPRangeRules(prefix string, rules []IPRangeRuleInput) []string {
+	var errs []string
+	for i, ir := range rules {
 		if ir.CIDR == "" {
-			errs = append(errs, fmt.Sprintf("ip_ranges[%d]: CIDR cannot be empty", i))
+			errs = append(errs, fmt.Sprintf("%s[%d]: CIDR cannot be empty", prefix, i))
 			continue
 		}
-		if err := validateCIDR(ir.CIDR, fmt.Sprintf("ip_ranges[%d]", i)); err != "" {
+		if err := validateCIDR(ir.CIDR, fmt.Sprintf("%s[%d]", prefix, i)); err != "" {
 			errs = append(errs, err)
 		}
 		if !ir.Action.IsValid() {
-			errs = append(errs, fmt.Sprintf("ip_ranges[%d]: action '%s' is not valid", i, ir.Action))
+			errs = append(errs, fmt.Sprintf("%s[%d]: action '%s' is not valid", prefix, i, ir.Action))
 		}
 	}
+	return errs
+}
 
-	// Validate client ACL rules
-	for i, acl := range req.ClientACL {
+// validateClientACL checks client ACL rules, reporting them as prefix[i].
+func validateClientACL(prefix string, rules []ClientACLInput) []string {
+	var errs []string
+	seen := make(map[string]bool, len(rules))
+	for i, acl := range rules {
 		if acl.CIDR == "" {
-			errs = append(errs, fmt.Sprintf("client_acl[%d]: CIDR cannot be empty", i))
+			errs = append(errs, fmt.Sprintf("%s[%d]: CIDR cannot be empty", prefix, i))
 			continue
 		}
 		// Allow IPv6 in client ACL (e.g. ::1)
@@ -243,37 +469,121 @@ func validateRules(req CreateConfigRequest) error {
 		if ip != nil {
 			// Valid bare IP — check for 0.0.0.0
 			if acl.CIDR == "0.0.0.0" {
-				errs = append(errs, fmt.Sprintf("client_acl[%d]: 0.0.0.0 is not allowed", i))
+				errs = append(errs, fmt.Sprintf("%s[%d]: 0.0.0.0 is not allowed", prefix, i))
 			}
 		} else {
 			_, ipnet, err := net.ParseCIDR(acl.CIDR)
 			if err != nil {
-				errs = append(errs, fmt.Sprintf("client_acl[%d]: '%s' is not a valid CIDR or IP address", i, acl.CIDR))
+				errs = append(errs, fmt.Sprintf("%s[%d]: '%s' is not a valid CIDR or IP address", prefix, i, acl.CIDR))
 			} else if ipnet.IP.To4() != nil && ipnet.IP.Equal(net.IPv4zero) {
-				errs = append(errs, fmt.Sprintf("client_acl[%d]: 0.0.0.0/%d is not allowed", i, maskSize(ipnet)))
+				errs = append(errs, fmt.Sprintf("%s[%d]: 0.0.0.0/%d is not allowed", prefix, i, maskSize(ipnet)))
 			}
 		}
 		if !acl.Action.IsValid() {
-			errs = append(errs, fmt.Sprintf("client_acl[%d]: action '%s' is not valid", i, acl.Action))
+			errs = append(errs, fmt.Sprintf("%s[%d]: action '%s' is not valid", prefix, i, acl.Action))
+		}
+		apply := aclApply(acl.Apply)
+		if !apply.IsValid() {
+			errs = append(errs, fmt.Sprintf("%s[%d]: apply '%s' is not valid, must be 'in' or 'out'", prefix, i, acl.Apply))
+		}
+		key := string(apply) + " " + acl.CIDR
+		if seen[key] {
+			errs = append(errs, fmt.Sprintf("%s[%d]: '%s' already has an apply %s rule", prefix, i, acl.CIDR, apply))
+		}
+		seen[key] = true
+		for _, m := range acl.Methods {
+			m = strings.ToUpper(strings.TrimSpace(m))
+			if m == "ALL" {
+				if len(acl.Methods) > 1 {
+					errs = append(errs, fmt.Sprintf("%s[%d]: ALL cannot be combined with other methods", prefix, i))
+				}
+				continue
+			}
+			if !slices.Contains(domain.ACLMethods, m) {
+				errs = append(errs, fmt.Sprintf("%s[%d]: method '%s' is not valid, must be ALL or one of %s", prefix, i, m, strings.Join(domain.ACLMethods, ", ")))
+			}
 		}
 	}
+	return errs
+}
 
-	// Validate parent proxies
-	for i, pp := range req.ParentProxies {
-		if pp.Address == "" {
-			errs = append(errs, fmt.Sprintf("parent_proxies[%d]: address cannot be empty", i))
-		} else if net.ParseIP(pp.Address) == nil {
-			errs = append(errs, fmt.Sprintf("parent_proxies[%d]: '%s' is not a valid IP address", i, pp.Address))
+// aclApply returns the apply of an ACL rule, in when unset.
+func aclApply(a domain.ACLApply) domain.ACLApply {
+	if a == "" {
+		return domain.ACLApplyIn
+	}
+	return a
+}
+
+// aclMethods normalizes the methods of a validated ACL rule: upper case, no
+// duplicates, and empty for all methods.
+func aclMethods(methods []string) []string {
+	out := make([]string, 0, len(methods))
+	for _, m := range methods {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if m == "ALL" {
+			return []string{}
 		}
-		if pp.Port < 1024 || pp.Port > 65535 {
-			errs = append(errs, fmt.Sprintf("parent_proxies[%d]: port %d is out of range (1024-65535)", i, pp.Port))
+		if !slices.Contains(out, m) {
+			out = append(out, m)
 		}
 	}
+	return out
+}
 
-	if len(errs) > 0 {
-		return fmt.Errorf("%w: %s", domain.ErrBadRequest, strings.Join(errs, "; "))
+// overlayFromInput builds the overlay of a config from validated input.
+func overlayFromInput(configID uuid.UUID, in ConfigOverlayInput) domain.ConfigOverlay {
+	o := domain.ConfigOverlay{
+		ConfigID:    configID,
+		Hostname:    in.Hostname,
+		GroupID:     in.GroupID,
+		Description: in.Description,
+		Domains:     make([]domain.OverlayDomainRule, 0, len(in.Domains)),
+		IPRanges:    make([]domain.OverlayIPRule, 0, len(in.IPRanges)),
+		ClientACL:   make([]domain.OverlayACLRule, 0, len(in.ClientACL)),
 	}
-	return nil
+	for _, d := range in.Domains {
+		o.Domains = append(o.Domains, domain.OverlayDomainRule{Domain: d.Domain, Action: d.Action, Priority: d.Priority})
+	}
+	for _, ir := range in.IPRanges {
+		o.IPRanges = append(o.IPRanges, domain.OverlayIPRule{CIDR: ir.CIDR, Action: ir.Action, Priority: ir.Priority})
+	}
+	for _, acl := range in.ClientACL {
+		o.ClientACL = append(o.ClientACL, domain.OverlayACLRule{CIDR: acl.CIDR, Action: acl.Action, Apply: aclApply(acl.Apply), Methods: aclMethods(acl.Methods), Priority: acl.Priority})
+	}
+	return o
+}
+
+// assignRuleSets references the requested rule set versions from a config,
+// resolving version 0 to the current one.
+func assignRuleSets(ctx context.Context, sets *repository.RuleSetRepo, refs *repository.ConfigRuleSetRepo, configID uuid.UUID, inputs []ConfigRuleSetInput) ([]domain.ConfigRuleSet, error) {
+	assigned := make([]domain.ConfigRuleSet, 0, len(inputs))
+	for i, in := range inputs {
+		rs, err := sets.GetByID(ctx, in.RuleSetID)
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("%w: rule_sets[%d]: rule set %s not found", domain.ErrBadRequest, i, in.RuleSetID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		ref := domain.ConfigRuleSet{
+			RuleSetID:     rs.ID,
+			Name:          rs.Name,
+			Version:       in.Version,
+			LatestVersion: rs.Version,
+			Priority:      in.Priority,
+		}
+		if ref.Version == 0 {
+			ref.Version = rs.Version
+		} else if ref.Version > rs.Version {
+			return nil, fmt.Errorf("%w: rule_sets[%d]: rule set '%s' has no version %d", domain.ErrBadRequest, i, rs.Name, in.Version)
+		}
+		if err := refs.Assign(ctx, configID, ref); err != nil {
+			return nil, err
+		}
+		assigned = append(assigned, ref)
+	}
+	return assigned, nil
 }
 
 func validateCIDR(cidr, prefix string) string {
@@ -296,6 +606,28 @@ func validateCIDR(cidr, prefix string) string {
 	return ""
 }
 
+// tlsPolicy drops an empty TLS policy, so the rule is stored without one.
+func tlsPolicy(p *domain.TLSPolicy) *domain.TLSPolicy {
+	if p.IsEmpty() {
+		return nil
+	}
+	return p
+}
+
+// portList normalizes a connect_ports value to single-space separated fields;
+// nil or blank means the config does not manage CONNECT ports.
+func portList(ports *string) *string {
+	if ports == nil {
+		return nil
+	}
+	fields := strings.Fields(*ports)
+	if len(fields) == 0 {
+		return nil
+	}
+	joined := strings.Join(fields, " ")
+	return &joined
+}
+
 func maskSize(ipnet *net.IPNet) int {
 	ones, _ := ipnet.Mask.Size()
 	return ones
@@ -310,10 +642,14 @@ func (s *ConfigService) Create(ctx context.Context, req CreateConfigRequest, use
 	if req.DefaultAction == "" {
 		req.DefaultAction = domain.ActionDirect
 	}
+	req.ConnectPorts = portList(req.ConnectPorts)
 
 	if err := validateRules(req); err != nil {
 		return nil, err
 	}
+	if err := s.checkOverlayGroups(ctx, req.Overlays); err != nil {
+		return nil, err
+	}
 
 	var result *ConfigDetail
 
@@ -323,13 +659,18 @@ func (s *ConfigService) Create(ctx context.Context, req CreateConfigRequest, use
 		txIPRanges := repository.NewIPRangeRuleRepo(tx)
 		txParents := repository.NewParentProxyRepo(tx)
 		txClientACL := repository.NewClientACLRepo(tx)
+		txRecords := repository.NewConfigRecordRepo(tx)
 		txConfigProxy := repository.NewConfigProxyRepo(tx)
+		txConfigGroups := repository.NewConfigGroupRepo(tx)
+		txOverlays := repository.NewConfigOverlayRepo(tx)
+		txRuleSets := repository.NewConfigRuleSetRepo(tx)
 		txAudit := repository.NewAuditRepo(tx)
 
 		cfg := &domain.Config{
 			Name:          req.Name,
 			Description:   req.Description,
 			DefaultAction: req.DefaultAction,
+			ConnectPorts:  req.ConnectPorts,
 			CreatedBy:     &userID,
 		}
 		if err := txConfigs.Create(ctx, cfg); err != nil {
@@ -338,7 +679,7 @@ func (s *ConfigService) Create(ctx context.Context, req CreateConfigRequest, use
 
 		domains := make([]domain.DomainRule, 0, len(req.Domains))
 		for _, d := range req.Domains {
-			dr := domain.DomainRule{ConfigID: cfg.ID, Domain: d.Domain, Action: d.Action, Priority: d.Priority}
+			dr := domain.DomainRule{ConfigID: cfg.ID, Domain: d.Domain, Action: d.Action, Priority: d.Priority, TLS: tlsPolicy(d.TLS)}
 			if err := txDomains.Create(ctx, &dr); err != nil {
 				return err
 			}
@@ -374,19 +715,47 @@ func (s *ConfigService) Create(ctx context.Context, req CreateConfigRequest, use
 		}
 		clientACL := make([]domain.ClientACLRule, 0, len(aclInputs))
 		for _, acl := range aclInputs {
-			rule := domain.ClientACLRule{ConfigID: cfg.ID, CIDR: acl.CIDR, Action: acl.Action, Priority: acl.Priority}
+			rule := domain.ClientACLRule{ConfigID: cfg.ID, CIDR: acl.CIDR, Action: acl.Action, Apply: aclApply(acl.Apply), Methods: aclMethods(acl.Methods), Priority: acl.Priority}
 			if err := txClientACL.Create(ctx, &rule); err != nil {
 				return err
 			}
 			clientACL = append(clientACL, rule)
 		}
 
+		records := make([]domain.RecordOverride, 0, len(req.Records))
+		for _, in := range req.Records {
+			rec := domain.RecordOverride{ConfigID: cfg.ID, Name: in.Name, Value: in.Value}
+			if err := txRecords.Create(ctx, &rec); err != nil {
+				return err
+			}
+			records = append(records, rec)
+		}
+
 		for _, pid := range req.ProxyIDs {
-			if err := txConfigProxy.Assign(ctx, cfg.ID, pid, userID); err != nil {
+			if err := txConfigProxy.Assign(ctx, cfg.ID, pid, &userID); err != nil {
+				return err
+			}
+		}
+		for _, gid := range req.GroupIDs {
+			if err := txConfigGroups.Assign(ctx, cfg.ID, gid, userID); err != nil {
 				return err
 			}
 		}
 
+		overlays := make([]domain.ConfigOverlay, 0, len(req.Overlays))
+		for _, in := range req.Overlays {
+			o := overlayFromInput(cfg.ID, in)
+			if err := txOverlays.Create(ctx, &o); err != nil {
+				return err
+			}
+			overlays = append(overlays, o)
+		}
+
+		ruleSets, err := assignRuleSets(ctx, repository.NewRuleSetRepo(tx), txRuleSets, cfg.ID, req.RuleSets)
+		if err != nil {
+			return err
+		}
+
 		_ = txAudit.Create(ctx, &domain.AuditLog{
 			UserID:     &userID,
 			Action:     "config.create",
@@ -402,7 +771,11 @@ func (s *ConfigService) Create(ctx context.Context, req CreateConfigRequest, use
 			IPRanges:      ipRanges,
 			ParentProxies: parents,
 			ClientACL:     clientACL,
+			Records:       records,
 			Proxies:       []domain.Proxy{},
+			Groups:        []domain.ProxyGroup{},
+			Overlays:      overlays,
+			RuleSets:      ruleSets,
 		}
 		return nil
 	})
@@ -418,10 +791,14 @@ func (s *ConfigService) Update(ctx context.Context, id uuid.UUID, req CreateConf
 	if req.DefaultAction == "" {
 		req.DefaultAction = domain.ActionDirect
 	}
+	req.ConnectPorts = portList(req.ConnectPorts)
 
 	if err := validateRules(req); err != nil {
 		return nil, err
 	}
+	if err := s.checkOverlayGroups(ctx, req.Overlays); err != nil {
+		return nil, err
+	}
 
 	var result *ConfigDetail
 
@@ -431,7 +808,11 @@ func (s *ConfigService) Update(ctx context.Context, id uuid.UUID, req CreateConf
 		txIPRanges := repository.NewIPRangeRuleRepo(tx)
 		txParents := repository.NewParentProxyRepo(tx)
 		txClientACL := repository.NewClientACLRepo(tx)
+		txRecords := repository.NewConfigRecordRepo(tx)
 		txConfigProxy := repository.NewConfigProxyRepo(tx)
+		txConfigGroups := repository.NewConfigGroupRepo(tx)
+		txOverlays := repository.NewConfigOverlayRepo(tx)
+		txRuleSets := repository.NewConfigRuleSetRepo(tx)
 		txAudit := repository.NewAuditRepo(tx)
 
 		cfg, err := txConfigs.GetByID(ctx, id)
@@ -445,6 +826,7 @@ func (s *ConfigService) Update(ctx context.Context, id uuid.UUID, req CreateConf
 		cfg.Name = req.Name
 		cfg.Description = req.Description
 		cfg.DefaultAction = req.DefaultAction
+		cfg.ConnectPorts = req.ConnectPorts
 		cfg.ModifiedBy = &userID
 		if err := txConfigs.Update(ctx, cfg); err != nil {
 			return err
@@ -463,13 +845,25 @@ func (s *ConfigService) Update(ctx context.Context, id uuid.UUID, req CreateConf
 		if err := txClientACL.DeleteByConfig(ctx, id); err != nil {
 			return err
 		}
+		if err := txRecords.DeleteByConfig(ctx, id); err != nil {
+			return err
+		}
 		if err := txConfigProxy.DeleteByConfig(ctx, id); err != nil {
 			return err
 		}
+		if err := txConfigGroups.DeleteByConfig(ctx, id); err != nil {
+			return err
+		}
+		if err := txOverlays.DeleteByConfig(ctx, id); err != nil {
+			return err
+		}
+		if err := txRuleSets.DeleteByConfig(ctx, id); err != nil {
+			return err
+		}
 
 		domains := make([]domain.DomainRule, 0, len(req.Domains))
 		for _, d := range req.Domains {
-			dr := domain.DomainRule{ConfigID: id, Domain: d.Domain, Action: d.Action, Priority: d.Priority}
+			dr := domain.DomainRule{ConfigID: id, Domain: d.Domain, Action: d.Action, Priority: d.Priority, TLS: tlsPolicy(d.TLS)}
 			if err := txDomains.Create(ctx, &dr); err != nil {
 				return err
 			}
@@ -496,19 +890,47 @@ func (s *ConfigService) Update(ctx context.Context, id uuid.UUID, req CreateConf
 
 		clientACL := make([]domain.ClientACLRule, 0, len(req.ClientACL))
 		for _, acl := range req.ClientACL {
-			rule := domain.ClientACLRule{ConfigID: id, CIDR: acl.CIDR, Action: acl.Action, Priority: acl.Priority}
+			rule := domain.ClientACLRule{ConfigID: id, CIDR: acl.CIDR, Action: acl.Action, Apply: aclApply(acl.Apply), Methods: aclMethods(acl.Methods), Priority: acl.Priority}
 			if err := txClientACL.Create(ctx, &rule); err != nil {
 				return err
 			}
 			clientACL = append(clientACL, rule)
 		}
 
+		records := make([]domain.RecordOverride, 0, len(req.Records))
+		for _, in := range req.Records {
+			rec := domain.RecordOverride{ConfigID: id, Name: in.Name, Value: in.Value}
+			if err := txRecords.Create(ctx, &rec); err != nil {
+				return err
+			}
+			records = append(records, rec)
+		}
+
 		for _, pid := range req.ProxyIDs {
-			if err := txConfigProxy.Assign(ctx, id, pid, userID); err != nil {
+			if err := txConfigProxy.Assign(ctx, id, pid, &userID); err != nil {
+				return err
+			}
+		}
+		for _, gid := range req.GroupIDs {
+			if err := txConfigGroups.Assign(ctx, id, gid, userID); err != nil {
 				return err
 			}
 		}
 
+		overlays := make([]domain.ConfigOverlay, 0, len(req.Overlays))
+		for _, in := range req.Overlays {
+			o := overlayFromInput(id, in)
+			if err := txOverlays.Create(ctx, &o); err != nil {
+				return err
+			}
+			overlays = append(overlays, o)
+		}
+
+		ruleSets, err := assignRuleSets(ctx, repository.NewRuleSetRepo(tx), txRuleSets, id, req.RuleSets)
+		if err != nil {
+			return err
+		}
+
 		_ = txAudit.Create(ctx, &domain.AuditLog{
 			UserID:     &userID,
 			Action:     "config.update",
@@ -529,7 +951,11 @@ func (s *ConfigService) Update(ctx context.Context, id uuid.UUID, req CreateConf
 			IPRanges:      ipRanges,
 			ParentProxies: parents,
 			ClientACL:     clientACL,
+			Records:       records,
 			Proxies:       []domain.Proxy{},
+			Groups:        []domain.ProxyGroup{},
+			Overlays:      overlays,
+			RuleSets:      ruleSets,
 		}
 		return nil
 	})
@@ -544,123 +970,192 @@ func (s *ConfigService) Clone(ctx context.Context, id, userID uuid.UUID, ip, ua
 	var result *ConfigDetail
 
 	err := repository.WithTx(ctx, s.pool, func(tx pgx.Tx) error {
-		txConfigs := repository.NewConfigRepo(tx)
-		txDomains := repository.NewDomainRuleRepo(tx)
-		txIPRanges := repository.NewIPRangeRuleRepo(tx)
-		txParents := repository.NewParentProxyRepo(tx)
-		txClientACL := repository.NewClientACLRepo(tx)
-		txConfigProxy := repository.NewConfigProxyRepo(tx)
-		txAudit := repository.NewAuditRepo(tx)
+		var err error
+		result, err = cloneConfig(ctx, tx, id, userID, ip, ua)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// Load original config
-		original, err := txConfigs.GetByID(ctx, id)
-		if err != nil {
-			return err
-		}
+	return result, nil
+}
 
-		// Create new config with incremented version
-		newCfg := &domain.Config{
-			Name:          original.Name,
-			Description:   original.Description,
-			DefaultAction: original.DefaultAction,
-			CreatedBy:     &userID,
-		}
-		if err := txConfigs.CreateWithVersion(ctx, newCfg, original.Version+1); err != nil {
-			return err
-		}
+// cloneConfig creates a draft copy of a config with the next version inside tx.
+func cloneConfig(ctx context.Context, tx pgx.Tx, id, userID uuid.UUID, ip, ua string) (*ConfigDetail, error) {
+	txConfigs := repository.NewConfigRepo(tx)
+	txDomains := repository.NewDomainRuleRepo(tx)
+	txIPRanges := repository.NewIPRangeRuleRepo(tx)
+	txParents := repository.NewParentProxyRepo(tx)
+	txClientACL := repository.NewClientACLRepo(tx)
+	txRecords := repository.NewConfigRecordRepo(tx)
+	txConfigProxy := repository.NewConfigProxyRepo(tx)
+	txConfigGroups := repository.NewConfigGroupRepo(tx)
+	txOverlays := repository.NewConfigOverlayRepo(tx)
+	txRuleSets := repository.NewConfigRuleSetRepo(tx)
+	txAudit := repository.NewAuditRepo(tx)
+
+	// Load original config
+	original, err := txConfigs.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
-		// Copy domain rules
-		origDomains, err := txDomains.ListByConfig(ctx, id)
-		if err != nil {
-			return err
-		}
-		domains := make([]domain.DomainRule, 0, len(origDomains))
-		for _, d := range origDomains {
-			dr := domain.DomainRule{ConfigID: newCfg.ID, Domain: d.Domain, Action: d.Action, Priority: d.Priority}
-			if err := txDomains.Create(ctx, &dr); err != nil {
-				return err
-			}
-			domains = append(domains, dr)
-		}
+	// Create new config with incremented version
+	newCfg := &domain.Config{
+		Name:          original.Name,
+		Description:   original.Description,
+		DefaultAction: original.DefaultAction,
+		ConnectPorts:  original.ConnectPorts,
+		CreatedBy:     &userID,
+	}
+	if err := txConfigs.CreateWithVersion(ctx, newCfg, original.Version+1); err != nil {
+		return nil, err
+	}
 
-		// Copy IP range rules
-		origIPRanges, err := txIPRanges.ListByConfig(ctx, id)
-		if err != nil {
-			return err
-		}
-		ipRanges := make([]domain.IPRangeRule, 0, len(origIPRanges))
-		for _, ir := range origIPRanges {
-			rule := domain.IPRangeRule{ConfigID: newCfg.ID, CIDR: ir.CIDR, Action: ir.Action, Priority: ir.Priority}
-			if err := txIPRanges.Create(ctx, &rule); err != nil {
-				return err
-			}
-			ipRanges = append(ipRanges, rule)
+	// Copy domain rules
+	origDomains, err := txDomains.ListByConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	domains := make([]domain.DomainRule, 0, len(origDomains))
+	for _, d := range origDomains {
+		dr := domain.DomainRule{ConfigID: newCfg.ID, Domain: d.Domain, Action: d.Action, Priority: d.Priority, TLS: d.TLS}
+		if err := txDomains.Create(ctx, &dr); err != nil {
+			return nil, err
 		}
+		domains = append(domains, dr)
+	}
 
-		// Copy parent proxies
-		origParents, err := txParents.ListByConfig(ctx, id)
-		if err != nil {
-			return err
-		}
-		parents := make([]domain.ParentProxy, 0, len(origParents))
-		for _, pp := range origParents {
-			proxy := domain.ParentProxy{ConfigID: newCfg.ID, Address: pp.Address, Port: pp.Port, Priority: pp.Priority, Enabled: pp.Enabled}
-			if err := txParents.Create(ctx, &proxy); err != nil {
-				return err
-			}
-			parents = append(parents, proxy)
+	// Copy IP range rules
+	origIPRanges, err := txIPRanges.ListByConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ipRanges := make([]domain.IPRangeRule, 0, len(origIPRanges))
+	for _, ir := range origIPRanges {
+		rule := domain.IPRangeRule{ConfigID: newCfg.ID, CIDR: ir.CIDR, Action: ir.Action, Priority: ir.Priority}
+		if err := txIPRanges.Create(ctx, &rule); err != nil {
+			return nil, err
 		}
+		ipRanges = append(ipRanges, rule)
+	}
 
-		// Copy client ACL rules
-		origACL, err := txClientACL.ListByConfig(ctx, id)
-		if err != nil {
-			return err
+	// Copy parent proxies
+	origParents, err := txParents.ListByConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	parents := make([]domain.ParentProxy, 0, len(origParents))
+	for _, pp := range origParents {
+		proxy := domain.ParentProxy{ConfigID: newCfg.ID, Address: pp.Address, Port: pp.Port, Priority: pp.Priority, Enabled: pp.Enabled}
+		if err := txParents.Create(ctx, &proxy); err != nil {
+			return nil, err
 		}
-		clientACL := make([]domain.ClientACLRule, 0, len(origACL))
-		for _, acl := range origACL {
-			rule := domain.ClientACLRule{ConfigID: newCfg.ID, CIDR: acl.CIDR, Action: acl.Action, Priority: acl.Priority}
-			if err := txClientACL.Create(ctx, &rule); err != nil {
-				return err
-			}
-			clientACL = append(clientACL, rule)
+		parents = append(parents, proxy)
+	}
+
+	// Copy client ACL rules
+	origACL, err := txClientACL.ListByConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	clientACL := make([]domain.ClientACLRule, 0, len(origACL))
+	for _, acl := range origACL {
+		rule := domain.ClientACLRule{ConfigID: newCfg.ID, CIDR: acl.CIDR, Action: acl.Action, Apply: acl.Apply, Methods: acl.Methods, Priority: acl.Priority}
+		if err := txClientACL.Create(ctx, &rule); err != nil {
+			return nil, err
 		}
+		clientACL = append(clientACL, rule)
+	}
 
-		// Copy config_proxies assignments
-		origProxies, err := txConfigProxy.ListByConfig(ctx, id)
-		if err != nil {
-			return err
+	// Copy records.yaml overrides
+	origRecords, err := txRecords.ListByConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]domain.RecordOverride, 0, len(origRecords))
+	for _, o := range origRecords {
+		rec := domain.RecordOverride{ConfigID: newCfg.ID, Name: o.Name, Value: o.Value}
+		if err := txRecords.Create(ctx, &rec); err != nil {
+			return nil, err
 		}
-		for _, p := range origProxies {
-			if err := txConfigProxy.Assign(ctx, newCfg.ID, p.ID, userID); err != nil {
-				return err
-			}
+		records = append(records, rec)
+	}
+
+	// Copy config_proxies assignments
+	origProxies, err := txConfigProxy.ListByConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range origProxies {
+		if err := txConfigProxy.Assign(ctx, newCfg.ID, p.ID, &userID); err != nil {
+			return nil, err
 		}
+	}
 
-		_ = txAudit.Create(ctx, &domain.AuditLog{
-			UserID:     &userID,
-			Action:     "config.clone",
-			EntityType: "config",
-			EntityID:   &newCfg.ID,
-			OldValue:   jsonVal("source_id", id.String()),
-			IPAddress:  &ip,
-			UserAgent:  &ua,
-		})
+	// Copy config_groups assignments
+	origGroups, err := repository.NewProxyGroupRepo(tx).ListByConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range origGroups {
+		if err := txConfigGroups.Assign(ctx, newCfg.ID, g.ID, userID); err != nil {
+			return nil, err
+		}
+	}
 
-		result = &ConfigDetail{
-			Config:        *newCfg,
-			Domains:       domains,
-			IPRanges:      ipRanges,
-			ParentProxies: parents,
-			ClientACL:     clientACL,
-			Proxies:       origProxies,
+	// Copy overlays
+	overlays, err := txOverlays.ListByConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range overlays {
+		overlays[i].ConfigID = newCfg.ID
+		if err := txOverlays.Create(ctx, &overlays[i]); err != nil {
+			return nil, err
 		}
-		return nil
-	})
+	}
+	if overlays == nil {
+		overlays = []domain.ConfigOverlay{}
+	}
+
+	// Copy rule set references, pinned to the same versions
+	ruleSets, err := txRuleSets.ListByConfig(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	for _, ref := range ruleSets {
+		if err := txRuleSets.Assign(ctx, newCfg.ID, ref); err != nil {
+			return nil, err
+		}
+	}
+	if ruleSets == nil {
+		ruleSets = []domain.ConfigRuleSet{}
+	}
 
-	return result, nil
+	_ = txAudit.Create(ctx, &domain.AuditLog{
+		UserID:     &userID,
+		Action:     "config.clone",
+		EntityType: "config",
+		EntityID:   &newCfg.ID,
+		OldValue:   jsonVal("source_id", id.String()),
+		IPAddress:  &ip,
+		UserAgent:  &ua,
+	})
+
+	return &ConfigDetail{
+		Config:        *newCfg,
+		Domains:       domains,
+		IPRanges:      ipRanges,
+		ParentProxies: parents,
+		ClientACL:     clientACL,
+		Records:       records,
+		Proxies:       origProxies,
+		Groups:        origGroups,
+		Overlays:      overlays,
+		RuleSets:      ruleSets,
+	}, nil
 }
 
 func (s *ConfigService) Submit(ctx context.Context, id, userID uuid.UUID, ip, ua string) (*domain.Config, error) {
@@ -679,7 +1174,13 @@ func (s *ConfigService) Submit(ctx context.Context, id, userID uuid.UUID, ip, ua
 		UserAgent:  &ua,
 	})
 
-	return s.configs.GetByID(ctx, id)
+	cfg, err := s.configs.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.publishConfigEvent(ctx, domain.EventConfigSubmit, cfg, userID, "")
+	s.notifier.ConfigSubmitted(cfg, userID)
+	return cfg, nil
 }
 
 func (s *ConfigService) Approve(ctx context.Context, id, userID uuid.UUID, ip, ua string) (*domain.Config, error) {
@@ -720,10 +1221,24 @@ func (s *ConfigService) Approve(ctx context.Context, id, userID uuid.UUID, ip, u
 		UserAgent:  &ua,
 	})
 
-	return s.configs.GetByID(ctx, id)
+	submittedBy := cfg.SubmittedBy
+	cfg, err = s.configs.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.publishConfigEvent(ctx, domain.EventConfigApprove, cfg, userID, "")
+	s.pushes.ConfigActivated(ctx, cfg.ID)
+	s.notifier.ConfigApproved(cfg, userID, submittedBy)
+	return cfg, nil
 }
 
 func (s *ConfigService) Reject(ctx context.Context, id, userID uuid.UUID, reason, ip, ua string) (*domain.Config, error) {
+	// Reject clears submitted_by, keep it to notify the submitter
+	before, err := s.configs.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := s.configs.Reject(ctx, id); err != nil {
 		return nil, err
 	}
@@ -739,52 +1254,259 @@ func (s *ConfigService) Reject(ctx context.Context, id, userID uuid.UUID, reason
 		UserAgent:  &ua,
 	})
 
-	return s.configs.GetByID(ctx, id)
+	cfg, err := s.configs.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.publishConfigEvent(ctx, domain.EventConfigReject, cfg, userID, reason)
+	s.notifier.ConfigRejected(cfg, userID, before.SubmittedBy, reason)
+	return cfg, nil
+}
+
+// publishConfigEvent notifies webhooks about a config lifecycle transition.
+func (s *ConfigService) publishConfigEvent(ctx context.Context, event domain.WebhookEvent, cfg *domain.Config, userID uuid.UUID, reason string) {
+	data := map[string]interface{}{
+		"config_id": cfg.ID.String(),
+		"name":      cfg.Name,
+		"version":   cfg.Version,
+		"status":    cfg.Status,
+		"user_id":   userID.String(),
+	}
+	if reason != "" {
+		data["reason"] = reason
+	}
+	s.events.Publish(ctx, event, data)
 }
 
-// GenerateConfigHash computes SHA256 of the generated parent.config + sni.yaml + ip_allow.yaml content.
+// GenerateConfigHash computes the hash of the generated config files (see
+// ConfigFiles.Hash), without overlays.
 func (s *ConfigService) GenerateConfigHash(ctx context.Context, configID uuid.UUID) (string, error) {
-	parentConfig, sniYaml, ipAllowYaml, err := s.GenerateConfigFiles(ctx, configID)
+	files, err := s.GenerateConfigFiles(ctx, configID, "")
 	if err != nil {
 		return "", err
 	}
-	h := sha256.New()
-	h.Write([]byte(parentConfig))
-	h.Write([]byte(sniYaml))
-	h.Write([]byte(ipAllowYaml))
-	return hex.EncodeToString(h.Sum(nil)), nil
+	return files.Hash(), nil
 }
 
-// GenerateConfigFiles generates parent.config, sni.yaml, and ip_allow.yaml from DB rules.
-func (s *ConfigService) GenerateConfigFiles(ctx context.Context, configID uuid.UUID) (parentConfig, sniYaml, ipAllowYaml string, err error) {
+// HasOverlays reports whether a config has overlays for any proxy or group.
+func (s *ConfigService) HasOverlays(ctx context.Context, configID uuid.UUID) (bool, error) {
+	return s.overlays.ExistsForConfig(ctx, configID)
+}
+
+// GenerateConfigFiles generates parent.config, sni.yaml, ip_allow.yaml and the
+// additional managed files (records.yaml) from DB rules. With a hostname, the
+// overlays of the config that apply to that proxy are merged in (see
+// mergeOverlays); with "" the files are the config's own.
+func (s *ConfigService) GenerateConfigFiles(ctx context.Context, configID uuid.UUID, hostname string) (*ConfigFiles, error) {
 	domains, err := s.domains.ListByConfig(ctx, configID)
 	if err != nil {
-		return "", "", "", err
+		return nil, err
 	}
 	ipRanges, err := s.ipRanges.ListByConfig(ctx, configID)
 	if err != nil {
-		return "", "", "", err
+		return nil, err
 	}
 	parents, err := s.parents.ListByConfig(ctx, configID)
 	if err != nil {
-		return "", "", "", err
+		return nil, err
 	}
 
 	clientACL, err := s.clientACL.ListByConfig(ctx, configID)
 	if err != nil {
-		return "", "", "", err
+		return nil, err
+	}
+
+	records, err := s.records.ListByConfig(ctx, configID)
+	if err != nil {
+		return nil, err
 	}
 
 	cfg, cfgErr := s.configs.GetByID(ctx, configID)
 	if cfgErr != nil {
-		return "", "", "", cfgErr
+		return nil, cfgErr
+	}
+
+	ruleSets, err := s.ruleSets.ListContentByConfig(ctx, configID)
+	if err != nil {
+		return nil, err
 	}
+	domains, ipRanges = expandRuleSets(ruleSets, domains, ipRanges)
 
-	parentConfig = generateParentConfig(ipRanges, domains, parents, cfg.DefaultAction)
-	sniYaml = generateSNIYaml(domains)
-	ipAllowYaml = generateIPAllowYaml(clientACL)
+	var overlays []domain.ConfigOverlay
+	if hostname != "" {
+		overlays, err = s.overlays.ListForProxy(ctx, configID, hostname)
+		if err != nil {
+			return nil, err
+		}
+		domains, ipRanges, clientACL = mergeOverlays(overlays, domains, ipRanges, clientACL)
+	}
+
+	files := &ConfigFiles{
+		ParentConfig: generateParentConfig(ipRanges, domains, parents, cfg.DefaultAction),
+		SNIYaml:      generateSNIYaml(domains),
+		IPAllowYaml:  generateIPAllowYaml(clientACL, ipRanges),
+	}
+	if cfg.ConnectPorts != nil {
+		files.ConnectPorts = *cfg.ConnectPorts
+		// traffic_ctl config set only lasts until ATS restarts; the managed
+		// records.yaml carries the ports so they survive it
+		records = append(records, domain.RecordOverride{Name: domain.ConnectPortsRecord, Value: *cfg.ConnectPorts})
+	}
+	if recordsYaml := generateRecordsYaml(records); recordsYaml != "" {
+		files.Files = map[string]string{"records.yaml": recordsYaml}
+	}
+	if remapConfig := generateRemapConfig(domains); remapConfig != "" {
+		if files.Files == nil {
+			files.Files = map[string]string{}
+		}
+		files.Files["remap.config"] = remapConfig
+	}
+	for _, o := range overlays {
+		if o.Hostname != nil {
+			files.Overlays = append(files.Overlays, "proxy:"+*o.Hostname)
+		} else if o.GroupName != nil {
+			files.Overlays = append(files.Overlays, "group:"+*o.GroupName)
+		}
+	}
+
+	return files, nil
+}
+
+// expandRuleSets puts the rules of the referenced rule set versions among the
+// config's own, each at the priority of its reference. On a tie the config's
+// rule comes first, and a domain or CIDR already set by an earlier rule is
+// dropped. Priorities are renumbered so the generators keep the merged order.
+func expandRuleSets(ruleSets []repository.RuleSetContent, domains []domain.DomainRule, ipRanges []domain.IPRangeRule) ([]domain.DomainRule, []domain.IPRangeRule) {
+	if len(ruleSets) == 0 {
+		return domains, ipRanges
+	}
+
+	allDomains := slices.Clone(domains)
+	allIPs := slices.Clone(ipRanges)
+	for _, rs := range ruleSets {
+		for _, d := range rs.Domains {
+			allDomains = append(allDomains, domain.DomainRule{Domain: d.Domain, Action: d.Action, Priority: rs.Priority})
+		}
+		for _, ir := range rs.IPRanges {
+			allIPs = append(allIPs, domain.IPRangeRule{CIDR: ir.CIDR, Action: ir.Action, Priority: rs.Priority})
+		}
+	}
+
+	expandedDomains := mergeLayers([][]domain.DomainRule{allDomains},
+		func(r domain.DomainRule) string { return strings.ToLower(r.Domain) },
+		func(r domain.DomainRule) int { return r.Priority })
+	carryTLS(expandedDomains, domains)
+	for i := range expandedDomains {
+		expandedDomains[i].Priority = i
+	}
+	expandedIPs := mergeLayers([][]domain.IPRangeRule{allIPs},
+		func(r domain.IPRangeRule) string { return r.CIDR },
+		func(r domain.IPRangeRule) int { return r.Priority })
+	for i := range expandedIPs {
+		expandedIPs[i].Priority = i
+	}
+	return expandedDomains, expandedIPs
+}
+
+// mergeOverlays puts the rules of the overlays, in the order given (see
+// ConfigOverlayRepo.ListForProxy), ahead of the config's own. ATS uses the
+// first matching line of parent.config and ip_allow.yaml, so an overlay wins
+// over the config, and an earlier overlay over a later one; a rule for a
+// domain or CIDR already set by an earlier layer is dropped. Priorities are
+// renumbered so the generators keep the merged order.
+func mergeOverlays(overlays []domain.ConfigOverlay, domains []domain.DomainRule, ipRanges []domain.IPRangeRule, clientACL []domain.ClientACLRule) ([]domain.DomainRule, []domain.IPRangeRule, []domain.ClientACLRule) {
+	if len(overlays) == 0 {
+		return domains, ipRanges, clientACL
+	}
+
+	domainLayers := make([][]domain.DomainRule, 0, len(overlays)+1)
+	ipLayers := make([][]domain.IPRangeRule, 0, len(overlays)+1)
+	aclLayers := make([][]domain.ClientACLRule, 0, len(overlays)+1)
+	for _, o := range overlays {
+		var dl []domain.DomainRule
+		for _, d := range o.Domains {
+			dl = append(dl, domain.DomainRule{ConfigID: o.ConfigID, Domain: d.Domain, Action: d.Action, Priority: d.Priority})
+		}
+		var il []domain.IPRangeRule
+		for _, ir := range o.IPRanges {
+			il = append(il, domain.IPRangeRule{ConfigID: o.ConfigID, CIDR: ir.CIDR, Action: ir.Action, Priority: ir.Priority})
+		}
+		var al []domain.ClientACLRule
+		for _, acl := range o.ClientACL {
+			al = append(al, domain.ClientACLRule{ConfigID: o.ConfigID, CIDR: acl.CIDR, Action: acl.Action, Apply: aclApply(acl.Apply), Methods: acl.Methods, Priority: acl.Priority})
+		}
+		domainLayers = append(domainLayers, dl)
+		ipLayers = append(ipLayers, il)
+		aclLayers = append(aclLayers, al)
+	}
+
+	mergedDomains := mergeLayers(append(domainLayers, domains),
+		func(r domain.DomainRule) string { return strings.ToLower(r.Domain) },
+		func(r domain.DomainRule) int { return r.Priority })
+	carryTLS(mergedDomains, domains)
+	for i := range mergedDomains {
+		mergedDomains[i].Priority = i
+	}
+	mergedIPs := mergeLayers(append(ipLayers, ipRanges),
+		func(r domain.IPRangeRule) string { return r.CIDR },
+		func(r domain.IPRangeRule) int { return r.Priority })
+	for i := range mergedIPs {
+		mergedIPs[i].Priority = i
+	}
+	mergedACL := mergeLayers(append(aclLayers, clientACL),
+		func(r domain.ClientACLRule) string { return string(aclApply(r.Apply)) + " " + r.CIDR },
+		func(r domain.ClientACLRule) int { return r.Priority })
+	for i := range mergedACL {
+		mergedACL[i].Priority = i
+	}
+	return mergedDomains, mergedIPs, mergedACL
+}
+
+// carryTLS keeps the TLS policy of the config's own domain rules when a rule
+// without one (from a rule set or an overlay) wins over them for the same
+// domain. A deny rule takes no policy and forward_route only stays on a
+// direct rule, as validateDomainRules requires.
+func carryTLS(merged, own []domain.DomainRule) {
+	policies := make(map[string]*domain.TLSPolicy)
+	for _, r := range own {
+		key := strings.ToLower(r.Domain)
+		if _, ok := policies[key]; !ok && !r.TLS.IsEmpty() {
+			policies[key] = r.TLS
+		}
+	}
+	for i := range merged {
+		r := &merged[i]
+		tls, ok := policies[strings.ToLower(r.Domain)]
+		if !ok || !r.TLS.IsEmpty() || r.Action == domain.ActionDeny {
+			continue
+		}
+		carried := *tls
+		if r.Action != domain.ActionDirect {
+			carried.ForwardRoute = ""
+		}
+		if !carried.IsEmpty() {
+			r.TLS = &carried
+		}
+	}
+}
 
-	return parentConfig, sniYaml, ipAllowYaml, nil
+// mergeLayers concatenates the layers, each sorted by priority, keeping only
+// the first rule for each key.
+func mergeLayers[T any](layers [][]T, key func(T) string, priority func(T) int) []T {
+	seen := make(map[string]bool)
+	var merged []T
+	for _, layer := range layers {
+		layer = slices.Clone(layer)
+		sort.SliceStable(layer, func(i, j int) bool { return priority(layer[i]) < priority(layer[j]) })
+		for _, r := range layer {
+			if seen[key(r)] {
+				continue
+			}
+			seen[key(r)] = true
+			merged = append(merged, r)
+		}
+	}
+	return merged
 }
 
 // domainToATS converts user-facing domain format to ATS parent.config format.
@@ -835,7 +1557,9 @@ func generateParentConfig(ipRanges []domain.IPRangeRule, domainRules []domain.Do
 	// --- User-defined IP range rules → dest_ip lines ---
 	for _, ir := range ipRanges {
 		ipRange := cidrToRange(ir.CIDR)
-		if ir.Action == domain.ActionDirect {
+		if ir.Action == domain.ActionDirect || ir.Action == domain.ActionDeny {
+			// Denied ranges also go direct, so the apply: out rule of
+			// ip_allow.yaml sees the destination rather than a parent
 			b.WriteString(fmt.Sprintf("dest_ip=%s go_direct=true\n", ipRange))
 		} else if ir.Action == domain.ActionParent && parentStr != "" {
 			b.WriteString(fmt.Sprintf("dest_ip=%s parent=\"%s\" round_robin=strict go_direct=false\n", ipRange, parentStr))
@@ -873,6 +1597,9 @@ func domainToSNI(domain string) string {
 	return domain
 }
 
+// generateSNIYaml renders an entry for each direct rule (tunneled, or sent to
+// its forward_route) and for each rule with a TLS policy. Rules without one
+// render as before, so existing configs keep their hash.
 func generateSNIYaml(domainRules []domain.DomainRule) string {
 	var b strings.Builder
 	b.WriteString("sni:\n")
@@ -880,15 +1607,48 @@ func generateSNIYaml(domainRules []domain.DomainRule) string {
 	sort.Slice(domainRules, func(i, j int) bool { return domainRules[i].Priority < domainRules[j].Priority })
 
 	for _, dr := range domainRules {
-		if dr.Action == domain.ActionDirect {
-			fqdn := domainToSNI(dr.Domain)
-			b.WriteString(fmt.Sprintf("  - fqdn: '%s'\n    tunnel_route: direct\n", fqdn))
+		tls := dr.TLS
+		if dr.Action != domain.ActionDirect && tls.IsEmpty() {
+			continue
+		}
+
+		fqdn := domainToSNI(dr.Domain)
+		b.WriteString(fmt.Sprintf("  - fqdn: '%s'\n", fqdn))
+		// A forward_route terminates TLS instead of tunneling
+		if !tls.IsEmpty() && tls.ForwardRoute != "" {
+			b.WriteString(fmt.Sprintf("    forward_route: '%s'\n", tls.ForwardRoute))
+		} else if dr.Action == domain.ActionDirect {
+			b.WriteString("    tunnel_route: direct\n")
+		}
+		if !tls.IsEmpty() {
+			writeTLSPolicy(&b, tls)
 		}
 	}
 
 	return b.String()
 }
 
+// writeTLSPolicy renders the set fields of a TLS policy under a sni.yaml
+// entry; forward_route is rendered with the route.
+func writeTLSPolicy(b *strings.Builder, p *domain.TLSPolicy) {
+	if p.VerifyServerPolicy != "" {
+		b.WriteString(fmt.Sprintf("    verify_server_policy: %s\n", p.VerifyServerPolicy))
+	}
+	if p.HostSNIPolicy != "" {
+		b.WriteString(fmt.Sprintf("    host_sni_policy: %s\n", p.HostSNIPolicy))
+	}
+	if p.HTTP2 != nil {
+		h2 := "off"
+		if *p.HTTP2 {
+			h2 = "on"
+		}
+		b.WriteString(fmt.Sprintf("    http2: %s\n", h2))
+	}
+	if p.VerifyClient != "" {
+		b.WriteString(fmt.Sprintf("    verify_client: %s\n", p.VerifyClient))
+	}
+}
+
 // cidrToRange converts CIDR notation to IP range (e.g., 10.0.0.0/8 → 10.0.0.0-10.255.255.255)
 func cidrToRange(cidr string) string {
 	_, ipnet, err := net.ParseCIDR(cidr)
@@ -910,24 +1670,103 @@ func cidrToRange(cidr string) string {
 	return fmt.Sprintf("%s-%s", start.String(), end.String())
 }
 
-func generateIPAllowYaml(rules []domain.ClientACLRule) string {
+// generateIPAllowYaml renders the client ACL, each rule on the side it
+// applies to, and the IP ranges with action deny as apply: out rules, which
+// block the connection to the destination. ATS uses the first rule whose
+// ip_addrs match, so denied ranges come before the ACL's own out rules.
+func generateIPAllowYaml(rules []domain.ClientACLRule, ipRanges []domain.IPRangeRule) string {
 	var b strings.Builder
 	b.WriteString("ip_allow:\n")
 
 	sort.Slice(rules, func(i, j int) bool { return rules[i].Priority < rules[j].Priority })
+	sort.Slice(ipRanges, func(i, j int) bool { return ipRanges[i].Priority < ipRanges[j].Priority })
 
 	for _, r := range rules {
-		atsAction := "set_allow"
-		if r.Action == domain.ACLDeny {
-			atsAction = "set_deny"
+		if aclApply(r.Apply) == domain.ACLApplyIn {
+			writeIPAllowRule(&b, domain.ACLApplyIn, r.CIDR, r.Action, r.Methods)
 		}
-		b.WriteString(fmt.Sprintf("  - apply: in\n    ip_addrs: %s\n    action: %s\n    methods: ALL\n", r.CIDR, atsAction))
 	}
 
 	// Always append deny-all for both IPv4 and IPv6
 	b.WriteString("  - apply: in\n    ip_addrs: 0/0\n    action: set_deny\n    methods: ALL\n")
 	b.WriteString("  - apply: in\n    ip_addrs: ::/0\n    action: set_deny\n    methods: ALL\n")
 
+	out := false
+	for _, ir := range ipRanges {
+		if ir.Action == domain.ActionDeny {
+			writeIPAllowRule(&b, domain.ACLApplyOut, ir.CIDR, domain.ACLDeny, nil)
+			out = true
+		}
+	}
+	for _, r := range rules {
+		if r.Apply == domain.ACLApplyOut {
+			writeIPAllowRule(&b, domain.ACLApplyOut, r.CIDR, r.Action, r.Methods)
+			out = true
+		}
+	}
+	// ATS denies destinations no apply: out rule matches once any exists, so
+	// these trailing rules keep unmatched destinations allowed
+	if out {
+		b.WriteString("  - apply: out\n    ip_addrs: 0/0\n    action: set_allow\n    methods: ALL\n")
+		b.WriteString("  - apply: out\n    ip_addrs: ::/0\n    action: set_allow\n    methods: ALL\n")
+	}
+
+	return b.String()
+}
+
+// writeIPAllowRule writes one ip_allow.yaml rule. With methods, set_allow
+// allows only those and set_deny denies only those.
+func writeIPAllowRule(b *strings.Builder, apply domain.ACLApply, cidr string, action domain.ACLAction, methods []string) {
+	atsAction := "set_allow"
+	if action == domain.ACLDeny {
+		atsAction = "set_deny"
+	}
+	atsMethods := "ALL"
+	if len(methods) > 0 {
+		atsMethods = "[" + strings.Join(methods, ", ") + "]"
+	}
+	b.WriteString(fmt.Sprintf("  - apply: %s\n    ip_addrs: %s\n    action: %s\n    methods: %s\n", apply, cidr, atsAction, atsMethods))
+}
+
+// generateRemapConfig renders the domain rules with action deny as remap.config
+// rules under a deny filter. CONNECT requests are matched on port 443. Returns
+// "" when no domain is denied: the proxy uses the remap.config from its image,
+// which the helper restores if an earlier bundle had replaced it.
+func generateRemapConfig(domainRules []domain.DomainRule) string {
+	sort.Slice(domainRules, func(i, j int) bool { return domainRules[i].Priority < domainRules[j].Priority })
+
+	var rules []string
+	for _, dr := range domainRules {
+		if dr.Action != domain.ActionDeny {
+			continue
+		}
+		if host, ok := strings.CutPrefix(dr.Domain, "*."); ok {
+			// *.example.com also covers example.com, as in parent.config
+			re := `(.+\.)?` + regexp.QuoteMeta(host)
+			rules = append(rules,
+				fmt.Sprintf("regex_map http://%s/ http://%s/", re, host),
+				fmt.Sprintf("regex_map http://%s:443/ http://%s:443/", re, host),
+			)
+		} else {
+			rules = append(rules,
+				fmt.Sprintf("map http://%s/ http://%s/", dr.Domain, dr.Domain),
+				fmt.Sprintf("map http://%s:443/ http://%s:443/", dr.Domain, dr.Domain),
+			)
+		}
+	}
+	if len(rules) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("# Denied destinations (rules with action deny). Anything else is\n")
+	b.WriteString("# forwarded as usual, since remap is not required.\n")
+	b.WriteString(".definefilter deny_destination @action=set_deny\n")
+	b.WriteString(".activatefilter deny_destination\n")
+	for _, r := range rules {
+		b.WriteString(r + "\n")
+	}
+	b.WriteString(".deactivatefilter deny_destination\n")
 	return b.String()
 }
 
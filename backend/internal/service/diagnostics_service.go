@@ -0,0 +1,113 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+)
+
+// MaxDiagnosticsSize bounds an uploaded bundle; it must fit in a signed sync body.
+const MaxDiagnosticsSize = 8 << 20
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+type DiagnosticsService struct {
+	bundles  *repository.ProxyDiagnosticsRepo
+	commands *repository.ProxyCommandRepo
+	proxies  *repository.ProxyRepo
+	audit    *repository.AuditRepo
+	ttl      time.Duration
+}
+
+func NewDiagnosticsService(
+	bundles *repository.ProxyDiagnosticsRepo,
+	commands *repository.ProxyCommandRepo,
+	proxies *repository.ProxyRepo,
+	audit *repository.AuditRepo,
+	ttl time.Duration,
+) *DiagnosticsService {
+	return &DiagnosticsService{
+		bundles:  bundles,
+		commands: commands,
+		proxies:  proxies,
+		audit:    audit,
+		ttl:      ttl,
+	}
+}
+
+// Store saves a tar.gz uploaded by the proxy's helper. commandID, when set, must
+// be a diagnostics command of the same proxy.
+func (s *DiagnosticsService) Store(ctx context.Context, proxyID uuid.UUID, commandID string, data []byte) (*domain.DiagnosticsBundle, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty bundle", domain.ErrBadRequest)
+	}
+	if len(data) > MaxDiagnosticsSize {
+		return nil, fmt.Errorf("%w: bundle exceeds %d bytes", domain.ErrBadRequest, MaxDiagnosticsSize)
+	}
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return nil, fmt.Errorf("%w: bundle must be a tar.gz", domain.ErrBadRequest)
+	}
+
+	d := &domain.DiagnosticsBundle{
+		ProxyID:   proxyID,
+		SizeBytes: len(data),
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if commandID != "" {
+		id, err := uuid.Parse(commandID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid command id", domain.ErrBadRequest)
+		}
+		cmd, err := s.commands.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if cmd.ProxyID != proxyID || cmd.Type != domain.CommandDiagnostics {
+			return nil, fmt.Errorf("%w: command is not a diagnostics command of this proxy", domain.ErrBadRequest)
+		}
+		d.CommandID = &id
+	}
+
+	sum := sha256.Sum256(data)
+	d.SHA256 = hex.EncodeToString(sum[:])
+
+	if err := s.bundles.Create(ctx, d, data); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *DiagnosticsService) List(ctx context.Context, proxyID uuid.UUID) ([]domain.DiagnosticsBundle, error) {
+	if _, err := s.proxies.GetByID(ctx, proxyID); err != nil {
+		return nil, err
+	}
+	return s.bundles.ListByProxy(ctx, proxyID)
+}
+
+// Download returns a bundle with its content and records the download, since
+// bundles carry logs and config files of the proxy.
+func (s *DiagnosticsService) Download(ctx context.Context, proxyID, id uuid.UUID, userID uuid.UUID, ip, ua string) (*domain.DiagnosticsBundle, []byte, error) {
+	d, data, err := s.bundles.GetWithData(ctx, id, proxyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_ = s.audit.Create(ctx, &domain.AuditLog{
+		UserID:     &userID,
+		Action:     "proxy.diagnostics_download",
+		EntityType: "proxy",
+		EntityID:   &proxyID,
+		IPAddress:  &ip,
+		UserAgent:  &ua,
+		NewValue:   []byte(fmt.Sprintf(`{"bundle_id":%q}`, id.String())),
+	})
+
+	return d, data, nil
+}
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/feed"
+	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+)
+
+// Bounds of FeedSource.IntervalMinutes.
+const (
+	minFeedInterval     = 15
+	maxFeedInterval     = 7 * 24 * 60
+	defaultFeedInterval = 24 * 60
+)
+
+// FeedService keeps feed sources and turns what they publish into feed
+// updates to review; applying one goes through RuleSetService.
+type FeedService struct {
+	feeds   *repository.FeedSourceRepo
+	updates *repository.FeedUpdateRepo
+	sets    *repository.RuleSetRepo
+	audit   *repository.AuditRepo
+	fetcher feed.Fetcher
+	dir     string
+}
+
+func NewFeedService(feeds *repository.FeedSourceRepo, updates *repository.FeedUpdateRepo, sets *repository.RuleSetRepo, audit *repository.AuditRepo, fetcher feed.Fetcher, dir string) *FeedService {
+	return &FeedService{
+		feeds:   feeds,
+		updates: updates,
+		sets:    sets,
+		audit:   audit,
+		fetcher: fetcher,
+		dir:     dir,
+	}
+}
+
+type FeedRequest struct {
+	Name            string            `json:"name"`
+	RuleSetID       uuid.UUID         `json:"rule_set_id"`
+	Source          string            `json:"source"`
+	Format          string            `json:"format"`
+	Action          domain.RuleAction `json:"action"`
+	IntervalMinutes int               `json:"interval_minutes"`
+	Enabled         *bool             `json:"enabled,omitempty"`
+}
+
+func (s *FeedService) List(ctx context.Context) ([]domain.FeedSource, error) {
+	feeds, err := s.feeds.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if feeds == nil {
+		feeds = []domain.FeedSource{}
+	}
+	return feeds, nil
+}
+
+func (s *FeedService) Get(ctx context.Context, id uuid.UUID) (*domain.FeedSource, error) {
+	return s.feeds.GetByID(ctx, id)
+}
+
+// ListUpdates returns the latest updates of a feed, newest first.
+func (s *FeedService) ListUpdates(ctx context.Context, id uuid.UUID) ([]domain.FeedUpdate, error) {
+	if _, err := s.feeds.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	updates, err := s.updates.ListByFeed(ctx, id, 20)
+	if err != nil {
+		return nil, err
+	}
+	if updates == nil {
+		updates = []domain.FeedUpdate{}
+	}
+	return updates, nil
+}
+
+func (s *FeedService) validate(ctx context.Context, f *domain.FeedSource) error {
+	f.Name = strings.TrimSpace(f.Name)
+	if f.Name == "" {
+		return fmt.Errorf("%w: name is required", domain.ErrBadRequest)
+	}
+	if len(f.Name) > 100 {
+		return fmt.Errorf("%w: name must be at most 100 characters", domain.ErrBadRequest)
+	}
+
+	f.Source = strings.TrimSpace(f.Source)
+	switch {
+	case f.Source == "":
+		return fmt.Errorf("%w: source is required", domain.ErrBadRequest)
+	case feed.IsURL(f.Source):
+		if err := feed.CheckURL(f.Source); err != nil {
+			return fmt.Errorf("%w: source: %v", domain.ErrBadRequest, err)
+		}
+	default:
+		if _, err := feed.ResolvePath(s.dir, f.Source); err != nil {
+			return fmt.Errorf("%w: source: %v", domain.ErrBadRequest, err)
+		}
+	}
+
+	if _, ok := feed.Lookup(f.Format); !ok {
+		return fmt.Errorf("%w: format '%s' is not valid, must be one of %s", domain.ErrBadRequest, f.Format, strings.Join(feed.Formats(), ", "))
+	}
+	if f.Action == "" {
+		f.Action = domain.ActionDirect
+	}
+	if !f.Action.IsValid() {
+		return fmt.Errorf("%w: action '%s' is not valid", domain.ErrBadRequest, f.Action)
+	}
+	if f.IntervalMinutes == 0 {
+		f.IntervalMinutes = defaultFeedInterval
+	}
+	if f.IntervalMinutes < minFeedInterval || f.IntervalMinutes > maxFeedInterval {
+		return fmt.Errorf("%w: interval_minutes must be between %d and %d", domain.ErrBadRequest, minFeedInterval, maxFeedInterval)
+	}
+
+	existing, err := s.feeds.GetByName(ctx, f.Name)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+	if existing != nil && existing.ID != f.ID {
+		return fmt.Errorf("%w: feed '%s' already exists", domain.ErrConflict, f.Name)
+	}
+	return nil
+}
+
+func (s *FeedService) Create(ctx context.Context, req FeedRequest, userID uuid.UUID, ip, ua string) (*domain.FeedSource, error) {
+	f := &domain.FeedSource{
+		Name:            req.Name,
+		RuleSetID:       req.RuleSetID,
+		Source:          req.Source,
+		Format:          req.Format,
+		Action:          req.Action,
+		IntervalMinutes: req.IntervalMinutes,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+		CreatedBy:       &userID,
+	}
+	if err := s.validate(ctx, f); err != nil {
+		return nil, err
+	}
+	if _, err := s.sets.GetByID(ctx, f.RuleSetID); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("%w: rule set %s not found", domain.ErrBadRequest, f.RuleSetID)
+		}
+		return nil, err
+	}
+	other, err := s.feeds.GetByRuleSet(ctx, f.RuleSetID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+	if other != nil {
+		return nil, fmt.Errorf("%w: rule set is already fed by '%s'", domain.ErrConflict, other.Name)
+	}
+
+	if err := s.feeds.Create(ctx, f); err != nil {
+		return nil, err
+	}
+
+	s.logAudit(ctx, &userID, "feed.create", &f.ID, nil, feedValue(f), ip, ua)
+	return s.feeds.GetByID(ctx, f.ID)
+}
+
+// Update changes how a feed is fetched. The rule set it fills cannot change.
+func (s *FeedService) Update(ctx context.Context, id uuid.UUID, req FeedRequest, userID uuid.UUID, ip, ua string) (*domain.FeedSource, error) {
+	f, err := s.feeds.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	oldVal := feedValue(f)
+
+	f.Name = req.Name
+	f.Source = req.Source
+	f.Format = req.Format
+	f.Action = req.Action
+	f.IntervalMinutes = req.IntervalMinutes
+	if req.Enabled != nil {
+		f.Enabled = *req.Enabled
+	}
+	if err := s.validate(ctx, f); err != nil {
+		return nil, err
+	}
+	if err := s.feeds.Update(ctx, f); err != nil {
+		return nil, err
+	}
+
+	s.logAudit(ctx, &userID, "feed.update", &id, oldVal, feedValue(f), ip, ua)
+	return s.feeds.GetByID(ctx, id)
+}
+
+// Delete removes a feed; the rule set keeps its current content.
+func (s *FeedService) Delete(ctx context.Context, id, userID uuid.UUID, ip, ua string) error {
+	f, err := s.feeds.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.feeds.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.logAudit(ctx, &userID, "feed.delete", &id, feedValue(f), nil, ip, ua)
+	return nil
+}
+
+// RefreshNow fetches a feed on demand. It returns the pending update, or nil
+// when the rule set already has the feed's content.
+func (s *FeedService) RefreshNow(ctx context.Context, id uuid.UUID) (*domain.FeedUpdate, error) {
+	f, err := s.feeds.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	update, err := s.refresh(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrBadRequest, err)
+	}
+	return update, nil
+}
+
+// RefreshDue fetches the feeds whose interval has elapsed. Failures are
+// recorded on the feed and don't stop the others.
+func (s *FeedService) RefreshDue(ctx context.Context) error {
+	due, err := s.feeds.ListDue(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		update, err := s.refresh(ctx, &due[i])
+		if err != nil {
+			log.Printf("Feed %s: %v", due[i].Name, err)
+		} else if update != nil {
+			log.Printf("Feed %s: update pending review (+%d -%d)", due[i].Name, len(update.Added), len(update.Removed))
+		}
+	}
+	return nil
+}
+
+// refresh fetches and parses a feed and diffs it against the current version
+// of its rule set. A difference is kept as the feed's pending update (only the
+// latest one); no difference drops the pending update.
+func (s *FeedService) refresh(ctx context.Context, f *domain.FeedSource) (*domain.FeedUpdate, error) {
+	update, err := s.fetchUpdate(ctx, f)
+	if err != nil {
+		msg := err.Error()
+		if serr := s.feeds.SetFetchResult(ctx, f.ID, domain.FeedFetchError, &msg); serr != nil {
+			log.Printf("Feed %s: %v", f.Name, serr)
+		}
+		return nil, err
+	}
+
+	if update == nil {
+		if err := s.updates.DropPending(ctx, f.ID); err != nil {
+			return nil, err
+		}
+		return nil, s.feeds.SetFetchResult(ctx, f.ID, domain.FeedFetchUnchanged, nil)
+	}
+	if err := s.updates.SavePending(ctx, update); err != nil {
+		return nil, err
+	}
+	if err := s.feeds.SetFetchResult(ctx, f.ID, domain.FeedFetchChanged, nil); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+// fetchUpdate builds the update for the feed's current content, or nil if it
+// matches the rule set.
+func (s *FeedService) fetchUpdate(ctx context.Context, f *domain.FeedSource) (*domain.FeedUpdate, error) {
+	parse, ok := feed.Lookup(f.Format)
+	if !ok {
+		return nil, fmt.Errorf("unknown feed format '%s'", f.Format)
+	}
+	data, err := s.fetcher.Fetch(ctx, f.Source)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	entries.Normalize()
+
+	update := &domain.FeedUpdate{
+		FeedID:   f.ID,
+		Domains:  make([]domain.RuleSetDomain, 0, len(entries.Domains)),
+		IPRanges: make([]domain.RuleSetCIDR, 0, len(entries.CIDRs)),
+		Added:    []string{},
+		Removed:  []string{},
+	}
+	// Vendor lists carry entries ATS can't use (e.g. "autodiscover.*.example.com")
+	for _, d := range entries.Domains {
+		if len(validateDomainRules("", []DomainRuleInput{{Domain: d, Action: f.Action}})) > 0 {
+			update.Skipped++
+			continue
+		}
+		update.Domains = append(update.Domains, domain.RuleSetDomain{Domain: d, Action: f.Action})
+	}
+	for _, c := range entries.CIDRs {
+		if validateCIDR(c, "") != "" {
+			update.Skipped++
+			continue
+		}
+		update.IPRanges = append(update.IPRanges, domain.RuleSetCIDR{CIDR: c, Action: f.Action})
+	}
+	if len(update.Domains)+len(update.IPRanges) == 0 {
+		return nil, fmt.Errorf("feed has no valid entries (%d skipped)", update.Skipped)
+	}
+
+	rs, err := s.sets.GetByID(ctx, f.RuleSetID)
+	if err != nil {
+		return nil, err
+	}
+	update.BaseVersion = rs.Version
+
+	current := make(map[string]domain.RuleAction, len(rs.Domains)+len(rs.IPRanges))
+	for _, d := range rs.Domains {
+		current[strings.ToLower(d.Domain)] = d.Action
+	}
+	for _, ir := range rs.IPRanges {
+		current[ir.CIDR] = ir.Action
+	}
+	changed := false
+	fetched := make(map[string]bool, len(update.Domains)+len(update.IPRanges))
+	check := func(key string) {
+		fetched[key] = true
+		action, ok := current[key]
+		if !ok {
+			update.Added = append(update.Added, key)
+		} else if action != f.Action {
+			changed = true
+		}
+	}
+	for _, d := range update.Domains {
+		check(d.Domain)
+	}
+	for _, ir := range update.IPRanges {
+		check(ir.CIDR)
+	}
+	for _, d := range rs.Domains {
+		if !fetched[strings.ToLower(d.Domain)] {
+			update.Removed = append(update.Removed, d.Domain)
+		}
+	}
+	for _, ir := range rs.IPRanges {
+		if !fetched[ir.CIDR] {
+			update.Removed = append(update.Removed, ir.CIDR)
+		}
+	}
+
+	if !changed && len(update.Added) == 0 && len(update.Removed) == 0 {
+		return nil, nil
+	}
+	return update, nil
+}
+
+// Discard closes the pending update of a feed without applying it. The next
+// fetch brings it back if the feed still differs.
+func (s *FeedService) Discard(ctx context.Context, feedID, updateID, userID uuid.UUID, ip, ua string) error {
+	u, err := s.updates.GetByID(ctx, updateID)
+	if err != nil {
+		return err
+	}
+	if u.FeedID != feedID {
+		return domain.ErrNotFound
+	}
+	if err := s.updates.Review(ctx, updateID, domain.FeedUpdateDiscarded, userID, nil); err != nil {
+		if errors.Is(err, domain.ErrInvalidStatus) {
+			return fmt.Errorf("%w: feed update is not pending", domain.ErrInvalidStatus)
+		}
+		return err
+	}
+
+	s.logAudit(ctx, &userID, "feed.discard", &feedID, nil, jsonVal("update_id", updateID.String()), ip, ua)
+	return nil
+}
+
+func feedValue(f *domain.FeedSource) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"name":             f.Name,
+		"rule_set_id":      f.RuleSetID,
+		"source":           f.Source,
+		"format":           f.Format,
+		"action":           f.Action,
+		"interval_minutes": f.IntervalMinutes,
+		"enabled":          f.Enabled,
+	})
+	return data
+}
+
+func (s *FeedService) logAudit(ctx context.Context, userID *uuid.UUID, action string, entityID *uuid.UUID, oldVal, newVal []byte, ip, ua string) {
+	_ = s.audit.Create(ctx, &domain.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityType: "feed",
+		EntityID:   entityID,
+		OldValue:   oldVal,
+		NewValue:   newVal,
+		IPAddress:  &ip,
+		UserAgent:  &ua,
+	})
+}
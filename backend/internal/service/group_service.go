@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+)
+
+type GroupService struct {
+	groups   *repository.ProxyGroupRepo
+	proxies  *repository.ProxyRepo
+	overlays *repository.ConfigOverlayRepo
+	audit    *repository.AuditRepo
+	pushes   *SyncNotifier
+}
+
+func NewGroupService(groups *repository.ProxyGroupRepo, proxies *repository.ProxyRepo, overlays *repository.ConfigOverlayRepo, audit *repository.AuditRepo, pushes *SyncNotifier) *GroupService {
+	return &GroupService{
+		groups:   groups,
+		proxies:  proxies,
+		overlays: overlays,
+		audit:    audit,
+		pushes:   pushes,
+	}
+}
+
+type GroupRequest struct {
+	Name        string            `json:"name"`
+	Description *string           `json:"description,omitempty"`
+	Selector    map[string]string `json:"selector"`
+	Priority    int               `json:"priority"`
+}
+
+func (s *GroupService) List(ctx context.Context) ([]domain.ProxyGroup, error) {
+	groups, err := s.groups.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if groups == nil {
+		groups = []domain.ProxyGroup{}
+	}
+	return groups, nil
+}
+
+// ListProxies returns the proxies currently selected by a group.
+func (s *GroupService) ListProxies(ctx context.Context, id uuid.UUID) ([]domain.Proxy, error) {
+	group, err := s.groups.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := s.proxies.ListBySelector(ctx, group.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if proxies == nil {
+		proxies = []domain.Proxy{}
+	}
+	return proxies, nil
+}
+
+func (s *GroupService) validate(ctx context.Context, g *domain.ProxyGroup) error {
+	g.Name = strings.TrimSpace(g.Name)
+	if g.Name == "" {
+		return fmt.Errorf("%w: name is required", domain.ErrBadRequest)
+	}
+	if len(g.Name) > 100 {
+		return fmt.Errorf("%w: name must be at most 100 characters", domain.ErrBadRequest)
+	}
+	if len(g.Selector) == 0 {
+		// An empty selector would match every proxy
+		return fmt.Errorf("%w: selector must have at least one label", domain.ErrBadRequest)
+	}
+	if err := validateLabels(g.Selector); err != nil {
+		return err
+	}
+
+	existing, err := s.groups.GetByName(ctx, g.Name)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+	if existing != nil && existing.ID != g.ID {
+		return fmt.Errorf("%w: group '%s' already exists", domain.ErrConflict, g.Name)
+	}
+	return nil
+}
+
+func (s *GroupService) Create(ctx context.Context, req GroupRequest, userID uuid.UUID, ip, ua string) (*domain.ProxyGroup, error) {
+	group := &domain.ProxyGroup{
+		Name:        req.Name,
+		Description: req.Description,
+		Selector:    req.Selector,
+		Priority:    req.Priority,
+		CreatedBy:   &userID,
+	}
+	if err := s.validate(ctx, group); err != nil {
+		return nil, err
+	}
+	if err := s.groups.Create(ctx, group); err != nil {
+		return nil, err
+	}
+	s.pushes.GroupChanged(ctx, group.ID)
+
+	s.logAudit(ctx, &userID, "group.create", &group.ID, nil, groupValue(group), ip, ua)
+	return s.groups.GetByID(ctx, group.ID)
+}
+
+func (s *GroupService) Update(ctx context.Context, id uuid.UUID, req GroupRequest, userID uuid.UUID, ip, ua string) (*domain.ProxyGroup, error) {
+	group, err := s.groups.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	oldVal := groupValue(group)
+
+	group.Name = req.Name
+	group.Description = req.Description
+	group.Selector = req.Selector
+	group.Priority = req.Priority
+	if err := s.validate(ctx, group); err != nil {
+		return nil, err
+	}
+	if err := s.groups.Update(ctx, group); err != nil {
+		return nil, err
+	}
+	s.pushes.GroupChanged(ctx, id)
+
+	s.logAudit(ctx, &userID, "group.update", &id, oldVal, groupValue(group), ip, ua)
+	return s.groups.GetByID(ctx, id)
+}
+
+// Delete removes a group; configs assigned to it stop applying to its proxies.
+func (s *GroupService) Delete(ctx context.Context, id, userID uuid.UUID, ip, ua string) error {
+	group, err := s.groups.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	// Deleting the group would silently drop its overlays from these configs
+	configs, err := s.overlays.ConfigNamesForGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(configs) > 0 {
+		return fmt.Errorf("%w: group has overlays in configs %s; remove them first", domain.ErrConflict, strings.Join(configs, ", "))
+	}
+	if err := s.groups.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.pushes.GroupChanged(ctx, id)
+
+	s.logAudit(ctx, &userID, "group.delete", &id, groupValue(group), nil, ip, ua)
+	return nil
+}
+
+func groupValue(g *domain.ProxyGroup) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"name":     g.Name,
+		"selector": g.Selector,
+		"priority": g.Priority,
+	})
+	return data
+}
+
+func (s *GroupService) logAudit(ctx context.Context, userID *uuid.UUID, action string, entityID *uuid.UUID, oldVal, newVal []byte, ip, ua string) {
+	_ = s.audit.Create(ctx, &domain.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityType: "group",
+		EntityID:   entityID,
+		OldValue:   oldVal,
+		NewValue:   newVal,
+		IPAddress:  &ip,
+		UserAgent:  &ua,
+	})
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	stdsync "sync"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+// logChannel carries the log lines of a capture as they are received from a helper.
+const logChannel = "proxy-manager:logs"
+
+// logSubscriptionBuffer is how many batches a slow stream may fall behind
+// before batches are dropped for it.
+const logSubscriptionBuffer = 64
+
+// LogStream fans captured log lines out to live tails. Lines go through Redis
+// pub/sub so a tail served by one replica sees lines a helper sent to another.
+type LogStream struct {
+	rdb *redis.Client
+
+	mu   stdsync.Mutex
+	subs map[*LogSubscription]struct{}
+}
+
+// LogSubscription receives the lines captured for one proxy.
+type LogSubscription struct {
+	proxyID uuid.UUID
+	ch      chan []domain.ProxyLog
+}
+
+// Lines delivers batches of lines in the order the helper sent them.
+func (s *LogSubscription) Lines() <-chan []domain.ProxyLog {
+	return s.ch
+}
+
+type logMessage struct {
+	ProxyID uuid.UUID         `json:"proxy_id"`
+	Lines   []domain.ProxyLog `json:"lines"`
+}
+
+func NewLogStream(rdb *redis.Client) *LogStream {
+	return &LogStream{
+		rdb:  rdb,
+		subs: make(map[*LogSubscription]struct{}),
+	}
+}
+
+// Run subscribes to the log channel until ctx is cancelled.
+func (s *LogStream) Run(ctx context.Context) {
+	pubsub := s.rdb.Subscribe(ctx, logChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var m logMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &m); err != nil {
+				log.Printf("Log stream: invalid message: %v", err)
+				continue
+			}
+			s.broadcast(m)
+		}
+	}
+}
+
+// Publish sends lines stored for a proxy to every live tail of it.
+func (s *LogStream) Publish(ctx context.Context, proxyID uuid.UUID, lines []domain.ProxyLog) {
+	if len(lines) == 0 {
+		return
+	}
+	m := logMessage{ProxyID: proxyID, Lines: lines}
+	payload, err := json.Marshal(m)
+	if err != nil {
+		log.Printf("Log stream: marshal: %v", err)
+		return
+	}
+	if err := s.rdb.Publish(ctx, logChannel, payload).Err(); err != nil {
+		// Redis down: still feed the tails on this replica
+		log.Printf("Log stream publish: %v", err)
+		s.broadcast(m)
+	}
+}
+
+func (s *LogStream) broadcast(m logMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs {
+		if sub.proxyID != m.ProxyID {
+			continue
+		}
+		select {
+		case sub.ch <- m.Lines:
+		default:
+		}
+	}
+}
+
+// Subscribe starts receiving the lines of a proxy; call Unsubscribe when done.
+func (s *LogStream) Subscribe(proxyID uuid.UUID) *LogSubscription {
+	sub := &LogSubscription{proxyID: proxyID, ch: make(chan []domain.ProxyLog, logSubscriptionBuffer)}
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+	return sub
+}
+
+func (s *LogStream) Unsubscribe(sub *LogSubscription) {
+	s.mu.Lock()
+	delete(s.subs, sub)
+	s.mu.Unlock()
+}
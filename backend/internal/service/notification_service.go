@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/notify"
+	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+)
+
+// subscribableEvents are the events users can opt into receiving by email.
+var subscribableEvents = map[domain.WebhookEvent]bool{
+	domain.EventDeployFailed: true,
+}
+
+// NotificationService emails users about config lifecycle changes and deploy
+// failures. With a nil sender notifications are disabled.
+type NotificationService struct {
+	sender    notify.Sender
+	templates *notify.Templates
+	users     *repository.UserRepo
+	subs      *repository.NotificationSubscriptionRepo
+	appURL    string
+}
+
+func NewNotificationService(
+	sender notify.Sender,
+	users *repository.UserRepo,
+	subs *repository.NotificationSubscriptionRepo,
+	appURL string,
+) *NotificationService {
+	return &NotificationService{
+		sender:    sender,
+		templates: notify.DefaultTemplates(),
+		users:     users,
+		subs:      subs,
+		appURL:    strings.TrimRight(appURL, "/"),
+	}
+}
+
+// ConfigSubmitted emails the eligible approvers (active root and admin users).
+func (s *NotificationService) ConfigSubmitted(cfg *domain.Config, submitterID uuid.UUID) {
+	s.dispatch(func(ctx context.Context) error {
+		to, err := s.approverEmails(ctx)
+		if err != nil {
+			return err
+		}
+		return s.send(ctx, to, notify.TemplateConfigSubmitted, notify.TemplateData{
+			ConfigName: cfg.Name,
+			Version:    cfg.Version,
+			Actor:      s.username(ctx, submitterID),
+			URL:        s.configURL(cfg.ID),
+		})
+	})
+}
+
+// ConfigApproved emails the user who submitted the config.
+func (s *NotificationService) ConfigApproved(cfg *domain.Config, approverID uuid.UUID, submitterID *uuid.UUID) {
+	s.notifySubmitter(notify.TemplateConfigApproved, cfg, approverID, submitterID, "")
+}
+
+// ConfigRejected emails the user who submitted the config, including the reason.
+func (s *NotificationService) ConfigRejected(cfg *domain.Config, rejecterID uuid.UUID, submitterID *uuid.UUID, reason string) {
+	s.notifySubmitter(notify.TemplateConfigRejected, cfg, rejecterID, submitterID, reason)
+}
+
+func (s *NotificationService) notifySubmitter(tpl string, cfg *domain.Config, actorID uuid.UUID, submitterID *uuid.UUID, reason string) {
+	if submitterID == nil {
+		return
+	}
+	s.dispatch(func(ctx context.Context) error {
+		submitter, err := s.users.GetByID(ctx, *submitterID)
+		if err != nil {
+			return err
+		}
+		return s.send(ctx, []string{submitter.Email}, tpl, notify.TemplateData{
+			ConfigName: cfg.Name,
+			Version:    cfg.Version,
+			Actor:      s.username(ctx, actorID),
+			Reason:     reason,
+			URL:        s.configURL(cfg.ID),
+		})
+	})
+}
+
+// DeployFailed emails the users subscribed to deploy.failed.
+func (s *NotificationService) DeployFailed(proxy *domain.Proxy, hash, message string) {
+	s.dispatch(func(ctx context.Context) error {
+		subscribers, err := s.subs.ListSubscribers(ctx, domain.EventDeployFailed)
+		if err != nil {
+			return err
+		}
+		to := make([]string, 0, len(subscribers))
+		for _, u := range subscribers {
+			to = append(to, u.Email)
+		}
+		return s.send(ctx, to, notify.TemplateDeployFailed, notify.TemplateData{
+			Hostname: proxy.Hostname,
+			Hash:     hash,
+			Reason:   message,
+			URL:      fmt.Sprintf("%s/proxies/%s", s.appURL, proxy.ID),
+		})
+	})
+}
+
+// dispatch runs fn in the background so notifications never delay the request.
+func (s *NotificationService) dispatch(fn func(ctx context.Context) error) {
+	if s.sender == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			log.Printf("Email notification error: %v", err)
+		}
+	}()
+}
+
+func (s *NotificationService) send(ctx context.Context, to []string, tpl string, data notify.TemplateData) error {
+	if len(to) == 0 {
+		return nil
+	}
+	subject, body, err := s.templates.Render(tpl, data)
+	if err != nil {
+		return err
+	}
+	return s.sender.Send(ctx, notify.Message{To: to, Subject: subject, Body: body})
+}
+
+func (s *NotificationService) approverEmails(ctx context.Context) ([]string, error) {
+	var emails []string
+	for _, role := range []domain.UserRole{domain.RoleRoot, domain.RoleAdmin} {
+		users, _, err := s.users.List(ctx, &role, 1000, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			emails = append(emails, u.Email)
+		}
+	}
+	return emails, nil
+}
+
+func (s *NotificationService) username(ctx context.Context, id uuid.UUID) string {
+	if u, err := s.users.GetByID(ctx, id); err == nil {
+		return u.Username
+	}
+	return id.String()
+}
+
+func (s *NotificationService) configURL(id uuid.UUID) string {
+	return fmt.Sprintf("%s/configs/%s", s.appURL, id)
+}
+
+// ========== Subscriptions ==========
+
+func (s *NotificationService) Subscriptions(ctx context.Context, userID uuid.UUID) ([]domain.WebhookEvent, error) {
+	events, err := s.subs.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []domain.WebhookEvent{}
+	}
+	return events, nil
+}
+
+func (s *NotificationService) SetSubscriptions(ctx context.Context, userID uuid.UUID, events []domain.WebhookEvent) ([]domain.WebhookEvent, error) {
+	for _, e := range events {
+		if !subscribableEvents[e] {
+			return nil, fmt.Errorf("%w: cannot subscribe to '%s'", domain.ErrBadRequest, e)
+		}
+	}
+	if err := s.subs.Replace(ctx, userID, events); err != nil {
+		return nil, err
+	}
+	return s.Subscriptions(ctx, userID)
+}
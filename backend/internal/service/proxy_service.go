@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,7 +19,10 @@ type ProxyService struct {
 	proxyLogs    *repository.ProxyLogsRepo
 	configs      *repository.ConfigRepo
 	configProxies *repository.ConfigProxyRepo
+	drift        *repository.ProxyDriftRepo
 	audit        *repository.AuditRepo
+	pushes       *SyncNotifier
+	logStream    *LogStream
 }
 
 func NewProxyService(
@@ -25,7 +31,10 @@ func NewProxyService(
 	proxyLogs *repository.ProxyLogsRepo,
 	configs *repository.ConfigRepo,
 	configProxies *repository.ConfigProxyRepo,
+	drift *repository.ProxyDriftRepo,
 	audit *repository.AuditRepo,
+	pushes *SyncNotifier,
+	logStream *LogStream,
 ) *ProxyService {
 	return &ProxyService{
 		proxies:       proxies,
@@ -33,19 +42,25 @@ func NewProxyService(
 		proxyLogs:     proxyLogs,
 		configs:       configs,
 		configProxies: configProxies,
+		drift:         drift,
 		audit:         audit,
+		pushes:        pushes,
+		logStream:     logStream,
 	}
 }
 
 type ProxyListItem struct {
 	ID                string                           `json:"id"`
 	Hostname          string                           `json:"hostname"`
+	Labels            map[string]string                `json:"labels"`
+	HelperLabels      map[string]string                `json:"helper_labels"`
 	Config            *ProxyConfigRef                  `json:"config,omitempty"`
 	IsOnline          bool                             `json:"is_online"`
 	LastSeen          *time.Time                       `json:"last_seen,omitempty"`
 	RegisteredAt      time.Time                        `json:"registered_at"`
 	CurrentConfigHash *string                          `json:"current_config_hash,omitempty"`
 	Stats             *repository.ProxyStatsSummary    `json:"stats,omitempty"`
+	Drift             *domain.ProxyDrift               `json:"drift,omitempty"`
 }
 
 type ProxyConfigRef struct {
@@ -72,6 +87,10 @@ func (s *ProxyService) List(ctx context.Context) (*ProxyListResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	drift, err := s.drift.List(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	items := make([]ProxyListItem, 0, len(proxies))
 	online := 0
@@ -79,6 +98,8 @@ func (s *ProxyService) List(ctx context.Context) (*ProxyListResponse, error) {
 		item := ProxyListItem{
 			ID:                p.ID.String(),
 			Hostname:          p.Hostname,
+			Labels:            p.Labels,
+			HelperLabels:      p.HelperLabels,
 			IsOnline:          p.IsOnline,
 			LastSeen:          p.LastSeen,
 			RegisteredAt:      p.RegisteredAt,
@@ -87,7 +108,7 @@ func (s *ProxyService) List(ctx context.Context) (*ProxyListResponse, error) {
 
 		cfg, err := s.configs.GetActiveForProxy(ctx, p.Hostname)
 		if err == nil {
-			item.Config = buildConfigRef(cfg, p.CurrentConfigHash)
+			item.Config = buildConfigRef(cfg, &p)
 		}
 
 		stats, err := s.proxyStats.SummaryForProxy(ctx, p.ID)
@@ -95,6 +116,8 @@ func (s *ProxyService) List(ctx context.Context) (*ProxyListResponse, error) {
 			item.Stats = stats
 		}
 
+		item.Drift = drift[p.ID]
+
 		if p.IsOnline {
 			online++
 		}
@@ -126,6 +149,8 @@ func (s *ProxyService) GetByID(ctx context.Context, id uuid.UUID) (*ProxyDetail,
 		ProxyListItem: ProxyListItem{
 			ID:                proxy.ID.String(),
 			Hostname:          proxy.Hostname,
+			Labels:            proxy.Labels,
+			HelperLabels:      proxy.HelperLabels,
 			IsOnline:          proxy.IsOnline,
 			LastSeen:          proxy.LastSeen,
 			RegisteredAt:      proxy.RegisteredAt,
@@ -135,7 +160,7 @@ func (s *ProxyService) GetByID(ctx context.Context, id uuid.UUID) (*ProxyDetail,
 
 	cfg, err := s.configs.GetActiveForProxy(ctx, proxy.Hostname)
 	if err == nil {
-		detail.Config = buildConfigRef(cfg, proxy.CurrentConfigHash)
+		detail.Config = buildConfigRef(cfg, proxy)
 	}
 
 	stats, err := s.proxyStats.SummaryForProxy(ctx, proxy.ID)
@@ -143,6 +168,11 @@ func (s *ProxyService) GetByID(ctx context.Context, id uuid.UUID) (*ProxyDetail,
 		detail.Stats = stats
 	}
 
+	drift, err := s.drift.GetByProxy(ctx, proxy.ID)
+	if err == nil {
+		detail.Drift = drift
+	}
+
 	history, err := s.proxyStats.ListByProxyAggregated(ctx, proxy.ID, 60)
 	if err == nil {
 		detail.StatsHistory = history
@@ -154,16 +184,30 @@ func (s *ProxyService) GetByID(ctx context.Context, id uuid.UUID) (*ProxyDetail,
 	return detail, nil
 }
 
-func (s *ProxyService) StartLogCapture(ctx context.Context, id uuid.UUID, durationMinutes int, userID uuid.UUID, ip, ua string) (time.Time, error) {
+// DefaultDebugTags are the ATS debug tags enabled when a capture names none.
+var DefaultDebugTags = []string{"parent_select"}
+
+const maxDebugTags = 10
+
+func (s *ProxyService) StartLogCapture(ctx context.Context, id uuid.UUID, durationMinutes int, debugTags []string, userID uuid.UUID, ip, ua string) (time.Time, []string, error) {
 	if durationMinutes <= 0 || durationMinutes > 5 {
-		return time.Time{}, fmt.Errorf("%w: duration must be between 1 and 5 minutes", domain.ErrBadRequest)
+		return time.Time{}, nil, fmt.Errorf("%w: duration must be between 1 and 5 minutes", domain.ErrBadRequest)
+	}
+	tags, err := normalizeDebugTags(debugTags)
+	if err != nil {
+		return time.Time{}, nil, err
 	}
 
 	until := time.Now().Add(time.Duration(durationMinutes) * time.Minute)
-	if err := s.proxies.SetCaptureLogsUntil(ctx, id, until); err != nil {
-		return time.Time{}, err
+	if err := s.proxies.SetCaptureLogsUntil(ctx, id, until, tags); err != nil {
+		return time.Time{}, nil, err
 	}
+	s.pushes.ProxyChanged(ctx, id)
 
+	newVal, _ := json.Marshal(map[string]interface{}{
+		"duration_minutes": durationMinutes,
+		"debug_tags":       tags,
+	})
 	_ = s.audit.Create(ctx, &domain.AuditLog{
 		UserID:     &userID,
 		Action:     "proxy.capture_logs",
@@ -171,15 +215,122 @@ func (s *ProxyService) StartLogCapture(ctx context.Context, id uuid.UUID, durati
 		EntityID:   &id,
 		IPAddress:  &ip,
 		UserAgent:  &ua,
+		NewValue:   newVal,
 	})
 
-	return until, nil
+	return until, tags, nil
+}
+
+// normalizeDebugTags validates and dedupes the tags of a capture, falling back
+// to DefaultDebugTags.
+func normalizeDebugTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var out []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if !domain.DebugTagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("%w: invalid debug tag %q", domain.ErrBadRequest, tag)
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	if len(out) > maxDebugTags {
+		return nil, fmt.Errorf("%w: at most %d debug tags", domain.ErrBadRequest, maxDebugTags)
+	}
+	if len(out) == 0 {
+		return DefaultDebugTags, nil
+	}
+	return out, nil
 }
 
 func (s *ProxyService) GetLogs(ctx context.Context, id uuid.UUID) ([]domain.ProxyLog, error) {
 	return s.proxyLogs.ListByProxy(ctx, id)
 }
 
+// CaptureUntil returns when the log capture of a proxy ends (zero if none was started).
+func (s *ProxyService) CaptureUntil(ctx context.Context, id uuid.UUID) (time.Time, error) {
+	proxy, err := s.proxies.GetByID(ctx, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if proxy.CaptureLogsUntil == nil {
+		return time.Time{}, nil
+	}
+	return *proxy.CaptureLogsUntil, nil
+}
+
+// SubscribeLogs starts receiving the lines captured for a proxy as the helper
+// sends them. The caller must UnsubscribeLogs.
+func (s *ProxyService) SubscribeLogs(id uuid.UUID) *LogSubscription {
+	return s.logStream.Subscribe(id)
+}
+
+func (s *ProxyService) UnsubscribeLogs(sub *LogSubscription) {
+	s.logStream.Unsubscribe(sub)
+}
+
+const maxLabels = 20
+
+var (
+	labelKeyPattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,62}$`)
+	labelValuePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,63}$`)
+)
+
+// validateLabels checks the labels of a proxy or the selector of a group.
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("%w: at most %d labels", domain.ErrBadRequest, maxLabels)
+	}
+	for k, v := range labels {
+		if !labelKeyPattern.MatchString(k) {
+			return fmt.Errorf("%w: invalid label key %q", domain.ErrBadRequest, k)
+		}
+		if !labelValuePattern.MatchString(v) {
+			return fmt.Errorf("%w: invalid value %q for label %q", domain.ErrBadRequest, v, k)
+		}
+	}
+	return nil
+}
+
+// SetLabels replaces the admin labels of a proxy; the labels its helper
+// reports are kept. The proxy is woken so a change of group membership reaches
+// it right away.
+func (s *ProxyService) SetLabels(ctx context.Context, id uuid.UUID, labels map[string]string, userID uuid.UUID, ip, ua string) (map[string]string, error) {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	if err := validateLabels(labels); err != nil {
+		return nil, err
+	}
+
+	proxy, err := s.proxies.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.proxies.SetLabels(ctx, id, labels); err != nil {
+		return nil, err
+	}
+	s.pushes.ProxyChanged(ctx, id)
+
+	oldVal, _ := json.Marshal(map[string]interface{}{"labels": proxy.Labels})
+	newVal, _ := json.Marshal(map[string]interface{}{"labels": labels})
+	_ = s.audit.Create(ctx, &domain.AuditLog{
+		UserID:     &userID,
+		Action:     "proxy.set_labels",
+		EntityType: "proxy",
+		EntityID:   &id,
+		IPAddress:  &ip,
+		UserAgent:  &ua,
+		OldValue:   oldVal,
+		NewValue:   newVal,
+	})
+
+	return labels, nil
+}
+
 func (s *ProxyService) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID, ip, ua string) error {
 	proxy, err := s.proxies.GetByID(ctx, id)
 	if err != nil {
@@ -203,15 +354,20 @@ func (s *ProxyService) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUI
 	return nil
 }
 
-func buildConfigRef(cfg *domain.Config, proxyHash *string) *ProxyConfigRef {
+func buildConfigRef(cfg *domain.Config, proxy *domain.Proxy) *ProxyConfigRef {
 	ref := &ProxyConfigRef{
 		ID:      cfg.ID.String(),
 		Name:    cfg.Name,
 		Version: cfg.Version,
 	}
-	if cfg.ConfigHash != nil {
-		ref.ConfigHash = *cfg.ConfigHash
-		ref.InSync = proxyHash != nil && *proxyHash == *cfg.ConfigHash
+	expected := cfg.ConfigHash
+	// With overlays the proxy runs the merged files, not the config's own
+	if proxy.TargetConfigID != nil && *proxy.TargetConfigID == cfg.ID {
+		expected = proxy.TargetConfigHash
+	}
+	if expected != nil {
+		ref.ConfigHash = *expected
+		ref.InSync = proxy.CurrentConfigHash != nil && *proxy.CurrentConfigHash == *expected
 	}
 	return ref
 }
@@ -237,10 +393,11 @@ func (s *ProxyService) AssignConfig(ctx context.Context, proxyID uuid.UUID, conf
 	}
 
 	if configID != nil {
-		if err := s.configProxies.Assign(ctx, *configID, proxyID, userID); err != nil {
+		if err := s.configProxies.Assign(ctx, *configID, proxyID, &userID); err != nil {
 			return err
 		}
 	}
+	s.pushes.ProxyChanged(ctx, proxyID)
 
 	newVal := []byte(`{"config_id":null}`)
 	if configID != nil {
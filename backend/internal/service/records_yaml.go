@@ -0,0 +1,124 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+)
+
+// baseRecords mirrors proxy-full/ats-config/records.yaml. A managed records.yaml
+// replaces the image's file, so it must carry the same forward-proxy baseline
+// with the config's overrides on top.
+var baseRecords = map[string]string{
+	"proxy.config.http.server_ports":                        "8080 8080:ipv6",
+	"proxy.config.http.cache.http":                          "0",
+	"proxy.config.http.parent_proxy.fail_threshold":         "10",
+	"proxy.config.http.parent_proxy.retry_time":             "30",
+	"proxy.config.http.parent_proxy.total_connect_attempts": "4",
+	"proxy.config.http.insert_request_via_str":              "1",
+	"proxy.config.http.insert_response_via_str":             "2",
+	"proxy.config.http.connect_attempts_timeout":            "30",
+	"proxy.config.http.keep_alive_no_activity_timeout_in":   "115",
+	"proxy.config.http.keep_alive_no_activity_timeout_out":  "120",
+	"proxy.config.http.transaction_no_activity_timeout_in":  "30",
+	"proxy.config.http.transaction_no_activity_timeout_out": "30",
+	"proxy.config.http.connect_ports":                       "443 563",
+	"proxy.config.url_remap.pristine_host_hdr":              "1",
+	"proxy.config.url_remap.remap_required":                 "0",
+	"proxy.config.reverse_proxy.enabled":                    "0",
+	"proxy.config.diags.debug.enabled":                      "0",
+	"proxy.config.diags.debug.tags":                         "http|parent",
+	"proxy.config.log.auto_delete_rolled_files":             "1",
+	"proxy.config.log.rolling_enabled":                      "1",
+	"proxy.config.log.rolling_interval_sec":                 "86400",
+	"proxy.config.log.rolling_size_mb":                      "100",
+}
+
+// recordNode is a level of the records.yaml tree; leaves carry a value.
+type recordNode struct {
+	value    string
+	children map[string]*recordNode
+}
+
+// generateRecordsYaml renders baseRecords merged with the overrides as the
+// nested records.yaml format (proxy.config.a.b: v → records: a: b: v). Keys are
+// sorted so the output, and therefore the config hash, is stable. Returns ""
+// when there are no overrides: the proxy keeps the records.yaml from its image.
+func generateRecordsYaml(overrides []domain.RecordOverride) string {
+	if len(overrides) == 0 {
+		return ""
+	}
+
+	values := make(map[string]string, len(baseRecords)+len(overrides))
+	for name, v := range baseRecords {
+		values[name] = v
+	}
+	for _, o := range overrides {
+		values[o.Name] = o.Value
+	}
+
+	root := &recordNode{children: map[string]*recordNode{}}
+	for name, v := range values {
+		node := root
+		for _, part := range strings.Split(strings.TrimPrefix(name, "proxy.config."), ".") {
+			child, ok := node.children[part]
+			if !ok {
+				child = &recordNode{children: map[string]*recordNode{}}
+				node.children[part] = child
+			}
+			node = child
+		}
+		node.value = v
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated by ATS Proxy Manager, do not edit on the proxy\n")
+	b.WriteString("records:\n")
+	writeRecordNodes(&b, root, 1)
+	return b.String()
+}
+
+func writeRecordNodes(b *strings.Builder, node *recordNode, depth int) {
+	keys := make([]string, 0, len(node.children))
+	for k := range node.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	indent := strings.Repeat("  ", depth)
+	for _, k := range keys {
+		child := node.children[k]
+		if len(child.children) == 0 {
+			b.WriteString(indent + k + ": " + child.value + "\n")
+			continue
+		}
+		b.WriteString(indent + k + ":\n")
+		writeRecordNodes(b, child, depth+1)
+	}
+}
+
+// ManagedRecordInfo describes an overridable record for the UI.
+type ManagedRecordInfo struct {
+	Name    string            `json:"name"`
+	Kind    domain.RecordKind `json:"kind"`
+	Min     *int64            `json:"min,omitempty"`
+	Max     *int64            `json:"max,omitempty"`
+	Default string            `json:"default,omitempty"`
+}
+
+// ManagedRecordList returns domain.ManagedRecords sorted by name, with the
+// value proxies get when a config does not override it.
+func ManagedRecordList() []ManagedRecordInfo {
+	list := make([]ManagedRecordInfo, 0, len(domain.ManagedRecords))
+	for name, spec := range domain.ManagedRecords {
+		info := ManagedRecordInfo{Name: name, Kind: spec.Kind, Default: baseRecords[name]}
+		if spec.Kind == domain.RecordInt {
+			min, max := spec.Min, spec.Max
+			info.Min, info.Max = &min, &max
+		}
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
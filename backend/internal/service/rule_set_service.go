@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+)
+
+type RuleSetService struct {
+	pool  *pgxpool.Pool
+	sets  *repository.RuleSetRepo
+	feeds *repository.FeedSourceRepo
+	audit *repository.AuditRepo
+}
+
+func NewRuleSetService(pool *pgxpool.Pool, sets *repository.RuleSetRepo, feeds *repository.FeedSourceRepo, audit *repository.AuditRepo) *RuleSetService {
+	return &RuleSetService{
+		pool:  pool,
+		sets:  sets,
+		feeds: feeds,
+		audit: audit,
+	}
+}
+
+type RuleSetRequest struct {
+	Name        string                 `json:"name"`
+	Description *string                `json:"description,omitempty"`
+	Domains     []domain.RuleSetDomain `json:"domains"`
+	IPRanges    []domain.RuleSetCIDR   `json:"ip_ranges"`
+}
+
+// RuleSetUpdate is the result of changing a rule set: the draft configs now
+// using the new version (existing drafts moved to it and drafts cloned from
+// active configs) and the configs pending approval that still reference an
+// older version and need to be re-submitted to pick it up.
+type RuleSetUpdate struct {
+	RuleSet         *domain.RuleSet `json:"rule_set"`
+	Drafts          []domain.Config `json:"drafts"`
+	PendingApproval []domain.Config `json:"pending_approval"`
+}
+
+func (s *RuleSetService) List(ctx context.Context) ([]domain.RuleSet, error) {
+	sets, err := s.sets.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if sets == nil {
+		sets = []domain.RuleSet{}
+	}
+	return sets, nil
+}
+
+func (s *RuleSetService) Get(ctx context.Context, id uuid.UUID) (*domain.RuleSet, error) {
+	return s.sets.GetByID(ctx, id)
+}
+
+func (s *RuleSetService) GetVersion(ctx context.Context, id uuid.UUID, version int) (*domain.RuleSetVersion, error) {
+	return s.sets.GetVersion(ctx, id, version)
+}
+
+func (s *RuleSetService) ListVersions(ctx context.Context, id uuid.UUID) ([]domain.RuleSetVersion, error) {
+	if _, err := s.sets.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	versions, err := s.sets.ListVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if versions == nil {
+		versions = []domain.RuleSetVersion{}
+	}
+	return versions, nil
+}
+
+func (s *RuleSetService) validate(ctx context.Context, rs *domain.RuleSet) error {
+	rs.Name = strings.TrimSpace(rs.Name)
+	if rs.Name == "" {
+		return fmt.Errorf("%w: name is required", domain.ErrBadRequest)
+	}
+	if len(rs.Name) > 100 {
+		return fmt.Errorf("%w: name must be at most 100 characters", domain.ErrBadRequest)
+	}
+	if len(rs.Domains)+len(rs.IPRanges) == 0 {
+		return fmt.Errorf("%w: rule set has no rules", domain.ErrBadRequest)
+	}
+
+	var errs []string
+	domainInputs := make([]DomainRuleInput, 0, len(rs.Domains))
+	seenDomains := make(map[string]bool, len(rs.Domains))
+	for i, d := range rs.Domains {
+		domainInputs = append(domainInputs, DomainRuleInput{Domain: d.Domain, Action: d.Action})
+		key := strings.ToLower(d.Domain)
+		if seenDomains[key] {
+			errs = append(errs, fmt.Sprintf("domains[%d]: '%s' is listed more than once", i, d.Domain))
+		}
+		seenDomains[key] = true
+	}
+	ipInputs := make([]IPRangeRuleInput, 0, len(rs.IPRanges))
+	seenCIDRs := make(map[string]bool, len(rs.IPRanges))
+	for i, ir := range rs.IPRanges {
+		ipInputs = append(ipInputs, IPRangeRuleInput{CIDR: ir.CIDR, Action: ir.Action})
+		if seenCIDRs[ir.CIDR] {
+			errs = append(errs, fmt.Sprintf("ip_ranges[%d]: '%s' is listed more than once", i, ir.CIDR))
+		}
+		seenCIDRs[ir.CIDR] = true
+	}
+	errs = append(errs, validateDomainRules("domains", domainInputs)...)
+	errs = append(errs, validateIPRangeRules("ip_ranges", ipInputs)...)
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %s", domain.ErrBadRequest, strings.Join(errs, "; "))
+	}
+
+	existing, err := s.sets.GetByName(ctx, rs.Name)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+	if existing != nil && existing.ID != rs.ID {
+		return fmt.Errorf("%w: rule set '%s' already exists", domain.ErrConflict, rs.Name)
+	}
+	return nil
+}
+
+func (s *RuleSetService) Create(ctx context.Context, req RuleSetRequest, userID uuid.UUID, ip, ua string) (*domain.RuleSet, error) {
+	rs := &domain.RuleSet{
+		Name:        req.Name,
+		Description: req.Description,
+		Domains:     req.Domains,
+		IPRanges:    req.IPRanges,
+		CreatedBy:   &userID,
+	}
+	if err := s.validate(ctx, rs); err != nil {
+		return nil, err
+	}
+
+	err := repository.WithTx(ctx, s.pool, func(tx pgx.Tx) error {
+		txSets := repository.NewRuleSetRepo(tx)
+		if err := txSets.Create(ctx, rs); err != nil {
+			return err
+		}
+		return txSets.AddVersion(ctx, &domain.RuleSetVersion{
+			RuleSetID: rs.ID,
+			Version:   rs.Version,
+			Domains:   rs.Domains,
+			IPRanges:  rs.IPRanges,
+			CreatedBy: &userID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAudit(ctx, &userID, "rule_set.create", &rs.ID, nil, ruleSetValue(rs), ip, ua)
+	return s.sets.GetByID(ctx, rs.ID)
+}
+
+// Update saves a rule set. When its rules change it gets a new version and the
+// configs that reference an older one are brought along, in the same
+// transaction, without touching what was approved: drafts move to the new
+// version, an active or approved config gets a new draft (unless it already
+// has one, which moves instead) and configs pending approval are reported, as
+// re-submitting them is up to their author.
+func (s *RuleSetService) Update(ctx context.Context, id uuid.UUID, req RuleSetRequest, userID uuid.UUID, ip, ua string) (*RuleSetUpdate, error) {
+	rs, err := s.sets.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	oldVal := ruleSetValue(rs)
+	changed := !slices.Equal(rs.Domains, req.Domains) || !slices.Equal(rs.IPRanges, req.IPRanges)
+
+	rs.Name = req.Name
+	rs.Description = req.Description
+	rs.Domains = req.Domains
+	rs.IPRanges = req.IPRanges
+	if err := s.validate(ctx, rs); err != nil {
+		return nil, err
+	}
+
+	result := &RuleSetUpdate{Drafts: []domain.Config{}, PendingApproval: []domain.Config{}}
+	err = repository.WithTx(ctx, s.pool, func(tx pgx.Tx) error {
+		return saveRuleSet(ctx, tx, rs, changed, userID, ip, ua, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logAudit(ctx, &userID, "rule_set.update", &id, oldVal, ruleSetValue(rs), ip, ua)
+
+	result.RuleSet, err = s.sets.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// saveRuleSet writes rs inside tx and, when its rules changed, adds the new
+// version and propagates it to the configs using the rule set (see Update).
+func saveRuleSet(ctx context.Context, tx pgx.Tx, rs *domain.RuleSet, changed bool, userID uuid.UUID, ip, ua string, result *RuleSetUpdate) error {
+	txSets := repository.NewRuleSetRepo(tx)
+	if err := txSets.Update(ctx, rs, changed); err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	if err := txSets.AddVersion(ctx, &domain.RuleSetVersion{
+		RuleSetID: rs.ID,
+		Version:   rs.Version,
+		Domains:   rs.Domains,
+		IPRanges:  rs.IPRanges,
+		CreatedBy: &userID,
+	}); err != nil {
+		return err
+	}
+	if err := propagateRuleSet(ctx, tx, rs, userID, ip, ua, result); err != nil {
+		return fmt.Errorf("update configs using rule set: %w", err)
+	}
+	return nil
+}
+
+// propagateRuleSet brings the configs referencing an older version of the
+// rule set to its current version inside tx (see Update).
+func propagateRuleSet(ctx context.Context, tx pgx.Tx, rs *domain.RuleSet, userID uuid.UUID, ip, ua string, result *RuleSetUpdate) error {
+	txRefs := repository.NewConfigRuleSetRepo(tx)
+	txConfigs := repository.NewConfigRepo(tx)
+
+	behind, err := txRefs.ListConfigsBehind(ctx, rs.ID, rs.Version)
+	if err != nil {
+		return err
+	}
+
+	drafted := make(map[string]bool)
+	for _, c := range behind {
+		if c.Status == domain.StatusDraft {
+			drafted[c.Name] = true
+			result.Drafts = append(result.Drafts, c)
+		}
+	}
+	for _, c := range behind {
+		switch c.Status {
+		case domain.StatusPendingApproval:
+			result.PendingApproval = append(result.PendingApproval, c)
+		case domain.StatusActive, domain.StatusApproved:
+			// Newest first: an approved config waiting to be activated is
+			// cloned rather than the active one it will replace
+			if drafted[c.Name] {
+				continue
+			}
+			drafted[c.Name] = true
+			hasDraft, err := txConfigs.HasDraft(ctx, c.Name)
+			if err != nil {
+				return err
+			}
+			if hasDraft {
+				continue
+			}
+			draft, err := cloneConfig(ctx, tx, c.ID, userID, ip, ua)
+			if err != nil {
+				return err
+			}
+			result.Drafts = append(result.Drafts, draft.Config)
+		}
+	}
+
+	return txRefs.UpgradeDrafts(ctx, rs.ID, rs.Version)
+}
+
+// ApplyFeedUpdate makes the pending update of a feed the new version of its
+// rule set, which brings the configs using it along as any Update does. The
+// update and the rule set are locked for the whole transaction, so two applies
+// of the same update, or an edit racing the version check, cannot both win.
+func (s *RuleSetService) ApplyFeedUpdate(ctx context.Context, feedID, updateID, userID uuid.UUID, ip, ua string) (*RuleSetUpdate, error) {
+	f, err := s.feeds.GetByID(ctx, feedID)
+	if err != nil {
+		return nil, err
+	}
+
+	var oldVal []byte
+	var rs *domain.RuleSet
+	result := &RuleSetUpdate{Drafts: []domain.Config{}, PendingApproval: []domain.Config{}}
+	err = repository.WithTx(ctx, s.pool, func(tx pgx.Tx) error {
+		txUpdates := repository.NewFeedUpdateRepo(tx)
+		u, err := txUpdates.GetByIDForUpdate(ctx, updateID)
+		if err != nil {
+			return err
+		}
+		if u.FeedID != feedID {
+			return domain.ErrNotFound
+		}
+		if u.Status != domain.FeedUpdatePending {
+			return fmt.Errorf("%w: feed update is not pending", domain.ErrInvalidStatus)
+		}
+		rs, err = repository.NewRuleSetRepo(tx).GetByIDForUpdate(ctx, f.RuleSetID)
+		if err != nil {
+			return err
+		}
+		if rs.Version != u.BaseVersion {
+			return fmt.Errorf("%w: rule set changed since the feed was fetched (version %d, update based on %d), refresh the feed", domain.ErrConflict, rs.Version, u.BaseVersion)
+		}
+
+		oldVal = ruleSetValue(rs)
+		changed := !slices.Equal(rs.Domains, u.Domains) || !slices.Equal(rs.IPRanges, u.IPRanges)
+		rs.Domains = u.Domains
+		rs.IPRanges = u.IPRanges
+		if err := s.validate(ctx, rs); err != nil {
+			return err
+		}
+		if err := saveRuleSet(ctx, tx, rs, changed, userID, ip, ua, result); err != nil {
+			return err
+		}
+		version := rs.Version
+		return txUpdates.Review(ctx, u.ID, domain.FeedUpdateApplied, userID, &version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logAudit(ctx, &userID, "rule_set.update", &rs.ID, oldVal, ruleSetValue(rs), ip, ua)
+	s.logAudit(ctx, &userID, "rule_set.feed_apply", &rs.ID, nil, jsonVal("feed", f.Name), ip, ua)
+
+	result.RuleSet, err = s.sets.GetByID(ctx, rs.ID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Delete removes a rule set that no config references.
+func (s *RuleSetService) Delete(ctx context.Context, id, userID uuid.UUID, ip, ua string) error {
+	rs, err := s.sets.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if rs.ConfigCount > 0 {
+		return fmt.Errorf("%w: rule set '%s' is referenced by %d config(s)", domain.ErrConflict, rs.Name, rs.ConfigCount)
+	}
+	if err := s.sets.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.logAudit(ctx, &userID, "rule_set.delete", &id, ruleSetValue(rs), nil, ip, ua)
+	return nil
+}
+
+func ruleSetValue(rs *domain.RuleSet) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"name":      rs.Name,
+		"version":   rs.Version,
+		"domains":   len(rs.Domains),
+		"ip_ranges": len(rs.IPRanges),
+	})
+	return data
+}
+
+func (s *RuleSetService) logAudit(ctx context.Context, userID *uuid.UUID, action string, entityID *uuid.UUID, oldVal, newVal []byte, ip, ua string) {
+	_ = s.audit.Create(ctx, &domain.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityType: "rule_set",
+		EntityID:   entityID,
+		OldValue:   oldVal,
+		NewValue:   newVal,
+		IPAddress:  &ip,
+		UserAgent:  &ua,
+	})
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/config"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+)
+
+// maxStatsPoints bounds the number of buckets a single query may return.
+const maxStatsPoints = 2000
+
+type StatsService struct {
+	proxies   *repository.ProxyRepo
+	configs   *repository.ConfigRepo
+	rollups   *repository.StatsRollupRepo
+	retention config.StatsRetention
+}
+
+func NewStatsService(
+	proxies *repository.ProxyRepo,
+	configs *repository.ConfigRepo,
+	rollups *repository.StatsRollupRepo,
+	retention config.StatsRetention,
+) *StatsService {
+	return &StatsService{proxies: proxies, configs: configs, rollups: rollups, retention: retention}
+}
+
+// statsTier describes one storage tier: its native resolution and how far back it goes.
+type statsTier struct {
+	tier       domain.StatsTier
+	resolution time.Duration
+	retention  time.Duration
+}
+
+// tiers returns the storage tiers ordered from finest to coarsest.
+func (s *StatsService) tiers() []statsTier {
+	return []statsTier{
+		{domain.TierRaw, 0, s.retention.Raw},
+		{domain.Tier5m, 5 * time.Minute, s.retention.FiveMin},
+		{domain.TierHourly, time.Hour, s.retention.Hourly},
+		{domain.TierDaily, 24 * time.Hour, s.retention.Daily},
+	}
+}
+
+// selectTier picks the coarsest tier whose resolution still fits the
+// requested step, then moves to coarser tiers until one retains data back to
+// from. The effective step is never finer than the chosen tier resolution.
+func (s *StatsService) selectTier(from time.Time, step time.Duration, now time.Time) (domain.StatsTier, time.Duration) {
+	tiers := s.tiers()
+
+	idx := 0
+	for i, t := range tiers {
+		if t.resolution <= step {
+			idx = i
+		}
+	}
+	for idx < len(tiers)-1 && from.Before(now.Add(-tiers[idx].retention)) {
+		idx++
+	}
+
+	chosen := tiers[idx]
+	if step < chosen.resolution {
+		step = chosen.resolution
+	}
+	return chosen.tier, step
+}
+
+type StatsRange struct {
+	From time.Time
+	To   time.Time
+	Step time.Duration
+}
+
+type StatsSeriesResponse struct {
+	ProxyID     string                  `json:"proxy_id,omitempty"`
+	Tier        domain.StatsTier        `json:"tier"`
+	From        time.Time               `json:"from"`
+	To          time.Time               `json:"to"`
+	StepSeconds int64                   `json:"step_seconds"`
+	Points      []repository.StatsPoint `json:"points"`
+}
+
+func validateStatsRange(rng StatsRange) error {
+	if !rng.To.After(rng.From) {
+		return fmt.Errorf("%w: 'to' must be after 'from'", domain.ErrBadRequest)
+	}
+	if rng.Step <= 0 {
+		return fmt.Errorf("%w: step must be positive", domain.ErrBadRequest)
+	}
+	if rng.To.Sub(rng.From)/rng.Step > maxStatsPoints {
+		return fmt.Errorf("%w: range/step yields more than %d points, increase step", domain.ErrBadRequest, maxStatsPoints)
+	}
+	return nil
+}
+
+// ProxySeries returns the time series of one proxy, read from the tier that
+// best matches the requested range and step.
+func (s *StatsService) ProxySeries(ctx context.Context, proxyID uuid.UUID, rng StatsRange) (*StatsSeriesResponse, error) {
+	if err := validateStatsRange(rng); err != nil {
+		return nil, err
+	}
+	if _, err := s.proxies.GetByID(ctx, proxyID); err != nil {
+		return nil, err
+	}
+
+	tier, step := s.selectTier(rng.From, rng.Step, time.Now())
+
+	points, err := s.rollups.Query(ctx, repository.StatsQuery{
+		Tier:    tier,
+		ProxyID: &proxyID,
+		From:    rng.From,
+		To:      rng.To,
+		Step:    step,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if points == nil {
+		points = []repository.StatsPoint{}
+	}
+	for i := range points {
+		points[i].FillRatios()
+	}
+
+	return &StatsSeriesResponse{
+		ProxyID:     proxyID.String(),
+		Tier:        tier,
+		From:        rng.From,
+		To:          rng.To,
+		StepSeconds: int64(step.Seconds()),
+		Points:      points,
+	}, nil
+}
+
+type StatsSeries struct {
+	Key    string                  `json:"key"`
+	Label  string                  `json:"label"`
+	Points []repository.StatsPoint `json:"points"`
+}
+
+type FleetStatsResponse struct {
+	GroupBy     domain.StatsGroupBy `json:"group_by"`
+	Tier        domain.StatsTier    `json:"tier"`
+	From        time.Time           `json:"from"`
+	To          time.Time           `json:"to"`
+	StepSeconds int64               `json:"step_seconds"`
+	Series      []StatsSeries       `json:"series"`
+}
+
+// FleetSeries returns stats series grouped per proxy, per active config or for
+// the whole fleet. Proxies without an active config are grouped under an
+// empty key labelled "unassigned".
+func (s *StatsService) FleetSeries(ctx context.Context, groupBy domain.StatsGroupBy, rng StatsRange) (*FleetStatsResponse, error) {
+	if groupBy == "" {
+		groupBy = domain.GroupByFleet
+	}
+	if !groupBy.IsValid() {
+		return nil, fmt.Errorf("%w: group_by must be 'proxy', 'config' or 'fleet'", domain.ErrBadRequest)
+	}
+	if err := validateStatsRange(rng); err != nil {
+		return nil, err
+	}
+
+	tier, step := s.selectTier(rng.From, rng.Step, time.Now())
+
+	points, err := s.rollups.Query(ctx, repository.StatsQuery{
+		Tier:    tier,
+		GroupBy: groupBy,
+		From:    rng.From,
+		To:      rng.To,
+		Step:    step,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	series := []StatsSeries{}
+	index := make(map[string]int)
+	for _, p := range points {
+		p.FillRatios()
+		i, ok := index[p.GroupKey]
+		if !ok {
+			i = len(series)
+			index[p.GroupKey] = i
+			series = append(series, StatsSeries{
+				Key:    p.GroupKey,
+				Label:  p.GroupKey,
+				Points: []repository.StatsPoint{},
+			})
+		}
+		series[i].Points = append(series[i].Points, p)
+	}
+	if err := s.labelSeries(ctx, groupBy, series); err != nil {
+		return nil, err
+	}
+
+	return &FleetStatsResponse{
+		GroupBy:     groupBy,
+		Tier:        tier,
+		From:        rng.From,
+		To:          rng.To,
+		StepSeconds: int64(step.Seconds()),
+		Series:      series,
+	}, nil
+}
+
+// labelSeries replaces the series' group keys by human readable names, loaded
+// in one query; a key without a name keeps the key as label.
+func (s *StatsService) labelSeries(ctx context.Context, groupBy domain.StatsGroupBy, series []StatsSeries) error {
+	if groupBy != domain.GroupByProxy && groupBy != domain.GroupByConfig {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(series))
+	for _, ser := range series {
+		if id, err := uuid.Parse(ser.Key); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	var names map[uuid.UUID]string
+	var err error
+	if groupBy == domain.GroupByProxy {
+		names, err = s.proxies.HostnamesByIDs(ctx, ids)
+	} else {
+		names, err = s.configs.NamesByIDs(ctx, ids)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, ser := range series {
+		if groupBy == domain.GroupByConfig && ser.Key == "" {
+			series[i].Label = "unassigned"
+			continue
+		}
+		if id, err := uuid.Parse(ser.Key); err == nil {
+			if name, ok := names[id]; ok {
+				series[i].Label = name
+			}
+		}
+	}
+	return nil
+}
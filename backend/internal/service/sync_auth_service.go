@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+)
+
+// syncClockSkew is how far the X-Sync-Timestamp of a signed request may be from the server clock.
+const syncClockSkew = 5 * time.Minute
+
+// syncNoncePrefix is the Redis key prefix of the X-Sync-Nonce values already
+// seen. They are kept for the whole timestamp window (±syncClockSkew), so a
+// signed request cannot be replayed while its timestamp is still accepted.
+const syncNoncePrefix = "proxy-manager:sync-nonce:"
+
+// maxSyncNonce bounds the length of X-Sync-Nonce.
+const maxSyncNonce = 64
+
+// SyncAuthService manages enrollment tokens and the per-proxy secrets used to
+// sign helper requests to /sync.
+type SyncAuthService struct {
+	tokens   *repository.EnrollmentTokenRepo
+	creds    *repository.ProxyCredentialRepo
+	proxies  *repository.ProxyRepo
+	configs  *repository.ConfigRepo
+	groups   *repository.ProxyGroupRepo
+	audit    *repository.AuditRepo
+	pushes   *SyncNotifier
+	rdb      *redis.Client
+	required bool
+}
+
+func NewSyncAuthService(
+	tokens *repository.EnrollmentTokenRepo,
+	creds *repository.ProxyCredentialRepo,
+	proxies *repository.ProxyRepo,
+	configs *repository.ConfigRepo,
+	groups *repository.ProxyGroupRepo,
+	audit *repository.AuditRepo,
+	pushes *SyncNotifier,
+	rdb *redis.Client,
+	required bool,
+) *SyncAuthService {
+	return &SyncAuthService{
+		tokens:   tokens,
+		creds:    creds,
+		proxies:  proxies,
+		configs:  configs,
+		groups:   groups,
+		audit:    audit,
+		pushes:   pushes,
+		rdb:      rdb,
+		required: required,
+	}
+}
+
+// Required reports whether unsigned sync requests are rejected.
+func (s *SyncAuthService) Required() bool {
+	return s.required
+}
+
+// ========== Request signing ==========
+
+// SyncSignature computes the hex HMAC-SHA256 a helper sends in X-Sync-Signature:
+// HMAC(secret, "<timestamp>.<nonce>.<METHOD>.<request URI>.<body>").
+func SyncSignature(secret, timestamp, nonce, method, requestURI string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + nonce + "." + method + "." + requestURI + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Authenticate verifies a signed sync request and returns the calling proxy.
+// Each nonce is accepted once. A request signed with a pending rotated secret
+// completes the rotation.
+func (s *SyncAuthService) Authenticate(ctx context.Context, proxyID, timestamp, nonce, signature, method, requestURI string, body []byte) (*domain.Proxy, error) {
+	id, err := uuid.Parse(proxyID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid proxy id", domain.ErrUnauthorized)
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signature timestamp", domain.ErrUnauthorized)
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > syncClockSkew || skew < -syncClockSkew {
+		return nil, fmt.Errorf("%w: signature timestamp outside the allowed window", domain.ErrUnauthorized)
+	}
+	if nonce == "" || len(nonce) > maxSyncNonce {
+		return nil, fmt.Errorf("%w: invalid sync nonce", domain.ErrUnauthorized)
+	}
+
+	cred, err := s.creds.Get(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("%w: proxy has no sync credentials", domain.ErrUnauthorized)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sig := []byte(strings.TrimPrefix(signature, "sha256="))
+	promote := false
+	switch {
+	case hmac.Equal(sig, []byte(SyncSignature(cred.Secret, timestamp, nonce, method, requestURI, body))):
+	case cred.NextSecret != nil && hmac.Equal(sig, []byte(SyncSignature(*cred.NextSecret, timestamp, nonce, method, requestURI, body))):
+		promote = true
+	default:
+		return nil, fmt.Errorf("%w: invalid sync signature", domain.ErrUnauthorized)
+	}
+
+	// Only signed requests reach the nonce cache, so it cannot be filled by strangers
+	fresh, err := s.rdb.SetNX(ctx, syncNoncePrefix+id.String()+":"+nonce, 1, 2*syncClockSkew).Result()
+	if err != nil {
+		return nil, fmt.Errorf("check sync nonce: %w", err)
+	}
+	if !fresh {
+		return nil, fmt.Errorf("%w: sync nonce already used", domain.ErrUnauthorized)
+	}
+
+	if promote {
+		if err := s.creds.Promote(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	proxy, err := s.proxies.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.creds.Touch(ctx, id)
+	return proxy, nil
+}
+
+// PendingSecret returns the rotated secret the proxy has not switched to yet, if any.
+func (s *SyncAuthService) PendingSecret(ctx context.Context, proxyID uuid.UUID) string {
+	cred, err := s.creds.Get(ctx, proxyID)
+	if err != nil || cred.NextSecret == nil {
+		return ""
+	}
+	return *cred.NextSecret
+}
+
+// ========== Enrollment ==========
+
+func hashEnrollmentToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateSyncSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Enroll consumes one use of an enrollment token inside the register transaction.
+func (s *SyncAuthService) Enroll(ctx context.Context, tx pgx.Tx, token string) (*domain.EnrollmentToken, error) {
+	t, err := repository.NewEnrollmentTokenRepo(tx).Consume(ctx, hashEnrollmentToken(token))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("%w: invalid, expired or exhausted enrollment token", domain.ErrUnauthorized)
+	}
+	return t, err
+}
+
+// Issue creates a new secret for the proxy inside the register transaction.
+func (s *SyncAuthService) Issue(ctx context.Context, tx pgx.Tx, proxyID uuid.UUID, tokenID *uuid.UUID) (string, error) {
+	secret, err := generateSyncSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := repository.NewProxyCredentialRepo(tx).Issue(ctx, proxyID, secret, tokenID); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// Enrolled reports whether the proxy has sync credentials. An enrolled proxy
+// only registers again with a signed request, until an admin revokes them.
+// AcceptsUnsigned reports whether unsigned requests may act on a hostname:
+// only when sync auth is optional and the proxy has no credentials yet (or is
+// not registered).
+func (s *SyncAuthService) AcceptsUnsigned(ctx context.Context, hostname string) (bool, error) {
+	if s.required {
+		return false, nil
+	}
+	proxy, err := s.proxies.GetByHostname(ctx, hostname)
+	if errors.Is(err, domain.ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	enrolled, err := s.Enrolled(ctx, proxy.ID)
+	return !enrolled, err
+}
+
+func (s *SyncAuthService) Enrolled(ctx context.Context, proxyID uuid.UUID) (bool, error) {
+	_, err := s.creds.Get(ctx, proxyID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ========== Token management ==========
+
+type EnrollmentTokenRequest struct {
+	Name           string     `json:"name"`
+	ConfigID       *uuid.UUID `json:"config_id,omitempty"`
+	GroupID        *uuid.UUID `json:"group_id,omitempty"`
+	MaxUses        *int       `json:"max_uses,omitempty"`
+	ExpiresInHours *int       `json:"expires_in_hours,omitempty"`
+}
+
+// EnrollmentTokenResponse includes the token itself; it is only returned on create.
+type EnrollmentTokenResponse struct {
+	domain.EnrollmentToken
+	Token string `json:"token"`
+}
+
+func (s *SyncAuthService) ListTokens(ctx context.Context) ([]domain.EnrollmentToken, error) {
+	tokens, err := s.tokens.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tokens == nil {
+		tokens = []domain.EnrollmentToken{}
+	}
+	return tokens, nil
+}
+
+func (s *SyncAuthService) CreateToken(ctx context.Context, req EnrollmentTokenRequest, userID uuid.UUID, ip, ua string) (*EnrollmentTokenResponse, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", domain.ErrBadRequest)
+	}
+	if req.MaxUses != nil && *req.MaxUses < 1 {
+		return nil, fmt.Errorf("%w: max_uses must be at least 1", domain.ErrBadRequest)
+	}
+	if req.ConfigID != nil {
+		if _, err := s.configs.GetByID(ctx, *req.ConfigID); err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return nil, fmt.Errorf("%w: config not found", domain.ErrBadRequest)
+			}
+			return nil, err
+		}
+	}
+	if req.GroupID != nil {
+		if _, err := s.groups.GetByID(ctx, *req.GroupID); err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return nil, fmt.Errorf("%w: group not found", domain.ErrBadRequest)
+			}
+			return nil, err
+		}
+	}
+
+	t := &domain.EnrollmentToken{
+		Name:      req.Name,
+		ConfigID:  req.ConfigID,
+		GroupID:   req.GroupID,
+		MaxUses:   req.MaxUses,
+		CreatedBy: &userID,
+	}
+	if req.ExpiresInHours != nil {
+		if *req.ExpiresInHours < 1 {
+			return nil, fmt.Errorf("%w: expires_in_hours must be at least 1", domain.ErrBadRequest)
+		}
+		expires := time.Now().Add(time.Duration(*req.ExpiresInHours) * time.Hour)
+		t.ExpiresAt = &expires
+	}
+
+	raw, err := generateSyncSecret()
+	if err != nil {
+		return nil, err
+	}
+	token := "enr_" + raw
+
+	if err := s.tokens.Create(ctx, t, hashEnrollmentToken(token)); err != nil {
+		return nil, err
+	}
+
+	s.logAudit(ctx, &userID, "enrollment_token.create", "enrollment_token", &t.ID, nil, jsonVal("name", t.Name), ip, ua)
+	return &EnrollmentTokenResponse{EnrollmentToken: *t, Token: token}, nil
+}
+
+func (s *SyncAuthService) RevokeToken(ctx context.Context, id, userID uuid.UUID, ip, ua string) error {
+	t, err := s.tokens.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.tokens.Revoke(ctx, id); err != nil {
+		return err
+	}
+
+	s.logAudit(ctx, &userID, "enrollment_token.revoke", "enrollment_token", &id, jsonVal("name", t.Name), nil, ip, ua)
+	return nil
+}
+
+// ========== Proxy credentials ==========
+
+// CredentialStatus describes a proxy's sync credentials without exposing the secret.
+type CredentialStatus struct {
+	domain.ProxyCredential
+	RotationPending bool `json:"rotation_pending"`
+}
+
+func (s *SyncAuthService) Credentials(ctx context.Context, proxyID uuid.UUID) (*CredentialStatus, error) {
+	if _, err := s.proxies.GetByID(ctx, proxyID); err != nil {
+		return nil, err
+	}
+	cred, err := s.creds.Get(ctx, proxyID)
+	if err != nil {
+		return nil, err
+	}
+	return &CredentialStatus{ProxyCredential: *cred, RotationPending: cred.NextSecret != nil}, nil
+}
+
+// Rotate generates a new secret for the proxy. The helper receives it in its next
+// signed GET /sync and both secrets are accepted until the helper switches.
+func (s *SyncAuthService) Rotate(ctx context.Context, proxyID, userID uuid.UUID, ip, ua string) (*CredentialStatus, error) {
+	proxy, err := s.proxies.GetByID(ctx, proxyID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := generateSyncSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.creds.SetNext(ctx, proxyID, secret); err != nil {
+		return nil, err
+	}
+	s.pushes.ProxyChanged(ctx, proxyID)
+
+	s.logAudit(ctx, &userID, "proxy.credentials_rotate", "proxy", &proxyID, nil, jsonVal("hostname", proxy.Hostname), ip, ua)
+	return s.Credentials(ctx, proxyID)
+}
+
+// Revoke deletes the proxy's credentials. The helper must enroll again with a new token.
+func (s *SyncAuthService) Revoke(ctx context.Context, proxyID, userID uuid.UUID, ip, ua string) error {
+	proxy, err := s.proxies.GetByID(ctx, proxyID)
+	if err != nil {
+		return err
+	}
+	if err := s.creds.Delete(ctx, proxyID); err != nil {
+		return err
+	}
+
+	s.logAudit(ctx, &userID, "proxy.credentials_revoke", "proxy", &proxyID, jsonVal("hostname", proxy.Hostname), nil, ip, ua)
+	return nil
+}
+
+func (s *SyncAuthService) logAudit(ctx context.Context, userID *uuid.UUID, action, entityType string, entityID *uuid.UUID, oldVal, newVal []byte, ip, ua string) {
+	_ = s.audit.Create(ctx, &domain.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		OldValue:   oldVal,
+		NewValue:   newVal,
+		IPAddress:  &ip,
+		UserAgent:  &ua,
+	})
+}
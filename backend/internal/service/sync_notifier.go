@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	stdsync "sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// syncChannel carries "config:<id>" when a config is activated, "group:<id>"
+// when a proxy group changes and "proxy:<id>" when something changes for a
+// single proxy (assignment, labels, log capture, credentials).
+const syncChannel = "proxy-manager:sync"
+
+// SyncNotifier wakes long-polling GET /sync requests. Notifications go through
+// Redis pub/sub so every backend replica wakes its own waiters.
+type SyncNotifier struct {
+	rdb *redis.Client
+
+	mu      stdsync.Mutex
+	waiters map[*syncWaiter]struct{}
+}
+
+type syncWaiter struct {
+	proxyID uuid.UUID
+	ch      chan syncWake
+}
+
+// syncWake tells a waiter whether the notification was about its own proxy.
+type syncWake struct {
+	proxySpecific bool
+}
+
+func NewSyncNotifier(rdb *redis.Client) *SyncNotifier {
+	return &SyncNotifier{
+		rdb:     rdb,
+		waiters: make(map[*syncWaiter]struct{}),
+	}
+}
+
+// Run subscribes to the sync channel until ctx is cancelled. The go-redis
+// PubSub reconnects on its own after connection errors.
+func (n *SyncNotifier) Run(ctx context.Context) {
+	pubsub := n.rdb.Subscribe(ctx, syncChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			n.broadcast(msg.Payload)
+		}
+	}
+}
+
+// ConfigActivated wakes every waiter so it re-checks its active config.
+func (n *SyncNotifier) ConfigActivated(ctx context.Context, configID uuid.UUID) {
+	n.publish(ctx, "config:"+configID.String())
+}
+
+// GroupChanged wakes every waiter, since any proxy may have joined or left the group.
+func (n *SyncNotifier) GroupChanged(ctx context.Context, groupID uuid.UUID) {
+	n.publish(ctx, "group:"+groupID.String())
+}
+
+// ProxyChanged wakes the waiter of one proxy.
+func (n *SyncNotifier) ProxyChanged(ctx context.Context, proxyID uuid.UUID) {
+	n.publish(ctx, "proxy:"+proxyID.String())
+}
+
+func (n *SyncNotifier) publish(ctx context.Context, payload string) {
+	if err := n.rdb.Publish(ctx, syncChannel, payload).Err(); err != nil {
+		// Redis down: still wake the waiters on this replica
+		log.Printf("Sync notify %s: %v", payload, err)
+		n.broadcast(payload)
+	}
+}
+
+func (n *SyncNotifier) broadcast(payload string) {
+	kind, id, _ := strings.Cut(payload, ":")
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for w := range n.waiters {
+		var wake syncWake
+		switch kind {
+		case "config", "group":
+		case "proxy":
+			if id != w.proxyID.String() {
+				continue
+			}
+			wake.proxySpecific = true
+		default:
+			continue
+		}
+		select {
+		case w.ch <- wake:
+		default:
+		}
+	}
+}
+
+func (n *SyncNotifier) subscribe(proxyID uuid.UUID) *syncWaiter {
+	w := &syncWaiter{proxyID: proxyID, ch: make(chan syncWake, 1)}
+	n.mu.Lock()
+	n.waiters[w] = struct{}{}
+	n.mu.Unlock()
+	return w
+}
+
+func (n *SyncNotifier) unsubscribe(w *syncWaiter) {
+	n.mu.Lock()
+	delete(n.waiters, w)
+	n.mu.Unlock()
+}
+
+// wait blocks until the waiter is woken, the timer fires or ctx is done.
+// It returns the wake and false when the wait ended without a notification.
+func (w *syncWaiter) wait(ctx context.Context, timer *time.Timer) (syncWake, bool) {
+	select {
+	case wake := <-w.ch:
+		return wake, true
+	case <-timer.C:
+		return syncWake{}, false
+	case <-ctx.Done():
+		return syncWake{}, false
+	}
+}
@@ -2,64 +2,137 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
 	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
-	"github.com/redis/go-redis/v9"
 )
 
 type SyncService struct {
+	pool         *pgxpool.Pool
 	proxies      *repository.ProxyRepo
 	configs      *repository.ConfigRepo
 	configProxy  *repository.ConfigProxyRepo
 	proxyStats   *repository.ProxyStatsRepo
 	proxyLogs    *repository.ProxyLogsRepo
+	parentStatus *repository.ProxyParentStatusRepo
+	drift        *repository.ProxyDriftRepo
 	configSvc    *ConfigService
-	rdb          *redis.Client
+	commands     *CommandService
+	diagnostics  *DiagnosticsService
+	accessLogs   *AccessLogService
+	events       *WebhookService
+	notifier     *NotificationService
+	auth         *SyncAuthService
+	pushes       *SyncNotifier
+	logStream    *LogStream
+	signer       *BundleSigner
 }
 
 func NewSyncService(
+	pool *pgxpool.Pool,
 	proxies *repository.ProxyRepo,
 	configs *repository.ConfigRepo,
 	configProxy *repository.ConfigProxyRepo,
 	proxyStats *repository.ProxyStatsRepo,
 	proxyLogs *repository.ProxyLogsRepo,
+	parentStatus *repository.ProxyParentStatusRepo,
+	drift *repository.ProxyDriftRepo,
 	configSvc *ConfigService,
-	rdb *redis.Client,
+	commands *CommandService,
+	diagnostics *DiagnosticsService,
+	accessLogs *AccessLogService,
+	events *WebhookService,
+	notifier *NotificationService,
+	auth *SyncAuthService,
+	pushes *SyncNotifier,
+	logStream *LogStream,
+	signer *BundleSigner,
 ) *SyncService {
 	return &SyncService{
-		proxies:     proxies,
-		configs:     configs,
-		configProxy: configProxy,
-		proxyStats:  proxyStats,
-		proxyLogs:   proxyLogs,
-		configSvc:   configSvc,
-		rdb:         rdb,
+		pool:         pool,
+		proxies:      proxies,
+		configs:      configs,
+		configProxy:  configProxy,
+		proxyStats:   proxyStats,
+		proxyLogs:    proxyLogs,
+		parentStatus: parentStatus,
+		drift:        drift,
+		configSvc:    configSvc,
+		commands:     commands,
+		diagnostics:  diagnostics,
+		accessLogs:   accessLogs,
+		events:       events,
+		notifier:     notifier,
+		auth:         auth,
+		pushes:       pushes,
+		logStream:    logStream,
+		signer:       signer,
 	}
 }
 
 // RegisterRequest mirrors helper's RegisterRequest
 type RegisterRequest struct {
-	Hostname string `json:"hostname"`
-	ConfigID string `json:"config_id"`
-	ProxyID  string `json:"proxy_id,omitempty"`
-	RemoteIP string `json:"-"` // extracted from request by handler
+	Hostname        string `json:"hostname"`
+	ConfigID        string `json:"config_id"`
+	ProxyID         string `json:"proxy_id,omitempty"`
+	EnrollmentToken string `json:"enrollment_token,omitempty"`
+	RemoteIP        string `json:"-"` // extracted from request by handler
+
+	// Labels set by the helper (--labels). They replace the helper labels of
+	// the proxy; labels set by an admin are kept apart and win on the same key.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// ClientCertFingerprint is set by the handler when the helper presented a verified certificate.
+	ClientCertFingerprint string `json:"-"`
+
+	// Authenticated is the proxy that signed the request, set by the handler.
+	Authenticated *domain.Proxy `json:"-"`
 }
 
 type RegisterResponse struct {
 	ProxyID  string `json:"proxy_id"`
 	ConfigID string `json:"config_id"`
 	Status   string `json:"status"`
+	// ProxySecret is returned only when an enrollment token was exchanged.
+	ProxySecret string `json:"proxy_secret,omitempty"`
 }
 
+// Register creates or recovers a proxy. A request signed with the proxy's
+// credentials re-registers it; otherwise an enrollment token is exchanged for
+// a new secret. Unsigned, tokenless registration is only allowed when sync
+// authentication is not required. A proxy that already has credentials can
+// only register with them, until an admin revokes them.
 func (s *SyncService) Register(ctx context.Context, req RegisterRequest) (*RegisterResponse, error) {
 	if req.Hostname == "" {
 		return nil, fmt.Errorf("%w: hostname is required", domain.ErrBadRequest)
 	}
+	if err := validateLabels(req.Labels); err != nil {
+		return nil, err
+	}
+
+	if req.Authenticated != nil {
+		if req.Authenticated.Hostname != req.Hostname {
+			return nil, fmt.Errorf("%w: credentials belong to another hostname", domain.ErrForbidden)
+		}
+		_ = s.proxies.UpdateRegisteredIP(ctx, req.Authenticated.ID, req.RemoteIP)
+		_ = s.proxies.SetHelperLabels(ctx, req.Authenticated.ID, req.Labels)
+		_ = s.proxies.UpdateLastSeen(ctx, req.Authenticated.ID)
+		s.recordClientCert(ctx, req.Authenticated.ID, req.ClientCertFingerprint)
+		return registered(req.Authenticated, ""), nil
+	}
+
+	if req.EnrollmentToken == "" && s.auth.Required() {
+		return nil, fmt.Errorf("%w: enrollment token or signed request required", domain.ErrUnauthorized)
+	}
 
 	// Check if a proxy with this hostname already exists
 	existing, err := s.proxies.GetByHostname(ctx, req.Hostname)
@@ -67,61 +140,130 @@ func (s *SyncService) Register(ctx context.Context, req RegisterRequest) (*Regis
 		return nil, fmt.Errorf("register proxy: %w", err)
 	}
 
+	if existing != nil && existing.IsOnline {
+		// Proxy is online — only allow re-register if same identity (IP or proxy_id)
+		sameIP := existing.RegisteredIP != nil && *existing.RegisteredIP == req.RemoteIP
+		sameID := req.ProxyID != "" && req.ProxyID == existing.ID.String()
+
+		if !sameIP && !sameID {
+			return nil, fmt.Errorf("%w: hostname '%s' is already registered by an active proxy", domain.ErrConflict, req.Hostname)
+		}
+	}
+
 	if existing != nil {
-		if existing.IsOnline {
-			// Proxy is online — only allow re-register if same identity (IP or proxy_id)
-			sameIP := existing.RegisteredIP != nil && *existing.RegisteredIP == req.RemoteIP
-			sameID := req.ProxyID != "" && req.ProxyID == existing.ID.String()
+		// Enrolling again would replace the secret of a running helper
+		enrolled, err := s.auth.Enrolled(ctx, existing.ID)
+		if err != nil {
+			return nil, fmt.Errorf("register proxy: %w", err)
+		}
+		if enrolled {
+			return nil, fmt.Errorf("%w: hostname '%s' is already enrolled; revoke its credentials to enroll it again", domain.ErrConflict, req.Hostname)
+		}
+	}
 
-			if !sameIP && !sameID {
-				return nil, fmt.Errorf("%w: hostname '%s' is already registered by an active proxy", domain.ErrConflict, req.Hostname)
+	proxy := existing
+	secret := ""
+	err = repository.WithTx(ctx, s.pool, func(tx pgx.Tx) error {
+		var token *domain.EnrollmentToken
+		if req.EnrollmentToken != "" {
+			token, err = s.auth.Enroll(ctx, tx, req.EnrollmentToken)
+			if err != nil {
+				return err
 			}
 		}
 
-		// Same proxy re-registering (recovery) or offline proxy — update IP and mark online
-		_ = s.proxies.UpdateRegisteredIP(ctx, existing.ID, req.RemoteIP)
-		_ = s.proxies.UpdateLastSeen(ctx, existing.ID)
-
-		configID := ""
-		if existing.ConfigID != nil {
-			configID = existing.ConfigID.String()
+		txProxies := repository.NewProxyRepo(tx)
+		if proxy != nil {
+			// Same proxy re-registering (recovery) or offline proxy — update IP and mark online
+			if err := txProxies.UpdateRegisteredIP(ctx, proxy.ID, req.RemoteIP); err != nil {
+				return err
+			}
+			if err := txProxies.SetHelperLabels(ctx, proxy.ID, req.Labels); err != nil {
+				return fmt.Errorf("set helper labels: %w", err)
+			}
+		} else {
+			// New proxy — create it
+			proxy = &domain.Proxy{
+				Hostname:     req.Hostname,
+				RegisteredIP: &req.RemoteIP,
+				HelperLabels: req.Labels,
+			}
+			if req.ConfigID != "" {
+				if cfgID, err := uuid.Parse(req.ConfigID); err == nil {
+					proxy.ConfigID = &cfgID
+				}
+			}
+			if err := txProxies.Create(ctx, proxy); err != nil {
+				return fmt.Errorf("register proxy: %w", err)
+			}
 		}
 
-		return &RegisterResponse{
-			ProxyID:  existing.ID.String(),
-			ConfigID: configID,
-			Status:   "registered",
-		}, nil
+		if token == nil {
+			return nil
+		}
+		if err := applyEnrollmentScope(ctx, tx, token, proxy); err != nil {
+			return err
+		}
+		secret, err = s.auth.Issue(ctx, tx, proxy.ID, &token.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// New proxy — create it
-	proxy := &domain.Proxy{
-		Hostname:     req.Hostname,
-		RegisteredIP: &req.RemoteIP,
-	}
+	_ = s.proxies.UpdateLastSeen(ctx, proxy.ID)
+	s.recordClientCert(ctx, proxy.ID, req.ClientCertFingerprint)
+
+	return registered(proxy, secret), nil
+}
 
-	if req.ConfigID != "" {
-		if cfgID, err := uuid.Parse(req.ConfigID); err == nil {
-			proxy.ConfigID = &cfgID
+// applyEnrollmentScope assigns the token's config to the proxy, replacing its
+// direct assignments, and adds the selector of the token's group to the
+// proxy's labels so the proxy joins the group.
+func applyEnrollmentScope(ctx context.Context, tx pgx.Tx, token *domain.EnrollmentToken, proxy *domain.Proxy) error {
+	if token.ConfigID != nil {
+		configProxies := repository.NewConfigProxyRepo(tx)
+		if err := configProxies.DeleteByProxy(ctx, proxy.ID); err != nil {
+			return fmt.Errorf("remove old associations: %w", err)
 		}
+		if err := configProxies.Assign(ctx, *token.ConfigID, proxy.ID, token.CreatedBy); err != nil {
+			return err
+		}
+		proxy.ConfigID = token.ConfigID
 	}
 
-	if err := s.proxies.Create(ctx, proxy); err != nil {
-		return nil, fmt.Errorf("register proxy: %w", err)
+	if token.GroupID != nil {
+		group, err := repository.NewProxyGroupRepo(tx).GetByID(ctx, *token.GroupID)
+		if err != nil {
+			return fmt.Errorf("get enrollment group: %w", err)
+		}
+		if len(group.Selector) > 0 {
+			if err := repository.NewProxyRepo(tx).MergeLabels(ctx, proxy.ID, group.Selector); err != nil {
+				return fmt.Errorf("merge proxy labels: %w", err)
+			}
+		}
 	}
+	return nil
+}
 
-	_ = s.proxies.UpdateLastSeen(ctx, proxy.ID)
+func (s *SyncService) recordClientCert(ctx context.Context, proxyID uuid.UUID, fingerprint string) {
+	if fingerprint != "" {
+		_ = s.proxies.UpdateClientCertFingerprint(ctx, proxyID, fingerprint)
+	}
+}
 
+func registered(proxy *domain.Proxy, secret string) *RegisterResponse {
 	configID := ""
 	if proxy.ConfigID != nil {
 		configID = proxy.ConfigID.String()
 	}
 
 	return &RegisterResponse{
-		ProxyID:  proxy.ID.String(),
-		ConfigID: configID,
-		Status:   "registered",
-	}, nil
+		ProxyID:     proxy.ID.String(),
+		ConfigID:    configID,
+		Status:      "registered",
+		ProxySecret: secret,
+	}
 }
 
 // ConfigResponse mirrors helper's ConfigResponse
@@ -131,12 +273,68 @@ type ConfigResponse struct {
 	Config       *ConfigFiles `json:"config,omitempty"`
 	CaptureLogs  bool         `json:"capture_logs"`
 	CaptureUntil *time.Time   `json:"capture_until,omitempty"`
+	// DebugTags are the ATS debug tags to enable while capturing logs.
+	DebugTags []string `json:"debug_tags,omitempty"`
+	// RotateSecret is the new sync secret the helper must switch to.
+	RotateSecret string `json:"rotate_secret,omitempty"`
+	// Signature is the base64 Ed25519 signature of the bundle (see BundleMessage).
+	Signature string `json:"signature,omitempty"`
+	KeyID     string `json:"key_id,omitempty"`
+	// Commands are queued remote commands, sent on every sync until the
+	// helper reports them received.
+	Commands []SyncCommand `json:"commands,omitempty"`
 }
 
 type ConfigFiles struct {
 	ParentConfig string `json:"parent_config"`
 	SNIYaml      string `json:"sni_yaml"`
 	IPAllowYaml  string `json:"ip_allow_yaml,omitempty"`
+	// Files carries the additional managed files (records.yaml, ...) keyed by
+	// their name on the proxy. Only present when the config manages any.
+	Files map[string]string `json:"files,omitempty"`
+	// ConnectPorts is the config's CONNECT port list, set by the helper with
+	// traffic_ctl instead of a file. Empty keeps the proxy's own.
+	ConnectPorts string `json:"connect_ports,omitempty"`
+	// Overlays names the config overlays merged into the files ("proxy:<hostname>"
+	// or "group:<name>"), in merge order. Informational; not hashed or signed.
+	Overlays []string `json:"overlays,omitempty"`
+}
+
+// Named returns the files keyed by their name on the proxy, as signed.
+func (f *ConfigFiles) Named() map[string]string {
+	named := map[string]string{
+		"parent.config": f.ParentConfig,
+		"sni.yaml":      f.SNIYaml,
+		"ip_allow.yaml": f.IPAllowYaml,
+	}
+	for name, content := range f.Files {
+		named[name] = content
+	}
+	return named
+}
+
+// Hash is the SHA256 of parent.config + sni.yaml + ip_allow.yaml followed by
+// each additional file, sorted by name, as "name\nlen\ncontent", and the
+// CONNECT ports in the same form when set. Without additional files or ports
+// it is the hash of the three base files, as before.
+func (f *ConfigFiles) Hash() string {
+	h := sha256.New()
+	h.Write([]byte(f.ParentConfig))
+	h.Write([]byte(f.SNIYaml))
+	h.Write([]byte(f.IPAllowYaml))
+
+	names := make([]string, 0, len(f.Files))
+	for name := range f.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\n%d\n%s", name, len(f.Files[name]), f.Files[name])
+	}
+	if f.ConnectPorts != "" {
+		fmt.Fprintf(h, "connect_ports\n%d\n%s", len(f.ConnectPorts), f.ConnectPorts)
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 func (s *SyncService) GetConfig(ctx context.Context, hostname, currentHash string) (*ConfigResponse, error) {
@@ -148,69 +346,171 @@ func (s *SyncService) GetConfig(ctx context.Context, hostname, currentHash strin
 	// Update last_seen
 	_ = s.proxies.UpdateLastSeen(ctx, proxy.ID)
 
+	resp, err := s.configFor(ctx, proxy, currentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Commands, err = s.commands.Pending(ctx, proxy.ID)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// configFor builds the config part of the sync response for a proxy.
+func (s *SyncService) configFor(ctx context.Context, proxy *domain.Proxy, currentHash string) (*ConfigResponse, error) {
+	hostname := proxy.Hostname
+
 	// Find active config for this proxy
 	cfg, err := s.configs.GetActiveForProxy(ctx, hostname)
 	if err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
 			// No active config, return unchanged with capture_logs info
 			resp := &ConfigResponse{Unchanged: true}
-			if proxy.CaptureLogsUntil != nil && proxy.CaptureLogsUntil.After(time.Now()) {
-				resp.CaptureLogs = true
-				resp.CaptureUntil = proxy.CaptureLogsUntil
-			}
+			resp.setCapture(proxy)
 			return resp, nil
 		}
 		return nil, err
 	}
 
-	// Check capture_logs
-	captureLogs := false
-	var captureUntil *time.Time
-	if proxy.CaptureLogsUntil != nil && proxy.CaptureLogsUntil.After(time.Now()) {
-		captureLogs = true
-		captureUntil = proxy.CaptureLogsUntil
-	}
-
 	// Check if config has changed
 	configHash := ""
 	if cfg.ConfigHash != nil {
 		configHash = *cfg.ConfigHash
 	}
 
-	if configHash != "" && configHash == currentHash {
-		return &ConfigResponse{
-			Unchanged:    true,
-			CaptureLogs:  captureLogs,
-			CaptureUntil: captureUntil,
-		}, nil
+	// Overlays make the files, and so the hash, specific to the proxy: those
+	// are generated on every sync instead of trusting the config's hash
+	overlaid, err := s.configSvc.HasOverlays(ctx, cfg.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !overlaid && configHash != "" && configHash == currentHash {
+		resp := &ConfigResponse{Unchanged: true}
+		resp.setCapture(proxy)
+		return resp, nil
 	}
 
 	// Generate config files
-	parentConfig, sniYaml, ipAllowYaml, err := s.configSvc.GenerateConfigFiles(ctx, cfg.ID)
+	files, err := s.configSvc.GenerateConfigFiles(ctx, cfg.ID, hostname)
 	if err != nil {
 		return nil, fmt.Errorf("generate config files: %w", err)
 	}
 
-	// If hash was empty, compute it now
-	if configHash == "" {
-		configHash, err = s.configSvc.GenerateConfigHash(ctx, cfg.ID)
-		if err != nil {
-			return nil, err
-		}
+	if len(files.Overlays) > 0 {
+		configHash = files.Hash()
+	} else if configHash == "" {
+		// If hash was empty, compute it now
+		configHash = files.Hash()
 		_ = s.configs.UpdateHash(ctx, cfg.ID, configHash)
 	}
+	if overlaid {
+		s.setTargetHash(ctx, proxy, cfg.ID, configHash, len(files.Overlays) > 0)
+		if configHash == currentHash {
+			resp := &ConfigResponse{Unchanged: true}
+			resp.setCapture(proxy)
+			return resp, nil
+		}
+	}
 
-	return &ConfigResponse{
+	signature, keyID, err := s.signer.Sign(ctx, configHash, files.Named(), files.ConnectPorts)
+	if err != nil {
+		return nil, fmt.Errorf("sign config bundle: %w", err)
+	}
+
+	resp := &ConfigResponse{
 		Unchanged: false,
 		Hash:      configHash,
-		Config: &ConfigFiles{
-			ParentConfig: parentConfig,
-			SNIYaml:      sniYaml,
-			IPAllowYaml:  ipAllowYaml,
-		},
-		CaptureLogs:  captureLogs,
-		CaptureUntil: captureUntil,
-	}, nil
+		Config:    files,
+		Signature: signature,
+		KeyID:     keyID,
+	}
+	resp.setCapture(proxy)
+	return resp, nil
+}
+
+// setTargetHash records the hash a proxy is expected to run when overlays
+// apply to it, so its sync status is checked against the merged files.
+func (s *SyncService) setTargetHash(ctx context.Context, proxy *domain.Proxy, configID uuid.UUID, hash string, applies bool) {
+	if !applies {
+		if proxy.TargetConfigID != nil {
+			_ = s.proxies.SetTargetHash(ctx, proxy.ID, nil, nil)
+		}
+		return
+	}
+	if proxy.TargetConfigID != nil && *proxy.TargetConfigID == configID &&
+		proxy.TargetConfigHash != nil && *proxy.TargetConfigHash == hash {
+		return
+	}
+	_ = s.proxies.SetTargetHash(ctx, proxy.ID, &configID, &hash)
+}
+
+// setCapture fills in the log capture requested for the proxy, if still active.
+func (r *ConfigResponse) setCapture(proxy *domain.Proxy) {
+	if proxy.CaptureLogsUntil == nil || !proxy.CaptureLogsUntil.After(time.Now()) {
+		return
+	}
+	r.CaptureLogs = true
+	r.CaptureUntil = proxy.CaptureLogsUntil
+	r.DebugTags = proxy.CaptureDebugTags
+	if len(r.DebugTags) == 0 {
+		r.DebugTags = DefaultDebugTags
+	}
+}
+
+// SigningKey returns the public key helpers verify config bundles with.
+func (s *SyncService) SigningKey(ctx context.Context) (*domain.SigningKey, error) {
+	return s.signer.PublicKey(ctx)
+}
+
+// MaxSyncWait caps how long a long-poll GET /sync is held.
+const MaxSyncWait = 60 * time.Second
+
+// WaitForConfig is the long-poll form of GetConfig. While the config is
+// unchanged the request is held for up to wait, returning early when a config
+// is activated, a command is queued or something else changes for this proxy.
+// received are the commands the helper got in its previous sync; they are
+// marked delivered first so they are not sent again.
+func (s *SyncService) WaitForConfig(ctx context.Context, hostname, currentHash string, received []uuid.UUID, wait time.Duration) (*ConfigResponse, error) {
+	proxy, err := s.proxies.GetByHostname(ctx, hostname)
+	if err != nil {
+		return nil, fmt.Errorf("proxy not found: %w", err)
+	}
+	if err := s.commands.Received(ctx, proxy.ID, received); err != nil {
+		return nil, err
+	}
+
+	if wait <= 0 {
+		return s.GetConfig(ctx, hostname, currentHash)
+	}
+	if wait > MaxSyncWait {
+		wait = MaxSyncWait
+	}
+
+	// Subscribe before the first check so an activation in between is not missed
+	waiter := s.pushes.subscribe(proxy.ID)
+	defer s.pushes.unsubscribe(waiter)
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		resp, err := s.GetConfig(ctx, hostname, currentHash)
+		if err != nil || !resp.Unchanged || len(resp.Commands) > 0 {
+			return resp, err
+		}
+
+		wake, ok := waiter.wait(ctx, timer)
+		if !ok {
+			return resp, nil
+		}
+		if wake.proxySpecific {
+			// Capture, assignment or credentials changed: answer with the fresh state
+			return s.GetConfig(ctx, hostname, currentHash)
+		}
+	}
 }
 
 // AckRequest mirrors helper's AckRequest
@@ -233,6 +533,21 @@ func (s *SyncService) Ack(ctx context.Context, req AckRequest) error {
 		}
 	}
 
+	// Keep the last result so failed applies can be alerted on
+	if err := s.proxies.UpdateAckResult(ctx, proxy.ID, req.Status, req.Message); err != nil {
+		return err
+	}
+
+	if req.Status == "error" {
+		s.events.Publish(ctx, domain.EventDeployFailed, map[string]interface{}{
+			"proxy_id": proxy.ID.String(),
+			"hostname": proxy.Hostname,
+			"hash":     req.Hash,
+			"message":  req.Message,
+		})
+		s.notifier.DeployFailed(proxy, req.Hash, req.Message)
+	}
+
 	return nil
 }
 
@@ -241,6 +556,14 @@ type SyncStatsRequest struct {
 	Hostname  string       `json:"hostname"`
 	Timestamp time.Time    `json:"timestamp"`
 	Metrics   SyncMetrics  `json:"metrics"`
+	Parents   []SyncParent `json:"parents,omitempty"`
+}
+
+// SyncParent is the reachability of one parent proxy as probed by the helper.
+type SyncParent struct {
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+	Up      bool   `json:"up"`
 }
 
 type SyncMetrics struct {
@@ -291,7 +614,80 @@ func (s *SyncService) Stats(ctx context.Context, req SyncStatsRequest) error {
 		BytesOut:          req.Metrics.BytesOut,
 	}
 
-	return s.proxyStats.Create(ctx, stat)
+	prev, err := s.proxyStats.GetLatest(ctx, proxy.ID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+	stat.Delta = computeStatDelta(prev, stat, time.Now())
+
+	if err := s.proxyStats.Create(ctx, stat); err != nil {
+		return err
+	}
+
+	// Older helpers do not report parents; leave the stored status alone
+	if req.Parents == nil {
+		return nil
+	}
+	parents := make([]domain.ProxyParentStatus, 0, len(req.Parents))
+	for _, p := range req.Parents {
+		parents = append(parents, domain.ProxyParentStatus{Address: p.Address, Port: p.Port, IsUp: p.Up})
+	}
+	return s.parentStatus.Replace(ctx, proxy.ID, parents)
+}
+
+// computeStatDelta derives per-interval deltas from two cumulative samples.
+// Without a previous sample there is no baseline and all deltas are zero.
+// If any counter went backwards ATS was restarted, so the whole sample is
+// treated as a reset and the current raw values become the deltas.
+func computeStatDelta(prev, cur *domain.ProxyStat, now time.Time) domain.ProxyStatDelta {
+	if prev == nil {
+		return domain.ProxyStatDelta{}
+	}
+
+	type pair struct {
+		prev, cur int64
+		out       *int64
+	}
+
+	var d domain.ProxyStatDelta
+	counters := []pair{
+		{prev.TotalConnections, cur.TotalConnections, &d.TotalConnections},
+		{prev.CacheHits, cur.CacheHits, &d.CacheHits},
+		{prev.CacheMisses, cur.CacheMisses, &d.CacheMisses},
+		{int64(prev.Errors), int64(cur.Errors), &d.Errors},
+		{prev.TotalRequests, cur.TotalRequests, &d.TotalRequests},
+		{prev.ConnectRequests, cur.ConnectRequests, &d.ConnectRequests},
+		{prev.Responses2xx, cur.Responses2xx, &d.Responses2xx},
+		{prev.Responses3xx, cur.Responses3xx, &d.Responses3xx},
+		{prev.Responses4xx, cur.Responses4xx, &d.Responses4xx},
+		{prev.Responses5xx, cur.Responses5xx, &d.Responses5xx},
+		{int64(prev.ErrConnectFail), int64(cur.ErrConnectFail), &d.ErrConnectFail},
+		{int64(prev.ErrClientAbort), int64(cur.ErrClientAbort), &d.ErrClientAbort},
+		{int64(prev.BrokenServerConns), int64(cur.BrokenServerConns), &d.BrokenServerConns},
+		{prev.BytesIn, cur.BytesIn, &d.BytesIn},
+		{prev.BytesOut, cur.BytesOut, &d.BytesOut},
+	}
+
+	for _, c := range counters {
+		if c.cur < c.prev {
+			d.CounterReset = true
+			break
+		}
+	}
+
+	for _, c := range counters {
+		if d.CounterReset {
+			*c.out = c.cur
+		} else {
+			*c.out = c.cur - c.prev
+		}
+	}
+
+	if elapsed := now.Sub(prev.CollectedAt).Seconds(); elapsed > 0 {
+		d.IntervalSeconds = elapsed
+	}
+
+	return d
 }
 
 // LogsRequest mirrors helper's LogsRequest
@@ -303,6 +699,7 @@ type SyncLogsRequest struct {
 type SyncLogLine struct {
 	Timestamp time.Time `json:"timestamp"`
 	Level     string    `json:"level"`
+	Tag       string    `json:"tag,omitempty"`
 	Message   string    `json:"message"`
 }
 
@@ -317,16 +714,25 @@ func (s *SyncService) Logs(ctx context.Context, req SyncLogsRequest) (*LogsRespo
 		return nil, fmt.Errorf("proxy not found: %w", err)
 	}
 
+	stored := make([]domain.ProxyLog, 0, len(req.Lines))
 	for _, line := range req.Lines {
 		level := line.Level
 		msg := line.Message
 		log := domain.ProxyLog{
-			ProxyID:  proxy.ID,
-			LogLevel: &level,
-			Message:  &msg,
+			ProxyID:    proxy.ID,
+			CapturedAt: line.Timestamp,
+			LogLevel:   &level,
+			Message:    &msg,
+		}
+		if line.Tag != "" {
+			tag := truncate(line.Tag, 64)
+			log.Tag = &tag
+		}
+		if err := s.proxyLogs.Create(ctx, &log); err == nil {
+			stored = append(stored, log)
 		}
-		_ = s.proxyLogs.Create(ctx, &log)
 	}
+	s.logStream.Publish(ctx, proxy.ID, stored)
 
 	continueCapture := false
 	if proxy.CaptureLogsUntil != nil && proxy.CaptureLogsUntil.After(time.Now()) {
@@ -338,3 +744,96 @@ func (s *SyncService) Logs(ctx context.Context, req SyncLogsRequest) (*LogsRespo
 		ContinueCapture: continueCapture,
 	}, nil
 }
+
+// SyncDriftRequest is the helper's comparison of the files on disk against the
+// bundle it last applied.
+type SyncDriftRequest struct {
+	Hostname     string   `json:"hostname"`
+	ExpectedHash string   `json:"expected_hash"`
+	ActualHash   string   `json:"actual_hash"`
+	Drifted      bool     `json:"drifted"`
+	Files        []string `json:"files,omitempty"`
+	Remediated   bool     `json:"remediated,omitempty"`
+}
+
+func (s *SyncService) Drift(ctx context.Context, req SyncDriftRequest) (*domain.ProxyDrift, error) {
+	proxy, err := s.proxies.GetByHostname(ctx, req.Hostname)
+	if err != nil {
+		return nil, fmt.Errorf("proxy not found: %w", err)
+	}
+	if len(req.ExpectedHash) > 64 || len(req.ActualHash) > 64 {
+		return nil, fmt.Errorf("%w: hashes must be at most 64 characters", domain.ErrBadRequest)
+	}
+
+	_ = s.proxies.UpdateLastSeen(ctx, proxy.ID)
+
+	d := &domain.ProxyDrift{
+		ProxyID:    proxy.ID,
+		Drifted:    req.Drifted,
+		Files:      req.Files,
+		Remediated: req.Remediated,
+	}
+	if req.ExpectedHash != "" {
+		d.ExpectedHash = &req.ExpectedHash
+	}
+	if req.ActualHash != "" {
+		d.ActualHash = &req.ActualHash
+	}
+	if err := s.drift.Upsert(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// CommandResultRequest mirrors helper's CommandResult
+type CommandResultRequest struct {
+	Hostname string               `json:"hostname"`
+	ID       string               `json:"id"`
+	Status   domain.CommandStatus `json:"status"`
+	Output   string               `json:"output,omitempty"`
+	Error    string               `json:"error,omitempty"`
+}
+
+func (s *SyncService) CommandResult(ctx context.Context, req CommandResultRequest) (*domain.ProxyCommand, error) {
+	proxy, err := s.proxies.GetByHostname(ctx, req.Hostname)
+	if err != nil {
+		return nil, fmt.Errorf("proxy not found: %w", err)
+	}
+	id, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid command id", domain.ErrBadRequest)
+	}
+
+	_ = s.proxies.UpdateLastSeen(ctx, proxy.ID)
+
+	return s.commands.Result(ctx, proxy.ID, id, req.Status, req.Output, req.Error)
+}
+
+// Diagnostics stores a diagnostics bundle uploaded by the helper.
+func (s *SyncService) Diagnostics(ctx context.Context, hostname, commandID string, data []byte) (*domain.DiagnosticsBundle, error) {
+	proxy, err := s.proxies.GetByHostname(ctx, hostname)
+	if err != nil {
+		return nil, fmt.Errorf("proxy not found: %w", err)
+	}
+
+	_ = s.proxies.UpdateLastSeen(ctx, proxy.ID)
+
+	return s.diagnostics.Store(ctx, proxy.ID, commandID, data)
+}
+
+// SyncAccessLogsRequest mirrors helper's AccessLogsRequest
+type SyncAccessLogsRequest struct {
+	Hostname string           `json:"hostname"`
+	Entries  []AccessLogEntry `json:"entries"`
+}
+
+func (s *SyncService) AccessLogs(ctx context.Context, req SyncAccessLogsRequest) (*IngestResult, error) {
+	proxy, err := s.proxies.GetByHostname(ctx, req.Hostname)
+	if err != nil {
+		return nil, fmt.Errorf("proxy not found: %w", err)
+	}
+
+	_ = s.proxies.UpdateLastSeen(ctx, proxy.ID)
+
+	return s.accessLogs.Ingest(ctx, proxy.ID, req.Entries)
+}
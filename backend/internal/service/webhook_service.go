@@ -0,0 +1,335 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ats-proxy/proxy-manager/backend/internal/domain"
+	"github.com/ats-proxy/proxy-manager/backend/internal/repository"
+)
+
+const (
+	// webhookMaxAttempts is how many times a delivery is tried before it is marked failed.
+	webhookMaxAttempts = 6
+	// webhookBaseBackoff is the delay before the first retry; it doubles on each attempt.
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = time.Hour
+	// webhookLease keeps a claimed delivery away from other workers while it is being sent.
+	webhookLease = 2 * time.Minute
+)
+
+type WebhookService struct {
+	webhooks   *repository.WebhookRepo
+	deliveries *repository.WebhookDeliveryRepo
+	audit      *repository.AuditRepo
+	httpClient *http.Client
+}
+
+func NewWebhookService(webhooks *repository.WebhookRepo, deliveries *repository.WebhookDeliveryRepo, audit *repository.AuditRepo) *WebhookService {
+	return &WebhookService{
+		webhooks:   webhooks,
+		deliveries: deliveries,
+		audit:      audit,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ========== Publishing ==========
+
+// Publish queues a delivery of the event to every enabled webhook subscribed to
+// it. Failures are logged and never block the caller.
+func (s *WebhookService) Publish(ctx context.Context, event domain.WebhookEvent, data interface{}) {
+	hooks, err := s.webhooks.ListSubscribed(ctx, event)
+	if err != nil {
+		log.Printf("Webhook publish %s: %v", event, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Webhook publish %s: marshal payload: %v", event, err)
+		return
+	}
+
+	for _, h := range hooks {
+		d := &domain.WebhookDelivery{WebhookID: h.ID, Event: event, Payload: payload}
+		if err := s.deliveries.Create(ctx, d); err != nil {
+			log.Printf("Webhook publish %s to %s: %v", event, h.Name, err)
+		}
+	}
+}
+
+// ========== Delivery ==========
+
+// webhookEnvelope is the JSON body POSTed to webhook endpoints.
+type webhookEnvelope struct {
+	ID        string              `json:"id"`
+	Event     domain.WebhookEvent `json:"event"`
+	CreatedAt time.Time           `json:"created_at"`
+	Data      json.RawMessage     `json:"data"`
+}
+
+// DeliverDue sends pending deliveries whose next attempt is due. Non-2xx
+// responses and transport errors are retried with exponential backoff.
+func (s *WebhookService) DeliverDue(ctx context.Context, limit int) error {
+	due, err := s.deliveries.ClaimDue(ctx, limit, webhookLease)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range due {
+		hook, err := s.webhooks.GetByID(ctx, d.WebhookID)
+		if err != nil {
+			log.Printf("Webhook delivery %s: %v", d.ID, err)
+			continue
+		}
+
+		code, sendErr := s.send(ctx, hook, d)
+
+		attempt := d.Attempts + 1
+		status := domain.DeliverySuccess
+		var lastErr *string
+		if sendErr != nil {
+			msg := sendErr.Error()
+			lastErr = &msg
+			status = domain.DeliveryPending
+			if attempt >= webhookMaxAttempts || !hook.Enabled {
+				status = domain.DeliveryFailed
+			}
+		}
+
+		if err := s.deliveries.RecordAttempt(ctx, d.ID, status, code, lastErr, time.Now().Add(webhookBackoff(attempt))); err != nil {
+			log.Printf("Webhook delivery %s: %v", d.ID, err)
+		}
+		if status == domain.DeliveryFailed {
+			log.Printf("Webhook delivery %s to %s failed after %d attempts: %v", d.Event, hook.Name, attempt, sendErr)
+		}
+	}
+	return nil
+}
+
+// webhookBackoff returns the wait before the attempt following the given one.
+func webhookBackoff(attempt int) time.Duration {
+	d := webhookBaseBackoff << (attempt - 1)
+	if d <= 0 || d > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return d
+}
+
+func (s *WebhookService) send(ctx context.Context, hook *domain.Webhook, d domain.WebhookDelivery) (*int, error) {
+	body, err := json.Marshal(webhookEnvelope{
+		ID:        d.ID.String(),
+		Event:     d.Event,
+		CreatedAt: d.CreatedAt,
+		Data:      d.Payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ats-proxy-manager-webhook/1.0")
+	req.Header.Set("X-Webhook-Event", string(d.Event))
+	req.Header.Set("X-Webhook-Delivery", d.ID.String())
+	req.Header.Set("X-Webhook-Timestamp", ts)
+	req.Header.Set("X-Webhook-Signature", "sha256="+signWebhook(hook.Secret, ts, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	code := resp.StatusCode
+	if code < 200 || code >= 300 {
+		return &code, fmt.Errorf("endpoint returned HTTP %d", code)
+	}
+	return &code, nil
+}
+
+// signWebhook computes HMAC-SHA256 over "<timestamp>.<body>" so receivers can
+// verify the sender and reject replays.
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ========== Management ==========
+
+func (s *WebhookService) List(ctx context.Context) ([]domain.Webhook, error) {
+	hooks, err := s.webhooks.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if hooks == nil {
+		hooks = []domain.Webhook{}
+	}
+	return hooks, nil
+}
+
+type WebhookRequest struct {
+	Name    string                `json:"name"`
+	URL     string                `json:"url"`
+	Secret  string                `json:"secret,omitempty"`
+	Events  []domain.WebhookEvent `json:"events"`
+	Enabled *bool                 `json:"enabled,omitempty"`
+}
+
+// WebhookResponse includes the signing secret; it is only returned on create.
+type WebhookResponse struct {
+	domain.Webhook
+	Secret string `json:"secret,omitempty"`
+}
+
+func validateWebhook(w *domain.Webhook) error {
+	if w.Name == "" {
+		return fmt.Errorf("%w: name is required", domain.ErrBadRequest)
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", domain.ErrBadRequest)
+	}
+	if len(w.Events) == 0 {
+		return fmt.Errorf("%w: at least one event is required", domain.ErrBadRequest)
+	}
+	for _, e := range w.Events {
+		if !e.IsValid() {
+			return fmt.Errorf("%w: unknown event '%s'", domain.ErrBadRequest, e)
+		}
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *WebhookService) Create(ctx context.Context, req WebhookRequest, userID uuid.UUID, ip, ua string) (*WebhookResponse, error) {
+	hook := &domain.Webhook{
+		Name:      req.Name,
+		URL:       req.URL,
+		Secret:    req.Secret,
+		Events:    req.Events,
+		Enabled:   true,
+		CreatedBy: &userID,
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	if err := validateWebhook(hook); err != nil {
+		return nil, err
+	}
+	if hook.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		hook.Secret = secret
+	}
+
+	if err := s.webhooks.Create(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	s.logAudit(ctx, &userID, "webhook.create", &hook.ID, nil, jsonVal("url", hook.URL), ip, ua)
+	return &WebhookResponse{Webhook: *hook, Secret: hook.Secret}, nil
+}
+
+func (s *WebhookService) Update(ctx context.Context, id uuid.UUID, req WebhookRequest, userID uuid.UUID, ip, ua string) (*WebhookResponse, error) {
+	hook, err := s.webhooks.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	oldURL := hook.URL
+
+	if req.Name != "" {
+		hook.Name = req.Name
+	}
+	if req.URL != "" {
+		hook.URL = req.URL
+	}
+	if req.Secret != "" {
+		hook.Secret = req.Secret
+	}
+	if req.Events != nil {
+		hook.Events = req.Events
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	if err := validateWebhook(hook); err != nil {
+		return nil, err
+	}
+
+	if err := s.webhooks.Update(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	s.logAudit(ctx, &userID, "webhook.update", &id, jsonVal("url", oldURL), jsonVal("url", hook.URL), ip, ua)
+	return &WebhookResponse{Webhook: *hook}, nil
+}
+
+func (s *WebhookService) Delete(ctx context.Context, id, userID uuid.UUID, ip, ua string) error {
+	hook, err := s.webhooks.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.webhooks.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.logAudit(ctx, &userID, "webhook.delete", &id, jsonVal("url", hook.URL), nil, ip, ua)
+	return nil
+}
+
+func (s *WebhookService) Deliveries(ctx context.Context, webhookID uuid.UUID, page, limit int) ([]domain.WebhookDelivery, int, error) {
+	if _, err := s.webhooks.GetByID(ctx, webhookID); err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * limit
+	return s.deliveries.ListByWebhook(ctx, webhookID, limit, offset)
+}
+
+func (s *WebhookService) logAudit(ctx context.Context, userID *uuid.UUID, action string, entityID *uuid.UUID, oldVal, newVal []byte, ip, ua string) {
+	_ = s.audit.Create(ctx, &domain.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityType: "webhook",
+		EntityID:   entityID,
+		OldValue:   oldVal,
+		NewValue:   newVal,
+		IPAddress:  &ip,
+		UserAgent:  &ua,
+	})
+}
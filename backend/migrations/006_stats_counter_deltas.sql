@@ -0,0 +1,19 @@
+-- Migration 006: Store per-interval deltas alongside the cumulative ATS counters
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS interval_seconds DOUBLE PRECISION DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS counter_reset BOOLEAN DEFAULT FALSE;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS total_connections_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS cache_hits_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS cache_misses_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS errors_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS total_requests_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS connect_requests_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS responses_2xx_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS responses_3xx_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS responses_4xx_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS responses_5xx_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS err_connect_fail_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS err_client_abort_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS broken_server_conns_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS bytes_in_delta BIGINT DEFAULT 0;
+ALTER TABLE proxy_stats ADD COLUMN IF NOT EXISTS bytes_out_delta BIGINT DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_proxy_stats_proxy_time ON proxy_stats(proxy_id, collected_at DESC);
@@ -0,0 +1,78 @@
+-- Migration 007: Rollup tables for long-term stats retention (5 minutes, hourly, daily)
+CREATE TABLE IF NOT EXISTS proxy_stats_5m (
+    proxy_id UUID NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    samples INTEGER NOT NULL DEFAULT 0,
+    active_connections_max INTEGER DEFAULT 0,
+    active_connections_avg DOUBLE PRECISION DEFAULT 0,
+    interval_seconds DOUBLE PRECISION DEFAULT 0,
+    total_connections BIGINT DEFAULT 0,
+    cache_hits BIGINT DEFAULT 0,
+    cache_misses BIGINT DEFAULT 0,
+    errors BIGINT DEFAULT 0,
+    total_requests BIGINT DEFAULT 0,
+    connect_requests BIGINT DEFAULT 0,
+    responses_2xx BIGINT DEFAULT 0,
+    responses_3xx BIGINT DEFAULT 0,
+    responses_4xx BIGINT DEFAULT 0,
+    responses_5xx BIGINT DEFAULT 0,
+    err_connect_fail BIGINT DEFAULT 0,
+    err_client_abort BIGINT DEFAULT 0,
+    broken_server_conns BIGINT DEFAULT 0,
+    bytes_in BIGINT DEFAULT 0,
+    bytes_out BIGINT DEFAULT 0,
+    PRIMARY KEY (proxy_id, bucket)
+);
+CREATE INDEX IF NOT EXISTS idx_proxy_stats_5m_bucket ON proxy_stats_5m(bucket DESC);
+
+CREATE TABLE IF NOT EXISTS proxy_stats_1h (
+    proxy_id UUID NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    samples INTEGER NOT NULL DEFAULT 0,
+    active_connections_max INTEGER DEFAULT 0,
+    active_connections_avg DOUBLE PRECISION DEFAULT 0,
+    interval_seconds DOUBLE PRECISION DEFAULT 0,
+    total_connections BIGINT DEFAULT 0,
+    cache_hits BIGINT DEFAULT 0,
+    cache_misses BIGINT DEFAULT 0,
+    errors BIGINT DEFAULT 0,
+    total_requests BIGINT DEFAULT 0,
+    connect_requests BIGINT DEFAULT 0,
+    responses_2xx BIGINT DEFAULT 0,
+    responses_3xx BIGINT DEFAULT 0,
+    responses_4xx BIGINT DEFAULT 0,
+    responses_5xx BIGINT DEFAULT 0,
+    err_connect_fail BIGINT DEFAULT 0,
+    err_client_abort BIGINT DEFAULT 0,
+    broken_server_conns BIGINT DEFAULT 0,
+    bytes_in BIGINT DEFAULT 0,
+    bytes_out BIGINT DEFAULT 0,
+    PRIMARY KEY (proxy_id, bucket)
+);
+CREATE INDEX IF NOT EXISTS idx_proxy_stats_1h_bucket ON proxy_stats_1h(bucket DESC);
+
+CREATE TABLE IF NOT EXISTS proxy_stats_1d (
+    proxy_id UUID NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    samples INTEGER NOT NULL DEFAULT 0,
+    active_connections_max INTEGER DEFAULT 0,
+    active_connections_avg DOUBLE PRECISION DEFAULT 0,
+    interval_seconds DOUBLE PRECISION DEFAULT 0,
+    total_connections BIGINT DEFAULT 0,
+    cache_hits BIGINT DEFAULT 0,
+    cache_misses BIGINT DEFAULT 0,
+    errors BIGINT DEFAULT 0,
+    total_requests BIGINT DEFAULT 0,
+    connect_requests BIGINT DEFAULT 0,
+    responses_2xx BIGINT DEFAULT 0,
+    responses_3xx BIGINT DEFAULT 0,
+    responses_4xx BIGINT DEFAULT 0,
+    responses_5xx BIGINT DEFAULT 0,
+    err_connect_fail BIGINT DEFAULT 0,
+    err_client_abort BIGINT DEFAULT 0,
+    broken_server_conns BIGINT DEFAULT 0,
+    bytes_in BIGINT DEFAULT 0,
+    bytes_out BIGINT DEFAULT 0,
+    PRIMARY KEY (proxy_id, bucket)
+);
+CREATE INDEX IF NOT EXISTS idx_proxy_stats_1d_bucket ON proxy_stats_1d(bucket DESC);
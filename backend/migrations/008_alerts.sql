@@ -0,0 +1,44 @@
+-- Migration 008: Alerting (rules, alert state, last ack result and parent proxy health per proxy)
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS last_ack_status VARCHAR(20);
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS last_ack_message TEXT;
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS last_ack_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS proxy_parent_status (
+    proxy_id UUID NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    address VARCHAR(255) NOT NULL,
+    port INTEGER NOT NULL,
+    is_up BOOLEAN NOT NULL DEFAULT TRUE,
+    checked_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (proxy_id, address, port)
+);
+
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(30) NOT NULL,
+    threshold DOUBLE PRECISION NOT NULL DEFAULT 0,
+    duration_minutes INTEGER NOT NULL DEFAULT 5,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS alerts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rule_id UUID NOT NULL REFERENCES alert_rules(id) ON DELETE CASCADE,
+    proxy_id UUID REFERENCES proxies(id) ON DELETE CASCADE,
+    subject VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    message TEXT,
+    value DOUBLE PRECISION,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    fired_at TIMESTAMP WITH TIME ZONE,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    last_evaluated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_alerts_status ON alerts(status);
+CREATE INDEX IF NOT EXISTS idx_alerts_started ON alerts(started_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_open
+    ON alerts(rule_id, COALESCE(proxy_id, '00000000-0000-0000-0000-000000000000'::uuid), subject)
+    WHERE status IN ('pending', 'firing');
@@ -0,0 +1,7 @@
+-- Migration 010: Per-user email notification subscriptions
+CREATE TABLE IF NOT EXISTS notification_subscriptions (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, event)
+);
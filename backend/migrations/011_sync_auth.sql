@@ -0,0 +1,24 @@
+-- Migration 011: Sync authentication (enrollment tokens and per-proxy credentials)
+CREATE TABLE IF NOT EXISTS enrollment_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    config_id UUID REFERENCES configs(id) ON DELETE CASCADE,
+    max_uses INTEGER,
+    use_count INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS proxy_credentials (
+    proxy_id UUID PRIMARY KEY REFERENCES proxies(id) ON DELETE CASCADE,
+    secret VARCHAR(128) NOT NULL,
+    next_secret VARCHAR(128),
+    enrollment_token_id UUID REFERENCES enrollment_tokens(id) ON DELETE SET NULL,
+    issued_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    rotated_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
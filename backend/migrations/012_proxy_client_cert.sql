@@ -0,0 +1,2 @@
+-- Migration 012: Client certificate fingerprint of proxies using mTLS
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS client_cert_fingerprint VARCHAR(64);
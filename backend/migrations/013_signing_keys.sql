@@ -0,0 +1,10 @@
+-- Migration 013: Ed25519 keys used to sign the config bundles served to helpers
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    algorithm VARCHAR(20) NOT NULL DEFAULT 'ed25519',
+    public_key TEXT NOT NULL,
+    private_key TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    retired_at TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_signing_keys_active ON signing_keys((retired_at IS NULL)) WHERE retired_at IS NULL;
@@ -0,0 +1,11 @@
+-- Migration 014: Drift between the files on each proxy and its applied bundle
+CREATE TABLE IF NOT EXISTS proxy_drift (
+    proxy_id UUID PRIMARY KEY REFERENCES proxies(id) ON DELETE CASCADE,
+    drifted BOOLEAN NOT NULL DEFAULT FALSE,
+    expected_hash VARCHAR(64),
+    actual_hash VARCHAR(64),
+    files TEXT[] NOT NULL DEFAULT '{}',
+    remediated BOOLEAN NOT NULL DEFAULT FALSE,
+    detected_at TIMESTAMP WITH TIME ZONE,
+    checked_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
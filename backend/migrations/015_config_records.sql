@@ -0,0 +1,10 @@
+-- Migration 015: Managed records.yaml overrides per config
+CREATE TABLE IF NOT EXISTS config_records (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    config_id UUID NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+    name VARCHAR(200) NOT NULL,
+    value TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(config_id, name)
+);
+CREATE INDEX IF NOT EXISTS idx_config_records_config ON config_records(config_id);
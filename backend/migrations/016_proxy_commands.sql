@@ -0,0 +1,18 @@
+-- Migration 016: Remote commands queued for a proxy's helper
+CREATE TABLE IF NOT EXISTS proxy_commands (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    proxy_id UUID NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    type VARCHAR(30) NOT NULL,
+    args JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    timeout_seconds INTEGER NOT NULL,
+    output TEXT,
+    error TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_proxy_commands_proxy ON proxy_commands(proxy_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_proxy_commands_pending ON proxy_commands(proxy_id) WHERE status = 'pending';
@@ -0,0 +1,13 @@
+-- Migration 017: Diagnostics bundles uploaded by helpers (tar.gz, kept until expires_at)
+CREATE TABLE IF NOT EXISTS proxy_diagnostics (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    proxy_id UUID NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    command_id UUID REFERENCES proxy_commands(id) ON DELETE SET NULL,
+    size_bytes INTEGER NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    data BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_proxy_diagnostics_proxy ON proxy_diagnostics(proxy_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_proxy_diagnostics_expires ON proxy_diagnostics(expires_at);
@@ -0,0 +1,26 @@
+-- Migration 018: Access logs shipped by helpers, partitioned by day.
+-- Daily partitions are created and dropped by the backend scheduler
+-- (ACCESS_LOG_RETENTION_DAYS); the default partition catches rows outside them.
+CREATE TABLE IF NOT EXISTS access_logs (
+    id BIGINT GENERATED ALWAYS AS IDENTITY,
+    ts TIMESTAMP WITH TIME ZONE NOT NULL,
+    proxy_id UUID NOT NULL,
+    client_ip INET NOT NULL,
+    username VARCHAR(255),
+    method VARCHAR(16) NOT NULL,
+    url TEXT NOT NULL,
+    host VARCHAR(255) NOT NULL,
+    status SMALLINT NOT NULL,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    duration_ms INTEGER,
+    route VARCHAR(32),
+    upstream VARCHAR(255),
+    user_agent VARCHAR(512)
+) PARTITION BY RANGE (ts);
+
+CREATE TABLE IF NOT EXISTS access_logs_default PARTITION OF access_logs DEFAULT;
+
+CREATE INDEX IF NOT EXISTS idx_access_logs_ts ON access_logs(ts DESC);
+CREATE INDEX IF NOT EXISTS idx_access_logs_proxy_ts ON access_logs(proxy_id, ts DESC);
+CREATE INDEX IF NOT EXISTS idx_access_logs_client_ts ON access_logs(client_ip, ts DESC);
+CREATE INDEX IF NOT EXISTS idx_access_logs_host_ts ON access_logs(host, ts DESC);
@@ -0,0 +1,3 @@
+-- Migration 019: Debug tags chosen for a log capture and tag of each captured line
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS capture_debug_tags TEXT[];
+ALTER TABLE proxy_logs ADD COLUMN IF NOT EXISTS tag VARCHAR(64);
@@ -0,0 +1,26 @@
+-- Migration 020: Proxy labels and groups selected by label, with configs assigned to groups
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_proxies_labels ON proxies USING GIN (labels);
+
+CREATE TABLE IF NOT EXISTS proxy_groups (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT,
+    selector JSONB NOT NULL DEFAULT '{}',
+    priority INTEGER NOT NULL DEFAULT 0,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS config_groups (
+    config_id UUID NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+    group_id UUID NOT NULL REFERENCES proxy_groups(id) ON DELETE CASCADE,
+    assigned_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    assigned_by UUID REFERENCES users(id),
+
+    PRIMARY KEY (config_id, group_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_config_groups_group ON config_groups(group_id);
@@ -0,0 +1,22 @@
+-- Migration 021: Per-proxy and per-group overlays merged on top of a config's rules
+CREATE TABLE IF NOT EXISTS config_overlays (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    config_id UUID NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+    hostname VARCHAR(255),
+    group_id UUID REFERENCES proxy_groups(id) ON DELETE CASCADE,
+    description TEXT,
+    domains JSONB NOT NULL DEFAULT '[]',
+    ip_ranges JSONB NOT NULL DEFAULT '[]',
+    client_acl JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT config_overlays_target CHECK ((hostname IS NULL) <> (group_id IS NULL)),
+    UNIQUE (config_id, hostname),
+    UNIQUE (config_id, group_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_config_overlays_config ON config_overlays(config_id);
+
+-- Hash of the merged files last handed to a proxy whose config has overlays for it
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS target_config_id UUID;
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS target_config_hash VARCHAR(64);
@@ -0,0 +1,33 @@
+-- Migration 022: Named, versioned rule sets referenced by configs
+CREATE TABLE IF NOT EXISTS rule_sets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS rule_set_versions (
+    rule_set_id UUID NOT NULL REFERENCES rule_sets(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    domains JSONB NOT NULL DEFAULT '[]',
+    ip_ranges JSONB NOT NULL DEFAULT '[]',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (rule_set_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS config_rule_sets (
+    config_id UUID NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+    rule_set_id UUID NOT NULL,
+    version INTEGER NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (config_id, rule_set_id),
+    FOREIGN KEY (rule_set_id, version) REFERENCES rule_set_versions(rule_set_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_config_rule_sets_rule_set ON config_rule_sets(rule_set_id);
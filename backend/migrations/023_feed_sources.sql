@@ -0,0 +1,37 @@
+-- Migration 023: External feeds that keep rule sets up to date
+CREATE TABLE IF NOT EXISTS feed_sources (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    rule_set_id UUID NOT NULL UNIQUE REFERENCES rule_sets(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,
+    format VARCHAR(50) NOT NULL,
+    action VARCHAR(20) NOT NULL DEFAULT 'direct',
+    interval_minutes INTEGER NOT NULL DEFAULT 1440,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_fetched_at TIMESTAMP WITH TIME ZONE,
+    last_status VARCHAR(20),
+    last_error TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS feed_updates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    feed_id UUID NOT NULL REFERENCES feed_sources(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'applied', 'discarded')),
+    base_version INTEGER NOT NULL,
+    domains JSONB NOT NULL DEFAULT '[]',
+    ip_ranges JSONB NOT NULL DEFAULT '[]',
+    added JSONB NOT NULL DEFAULT '[]',
+    removed JSONB NOT NULL DEFAULT '[]',
+    skipped INTEGER NOT NULL DEFAULT 0,
+    fetched_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    applied_version INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_feed_updates_feed ON feed_updates(feed_id, fetched_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_feed_updates_pending ON feed_updates(feed_id) WHERE status = 'pending';
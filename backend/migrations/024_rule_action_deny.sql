@@ -0,0 +1,2 @@
+-- Migration 024: deny action for domain and IP range rules (blocked destinations)
+ALTER TYPE rule_action ADD VALUE IF NOT EXISTS 'deny';
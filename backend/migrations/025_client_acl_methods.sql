@@ -0,0 +1,5 @@
+-- Migration 025: HTTP methods and direction (apply in/out) of client ACL rules
+ALTER TABLE client_acl_rules ADD COLUMN IF NOT EXISTS apply VARCHAR(3) NOT NULL DEFAULT 'in';
+ALTER TABLE client_acl_rules ADD COLUMN IF NOT EXISTS methods TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE client_acl_rules DROP CONSTRAINT IF EXISTS client_acl_rules_config_id_cidr_key;
+ALTER TABLE client_acl_rules ADD CONSTRAINT client_acl_rules_config_id_apply_cidr_key UNIQUE (config_id, apply, cidr);
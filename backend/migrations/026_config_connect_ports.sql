@@ -0,0 +1,2 @@
+-- Migration 026: CONNECT port allow-list per config (NULL keeps the proxy image's)
+ALTER TABLE configs ADD COLUMN IF NOT EXISTS connect_ports VARCHAR(255);
@@ -0,0 +1,2 @@
+-- Migration 027: per-domain TLS policy rendered into sni.yaml
+ALTER TABLE domain_rules ADD COLUMN IF NOT EXISTS tls_policy JSONB;
@@ -0,0 +1,6 @@
+-- Migration 028: how far each stats rollup tier has been computed, so buckets
+-- missed while the backend was down are rolled up when it comes back
+CREATE TABLE IF NOT EXISTS stats_rollup_watermarks (
+    tier VARCHAR(10) PRIMARY KEY,
+    rolled_until TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,4 @@
+-- Migration 029: enrollment tokens may also place the proxies they register
+-- in a group (the group's selector is added to the proxy's labels)
+ALTER TABLE enrollment_tokens
+    ADD COLUMN IF NOT EXISTS group_id UUID REFERENCES proxy_groups(id) ON DELETE CASCADE;
@@ -0,0 +1,4 @@
+-- Migration 030: index for the subdomain search of GET /access-logs?host=.
+-- "host LIKE '%.example.com'" cannot use an index; the search matches the
+-- reversed host by prefix instead ("moc.elpmaxe.%"), which this index serves.
+CREATE INDEX IF NOT EXISTS idx_access_logs_host_reverse ON access_logs(reverse(host) text_pattern_ops);
@@ -0,0 +1,10 @@
+-- Migration 031: labels reported by the helper (--labels) kept apart from the
+-- ones set by admins. Registering replaces helper_labels only, so a key an
+-- admin removed is not merged back. Group selectors match effective_labels,
+-- where an admin label overrides a helper label with the same key.
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS helper_labels JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS effective_labels JSONB
+    GENERATED ALWAYS AS (helper_labels || labels) STORED;
+
+DROP INDEX IF EXISTS idx_proxies_labels;
+CREATE INDEX IF NOT EXISTS idx_proxies_effective_labels ON proxies USING GIN (effective_labels);
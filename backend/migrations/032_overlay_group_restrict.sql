@@ -0,0 +1,5 @@
+-- Migration 032: deleting a group no longer deletes the config overlays that
+-- target it; the group has to be taken out of those overlays first.
+ALTER TABLE config_overlays DROP CONSTRAINT IF EXISTS config_overlays_group_id_fkey;
+ALTER TABLE config_overlays ADD CONSTRAINT config_overlays_group_id_fkey
+    FOREIGN KEY (group_id) REFERENCES proxy_groups(id) ON DELETE RESTRICT;
@@ -14,7 +14,7 @@ CREATE EXTENSION IF NOT EXISTS "pgcrypto";
 
 CREATE TYPE user_role AS ENUM ('root', 'admin', 'regular');
 CREATE TYPE config_status AS ENUM ('draft', 'pending_approval', 'approved', 'active');
-CREATE TYPE rule_action AS ENUM ('direct', 'parent');
+CREATE TYPE rule_action AS ENUM ('direct', 'parent', 'deny');
 
 -- =============================================================================
 -- TABLES
@@ -73,7 +73,10 @@ CREATE TABLE configs (
     config_hash VARCHAR(64),
 
     -- Comportamento padrão para tráfego sem regra
-    default_action VARCHAR(20) NOT NULL DEFAULT 'direct'
+    default_action VARCHAR(20) NOT NULL DEFAULT 'direct',
+
+    -- Portas liberadas para CONNECT (ex: "443 563 8443"); NULL mantém as da imagem
+    connect_ports VARCHAR(255)
 );
 
 -- Índices
@@ -90,6 +93,7 @@ CREATE TABLE domain_rules (
     domain VARCHAR(255) NOT NULL,  -- Ex: .provengo.local, .provengo.dev, .svc.cluster.local
     action rule_action NOT NULL DEFAULT 'direct',
     priority INTEGER NOT NULL DEFAULT 100,
+    tls_policy JSONB,  -- Política TLS do sni.yaml (verify_server_policy, forward_route, ...)
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     
     UNIQUE(config_id, domain)
@@ -125,15 +129,34 @@ CREATE TABLE client_acl_rules (
     config_id UUID NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
     cidr VARCHAR(50) NOT NULL,
     action VARCHAR(20) NOT NULL DEFAULT 'allow',  -- allow | deny
+    apply VARCHAR(3) NOT NULL DEFAULT 'in',        -- in (cliente) | out (destino)
+    methods TEXT[] NOT NULL DEFAULT '{}',          -- Métodos HTTP; vazio = todos
     priority INTEGER NOT NULL DEFAULT 100,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
 
-    UNIQUE(config_id, cidr)
+    UNIQUE(config_id, apply, cidr)
 );
 
 -- Índices
 CREATE INDEX idx_client_acl_rules_config ON client_acl_rules(config_id);
 
+-- -----------------------------------------------------------------------------
+-- Config Records (Overrides do records.yaml, nomes na allow-list do backend)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE config_records (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    config_id UUID NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+    name VARCHAR(200) NOT NULL,  -- ex: proxy.config.http.connect_ports
+    value TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE(config_id, name)
+);
+
+-- Índices
+CREATE INDEX idx_config_records_config ON config_records(config_id);
+
 -- -----------------------------------------------------------------------------
 -- Parent Proxies (Proxies upstream)
 -- -----------------------------------------------------------------------------
@@ -172,13 +195,33 @@ CREATE TABLE proxies (
     registered_ip VARCHAR(45),
 
     -- Captura de logs
-    capture_logs_until TIMESTAMP WITH TIME ZONE
+    capture_logs_until TIMESTAMP WITH TIME ZONE,
+    capture_debug_tags TEXT[],  -- debug tags do ATS habilitadas na captura
+
+    -- Resultado do último ack do helper
+    last_ack_status VARCHAR(20),  -- ok, error
+    last_ack_message TEXT,
+    last_ack_at TIMESTAMP WITH TIME ZONE,
+
+    -- mTLS: SHA-256 do certificado de cliente usado no último registro
+    client_cert_fingerprint VARCHAR(64),
+
+    -- Labels (site, env, role, ...) usados pelos seletores dos grupos
+    labels JSONB NOT NULL DEFAULT '{}',         -- definidos pelos admins
+    helper_labels JSONB NOT NULL DEFAULT '{}',  -- enviados pelo helper (--labels) no registro
+    -- Labels vistos pelos seletores; os dos admins prevalecem na mesma chave
+    effective_labels JSONB GENERATED ALWAYS AS (helper_labels || labels) STORED,
+
+    -- Hash dos arquivos (config + overlays) entregues ao proxy, quando há overlays para ele
+    target_config_id UUID,
+    target_config_hash VARCHAR(64)
 );
 
 -- Índices
 CREATE INDEX idx_proxies_hostname ON proxies(hostname);
 CREATE INDEX idx_proxies_config ON proxies(config_id);
 CREATE INDEX idx_proxies_online ON proxies(is_online);
+CREATE INDEX idx_proxies_effective_labels ON proxies USING GIN (effective_labels);
 
 -- -----------------------------------------------------------------------------
 -- Config-Proxy Association (Quais proxies usam qual config)
@@ -193,6 +236,143 @@ CREATE TABLE config_proxies (
     PRIMARY KEY (config_id, proxy_id)
 );
 
+-- -----------------------------------------------------------------------------
+-- Proxy Groups (Grupos de proxies definidos por seletor de labels)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE proxy_groups (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT,
+    selector JSONB NOT NULL DEFAULT '{}',  -- Labels exigidos; {} seleciona todos os proxies
+    priority INTEGER NOT NULL DEFAULT 0,   -- Maior vence quando grupos de configs ativas se sobrepõem
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- -----------------------------------------------------------------------------
+-- Config-Group Association (Configs aplicadas aos proxies de um grupo)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE config_groups (
+    config_id UUID NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+    group_id UUID NOT NULL REFERENCES proxy_groups(id) ON DELETE CASCADE,
+    assigned_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    assigned_by UUID REFERENCES users(id),
+
+    PRIMARY KEY (config_id, group_id)
+);
+
+-- Índices
+CREATE INDEX idx_config_groups_group ON config_groups(group_id);
+
+-- -----------------------------------------------------------------------------
+-- Config Overlays (Regras extras de uma config para um proxy ou grupo)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE config_overlays (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    config_id UUID NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+    hostname VARCHAR(255),                                   -- Overlay de um proxy...
+    group_id UUID REFERENCES proxy_groups(id) ON DELETE RESTRICT, -- ...ou de um grupo
+    description TEXT,
+    domains JSONB NOT NULL DEFAULT '[]',     -- [{domain, action, priority}]
+    ip_ranges JSONB NOT NULL DEFAULT '[]',   -- [{cidr, action, priority}]
+    client_acl JSONB NOT NULL DEFAULT '[]',  -- [{cidr, action, priority}]
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT config_overlays_target CHECK ((hostname IS NULL) <> (group_id IS NULL)),
+    UNIQUE (config_id, hostname),
+    UNIQUE (config_id, group_id)
+);
+
+-- Índices
+CREATE INDEX idx_config_overlays_config ON config_overlays(config_id);
+
+-- -----------------------------------------------------------------------------
+-- Rule Sets (Listas de domínios/CIDRs versionadas, referenciadas pelas configs)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE rule_sets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT,
+    version INTEGER NOT NULL DEFAULT 1,  -- Versão atual
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Cada alteração gera uma versão nova; versões não mudam depois de criadas
+CREATE TABLE rule_set_versions (
+    rule_set_id UUID NOT NULL REFERENCES rule_sets(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    domains JSONB NOT NULL DEFAULT '[]',    -- [{domain, action}]
+    ip_ranges JSONB NOT NULL DEFAULT '[]',  -- [{cidr, action}]
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (rule_set_id, version)
+);
+
+-- Referência de uma config a uma versão fixa de um rule set
+CREATE TABLE config_rule_sets (
+    config_id UUID NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+    rule_set_id UUID NOT NULL,
+    version INTEGER NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,  -- Posição das regras entre as da config
+
+    PRIMARY KEY (config_id, rule_set_id),
+    FOREIGN KEY (rule_set_id, version) REFERENCES rule_set_versions(rule_set_id, version)
+);
+
+-- Índices
+CREATE INDEX idx_config_rule_sets_rule_set ON config_rule_sets(rule_set_id);
+
+-- -----------------------------------------------------------------------------
+-- Feed Sources (Listas externas de domínios/IPs que alimentam um rule set)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE feed_sources (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    rule_set_id UUID NOT NULL UNIQUE REFERENCES rule_sets(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,                    -- URL http(s) ou arquivo em FEED_DIR
+    format VARCHAR(50) NOT NULL,             -- Parser: plain, m365, aws, gcp
+    action VARCHAR(20) NOT NULL DEFAULT 'direct',
+    interval_minutes INTEGER NOT NULL DEFAULT 1440,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_fetched_at TIMESTAMP WITH TIME ZONE,
+    last_status VARCHAR(20),                 -- changed, unchanged, error
+    last_error TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Conteúdo buscado que difere da versão atual do rule set, aguardando revisão
+CREATE TABLE feed_updates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    feed_id UUID NOT NULL REFERENCES feed_sources(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'applied', 'discarded')),
+    base_version INTEGER NOT NULL,           -- Versão do rule set usada no diff
+    domains JSONB NOT NULL DEFAULT '[]',
+    ip_ranges JSONB NOT NULL DEFAULT '[]',
+    added JSONB NOT NULL DEFAULT '[]',
+    removed JSONB NOT NULL DEFAULT '[]',
+    skipped INTEGER NOT NULL DEFAULT 0,      -- Entradas inválidas ignoradas
+    fetched_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    applied_version INTEGER
+);
+
+-- Índices
+CREATE INDEX idx_feed_updates_feed ON feed_updates(feed_id, fetched_at DESC);
+CREATE UNIQUE INDEX idx_feed_updates_pending ON feed_updates(feed_id) WHERE status = 'pending';
+
 -- -----------------------------------------------------------------------------
 -- Proxy Stats (Métricas coletadas dos proxies)
 -- -----------------------------------------------------------------------------
@@ -220,17 +400,125 @@ CREATE TABLE proxy_stats (
     err_client_abort INTEGER DEFAULT 0,
     broken_server_conns INTEGER DEFAULT 0,
     bytes_in BIGINT DEFAULT 0,
-    bytes_out BIGINT DEFAULT 0
+    bytes_out BIGINT DEFAULT 0,
+
+    -- Deltas por intervalo (contadores do ATS são cumulativos desde o start)
+    interval_seconds DOUBLE PRECISION DEFAULT 0,
+    counter_reset BOOLEAN DEFAULT FALSE,
+    total_connections_delta BIGINT DEFAULT 0,
+    cache_hits_delta BIGINT DEFAULT 0,
+    cache_misses_delta BIGINT DEFAULT 0,
+    errors_delta BIGINT DEFAULT 0,
+    total_requests_delta BIGINT DEFAULT 0,
+    connect_requests_delta BIGINT DEFAULT 0,
+    responses_2xx_delta BIGINT DEFAULT 0,
+    responses_3xx_delta BIGINT DEFAULT 0,
+    responses_4xx_delta BIGINT DEFAULT 0,
+    responses_5xx_delta BIGINT DEFAULT 0,
+    err_connect_fail_delta BIGINT DEFAULT 0,
+    err_client_abort_delta BIGINT DEFAULT 0,
+    broken_server_conns_delta BIGINT DEFAULT 0,
+    bytes_in_delta BIGINT DEFAULT 0,
+    bytes_out_delta BIGINT DEFAULT 0
 );
 
 -- Índices
 CREATE INDEX idx_proxy_stats_proxy ON proxy_stats(proxy_id);
 CREATE INDEX idx_proxy_stats_time ON proxy_stats(collected_at DESC);
+CREATE INDEX idx_proxy_stats_proxy_time ON proxy_stats(proxy_id, collected_at DESC);
 
 -- Particionar por tempo (opcional, para alta volumetria)
 -- CREATE TABLE proxy_stats_2025_02 PARTITION OF proxy_stats
 --     FOR VALUES FROM ('2025-02-01') TO ('2025-03-01');
 
+-- -----------------------------------------------------------------------------
+-- Proxy Stats Rollups (Agregações de longo prazo: 5 minutos, hora, dia)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE proxy_stats_5m (
+    proxy_id UUID NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    samples INTEGER NOT NULL DEFAULT 0,
+    active_connections_max INTEGER DEFAULT 0,
+    active_connections_avg DOUBLE PRECISION DEFAULT 0,
+    interval_seconds DOUBLE PRECISION DEFAULT 0,
+    total_connections BIGINT DEFAULT 0,
+    cache_hits BIGINT DEFAULT 0,
+    cache_misses BIGINT DEFAULT 0,
+    errors BIGINT DEFAULT 0,
+    total_requests BIGINT DEFAULT 0,
+    connect_requests BIGINT DEFAULT 0,
+    responses_2xx BIGINT DEFAULT 0,
+    responses_3xx BIGINT DEFAULT 0,
+    responses_4xx BIGINT DEFAULT 0,
+    responses_5xx BIGINT DEFAULT 0,
+    err_connect_fail BIGINT DEFAULT 0,
+    err_client_abort BIGINT DEFAULT 0,
+    broken_server_conns BIGINT DEFAULT 0,
+    bytes_in BIGINT DEFAULT 0,
+    bytes_out BIGINT DEFAULT 0,
+    PRIMARY KEY (proxy_id, bucket)
+);
+CREATE INDEX idx_proxy_stats_5m_bucket ON proxy_stats_5m(bucket DESC);
+
+CREATE TABLE proxy_stats_1h (
+    proxy_id UUID NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    samples INTEGER NOT NULL DEFAULT 0,
+    active_connections_max INTEGER DEFAULT 0,
+    active_connections_avg DOUBLE PRECISION DEFAULT 0,
+    interval_seconds DOUBLE PRECISION DEFAULT 0,
+    total_connections BIGINT DEFAULT 0,
+    cache_hits BIGINT DEFAULT 0,
+    cache_misses BIGINT DEFAULT 0,
+    errors BIGINT DEFAULT 0,
+    total_requests BIGINT DEFAULT 0,
+    connect_requests BIGINT DEFAULT 0,
+    responses_2xx BIGINT DEFAULT 0,
+    responses_3xx BIGINT DEFAULT 0,
+    responses_4xx BIGINT DEFAULT 0,
+    responses_5xx BIGINT DEFAULT 0,
+    err_connect_fail BIGINT DEFAULT 0,
+    err_client_abort BIGINT DEFAULT 0,
+    broken_server_conns BIGINT DEFAULT 0,
+    bytes_in BIGINT DEFAULT 0,
+    bytes_out BIGINT DEFAULT 0,
+    PRIMARY KEY (proxy_id, bucket)
+);
+CREATE INDEX idx_proxy_stats_1h_bucket ON proxy_stats_1h(bucket DESC);
+
+CREATE TABLE proxy_stats_1d (
+    proxy_id UUID NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    samples INTEGER NOT NULL DEFAULT 0,
+    active_connections_max INTEGER DEFAULT 0,
+    active_connections_avg DOUBLE PRECISION DEFAULT 0,
+    interval_seconds DOUBLE PRECISION DEFAULT 0,
+    total_connections BIGINT DEFAULT 0,
+    cache_hits BIGINT DEFAULT 0,
+    cache_misses BIGINT DEFAULT 0,
+    errors BIGINT DEFAULT 0,
+    total_requests BIGINT DEFAULT 0,
+    connect_requests BIGINT DEFAULT 0,
+    responses_2xx BIGINT DEFAULT 0,
+    responses_3xx BIGINT DEFAULT 0,
+    responses_4xx BIGINT DEFAULT 0,
+    responses_5xx BIGINT DEFAULT 0,
+    err_connect_fail BIGINT DEFAULT 0,
+    err_client_abort BIGINT DEFAULT 0,
+    broken_server_conns BIGINT DEFAULT 0,
+    bytes_in BIGINT DEFAULT 0,
+    bytes_out BIGINT DEFAULT 0,
+    PRIMARY KEY (proxy_id, bucket)
+);
+CREATE INDEX idx_proxy_stats_1d_bucket ON proxy_stats_1d(bucket DESC);
+
+-- Até onde cada tier foi agregado; após uma parada o rollup retoma daqui
+CREATE TABLE stats_rollup_watermarks (
+    tier VARCHAR(10) PRIMARY KEY,
+    rolled_until TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
 -- -----------------------------------------------------------------------------
 -- Proxy Logs (Logs capturados temporariamente)
 -- -----------------------------------------------------------------------------
@@ -240,6 +528,7 @@ CREATE TABLE proxy_logs (
     proxy_id UUID NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
     captured_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     log_level VARCHAR(20),
+    tag VARCHAR(64),  -- debug tag do ATS
     message TEXT,
     
     -- Auto-delete após 1 hora
@@ -251,6 +540,242 @@ CREATE INDEX idx_proxy_logs_proxy ON proxy_logs(proxy_id);
 CREATE INDEX idx_proxy_logs_time ON proxy_logs(captured_at DESC);
 CREATE INDEX idx_proxy_logs_expires ON proxy_logs(expires_at);
 
+-- -----------------------------------------------------------------------------
+-- Parent Proxy Status (Saúde dos parents vista por cada proxy)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE proxy_parent_status (
+    proxy_id UUID NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    address VARCHAR(255) NOT NULL,
+    port INTEGER NOT NULL,
+    is_up BOOLEAN NOT NULL DEFAULT TRUE,
+    checked_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (proxy_id, address, port)
+);
+
+-- -----------------------------------------------------------------------------
+-- Proxy Commands (Comandos remotos para o helper: resync, reload, restart, ...)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE proxy_commands (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    proxy_id UUID NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    type VARCHAR(30) NOT NULL,                    -- resync | reload | restart | diagnostics | clear_hostdb | connectivity_test
+    args JSONB NOT NULL DEFAULT '{}',             -- ex: {"url": "..."} no connectivity_test
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending | delivered | succeeded | failed | timed_out | expired | cancelled
+    timeout_seconds INTEGER NOT NULL,             -- Tempo máximo de execução no helper
+    output TEXT,
+    error TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL, -- Não entregue até aqui vira expired
+    delivered_at TIMESTAMP WITH TIME ZONE,        -- Entregue no GET /sync
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Índices
+CREATE INDEX idx_proxy_commands_proxy ON proxy_commands(proxy_id, created_at DESC);
+CREATE INDEX idx_proxy_commands_pending ON proxy_commands(proxy_id) WHERE status = 'pending';
+
+-- -----------------------------------------------------------------------------
+-- Proxy Diagnostics (Bundles tar.gz enviados pelo helper, com validade)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE proxy_diagnostics (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    proxy_id UUID NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    command_id UUID REFERENCES proxy_commands(id) ON DELETE SET NULL, -- Comando diagnostics que gerou o bundle
+    size_bytes INTEGER NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    data BYTEA NOT NULL,                          -- tar.gz
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL  -- Removido pelo scheduler após a validade
+);
+
+-- Índices
+CREATE INDEX idx_proxy_diagnostics_proxy ON proxy_diagnostics(proxy_id, created_at DESC);
+CREATE INDEX idx_proxy_diagnostics_expires ON proxy_diagnostics(expires_at);
+
+-- -----------------------------------------------------------------------------
+-- Access Logs (Logs de acesso enviados pelos helpers, particionados por dia)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE access_logs (
+    id BIGINT GENERATED ALWAYS AS IDENTITY,
+    ts TIMESTAMP WITH TIME ZONE NOT NULL,         -- Horário da requisição no proxy
+    proxy_id UUID NOT NULL,                       -- Sem FK: partições são removidas por retenção
+    client_ip INET NOT NULL,
+    username VARCHAR(255),                        -- Usuário autenticado no proxy, se houver
+    method VARCHAR(16) NOT NULL,
+    url TEXT NOT NULL,                            -- CONNECT: host:porta
+    host VARCHAR(255) NOT NULL,                   -- Destino, em minúsculas
+    status SMALLINT NOT NULL,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    duration_ms INTEGER,
+    route VARCHAR(32),                            -- DIRECT, PARENT_HIT, ...
+    upstream VARCHAR(255),                        -- Parent ou servidor de origem
+    user_agent VARCHAR(512)
+) PARTITION BY RANGE (ts);
+
+-- Partições diárias (access_logs_pYYYYMMDD) são criadas e removidas pelo scheduler
+-- conforme ACCESS_LOG_RETENTION_DAYS; a default recebe o que cair fora delas.
+CREATE TABLE access_logs_default PARTITION OF access_logs DEFAULT;
+
+-- Índices
+CREATE INDEX idx_access_logs_ts ON access_logs(ts DESC);
+CREATE INDEX idx_access_logs_proxy_ts ON access_logs(proxy_id, ts DESC);
+CREATE INDEX idx_access_logs_client_ts ON access_logs(client_ip, ts DESC);
+CREATE INDEX idx_access_logs_host_ts ON access_logs(host, ts DESC);
+CREATE INDEX idx_access_logs_host_reverse ON access_logs(reverse(host) text_pattern_ops);  -- Busca por subdomínios
+
+-- -----------------------------------------------------------------------------
+-- Proxy Drift (Arquivos em disco do proxy divergentes do bundle aplicado)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE proxy_drift (
+    proxy_id UUID PRIMARY KEY REFERENCES proxies(id) ON DELETE CASCADE,
+    drifted BOOLEAN NOT NULL DEFAULT FALSE,
+    expected_hash VARCHAR(64),                 -- Hash do bundle aplicado
+    actual_hash VARCHAR(64),                   -- Hash recalculado dos arquivos em disco
+    files TEXT[] NOT NULL DEFAULT '{}',        -- Arquivos alterados
+    remediated BOOLEAN NOT NULL DEFAULT FALSE, -- Helper reaplicou o bundle automaticamente
+    detected_at TIMESTAMP WITH TIME ZONE,      -- Início do drift atual
+    checked_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- -----------------------------------------------------------------------------
+-- Alerting (Regras e estado dos alertas)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE alert_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(30) NOT NULL,  -- proxy_offline, config_mismatch, error_rate, ack_failed, parent_down, config_drift
+    threshold DOUBLE PRECISION NOT NULL DEFAULT 0,  -- % para error_rate
+    duration_minutes INTEGER NOT NULL DEFAULT 5,  -- tempo em condição antes de disparar
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE alerts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rule_id UUID NOT NULL REFERENCES alert_rules(id) ON DELETE CASCADE,
+    proxy_id UUID REFERENCES proxies(id) ON DELETE CASCADE,
+    subject VARCHAR(255) NOT NULL DEFAULT '',  -- Ex: parent address:port
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',  -- pending, firing, resolved
+    message TEXT,
+    value DOUBLE PRECISION,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    fired_at TIMESTAMP WITH TIME ZONE,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    last_evaluated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Índices
+CREATE INDEX idx_alerts_status ON alerts(status);
+CREATE INDEX idx_alerts_started ON alerts(started_at DESC);
+CREATE UNIQUE INDEX idx_alerts_open
+    ON alerts(rule_id, COALESCE(proxy_id, '00000000-0000-0000-0000-000000000000'::uuid), subject)
+    WHERE status IN ('pending', 'firing');
+
+-- -----------------------------------------------------------------------------
+-- Webhooks (Notificações de saída e log de entregas)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    secret VARCHAR(128) NOT NULL,  -- chave HMAC das assinaturas
+    events TEXT[] NOT NULL DEFAULT '{}',  -- Ex: config.submit, alert.firing
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',  -- pending, success, failed
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_status_code INTEGER,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Índices
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+-- -----------------------------------------------------------------------------
+-- Notification Subscriptions (Eventos que cada usuário recebe por email)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE notification_subscriptions (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,  -- Ex: deploy.failed
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, event)
+);
+
+-- -----------------------------------------------------------------------------
+-- Enrollment Tokens (Tokens de registro de helpers, criados por admins)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE enrollment_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,                      -- SHA-256 do token, o token em si não é guardado
+    config_id UUID REFERENCES configs(id) ON DELETE CASCADE,    -- Config atribuída aos proxies registrados com o token
+    group_id UUID REFERENCES proxy_groups(id) ON DELETE CASCADE, -- Grupo: o seletor dele é adicionado aos labels do proxy
+    max_uses INTEGER,                                            -- NULL = ilimitado
+    use_count INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- -----------------------------------------------------------------------------
+-- Proxy Credentials (Segredo HMAC de cada proxy para assinar o sync)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE proxy_credentials (
+    proxy_id UUID PRIMARY KEY REFERENCES proxies(id) ON DELETE CASCADE,
+    secret VARCHAR(128) NOT NULL,
+    next_secret VARCHAR(128),                                    -- Rotação pendente, entregue ao helper no próximo sync
+    enrollment_token_id UUID REFERENCES enrollment_tokens(id) ON DELETE SET NULL,
+    issued_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    rotated_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+-- -----------------------------------------------------------------------------
+-- Signing Keys (Chave Ed25519 que assina os bundles de config enviados aos helpers)
+-- -----------------------------------------------------------------------------
+
+CREATE TABLE signing_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    algorithm VARCHAR(20) NOT NULL DEFAULT 'ed25519',
+    public_key TEXT NOT NULL,                  -- base64
+    private_key TEXT NOT NULL,                 -- base64, gerada pelo backend no primeiro uso
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    retired_at TIMESTAMP WITH TIME ZONE
+);
+
+-- No máximo uma chave ativa
+CREATE UNIQUE INDEX idx_signing_keys_active ON signing_keys((retired_at IS NULL)) WHERE retired_at IS NULL;
+
 -- -----------------------------------------------------------------------------
 -- Audit Log (Histórico de ações)
 -- -----------------------------------------------------------------------------
@@ -405,9 +930,9 @@ LEFT JOIN configs c ON p.config_id = c.id
 LEFT JOIN LATERAL (
     SELECT 
         (SELECT active_connections FROM proxy_stats WHERE proxy_id = p.id ORDER BY collected_at DESC LIMIT 1) as active_connections,
-        SUM(total_connections) as total_connections_1h,
-        SUM(cache_hits) as cache_hits_1h,
-        SUM(cache_misses) as cache_misses_1h
+        SUM(total_connections_delta) as total_connections_1h,
+        SUM(cache_hits_delta) as cache_hits_1h,
+        SUM(cache_misses_delta) as cache_misses_1h
     FROM proxy_stats
     WHERE proxy_id = p.id
     AND collected_at > NOW() - INTERVAL '1 hour'
@@ -448,7 +973,7 @@ SELECT
     (SELECT COUNT(*) FROM configs WHERE status = 'active') as configs_active,
     (SELECT COALESCE(SUM(active_connections), 0) FROM proxy_stats 
      WHERE collected_at > NOW() - INTERVAL '1 minute') as total_active_connections,
-    (SELECT COALESCE(SUM(total_connections), 0) FROM proxy_stats 
+    (SELECT COALESCE(SUM(total_connections_delta), 0) FROM proxy_stats 
      WHERE collected_at > NOW() - INTERVAL '1 hour') as total_connections_1h;
 
 -- =============================================================================
@@ -0,0 +1,226 @@
+'use client';
+
+import { useEffect, useState } from 'react';
+import toast from 'react-hot-toast';
+import { api } from '@/lib/api';
+import type { AccessLog, Proxy, PaginatedResponse, ApiError } from '@/types';
+import { Pagination } from '@/components/pagination';
+import { TableSkeleton } from '@/components/loading';
+import { EmptyState } from '@/components/empty-state';
+import { formatBytes } from '@/lib/utils';
+
+interface Filters {
+  proxy_id: string;
+  client_ip: string;
+  host: string;
+  username: string;
+  status: string;
+  from: string;
+  to: string;
+}
+
+const emptyFilters: Filters = {
+  proxy_id: '',
+  client_ip: '',
+  host: '',
+  username: '',
+  status: '',
+  from: '',
+  to: '',
+};
+
+function formatTimestamp(ts: string): string {
+  return new Date(ts).toLocaleString('pt-BR', {
+    day: '2-digit',
+    month: '2-digit',
+    hour: '2-digit',
+    minute: '2-digit',
+    second: '2-digit',
+  });
+}
+
+function statusColor(status: number): string {
+  if (status >= 500) return 'text-red-600';
+  if (status >= 400) return 'text-yellow-600';
+  return 'text-gray-700';
+}
+
+// datetime-local não tem fuso; o backend espera RFC3339
+function toRFC3339(value: string): string | undefined {
+  return value ? new Date(value).toISOString() : undefined;
+}
+
+export default function AccessLogsPage() {
+  const [logs, setLogs] = useState<AccessLog[]>([]);
+  const [proxies, setProxies] = useState<Proxy[]>([]);
+  const [pagination, setPagination] = useState({ page: 1, limit: 50, total: 0, total_pages: 0 });
+  const [loading, setLoading] = useState(true);
+  const [form, setForm] = useState<Filters>(emptyFilters);
+  const [filters, setFilters] = useState<Filters>(emptyFilters);
+
+  async function load(page = 1) {
+    setLoading(true);
+    try {
+      const res: PaginatedResponse<AccessLog> = await api.accessLogs.list({
+        proxy_id: filters.proxy_id || undefined,
+        client_ip: filters.client_ip || undefined,
+        host: filters.host || undefined,
+        username: filters.username || undefined,
+        status: filters.status || undefined,
+        from: toRFC3339(filters.from),
+        to: toRFC3339(filters.to),
+        page,
+        limit: 50,
+      });
+      setLogs(res.data || []);
+      setPagination(res.pagination);
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao carregar access logs');
+    } finally {
+      setLoading(false);
+    }
+  }
+
+  useEffect(() => {
+    api.proxies
+      .list()
+      .then((res) => setProxies(res.data || []))
+      .catch(() => {});
+  }, []);
+
+  useEffect(() => {
+    load(1);
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [filters]);
+
+  function set(field: keyof Filters, value: string) {
+    setForm((f) => ({ ...f, [field]: value }));
+  }
+
+  const inputClass = 'px-3 py-1.5 border border-gray-300 rounded-md text-sm';
+
+  return (
+    <div>
+      <h1 className="text-2xl font-bold text-gray-900 mb-6">Access Logs</h1>
+
+      <form
+        onSubmit={(e) => {
+          e.preventDefault();
+          setFilters(form);
+        }}
+        className="flex flex-wrap gap-2 mb-4 items-end"
+      >
+        <select value={form.proxy_id} onChange={(e) => set('proxy_id', e.target.value)} className={inputClass}>
+          <option value="">Todos os proxies</option>
+          {proxies.map((p) => (
+            <option key={p.id} value={p.id}>
+              {p.hostname}
+            </option>
+          ))}
+        </select>
+        <input
+          value={form.client_ip}
+          onChange={(e) => set('client_ip', e.target.value)}
+          placeholder="IP ou CIDR do cliente"
+          className={`${inputClass} w-44`}
+        />
+        <input
+          value={form.host}
+          onChange={(e) => set('host', e.target.value)}
+          placeholder="Host"
+          className={`${inputClass} w-48`}
+        />
+        <input
+          value={form.username}
+          onChange={(e) => set('username', e.target.value)}
+          placeholder="Usuário"
+          className={`${inputClass} w-32`}
+        />
+        <input
+          value={form.status}
+          onChange={(e) => set('status', e.target.value)}
+          placeholder="Status (403, 5xx)"
+          className={`${inputClass} w-32`}
+        />
+        <div className="flex gap-2 items-center">
+          <label className="text-sm text-gray-600">De:</label>
+          <input
+            type="datetime-local"
+            value={form.from}
+            onChange={(e) => set('from', e.target.value)}
+            className={inputClass}
+          />
+          <label className="text-sm text-gray-600">Até:</label>
+          <input
+            type="datetime-local"
+            value={form.to}
+            onChange={(e) => set('to', e.target.value)}
+            className={inputClass}
+          />
+        </div>
+        <button
+          type="submit"
+          className="px-4 py-1.5 text-sm bg-blue-600 text-white rounded-md hover:bg-blue-700"
+        >
+          Buscar
+        </button>
+      </form>
+      <p className="text-xs text-gray-500 mb-4">Sem período informado, mostra a última hora. Intervalo máximo de 7 dias.</p>
+
+      {loading ? (
+        <TableSkeleton cols={7} />
+      ) : logs.length === 0 ? (
+        <EmptyState title="Nenhuma requisição encontrada" />
+      ) : (
+        <>
+          <div className="bg-white rounded-lg border overflow-x-auto">
+            <table className="w-full text-sm">
+              <thead className="bg-gray-50 border-b">
+                <tr>
+                  <th className="text-left px-4 py-3 font-medium text-gray-600">Data</th>
+                  <th className="text-left px-4 py-3 font-medium text-gray-600">Proxy</th>
+                  <th className="text-left px-4 py-3 font-medium text-gray-600">Cliente</th>
+                  <th className="text-left px-4 py-3 font-medium text-gray-600">Requisição</th>
+                  <th className="text-left px-4 py-3 font-medium text-gray-600">Status</th>
+                  <th className="text-left px-4 py-3 font-medium text-gray-600">Bytes</th>
+                  <th className="text-left px-4 py-3 font-medium text-gray-600">Duração</th>
+                  <th className="text-left px-4 py-3 font-medium text-gray-600">Rota</th>
+                </tr>
+              </thead>
+              <tbody className="divide-y">
+                {logs.map((log) => (
+                  <tr key={log.id} className="hover:bg-gray-50">
+                    <td className="px-4 py-3 text-gray-600 whitespace-nowrap">{formatTimestamp(log.timestamp)}</td>
+                    <td className="px-4 py-3 whitespace-nowrap">{log.hostname || log.proxy_id.slice(0, 8)}</td>
+                    <td className="px-4 py-3 font-mono text-xs whitespace-nowrap">
+                      {log.client_ip}
+                      {log.username && <span className="text-gray-500 ml-1">({log.username})</span>}
+                    </td>
+                    <td className="px-4 py-3 max-w-md">
+                      <span className="inline-flex px-2 py-0.5 rounded bg-gray-100 text-xs font-mono mr-1">
+                        {log.method}
+                      </span>
+                      <span className="font-mono text-xs break-all" title={log.user_agent}>
+                        {log.url}
+                      </span>
+                    </td>
+                    <td className={`px-4 py-3 font-mono ${statusColor(log.status)}`}>{log.status}</td>
+                    <td className="px-4 py-3 text-gray-600 whitespace-nowrap">{formatBytes(log.bytes)}</td>
+                    <td className="px-4 py-3 text-gray-600 whitespace-nowrap">
+                      {log.duration_ms !== undefined ? `${log.duration_ms} ms` : '-'}
+                    </td>
+                    <td className="px-4 py-3 text-xs text-gray-500">
+                      {log.route || '-'}
+                      {log.upstream && <div className="font-mono">{log.upstream}</div>}
+                    </td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </div>
+          <Pagination pagination={pagination} onPageChange={load} />
+        </>
+      )}
+    </div>
+  );
+}
@@ -2,13 +2,15 @@
 
 import { useEffect, useState, useCallback } from 'react';
 import { useParams, useRouter } from 'next/navigation';
+import Link from 'next/link';
 import toast from 'react-hot-toast';
 import { api } from '@/lib/api';
-import type { Config, ConfigPreview, RuleAction, DomainRule, IPRangeRule, ParentProxy, ClientACLRule, Proxy, ApiError } from '@/types';
+import type { Config, ConfigOverlay, ConfigRuleSet, ConfigPreview, RuleSet, RuleAction, DomainRule, IPRangeRule, ParentProxy, ClientACLRule, ACLApply, RecordOverride, Proxy, ProxyGroup, ApiError } from '@/types';
 import { StatusBadge } from '@/components/status-badge';
 import { ConfirmDialog } from '@/components/confirm-dialog';
 import { Loading } from '@/components/loading';
-import { formatDate } from '@/lib/utils';
+import { formatDate, formatLabels, parseMethods } from '@/lib/utils';
+import { TLSPolicyFields, formatTLSPolicy } from '@/components/tls-policy-fields';
 
 const DOMAIN_RE = /^(\*\.)?[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)+$/;
 const IPV4_RE = /^(\d{1,3}\.){3}\d{1,3}$/;
@@ -47,12 +49,19 @@ export default function ConfigDetailPage() {
   const [name, setName] = useState('');
   const [description, setDescription] = useState('');
   const [defaultAction, setDefaultAction] = useState<RuleAction>('direct');
+  const [connectPorts, setConnectPorts] = useState('');
   const [domains, setDomains] = useState<Omit<DomainRule, 'id'>[]>([]);
   const [ipRanges, setIpRanges] = useState<Omit<IPRangeRule, 'id'>[]>([]);
   const [parentProxies, setParentProxies] = useState<Omit<ParentProxy, 'id'>[]>([]);
   const [clientACL, setClientACL] = useState<Omit<ClientACLRule, 'id'>[]>([]);
+  const [records, setRecords] = useState<Omit<RecordOverride, 'id'>[]>([]);
   const [selectedProxyIds, setSelectedProxyIds] = useState<string[]>([]);
   const [availableProxies, setAvailableProxies] = useState<Proxy[]>([]);
+  const [selectedGroupIds, setSelectedGroupIds] = useState<string[]>([]);
+  const [availableGroups, setAvailableGroups] = useState<ProxyGroup[]>([]);
+  const [overlays, setOverlays] = useState<ConfigOverlay[]>([]);
+  const [ruleSets, setRuleSets] = useState<ConfigRuleSet[]>([]);
+  const [availableRuleSets, setAvailableRuleSets] = useState<RuleSet[]>([]);
 
   const load = useCallback(async () => {
     try {
@@ -61,8 +70,9 @@ export default function ConfigDetailPage() {
       setName(data.name);
       setDescription(data.description || '');
       setDefaultAction(data.default_action || 'direct');
+      setConnectPorts(data.connect_ports || '');
       setDomains(
-        (data.domains || []).map((d) => ({ domain: d.domain, action: d.action, priority: d.priority }))
+        (data.domains || []).map((d) => ({ domain: d.domain, action: d.action, priority: d.priority, tls: d.tls }))
       );
       setIpRanges(
         (data.ip_ranges || []).map((r) => ({ cidr: r.cidr, action: r.action, priority: r.priority }))
@@ -76,9 +86,22 @@ export default function ConfigDetailPage() {
         }))
       );
       setClientACL(
-        (data.client_acl || []).map((a) => ({ cidr: a.cidr, action: a.action, priority: a.priority }))
+        (data.client_acl || []).map((a) => ({ cidr: a.cidr, action: a.action, apply: a.apply, methods: a.methods, priority: a.priority }))
       );
+      setRecords((data.records || []).map((r) => ({ name: r.name, value: r.value })));
       setSelectedProxyIds((data.proxies || []).map((p) => p.id));
+      setSelectedGroupIds((data.groups || []).map((g) => g.id));
+      setOverlays(
+        (data.overlays || []).map((o) => ({
+          hostname: o.hostname,
+          group_id: o.group_id,
+          description: o.description,
+          domains: o.domains || [],
+          ip_ranges: o.ip_ranges || [],
+          client_acl: o.client_acl || [],
+        }))
+      );
+      setRuleSets(data.rule_sets || []);
     } catch (err) {
       toast.error((err as ApiError).message || 'Erro ao carregar config');
     } finally {
@@ -89,6 +112,8 @@ export default function ConfigDetailPage() {
   useEffect(() => {
     load();
     api.proxies.list().then((res) => setAvailableProxies(res.data || [])).catch(() => {});
+    api.groups.list().then((res) => setAvailableGroups(res.data || [])).catch(() => {});
+    api.ruleSets.list().then((res) => setAvailableRuleSets(res.data || [])).catch(() => {});
   }, [load]);
 
   async function handleSave() {
@@ -98,11 +123,16 @@ export default function ConfigDetailPage() {
         name: name.trim(),
         description: description.trim() || undefined,
         default_action: defaultAction,
-        domains,
+        connect_ports: connectPorts.trim() || undefined,
+        domains: domains.map((d) => ({ ...d, tls: d.action === 'deny' ? undefined : d.tls })),
         ip_ranges: ipRanges,
         parent_proxies: parentProxies,
-        client_acl: clientACL,
+        client_acl: clientACL.map((a) => ({ ...a, methods: a.methods?.filter(Boolean) })),
+        records,
         proxy_ids: selectedProxyIds,
+        group_ids: selectedGroupIds,
+        overlays,
+        rule_sets: ruleSets.map((r) => ({ rule_set_id: r.rule_set_id, version: r.version, priority: r.priority })),
       });
       toast.success('Config atualizada');
       setEditing(false);
@@ -235,6 +265,8 @@ export default function ConfigDetailPage() {
           setDescription={setDescription}
           defaultAction={defaultAction}
           setDefaultAction={setDefaultAction}
+          connectPorts={connectPorts}
+          setConnectPorts={setConnectPorts}
           domains={domains}
           setDomains={setDomains}
           ipRanges={ipRanges}
@@ -246,6 +278,14 @@ export default function ConfigDetailPage() {
           selectedProxyIds={selectedProxyIds}
           setSelectedProxyIds={setSelectedProxyIds}
           availableProxies={availableProxies}
+          selectedGroupIds={selectedGroupIds}
+          setSelectedGroupIds={setSelectedGroupIds}
+          availableGroups={availableGroups}
+          overlays={overlays}
+          setOverlays={setOverlays}
+          ruleSets={ruleSets}
+          setRuleSets={setRuleSets}
+          availableRuleSets={availableRuleSets}
           saving={saving}
           onSave={handleSave}
           onCancel={() => {
@@ -254,7 +294,7 @@ export default function ConfigDetailPage() {
           }}
         />
       ) : (
-        <ReadOnlyView config={config} />
+        <ReadOnlyView config={config} availableProxies={availableProxies} />
       )}
 
       <ConfirmDialog
@@ -286,19 +326,21 @@ export default function ConfigDetailPage() {
   );
 }
 
-function ReadOnlyView({ config }: { config: Config }) {
+function ReadOnlyView({ config, availableProxies }: { config: Config; availableProxies: Proxy[] }) {
   const [preview, setPreview] = useState<ConfigPreview | null>(null);
   const [previewLoading, setPreviewLoading] = useState(false);
   const [previewOpen, setPreviewOpen] = useState(false);
+  // Proxy whose overlays are merged into the preview ('' = config only)
+  const [previewHost, setPreviewHost] = useState('');
 
-  async function loadPreview() {
-    if (preview) {
+  async function loadPreview(hostname = previewHost, toggle = true) {
+    if (preview && toggle) {
       setPreviewOpen(!previewOpen);
       return;
     }
     setPreviewLoading(true);
     try {
-      const data = await api.configs.preview(config.id);
+      const data = await api.configs.preview(config.id, hostname || undefined);
       setPreview(data);
       setPreviewOpen(true);
     } catch (err) {
@@ -319,6 +361,10 @@ function ReadOnlyView({ config }: { config: Config }) {
             {config.default_action === 'parent' ? 'Parent Proxy' : 'Direct Connect'}
           </span>
         </p>
+        <p className="text-sm text-gray-700 mt-1">
+          Portas CONNECT:{' '}
+          <span className="font-mono">{config.connect_ports || '443 563 (padrão da imagem)'}</span>
+        </p>
       </div>
 
       {/* Domain Rules */}
@@ -332,6 +378,7 @@ function ReadOnlyView({ config }: { config: Config }) {
               <tr className="text-left text-gray-600">
                 <th className="pb-2 font-medium">Domínio</th>
                 <th className="pb-2 font-medium">Ação</th>
+                <th className="pb-2 font-medium">TLS</th>
                 <th className="pb-2 font-medium">Prioridade</th>
               </tr>
             </thead>
@@ -339,7 +386,8 @@ function ReadOnlyView({ config }: { config: Config }) {
               {config.domains.map((d, i) => (
                 <tr key={i}>
                   <td className="py-2 font-mono text-xs">{d.domain}</td>
-                  <td className="py-2">{d.action}</td>
+                  <td className={`py-2 ${d.action === 'deny' ? 'text-red-500' : ''}`}>{d.action}</td>
+                  <td className="py-2 text-xs text-gray-600">{formatTLSPolicy(d.tls) || '—'}</td>
                   <td className="py-2">{d.priority}</td>
                 </tr>
               ))}
@@ -366,7 +414,7 @@ function ReadOnlyView({ config }: { config: Config }) {
               {config.ip_ranges.map((r, i) => (
                 <tr key={i}>
                   <td className="py-2 font-mono text-xs">{r.cidr}</td>
-                  <td className="py-2">{r.action}</td>
+                  <td className={`py-2 ${r.action === 'deny' ? 'text-red-500' : ''}`}>{r.action}</td>
                   <td className="py-2">{r.priority}</td>
                 </tr>
               ))}
@@ -419,6 +467,8 @@ function ReadOnlyView({ config }: { config: Config }) {
               <tr className="text-left text-gray-600">
                 <th className="pb-2 font-medium">CIDR</th>
                 <th className="pb-2 font-medium">Ação</th>
+                <th className="pb-2 font-medium">Sentido</th>
+                <th className="pb-2 font-medium">Métodos</th>
                 <th className="pb-2 font-medium">Prioridade</th>
               </tr>
             </thead>
@@ -431,6 +481,8 @@ function ReadOnlyView({ config }: { config: Config }) {
                       {acl.action}
                     </span>
                   </td>
+                  <td className="py-2">{acl.apply === 'out' ? 'destino' : 'cliente'}</td>
+                  <td className="py-2 font-mono text-xs">{acl.methods?.length ? acl.methods.join(' ') : 'ALL'}</td>
                   <td className="py-2">{acl.priority}</td>
                 </tr>
               ))}
@@ -459,23 +511,136 @@ function ReadOnlyView({ config }: { config: Config }) {
         )}
       </div>
 
+      {/* Assigned Groups */}
+      <div className="bg-white rounded-lg border p-5">
+        <h2 className="text-base font-semibold text-gray-900 mb-3">Grupos Associados</h2>
+        {!config.groups?.length ? (
+          <p className="text-sm text-gray-500">Nenhum grupo associado.</p>
+        ) : (
+          <div className="flex flex-wrap gap-2">
+            {config.groups.map((g) => (
+              <span
+                key={g.id}
+                className="inline-flex items-center gap-1.5 px-3 py-1 bg-gray-100 rounded-full text-sm"
+                title={formatLabels(g.selector) || 'todos os proxies'}
+              >
+                {g.name}
+                <span className="text-xs text-gray-500">({g.proxy_count} proxies)</span>
+              </span>
+            ))}
+          </div>
+        )}
+      </div>
+
+      {/* Rule sets */}
+      <div className="bg-white rounded-lg border p-5">
+        <h2 className="text-base font-semibold text-gray-900 mb-3">Conjuntos de Regras</h2>
+        {!config.rule_sets?.length ? (
+          <p className="text-sm text-gray-500">Nenhum conjunto de regras.</p>
+        ) : (
+          <table className="w-full text-sm">
+            <thead>
+              <tr className="border-b">
+                <th className="text-left py-2 font-medium text-gray-600">Conjunto</th>
+                <th className="text-left py-2 font-medium text-gray-600">Versão</th>
+                <th className="text-right py-2 font-medium text-gray-600">Prioridade</th>
+              </tr>
+            </thead>
+            <tbody>
+              {config.rule_sets.map((r) => (
+                <tr key={r.rule_set_id} className="border-b last:border-0">
+                  <td className="py-2">
+                    <Link href="/rule-sets" className="text-blue-600 hover:underline">{r.name}</Link>
+                  </td>
+                  <td className="py-2">
+                    v{r.version}
+                    {r.latest_version !== undefined && r.latest_version > r.version && (
+                      <span className="ml-2 text-xs px-2 py-0.5 rounded bg-amber-100 text-amber-800">
+                        desatualizado (atual v{r.latest_version})
+                      </span>
+                    )}
+                  </td>
+                  <td className="py-2 text-right">{r.priority}</td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        )}
+      </div>
+
+      {/* Overlays */}
+      <div className="bg-white rounded-lg border p-5">
+        <h2 className="text-base font-semibold text-gray-900 mb-3">Overlays</h2>
+        {!config.overlays?.length ? (
+          <p className="text-sm text-gray-500">Nenhum overlay.</p>
+        ) : (
+          <div className="space-y-3">
+            {config.overlays.map((o, i) => (
+              <div key={o.id || i} className="border rounded-md p-3">
+                <div className="flex items-center gap-2 mb-2">
+                  <span className="text-xs font-medium px-2 py-0.5 rounded bg-gray-100 text-gray-700">
+                    {o.hostname ? 'Proxy' : 'Grupo'}
+                  </span>
+                  <span className="text-sm font-medium">{o.hostname || o.group_name}</span>
+                  {o.description && <span className="text-xs text-gray-500">{o.description}</span>}
+                </div>
+                <ul className="text-xs font-mono text-gray-700 space-y-0.5">
+                  {o.domains.map((d, j) => (
+                    <li key={`d${j}`}>domínio {d.domain} → {d.action}</li>
+                  ))}
+                  {o.ip_ranges.map((r, j) => (
+                    <li key={`i${j}`}>ip {r.cidr} → {r.action}</li>
+                  ))}
+                  {o.client_acl.map((a, j) => (
+                    <li key={`a${j}`}>acl {a.cidr} → {a.action}</li>
+                  ))}
+                </ul>
+              </div>
+            ))}
+          </div>
+        )}
+      </div>
+
       {/* Config File Preview */}
       <div className="bg-white rounded-lg border p-5">
         <div className="flex items-center justify-between mb-3">
           <h2 className="text-base font-semibold text-gray-900">Preview dos Arquivos</h2>
-          <button
-            onClick={loadPreview}
-            disabled={previewLoading}
-            className="px-3 py-1 text-sm text-blue-600 border border-blue-300 rounded-md hover:bg-blue-50 transition-colors disabled:opacity-50"
-          >
-            {previewLoading ? 'Carregando...' : previewOpen ? 'Ocultar' : 'Visualizar'}
-          </button>
+          <div className="flex items-center gap-2">
+            {!!config.overlays?.length && (
+              <select
+                value={previewHost}
+                onChange={(e) => {
+                  setPreviewHost(e.target.value);
+                  loadPreview(e.target.value, false);
+                }}
+                className="px-2 py-1 border border-gray-300 rounded-md text-sm"
+              >
+                <option value="">Só a config</option>
+                {availableProxies.map((p) => (
+                  <option key={p.id} value={p.hostname}>{p.hostname}</option>
+                ))}
+              </select>
+            )}
+            <button
+              onClick={() => loadPreview()}
+              disabled={previewLoading}
+              className="px-3 py-1 text-sm text-blue-600 border border-blue-300 rounded-md hover:bg-blue-50 transition-colors disabled:opacity-50"
+            >
+              {previewLoading ? 'Carregando...' : previewOpen ? 'Ocultar' : 'Visualizar'}
+            </button>
+          </div>
         </div>
         <p className="text-xs text-gray-500 mb-3">
           Mostra como os arquivos de configuração seriam gerados para o ATS.
+          {!!config.overlays?.length && ' Escolha um proxy para ver os arquivos com os overlays que se aplicam a ele.'}
         </p>
         {previewOpen && preview && (
           <div className="space-y-4">
+            {!!preview.overlays?.length && (
+              <p className="text-xs text-gray-600">
+                Overlays aplicados: <span className="font-mono">{preview.overlays.join(', ')}</span>
+              </p>
+            )}
             <div>
               <h3 className="text-sm font-medium text-gray-700 mb-1">parent.config</h3>
               <pre className="bg-gray-900 text-green-400 text-xs p-4 rounded-md overflow-x-auto whitespace-pre-wrap font-mono">
@@ -494,6 +659,14 @@ function ReadOnlyView({ config }: { config: Config }) {
                 {preview.ip_allow_yaml || '(vazio)'}
               </pre>
             </div>
+            {Object.entries(preview.files || {}).map(([fileName, content]) => (
+              <div key={fileName}>
+                <h3 className="text-sm font-medium text-gray-700 mb-1">{fileName}</h3>
+                <pre className="bg-gray-900 text-green-400 text-xs p-4 rounded-md overflow-x-auto whitespace-pre-wrap font-mono">
+                  {content || '(vazio)'}
+                </pre>
+              </div>
+            ))}
           </div>
         )}
       </div>
@@ -505,23 +678,35 @@ function EditForm({
   name, setName,
   description, setDescription,
   defaultAction, setDefaultAction,
+  connectPorts, setConnectPorts,
   domains, setDomains,
   ipRanges, setIpRanges,
   parentProxies, setParentProxies,
   clientACL, setClientACL,
   selectedProxyIds, setSelectedProxyIds,
   availableProxies,
+  selectedGroupIds, setSelectedGroupIds,
+  availableGroups,
+  overlays, setOverlays,
+  ruleSets, setRuleSets,
+  availableRuleSets,
   saving, onSave, onCancel,
 }: {
   name: string; setName: (v: string) => void;
   description: string; setDescription: (v: string) => void;
   defaultAction: RuleAction; setDefaultAction: (v: RuleAction) => void;
+  connectPorts: string; setConnectPorts: (v: string) => void;
   domains: Omit<DomainRule, 'id'>[]; setDomains: (v: Omit<DomainRule, 'id'>[]) => void;
   ipRanges: Omit<IPRangeRule, 'id'>[]; setIpRanges: (v: Omit<IPRangeRule, 'id'>[]) => void;
   parentProxies: Omit<ParentProxy, 'id'>[]; setParentProxies: (v: Omit<ParentProxy, 'id'>[]) => void;
   clientACL: Omit<ClientACLRule, 'id'>[]; setClientACL: (v: Omit<ClientACLRule, 'id'>[]) => void;
   selectedProxyIds: string[]; setSelectedProxyIds: (v: string[]) => void;
   availableProxies: Proxy[];
+  selectedGroupIds: string[]; setSelectedGroupIds: (v: string[]) => void;
+  availableGroups: ProxyGroup[];
+  overlays: ConfigOverlay[]; setOverlays: (v: ConfigOverlay[]) => void;
+  ruleSets: ConfigRuleSet[]; setRuleSets: (v: ConfigRuleSet[]) => void;
+  availableRuleSets: RuleSet[];
   saving: boolean; onSave: () => void; onCancel: () => void;
 }) {
   return (
@@ -559,6 +744,18 @@ function EditForm({
             Define o que acontece com tráfego que não corresponde a nenhuma regra específica.
           </p>
         </div>
+        <div>
+          <label className="block text-sm font-medium text-gray-700 mb-1">Portas CONNECT</label>
+          <input
+            value={connectPorts}
+            onChange={(e) => setConnectPorts(e.target.value)}
+            className="w-full px-3 py-2 border border-gray-300 rounded-md text-sm font-mono focus:outline-none focus:ring-2 focus:ring-blue-500"
+            placeholder="443 563"
+          />
+          <p className="text-xs text-gray-500 mt-1">
+            Portas e faixas liberadas para túneis CONNECT (ex: 443 563 8443 2200-2299). Vazio mantém as da imagem.
+          </p>
+        </div>
       </div>
 
       {/* Domain Rules */}
@@ -575,50 +772,78 @@ function EditForm({
         </div>
         <div className="space-y-2">
           {domains.map((d, i) => (
-            <div key={i} className="flex gap-2 items-start">
-              <div className="flex-1">
+            <div key={i} className="space-y-2">
+              <div className="flex gap-2 items-start">
+                <div className="flex-1">
+                  <input
+                    value={d.domain}
+                    onChange={(e) => {
+                      const next = [...domains];
+                      next[i] = { ...next[i], domain: e.target.value };
+                      setDomains(next);
+                    }}
+                    className={`w-full px-3 py-2 border rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 ${d.domain && !isValidDomain(d.domain) ? 'border-red-500 bg-red-50' : 'border-gray-300'}`}
+                    placeholder="*.example.com"
+                  />
+                  {d.domain && !isValidDomain(d.domain) && (
+                    <p className="text-xs text-red-500 mt-0.5">Formato inválido (use *.example.com ou host.example.com)</p>
+                  )}
+                </div>
+                <select
+                  value={d.action}
+                  onChange={(e) => {
+                    const next = [...domains];
+                    next[i] = { ...next[i], action: e.target.value as RuleAction };
+                    setDomains(next);
+                  }}
+                  className="w-32 px-3 py-2 border border-gray-300 rounded-md text-sm"
+                >
+                  <option value="direct">Direct</option>
+                  <option value="parent">Parent</option>
+                  <option value="deny">Deny</option>
+                </select>
                 <input
-                  value={d.domain}
+                  type="number"
+                  value={d.priority}
                   onChange={(e) => {
                     const next = [...domains];
-                    next[i] = { ...next[i], domain: e.target.value };
+                    next[i] = { ...next[i], priority: parseInt(e.target.value) || 0 };
                     setDomains(next);
                   }}
-                  className={`w-full px-3 py-2 border rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 ${d.domain && !isValidDomain(d.domain) ? 'border-red-500 bg-red-50' : 'border-gray-300'}`}
-                  placeholder="*.example.com"
+                  className="w-24 px-3 py-2 border border-gray-300 rounded-md text-sm"
                 />
-                {d.domain && !isValidDomain(d.domain) && (
-                  <p className="text-xs text-red-500 mt-0.5">Formato inválido (use *.example.com ou host.example.com)</p>
+                {d.action !== 'deny' && (
+                  <button
+                    type="button"
+                    onClick={() => {
+                      const next = [...domains];
+                      next[i] = { ...next[i], tls: d.tls ? undefined : {} };
+                      setDomains(next);
+                    }}
+                    className={`px-2 py-2 text-xs border rounded-md ${d.tls ? 'text-blue-600 border-blue-300 bg-blue-50' : 'text-gray-600 border-gray-300 hover:bg-gray-50'}`}
+                    title="Política TLS (sni.yaml)"
+                  >
+                    TLS
+                  </button>
                 )}
+                <button
+                  onClick={() => setDomains(domains.filter((_, idx) => idx !== i))}
+                  className="w-8 h-8 flex items-center justify-center text-red-500 hover:bg-red-50 rounded text-lg mt-1"
+                >
+                  &times;
+                </button>
               </div>
-              <select
-                value={d.action}
-                onChange={(e) => {
-                  const next = [...domains];
-                  next[i] = { ...next[i], action: e.target.value as 'direct' | 'parent' };
-                  setDomains(next);
-                }}
-                className="w-32 px-3 py-2 border border-gray-300 rounded-md text-sm"
-              >
-                <option value="direct">Direct</option>
-                <option value="parent">Parent</option>
-              </select>
-              <input
-                type="number"
-                value={d.priority}
-                onChange={(e) => {
-                  const next = [...domains];
-                  next[i] = { ...next[i], priority: parseInt(e.target.value) || 0 };
-                  setDomains(next);
-                }}
-                className="w-24 px-3 py-2 border border-gray-300 rounded-md text-sm"
-              />
-              <button
-                onClick={() => setDomains(domains.filter((_, idx) => idx !== i))}
-                className="w-8 h-8 flex items-center justify-center text-red-500 hover:bg-red-50 rounded text-lg mt-1"
-              >
-                &times;
-              </button>
+              {d.tls && d.action !== 'deny' && (
+                <TLSPolicyFields
+                  policy={d.tls}
+                  onChange={(tls) => {
+                    const next = [...domains];
+                    next[i] = { ...next[i], tls };
+                    setDomains(next);
+                  }}
+                  allowForwardRoute={d.action === 'direct'}
+                />
+              )}
             </div>
           ))}
         </div>
@@ -658,13 +883,14 @@ function EditForm({
                 value={r.action}
                 onChange={(e) => {
                   const next = [...ipRanges];
-                  next[i] = { ...next[i], action: e.target.value as 'direct' | 'parent' };
+                  next[i] = { ...next[i], action: e.target.value as RuleAction };
                   setIpRanges(next);
                 }}
                 className="w-32 px-3 py-2 border border-gray-300 rounded-md text-sm"
               >
                 <option value="direct">Direct</option>
                 <option value="parent">Parent</option>
+                <option value="deny">Deny</option>
               </select>
               <input
                 type="number"
@@ -820,6 +1046,30 @@ function EditForm({
                 <option value="allow">Allow</option>
                 <option value="deny">Deny</option>
               </select>
+              <select
+                value={acl.apply || 'in'}
+                onChange={(e) => {
+                  const next = [...clientACL];
+                  next[i] = { ...next[i], apply: e.target.value as ACLApply };
+                  setClientACL(next);
+                }}
+                className="w-28 px-3 py-2 border border-gray-300 rounded-md text-sm"
+                title="in: IP do cliente; out: IP de destino"
+              >
+                <option value="in">Cliente</option>
+                <option value="out">Destino</option>
+              </select>
+              <input
+                value={(acl.methods || []).join(' ')}
+                onChange={(e) => {
+                  const next = [...clientACL];
+                  next[i] = { ...next[i], methods: parseMethods(e.target.value) };
+                  setClientACL(next);
+                }}
+                className="w-36 px-3 py-2 border border-gray-300 rounded-md text-sm font-mono"
+                placeholder="ALL"
+                title="Métodos HTTP (ex: GET HEAD); vazio = todos"
+              />
               <input
                 type="number"
                 value={acl.priority}
@@ -870,6 +1120,48 @@ function EditForm({
         )}
       </div>
 
+      {/* Group Assignment */}
+      <div className="bg-white rounded-lg border p-5">
+        <h2 className="text-base font-semibold text-gray-900 mb-3">Grupos Associados</h2>
+        {availableGroups.length === 0 ? (
+          <p className="text-sm text-gray-500">Nenhum grupo cadastrado.</p>
+        ) : (
+          <div className="space-y-1">
+            {availableGroups.map((group) => (
+              <label key={group.id} className="flex items-center gap-2 p-2 rounded hover:bg-gray-50 cursor-pointer">
+                <input
+                  type="checkbox"
+                  checked={selectedGroupIds.includes(group.id)}
+                  onChange={() =>
+                    setSelectedGroupIds(
+                      selectedGroupIds.includes(group.id)
+                        ? selectedGroupIds.filter((g) => g !== group.id)
+                        : [...selectedGroupIds, group.id]
+                    )
+                  }
+                  className="rounded"
+                />
+                <span className="text-sm">{group.name}</span>
+                <span className="text-xs text-gray-500 font-mono">{formatLabels(group.selector) || 'todos os proxies'}</span>
+              </label>
+            ))}
+          </div>
+        )}
+      </div>
+
+      <RuleSetsEditor
+        ruleSets={ruleSets}
+        setRuleSets={setRuleSets}
+        availableRuleSets={availableRuleSets}
+      />
+
+      <OverlaysEditor
+        overlays={overlays}
+        setOverlays={setOverlays}
+        availableProxies={availableProxies}
+        availableGroups={availableGroups}
+      />
+
       <div className="flex gap-3">
         <button
           onClick={onSave}
@@ -888,3 +1180,260 @@ function EditForm({
     </div>
   );
 }
+
+// RuleSetsEditor picks the rule sets the config references. A reference keeps
+// the version it was added with until explicitly moved to the current one.
+function RuleSetsEditor({
+  ruleSets, setRuleSets, availableRuleSets,
+}: {
+  ruleSets: ConfigRuleSet[]; setRuleSets: (v: ConfigRuleSet[]) => void;
+  availableRuleSets: RuleSet[];
+}) {
+  const unused = availableRuleSets.filter((rs) => !ruleSets.some((r) => r.rule_set_id === rs.id));
+
+  function update(i: number, r: ConfigRuleSet) {
+    const next = [...ruleSets];
+    next[i] = r;
+    setRuleSets(next);
+  }
+
+  return (
+    <div className="bg-white rounded-lg border p-5">
+      <div className="flex items-center justify-between mb-3">
+        <h2 className="text-base font-semibold text-gray-900">Conjuntos de Regras</h2>
+        {unused.length > 0 && (
+          <select
+            value=""
+            onChange={(e) => {
+              const rs = availableRuleSets.find((s) => s.id === e.target.value);
+              if (!rs) return;
+              setRuleSets([
+                ...ruleSets,
+                { rule_set_id: rs.id, name: rs.name, version: rs.version, latest_version: rs.version, priority: 0 },
+              ]);
+            }}
+            className="px-2 py-1 border border-gray-300 rounded-md text-sm"
+          >
+            <option value="">+ Adicionar conjunto</option>
+            {unused.map((rs) => (
+              <option key={rs.id} value={rs.id}>{rs.name} (v{rs.version})</option>
+            ))}
+          </select>
+        )}
+      </div>
+      <p className="text-xs text-gray-500 mb-3">
+        As regras do conjunto entram entre as da config na prioridade indicada; no empate vale a regra da config.
+      </p>
+      {ruleSets.length === 0 ? (
+        <p className="text-sm text-gray-500">Nenhum conjunto de regras.</p>
+      ) : (
+        <div className="space-y-2">
+          {ruleSets.map((r, i) => (
+            <div key={r.rule_set_id} className="flex items-center gap-3">
+              <span className="flex-1 text-sm font-medium">{r.name}</span>
+              <span className="text-sm text-gray-600">v{r.version}</span>
+              {r.latest_version !== undefined && r.latest_version > r.version && (
+                <button
+                  onClick={() => update(i, { ...r, version: r.latest_version! })}
+                  className="text-xs text-amber-700 hover:underline"
+                >
+                  Atualizar para v{r.latest_version}
+                </button>
+              )}
+              <input
+                type="number"
+                value={r.priority}
+                onChange={(e) => update(i, { ...r, priority: parseInt(e.target.value) || 0 })}
+                className="w-20 px-2 py-1 border border-gray-300 rounded-md text-sm"
+                title="Prioridade"
+              />
+              <button
+                onClick={() => setRuleSets(ruleSets.filter((_, idx) => idx !== i))}
+                className="text-red-500 hover:text-red-700 text-sm"
+              >
+                Remover
+              </button>
+            </div>
+          ))}
+        </div>
+      )}
+    </div>
+  );
+}
+
+type OverlayRuleKind = 'domains' | 'ip_ranges' | 'client_acl';
+
+// OverlaysEditor edits the extra rules of single proxies or groups. Priorities
+// follow the order of the rows; overlays win over the config's own rules.
+function OverlaysEditor({
+  overlays, setOverlays, availableProxies, availableGroups,
+}: {
+  overlays: ConfigOverlay[]; setOverlays: (v: ConfigOverlay[]) => void;
+  availableProxies: Proxy[];
+  availableGroups: ProxyGroup[];
+}) {
+  function update(i: number, o: ConfigOverlay) {
+    const next = [...overlays];
+    next[i] = o;
+    setOverlays(next);
+  }
+
+  function setTarget(i: number, value: string) {
+    const [kind, target] = value.split(':', 2);
+    update(i, {
+      ...overlays[i],
+      hostname: kind === 'proxy' ? target : undefined,
+      group_id: kind === 'group' ? target : undefined,
+    });
+  }
+
+  function addRule(i: number, kind: OverlayRuleKind) {
+    const o = overlays[i];
+    const priority = (o[kind].length + 1) * 10;
+    if (kind === 'domains') {
+      update(i, { ...o, domains: [...o.domains, { domain: '', action: 'direct', priority }] });
+    } else if (kind === 'ip_ranges') {
+      update(i, { ...o, ip_ranges: [...o.ip_ranges, { cidr: '', action: 'direct', priority }] });
+    } else {
+      update(i, { ...o, client_acl: [...o.client_acl, { cidr: '', action: 'allow', priority }] });
+    }
+  }
+
+  function setRule(i: number, kind: OverlayRuleKind, j: number, field: 'value' | 'action', value: string) {
+    const o = overlays[i];
+    if (kind === 'domains') {
+      const rules = [...o.domains];
+      rules[j] = field === 'value' ? { ...rules[j], domain: value } : { ...rules[j], action: value as RuleAction };
+      update(i, { ...o, domains: rules });
+    } else if (kind === 'ip_ranges') {
+      const rules = [...o.ip_ranges];
+      rules[j] = field === 'value' ? { ...rules[j], cidr: value } : { ...rules[j], action: value as RuleAction };
+      update(i, { ...o, ip_ranges: rules });
+    } else {
+      const rules = [...o.client_acl];
+      rules[j] = field === 'value' ? { ...rules[j], cidr: value } : { ...rules[j], action: value as ClientACLRule['action'] };
+      update(i, { ...o, client_acl: rules });
+    }
+  }
+
+  function removeRule(i: number, kind: OverlayRuleKind, j: number) {
+    const o = overlays[i];
+    if (kind === 'domains') {
+      update(i, { ...o, domains: o.domains.filter((_, idx) => idx !== j) });
+    } else if (kind === 'ip_ranges') {
+      update(i, { ...o, ip_ranges: o.ip_ranges.filter((_, idx) => idx !== j) });
+    } else {
+      update(i, { ...o, client_acl: o.client_acl.filter((_, idx) => idx !== j) });
+    }
+  }
+
+  const sections: { kind: OverlayRuleKind; label: string; placeholder: string; actions: string[] }[] = [
+    { kind: 'domains', label: 'Domínios', placeholder: '*.intranet.example.com', actions: ['direct', 'parent', 'deny'] },
+    { kind: 'ip_ranges', label: 'IPs', placeholder: '172.20.0.0/16', actions: ['direct', 'parent', 'deny'] },
+    { kind: 'client_acl', label: 'ACL de clientes', placeholder: '172.21.0.0/16', actions: ['allow', 'deny'] },
+  ];
+
+  return (
+    <div className="bg-white rounded-lg border p-5">
+      <div className="flex items-center justify-between mb-1">
+        <h2 className="text-base font-semibold text-gray-900">Overlays</h2>
+        <button
+          type="button"
+          onClick={() => setOverlays([...overlays, { domains: [], ip_ranges: [], client_acl: [] }])}
+          className="px-3 py-1 text-sm text-blue-600 border border-blue-300 rounded-md hover:bg-blue-50"
+        >
+          + Adicionar
+        </button>
+      </div>
+      <p className="text-xs text-gray-500 mb-3">
+        Regras extras de um proxy ou grupo, aplicadas antes das regras da config. O overlay do proxy
+        vence os dos grupos; entre grupos, vence o de maior prioridade.
+      </p>
+      <div className="space-y-4">
+        {overlays.map((o, i) => {
+          const target = o.hostname ? `proxy:${o.hostname}` : o.group_id ? `group:${o.group_id}` : '';
+          return (
+            <div key={i} className="border rounded-md p-3 space-y-3">
+              <div className="flex gap-2 items-center">
+                <select
+                  value={target}
+                  onChange={(e) => setTarget(i, e.target.value)}
+                  className="w-64 px-3 py-2 border border-gray-300 rounded-md text-sm"
+                >
+                  <option value="" disabled>Selecione proxy ou grupo</option>
+                  <optgroup label="Proxies">
+                    {availableProxies.map((p) => (
+                      <option key={p.id} value={`proxy:${p.hostname}`}>{p.hostname}</option>
+                    ))}
+                  </optgroup>
+                  <optgroup label="Grupos">
+                    {availableGroups.map((g) => (
+                      <option key={g.id} value={`group:${g.id}`}>{g.name}</option>
+                    ))}
+                  </optgroup>
+                </select>
+                <input
+                  value={o.description || ''}
+                  onChange={(e) => update(i, { ...o, description: e.target.value || undefined })}
+                  className="flex-1 px-3 py-2 border border-gray-300 rounded-md text-sm"
+                  placeholder="Descrição"
+                />
+                <button
+                  onClick={() => setOverlays(overlays.filter((_, idx) => idx !== i))}
+                  className="w-8 h-8 flex items-center justify-center text-red-500 hover:bg-red-50 rounded text-lg"
+                >
+                  &times;
+                </button>
+              </div>
+              {sections.map(({ kind, label, placeholder, actions }) => (
+                <div key={kind}>
+                  <div className="flex items-center justify-between mb-1">
+                    <span className="text-xs font-medium text-gray-600">{label}</span>
+                    <button
+                      type="button"
+                      onClick={() => addRule(i, kind)}
+                      className="text-xs text-blue-600 hover:underline"
+                    >
+                      + Regra
+                    </button>
+                  </div>
+                  <div className="space-y-1">
+                    {o[kind].map((r, j) => {
+                      const value = 'domain' in r ? r.domain : r.cidr;
+                      const valid = kind === 'domains' ? isValidDomain(value) : isValidCIDR(value);
+                      return (
+                        <div key={j} className="flex gap-2 items-center">
+                          <input
+                            value={value}
+                            onChange={(e) => setRule(i, kind, j, 'value', e.target.value)}
+                            className={`flex-1 px-3 py-1.5 border rounded-md text-sm font-mono ${value && !valid ? 'border-red-500 bg-red-50' : 'border-gray-300'}`}
+                            placeholder={placeholder}
+                          />
+                          <select
+                            value={r.action}
+                            onChange={(e) => setRule(i, kind, j, 'action', e.target.value)}
+                            className="w-28 px-2 py-1.5 border border-gray-300 rounded-md text-sm"
+                          >
+                            {actions.map((a) => (
+                              <option key={a} value={a}>{a}</option>
+                            ))}
+                          </select>
+                          <button
+                            onClick={() => removeRule(i, kind, j)}
+                            className="w-7 h-7 flex items-center justify-center text-red-500 hover:bg-red-50 rounded"
+                          >
+                            &times;
+                          </button>
+                        </div>
+                      );
+                    })}
+                  </div>
+                </div>
+              ))}
+            </div>
+          );
+        })}
+      </div>
+    </div>
+  );
+}
@@ -4,7 +4,9 @@ import { useState, useEffect } from 'react';
 import { useRouter } from 'next/navigation';
 import toast from 'react-hot-toast';
 import { api } from '@/lib/api';
-import type { RuleAction, DomainRule, IPRangeRule, ParentProxy, ClientACLRule, Proxy, ApiError } from '@/types';
+import type { RuleAction, TLSPolicy, DomainRule, IPRangeRule, ParentProxy, ClientACLRule, Proxy, ProxyGroup, ApiError } from '@/types';
+import { formatLabels, parseMethods } from '@/lib/utils';
+import { TLSPolicyFields } from '@/components/tls-policy-fields';
 
 const DOMAIN_RE = /^(\*\.)?[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)+$/;
 const IPV4_RE = /^(\d{1,3}\.){3}\d{1,3}$/;
@@ -32,6 +34,7 @@ export default function NewConfigPage() {
   const [name, setName] = useState('');
   const [description, setDescription] = useState('');
   const [defaultAction, setDefaultAction] = useState<RuleAction>('direct');
+  const [connectPorts, setConnectPorts] = useState('');
   const [domains, setDomains] = useState<Omit<DomainRule, 'id'>[]>([]);
   const [ipRanges, setIpRanges] = useState<Omit<IPRangeRule, 'id'>[]>([]);
   const [parentProxies, setParentProxies] = useState<Omit<ParentProxy, 'id'>[]>([]);
@@ -40,9 +43,12 @@ export default function NewConfigPage() {
   ]);
   const [selectedProxyIds, setSelectedProxyIds] = useState<string[]>([]);
   const [availableProxies, setAvailableProxies] = useState<Proxy[]>([]);
+  const [selectedGroupIds, setSelectedGroupIds] = useState<string[]>([]);
+  const [availableGroups, setAvailableGroups] = useState<ProxyGroup[]>([]);
 
   useEffect(() => {
     api.proxies.list().then((res) => setAvailableProxies(res.data || [])).catch(() => {});
+    api.groups.list().then((res) => setAvailableGroups(res.data || [])).catch(() => {});
   }, []);
 
   function addDomain() {
@@ -53,7 +59,7 @@ export default function NewConfigPage() {
     setDomains(domains.filter((_, idx) => idx !== i));
   }
 
-  function updateDomain(i: number, field: string, value: string | number) {
+  function updateDomain(i: number, field: string, value: string | number | TLSPolicy | undefined) {
     const next = [...domains];
     next[i] = { ...next[i], [field]: value };
     setDomains(next);
@@ -98,7 +104,7 @@ export default function NewConfigPage() {
     setClientACL(clientACL.filter((_, idx) => idx !== i));
   }
 
-  function updateClientACL(i: number, field: string, value: string | number) {
+  function updateClientACL(i: number, field: string, value: string | number | string[]) {
     const next = [...clientACL];
     next[i] = { ...next[i], [field]: value };
     setClientACL(next);
@@ -110,6 +116,12 @@ export default function NewConfigPage() {
     );
   }
 
+  function toggleGroup(id: string) {
+    setSelectedGroupIds((prev) =>
+      prev.includes(id) ? prev.filter((g) => g !== id) : [...prev, id]
+    );
+  }
+
   async function handleSubmit(e: React.FormEvent) {
     e.preventDefault();
     if (!name.trim()) {
@@ -122,11 +134,13 @@ export default function NewConfigPage() {
         name: name.trim(),
         description: description.trim() || undefined,
         default_action: defaultAction,
-        domains,
+        connect_ports: connectPorts.trim() || undefined,
+        domains: domains.map((d) => ({ ...d, tls: d.action === 'deny' ? undefined : d.tls })),
         ip_ranges: ipRanges,
         parent_proxies: parentProxies,
-        client_acl: clientACL,
+        client_acl: clientACL.map((a) => ({ ...a, methods: a.methods?.filter(Boolean) })),
         proxy_ids: selectedProxyIds,
+        group_ids: selectedGroupIds,
       });
       toast.success('Config criada com sucesso');
       router.push(`/configs/${res.id}`);
@@ -176,6 +190,17 @@ export default function NewConfigPage() {
                 Define o que acontece com tráfego que não corresponde a nenhuma regra específica.
               </p>
             </Field>
+            <Field label="Portas CONNECT">
+              <input
+                value={connectPorts}
+                onChange={(e) => setConnectPorts(e.target.value)}
+                className="px-3 py-2 border border-gray-300 rounded-md text-sm font-mono focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
+                placeholder="443 563"
+              />
+              <p className="text-xs text-gray-500 mt-1">
+                Portas e faixas liberadas para túneis CONNECT (ex: 443 563 8443 2200-2299). Vazio mantém as da imagem.
+              </p>
+            </Field>
           </div>
         </Section>
 
@@ -189,36 +214,56 @@ export default function NewConfigPage() {
           ) : (
             <div className="space-y-2">
               {domains.map((d, i) => (
-                <div key={i} className="flex gap-2 items-start">
-                  <div className="flex-1">
+                <div key={i} className="space-y-2">
+                  <div className="flex gap-2 items-start">
+                    <div className="flex-1">
+                      <input
+                        value={d.domain}
+                        onChange={(e) => updateDomain(i, 'domain', e.target.value)}
+                        className={`w-full px-3 py-2 border rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent ${d.domain && !isValidDomain(d.domain) ? 'border-red-500 bg-red-50' : 'border-gray-300'}`}
+                        placeholder="*.example.com"
+                      />
+                      {d.domain && !isValidDomain(d.domain) && (
+                        <p className="text-xs text-red-500 mt-0.5">Formato inválido (use *.example.com ou host.example.com)</p>
+                      )}
+                    </div>
+                    <select
+                      value={d.action}
+                      onChange={(e) => updateDomain(i, 'action', e.target.value)}
+                      className="px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent w-32"
+                    >
+                      <option value="direct">Direct</option>
+                      <option value="parent">Parent</option>
+                      <option value="deny">Deny</option>
+                    </select>
                     <input
-                      value={d.domain}
-                      onChange={(e) => updateDomain(i, 'domain', e.target.value)}
-                      className={`w-full px-3 py-2 border rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent ${d.domain && !isValidDomain(d.domain) ? 'border-red-500 bg-red-50' : 'border-gray-300'}`}
-                      placeholder="*.example.com"
+                      type="number"
+                      value={d.priority}
+                      onChange={(e) => updateDomain(i, 'priority', parseInt(e.target.value) || 0)}
+                      className="px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent w-24"
+                      placeholder="Prioridade"
                     />
-                    {d.domain && !isValidDomain(d.domain) && (
-                      <p className="text-xs text-red-500 mt-0.5">Formato inválido (use *.example.com ou host.example.com)</p>
+                    {d.action !== 'deny' && (
+                      <button
+                        type="button"
+                        onClick={() => updateDomain(i, 'tls', d.tls ? undefined : {})}
+                        className={`px-2 py-2 text-xs border rounded-md ${d.tls ? 'text-blue-600 border-blue-300 bg-blue-50' : 'text-gray-600 border-gray-300 hover:bg-gray-50'}`}
+                        title="Política TLS (sni.yaml)"
+                      >
+                        TLS
+                      </button>
                     )}
+                    <button type="button" onClick={() => removeDomain(i)} className="w-8 h-8 flex items-center justify-center text-red-500 hover:bg-red-50 rounded text-lg leading-none mt-1">
+                      &times;
+                    </button>
                   </div>
-                  <select
-                    value={d.action}
-                    onChange={(e) => updateDomain(i, 'action', e.target.value)}
-                    className="px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent w-32"
-                  >
-                    <option value="direct">Direct</option>
-                    <option value="parent">Parent</option>
-                  </select>
-                  <input
-                    type="number"
-                    value={d.priority}
-                    onChange={(e) => updateDomain(i, 'priority', parseInt(e.target.value) || 0)}
-                    className="px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent w-24"
-                    placeholder="Prioridade"
-                  />
-                  <button type="button" onClick={() => removeDomain(i)} className="w-8 h-8 flex items-center justify-center text-red-500 hover:bg-red-50 rounded text-lg leading-none mt-1">
-                    &times;
-                  </button>
+                  {d.tls && d.action !== 'deny' && (
+                    <TLSPolicyFields
+                      policy={d.tls}
+                      onChange={(tls) => updateDomain(i, 'tls', tls)}
+                      allowForwardRoute={d.action === 'direct'}
+                    />
+                  )}
                 </div>
               ))}
             </div>
@@ -254,6 +299,7 @@ export default function NewConfigPage() {
                   >
                     <option value="direct">Direct</option>
                     <option value="parent">Parent</option>
+                    <option value="deny">Deny</option>
                   </select>
                   <input
                     type="number"
@@ -362,6 +408,22 @@ export default function NewConfigPage() {
                     <option value="allow">Allow</option>
                     <option value="deny">Deny</option>
                   </select>
+                  <select
+                    value={acl.apply || 'in'}
+                    onChange={(e) => updateClientACL(i, 'apply', e.target.value)}
+                    className="px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent w-28"
+                    title="in: IP do cliente; out: IP de destino"
+                  >
+                    <option value="in">Cliente</option>
+                    <option value="out">Destino</option>
+                  </select>
+                  <input
+                    value={(acl.methods || []).join(' ')}
+                    onChange={(e) => updateClientACL(i, 'methods', parseMethods(e.target.value))}
+                    className="px-3 py-2 border border-gray-300 rounded-md text-sm font-mono focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent w-36"
+                    placeholder="ALL"
+                    title="Métodos HTTP (ex: GET HEAD); vazio = todos"
+                  />
                   <input
                     type="number"
                     value={acl.priority}
@@ -400,6 +462,33 @@ export default function NewConfigPage() {
           )}
         </Section>
 
+        {/* Group Assignment */}
+        <Section title="Grupos Associados">
+          <p className="text-xs text-gray-500 mb-2">
+            A config vale para todo proxy selecionado pelo grupo, inclusive os que se registrarem depois.
+            Uma config associada direto ao proxy tem precedência.
+          </p>
+          {availableGroups.length === 0 ? (
+            <p className="text-sm text-gray-500">Nenhum grupo cadastrado.</p>
+          ) : (
+            <div className="space-y-1">
+              {availableGroups.map((group) => (
+                <label key={group.id} className="flex items-center gap-2 p-2 rounded hover:bg-gray-50 cursor-pointer">
+                  <input
+                    type="checkbox"
+                    checked={selectedGroupIds.includes(group.id)}
+                    onChange={() => toggleGroup(group.id)}
+                    className="rounded"
+                  />
+                  <span className="text-sm">{group.name}</span>
+                  <span className="text-xs text-gray-500 font-mono">{formatLabels(group.selector) || 'todos os proxies'}</span>
+                  <span className="text-xs text-gray-400">({group.proxy_count} proxies)</span>
+                </label>
+              ))}
+            </div>
+          )}
+        </Section>
+
         <div className="flex gap-3">
           <button
             type="submit"
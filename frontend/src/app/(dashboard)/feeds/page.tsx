@@ -0,0 +1,544 @@
+'use client';
+
+import { Fragment, useEffect, useState } from 'react';
+import Link from 'next/link';
+import toast from 'react-hot-toast';
+import { api } from '@/lib/api';
+import { useAuthStore } from '@/stores/auth-store';
+import type { FeedSource, FeedUpdate, RuleSet, RuleSetUpdate, RuleAction, ApiError } from '@/types';
+import { ConfirmDialog } from '@/components/confirm-dialog';
+import { TableSkeleton } from '@/components/loading';
+import { EmptyState } from '@/components/empty-state';
+import { formatDate } from '@/lib/utils';
+
+interface FeedForm {
+  name: string;
+  ruleSetId: string;
+  source: string;
+  format: string;
+  action: RuleAction;
+  intervalMinutes: number;
+  enabled: boolean;
+}
+
+const emptyForm: FeedForm = {
+  name: '',
+  ruleSetId: '',
+  source: '',
+  format: 'plain',
+  action: 'direct',
+  intervalMinutes: 1440,
+  enabled: true,
+};
+
+const statusLabels: Record<string, { label: string; className: string }> = {
+  changed: { label: 'Atualização pendente', className: 'bg-amber-100 text-amber-800' },
+  unchanged: { label: 'Em dia', className: 'bg-green-100 text-green-800' },
+  error: { label: 'Erro', className: 'bg-red-100 text-red-800' },
+};
+
+const updateStatusLabels: Record<string, string> = {
+  pending: 'pendente',
+  applied: 'aplicada',
+  discarded: 'descartada',
+};
+
+export default function FeedsPage() {
+  const user = useAuthStore((s) => s.user);
+  const canEdit = user?.role === 'root' || user?.role === 'admin';
+
+  const [feeds, setFeeds] = useState<FeedSource[]>([]);
+  const [ruleSets, setRuleSets] = useState<RuleSet[]>([]);
+  const [formats, setFormats] = useState<string[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [expanded, setExpanded] = useState<string | null>(null);
+  const [updates, setUpdates] = useState<FeedUpdate[]>([]);
+  const [busy, setBusy] = useState<string | null>(null);
+  const [lastUpdate, setLastUpdate] = useState<RuleSetUpdate | null>(null);
+
+  // Create / edit modal (editing null = novo feed)
+  const [showForm, setShowForm] = useState(false);
+  const [editing, setEditing] = useState<FeedSource | null>(null);
+  const [form, setForm] = useState<FeedForm>(emptyForm);
+  const [saving, setSaving] = useState(false);
+
+  const [deleteTarget, setDeleteTarget] = useState<FeedSource | null>(null);
+
+  async function load() {
+    try {
+      const [feedsRes, setsRes, formatsRes] = await Promise.all([
+        api.feeds.list(),
+        api.ruleSets.list(),
+        api.feeds.formats(),
+      ]);
+      setFeeds(feedsRes.data || []);
+      setRuleSets(setsRes.data || []);
+      setFormats(formatsRes.data || []);
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao carregar feeds');
+    } finally {
+      setLoading(false);
+    }
+  }
+
+  useEffect(() => {
+    load();
+  }, []);
+
+  async function loadUpdates(feedId: string) {
+    const res = await api.feeds.updates(feedId);
+    setUpdates(res.data || []);
+  }
+
+  async function toggleUpdates(f: FeedSource) {
+    if (expanded === f.id) {
+      setExpanded(null);
+      return;
+    }
+    try {
+      await loadUpdates(f.id);
+      setExpanded(f.id);
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao carregar atualizações');
+    }
+  }
+
+  async function handleRefresh(f: FeedSource) {
+    setBusy(f.id);
+    try {
+      const res = await api.feeds.refresh(f.id);
+      toast.success(res.update ? 'Atualização pendente de revisão' : 'O conjunto já está igual ao feed');
+      await load();
+      if (expanded === f.id || res.update) {
+        await loadUpdates(f.id);
+        setExpanded(f.id);
+      }
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao buscar feed');
+      load();
+    } finally {
+      setBusy(null);
+    }
+  }
+
+  async function handleApply(f: FeedSource, u: FeedUpdate) {
+    setBusy(f.id);
+    try {
+      const res = await api.feeds.apply(f.id, u.id);
+      toast.success(`Conjunto ${f.rule_set_name} atualizado (versão ${res.rule_set.version})`);
+      if (res.drafts.length > 0 || res.pending_approval.length > 0) setLastUpdate(res);
+      await load();
+      await loadUpdates(f.id);
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao aplicar atualização');
+    } finally {
+      setBusy(null);
+    }
+  }
+
+  async function handleDiscard(f: FeedSource, u: FeedUpdate) {
+    setBusy(f.id);
+    try {
+      await api.feeds.discard(f.id, u.id);
+      toast.success('Atualização descartada');
+      await load();
+      await loadUpdates(f.id);
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao descartar atualização');
+    } finally {
+      setBusy(null);
+    }
+  }
+
+  function openCreate() {
+    setEditing(null);
+    setForm({ ...emptyForm, ruleSetId: ruleSets.find((rs) => !feeds.some((f) => f.rule_set_id === rs.id))?.id || '' });
+    setShowForm(true);
+  }
+
+  function openEdit(f: FeedSource) {
+    setEditing(f);
+    setForm({
+      name: f.name,
+      ruleSetId: f.rule_set_id,
+      source: f.source,
+      format: f.format,
+      action: f.action,
+      intervalMinutes: f.interval_minutes,
+      enabled: f.enabled,
+    });
+    setShowForm(true);
+  }
+
+  async function handleSave(e: React.FormEvent) {
+    e.preventDefault();
+    const data = {
+      name: form.name.trim(),
+      source: form.source.trim(),
+      format: form.format,
+      action: form.action,
+      interval_minutes: form.intervalMinutes,
+      enabled: form.enabled,
+    };
+    setSaving(true);
+    try {
+      if (editing) {
+        await api.feeds.update(editing.id, data);
+        toast.success('Feed atualizado');
+      } else {
+        await api.feeds.create({ ...data, rule_set_id: form.ruleSetId });
+        toast.success('Feed criado');
+      }
+      setShowForm(false);
+      load();
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao salvar feed');
+    } finally {
+      setSaving(false);
+    }
+  }
+
+  async function handleDelete() {
+    if (!deleteTarget) return;
+    try {
+      await api.feeds.delete(deleteTarget.id);
+      toast.success('Feed removido');
+      setDeleteTarget(null);
+      if (expanded === deleteTarget.id) setExpanded(null);
+      load();
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao remover feed');
+    }
+  }
+
+  const inputClass =
+    'w-full px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent';
+
+  return (
+    <div>
+      <div className="flex items-center justify-between mb-6">
+        <h1 className="text-2xl font-bold text-gray-900">Feeds</h1>
+        {canEdit && (
+          <button
+            onClick={openCreate}
+            className="px-4 py-2 bg-blue-600 text-white text-sm font-medium rounded-md hover:bg-blue-700"
+          >
+            Novo Feed
+          </button>
+        )}
+      </div>
+      <p className="text-sm text-gray-500 mb-4">
+        Listas externas (endpoints do Microsoft 365, faixas de IP de nuvem, listas em texto) que mantêm um conjunto
+        de regras. Mudanças no feed ficam pendentes até serem aplicadas, criando uma nova versão do conjunto.
+      </p>
+
+      {lastUpdate && (
+        <div className="mb-4 p-4 bg-blue-50 border border-blue-200 rounded-lg text-sm">
+          <div className="flex items-start justify-between">
+            <div className="space-y-2">
+              {lastUpdate.drafts.length > 0 && (
+                <div>
+                  <span className="font-medium">Drafts com a versão {lastUpdate.rule_set.version}:</span>{' '}
+                  {lastUpdate.drafts.map((c, i) => (
+                    <Fragment key={c.id}>
+                      {i > 0 && ', '}
+                      <Link href={`/configs/${c.id}`} className="text-blue-600 hover:underline">
+                        {c.name} v{c.version}
+                      </Link>
+                    </Fragment>
+                  ))}
+                </div>
+              )}
+              {lastUpdate.pending_approval.length > 0 && (
+                <div>
+                  <span className="font-medium">Aguardando aprovação com versão antiga:</span>{' '}
+                  {lastUpdate.pending_approval.map((c, i) => (
+                    <Fragment key={c.id}>
+                      {i > 0 && ', '}
+                      <Link href={`/configs/${c.id}`} className="text-blue-600 hover:underline">
+                        {c.name} v{c.version}
+                      </Link>
+                    </Fragment>
+                  ))}
+                </div>
+              )}
+            </div>
+            <button onClick={() => setLastUpdate(null)} className="text-gray-400 hover:text-gray-600">
+              &times;
+            </button>
+          </div>
+        </div>
+      )}
+
+      {loading ? (
+        <TableSkeleton cols={5} />
+      ) : feeds.length === 0 ? (
+        <EmptyState title="Nenhum feed cadastrado" />
+      ) : (
+        <div className="bg-white rounded-lg border overflow-hidden">
+          <table className="w-full text-sm">
+            <thead className="bg-gray-50 border-b">
+              <tr>
+                <th className="text-left px-4 py-3 font-medium text-gray-600">Nome</th>
+                <th className="text-left px-4 py-3 font-medium text-gray-600">Conjunto</th>
+                <th className="text-left px-4 py-3 font-medium text-gray-600">Formato</th>
+                <th className="text-left px-4 py-3 font-medium text-gray-600">Última busca</th>
+                <th className="text-right px-4 py-3 font-medium text-gray-600">Ações</th>
+              </tr>
+            </thead>
+            <tbody className="divide-y">
+              {feeds.map((f) => {
+                const status = f.last_status ? statusLabels[f.last_status] : undefined;
+                return (
+                  <Fragment key={f.id}>
+                    <tr className="hover:bg-gray-50">
+                      <td className="px-4 py-3">
+                        <button onClick={() => toggleUpdates(f)} className="font-medium text-blue-600 hover:underline">
+                          {f.name}
+                        </button>
+                        {!f.enabled && <span className="ml-2 text-xs text-gray-400">desativado</span>}
+                        <div className="text-xs text-gray-500 truncate max-w-xs" title={f.source}>
+                          {f.source}
+                        </div>
+                      </td>
+                      <td className="px-4 py-3">{f.rule_set_name}</td>
+                      <td className="px-4 py-3 text-gray-600">
+                        {f.format} · {f.action}
+                      </td>
+                      <td className="px-4 py-3">
+                        {f.last_fetched_at ? (
+                          <>
+                            <div className="text-gray-600">{formatDate(f.last_fetched_at)}</div>
+                            {status && (
+                              <span
+                                className={`inline-block px-2 py-0.5 rounded text-xs ${status.className}`}
+                                title={f.last_error}
+                              >
+                                {status.label}
+                              </span>
+                            )}
+                          </>
+                        ) : (
+                          <span className="text-gray-400">nunca</span>
+                        )}
+                      </td>
+                      <td className="px-4 py-3 text-right">
+                        {canEdit && (
+                          <div className="flex justify-end gap-1">
+                            <button
+                              onClick={() => handleRefresh(f)}
+                              disabled={busy === f.id}
+                              className="px-2 py-1 text-xs text-blue-600 hover:bg-blue-50 rounded disabled:opacity-40"
+                            >
+                              Buscar agora
+                            </button>
+                            <button
+                              onClick={() => openEdit(f)}
+                              className="px-2 py-1 text-xs text-blue-600 hover:bg-blue-50 rounded"
+                            >
+                              Editar
+                            </button>
+                            <button
+                              onClick={() => setDeleteTarget(f)}
+                              className="px-2 py-1 text-xs text-red-600 hover:bg-red-50 rounded"
+                            >
+                              Remover
+                            </button>
+                          </div>
+                        )}
+                      </td>
+                    </tr>
+                    {expanded === f.id && (
+                      <tr>
+                        <td colSpan={5} className="px-4 py-3 bg-gray-50">
+                          {f.last_status === 'error' && f.last_error && (
+                            <div className="mb-3 text-xs text-red-700">{f.last_error}</div>
+                          )}
+                          {updates.length === 0 ? (
+                            <div className="text-xs text-gray-500">Nenhuma atualização até agora.</div>
+                          ) : (
+                            <div className="space-y-4">
+                              {updates.map((u) => (
+                                <div key={u.id}>
+                                  <div className="flex items-center justify-between mb-1">
+                                    <div className="text-xs text-gray-500">
+                                      {formatDate(u.fetched_at)} · sobre v{u.base_version} ·{' '}
+                                      {updateStatusLabels[u.status]}
+                                      {u.applied_version && ` (v${u.applied_version})`} · {u.domains.length} domínios,{' '}
+                                      {u.ip_ranges.length} CIDRs
+                                      {u.skipped > 0 && ` · ${u.skipped} ignoradas`}
+                                    </div>
+                                    {canEdit && u.status === 'pending' && (
+                                      <div className="flex gap-1">
+                                        <button
+                                          onClick={() => handleApply(f, u)}
+                                          disabled={busy === f.id}
+                                          className="px-2 py-1 text-xs bg-blue-600 text-white rounded hover:bg-blue-700 disabled:opacity-50"
+                                        >
+                                          Aplicar
+                                        </button>
+                                        <button
+                                          onClick={() => handleDiscard(f, u)}
+                                          disabled={busy === f.id}
+                                          className="px-2 py-1 text-xs border rounded hover:bg-white disabled:opacity-50"
+                                        >
+                                          Descartar
+                                        </button>
+                                      </div>
+                                    )}
+                                  </div>
+                                  <div className="font-mono text-xs whitespace-pre-wrap max-h-48 overflow-y-auto">
+                                    {u.added.map((v) => (
+                                      <div key={`+${v}`} className="text-green-700">
+                                        + {v}
+                                      </div>
+                                    ))}
+                                    {u.removed.map((v) => (
+                                      <div key={`-${v}`} className="text-red-700">
+                                        - {v}
+                                      </div>
+                                    ))}
+                                    {u.added.length === 0 && u.removed.length === 0 && (
+                                      <div className="text-gray-500">Mudança apenas na ação das regras</div>
+                                    )}
+                                  </div>
+                                </div>
+                              ))}
+                            </div>
+                          )}
+                        </td>
+                      </tr>
+                    )}
+                  </Fragment>
+                );
+              })}
+            </tbody>
+          </table>
+        </div>
+      )}
+
+      {showForm && (
+        <div className="fixed inset-0 z-50 flex items-center justify-center">
+          <div className="fixed inset-0 bg-black/50" onClick={() => setShowForm(false)} />
+          <div className="relative bg-white rounded-lg shadow-xl max-w-lg w-full mx-4 p-6">
+            <h3 className="text-lg font-semibold mb-4">{editing ? 'Editar Feed' : 'Novo Feed'}</h3>
+            <form onSubmit={handleSave} className="space-y-3">
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Nome</label>
+                <input
+                  value={form.name}
+                  onChange={(e) => setForm({ ...form, name: e.target.value })}
+                  className={inputClass}
+                  required
+                />
+              </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Conjunto de regras</label>
+                <select
+                  value={form.ruleSetId}
+                  onChange={(e) => setForm({ ...form, ruleSetId: e.target.value })}
+                  disabled={!!editing}
+                  className={inputClass}
+                  required
+                >
+                  <option value="">Selecione...</option>
+                  {ruleSets
+                    .filter((rs) => rs.id === form.ruleSetId || !feeds.some((f) => f.rule_set_id === rs.id))
+                    .map((rs) => (
+                      <option key={rs.id} value={rs.id}>
+                        {rs.name}
+                      </option>
+                    ))}
+                </select>
+              </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Origem</label>
+                <input
+                  value={form.source}
+                  onChange={(e) => setForm({ ...form, source: e.target.value })}
+                  placeholder="https://endpoints.office.com/endpoints/worldwide?clientrequestid=..."
+                  className={`${inputClass} font-mono`}
+                  required
+                />
+                <p className="text-xs text-gray-500 mt-1">URL http(s) ou arquivo no diretório de feeds do servidor.</p>
+              </div>
+              <div className="grid grid-cols-3 gap-3">
+                <div>
+                  <label className="block text-sm font-medium text-gray-700 mb-1">Formato</label>
+                  <select
+                    value={form.format}
+                    onChange={(e) => setForm({ ...form, format: e.target.value })}
+                    className={inputClass}
+                  >
+                    {formats.map((fmt) => (
+                      <option key={fmt} value={fmt}>
+                        {fmt}
+                      </option>
+                    ))}
+                  </select>
+                </div>
+                <div>
+                  <label className="block text-sm font-medium text-gray-700 mb-1">Ação</label>
+                  <select
+                    value={form.action}
+                    onChange={(e) => setForm({ ...form, action: e.target.value as RuleAction })}
+                    className={inputClass}
+                  >
+                    <option value="direct">direct</option>
+                    <option value="parent">parent</option>
+                    <option value="deny">deny</option>
+                  </select>
+                </div>
+                <div>
+                  <label className="block text-sm font-medium text-gray-700 mb-1">Intervalo (min)</label>
+                  <input
+                    type="number"
+                    min={15}
+                    max={10080}
+                    value={form.intervalMinutes}
+                    onChange={(e) => setForm({ ...form, intervalMinutes: parseInt(e.target.value) || 0 })}
+                    className={inputClass}
+                  />
+                </div>
+              </div>
+              <label className="flex items-center gap-2 text-sm text-gray-700">
+                <input
+                  type="checkbox"
+                  checked={form.enabled}
+                  onChange={(e) => setForm({ ...form, enabled: e.target.checked })}
+                />
+                Buscar automaticamente
+              </label>
+              <div className="flex justify-end gap-2 pt-2">
+                <button
+                  type="button"
+                  onClick={() => setShowForm(false)}
+                  className="px-4 py-2 text-sm border rounded-md hover:bg-gray-50"
+                >
+                  Cancelar
+                </button>
+                <button
+                  type="submit"
+                  disabled={saving}
+                  className="px-4 py-2 text-sm bg-blue-600 text-white rounded-md hover:bg-blue-700 disabled:opacity-50"
+                >
+                  {saving ? 'Salvando...' : 'Salvar'}
+                </button>
+              </div>
+            </form>
+          </div>
+        </div>
+      )}
+
+      <ConfirmDialog
+        open={!!deleteTarget}
+        title="Remover Feed"
+        message={`Deseja remover o feed "${deleteTarget?.name}"? O conjunto ${deleteTarget?.rule_set_name} mantém as regras atuais.`}
+        confirmLabel="Remover"
+        variant="danger"
+        onConfirm={handleDelete}
+        onCancel={() => setDeleteTarget(null)}
+      />
+    </div>
+  );
+}
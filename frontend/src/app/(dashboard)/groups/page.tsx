@@ -0,0 +1,307 @@
+'use client';
+
+import { Fragment, useEffect, useState } from 'react';
+import Link from 'next/link';
+import toast from 'react-hot-toast';
+import { api } from '@/lib/api';
+import { useAuthStore } from '@/stores/auth-store';
+import type { ProxyGroup, ProxySummary, ApiError } from '@/types';
+import { ConfirmDialog } from '@/components/confirm-dialog';
+import { TableSkeleton } from '@/components/loading';
+import { EmptyState } from '@/components/empty-state';
+import { formatLabels, parseLabels } from '@/lib/utils';
+
+interface GroupForm {
+  name: string;
+  description: string;
+  selector: string;
+  priority: number;
+}
+
+const emptyForm: GroupForm = { name: '', description: '', selector: '', priority: 0 };
+
+export default function GroupsPage() {
+  const user = useAuthStore((s) => s.user);
+  const canEdit = user?.role === 'root' || user?.role === 'admin';
+
+  const [groups, setGroups] = useState<ProxyGroup[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [expanded, setExpanded] = useState<string | null>(null);
+  const [members, setMembers] = useState<ProxySummary[]>([]);
+
+  // Create / edit modal (editing null = novo grupo)
+  const [showForm, setShowForm] = useState(false);
+  const [editing, setEditing] = useState<ProxyGroup | null>(null);
+  const [form, setForm] = useState<GroupForm>(emptyForm);
+  const [saving, setSaving] = useState(false);
+
+  const [deleteTarget, setDeleteTarget] = useState<ProxyGroup | null>(null);
+
+  async function load() {
+    try {
+      const res = await api.groups.list();
+      setGroups(res.data || []);
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao carregar grupos');
+    } finally {
+      setLoading(false);
+    }
+  }
+
+  useEffect(() => {
+    load();
+  }, []);
+
+  async function toggleMembers(group: ProxyGroup) {
+    if (expanded === group.id) {
+      setExpanded(null);
+      return;
+    }
+    try {
+      const res = await api.groups.proxies(group.id);
+      setMembers(res.data || []);
+      setExpanded(group.id);
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao carregar proxies do grupo');
+    }
+  }
+
+  function openCreate() {
+    setEditing(null);
+    setForm(emptyForm);
+    setShowForm(true);
+  }
+
+  function openEdit(group: ProxyGroup) {
+    setEditing(group);
+    setForm({
+      name: group.name,
+      description: group.description || '',
+      selector: formatLabels(group.selector),
+      priority: group.priority,
+    });
+    setShowForm(true);
+  }
+
+  async function handleSave(e: React.FormEvent) {
+    e.preventDefault();
+    const selector = parseLabels(form.selector);
+    if (!selector || Object.keys(selector).length === 0) {
+      toast.error('Seletor: use o formato chave=valor, separados por vírgula');
+      return;
+    }
+    const data = {
+      name: form.name.trim(),
+      description: form.description.trim() || undefined,
+      selector,
+      priority: form.priority,
+    };
+    setSaving(true);
+    try {
+      if (editing) {
+        await api.groups.update(editing.id, data);
+        toast.success('Grupo atualizado');
+      } else {
+        await api.groups.create(data);
+        toast.success('Grupo criado');
+      }
+      setShowForm(false);
+      setExpanded(null);
+      load();
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao salvar grupo');
+    } finally {
+      setSaving(false);
+    }
+  }
+
+  async function handleDelete() {
+    if (!deleteTarget) return;
+    try {
+      await api.groups.delete(deleteTarget.id);
+      toast.success('Grupo removido');
+      setDeleteTarget(null);
+      load();
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao remover grupo');
+    }
+  }
+
+  const inputClass =
+    'w-full px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent';
+
+  return (
+    <div>
+      <div className="flex items-center justify-between mb-6">
+        <h1 className="text-2xl font-bold text-gray-900">Grupos</h1>
+        {canEdit && (
+          <button
+            onClick={openCreate}
+            className="px-4 py-2 bg-blue-600 text-white text-sm font-medium rounded-md hover:bg-blue-700"
+          >
+            Novo Grupo
+          </button>
+        )}
+      </div>
+      <p className="text-sm text-gray-500 mb-4">
+        Um grupo seleciona os proxies que têm todos os labels do seletor. Configs associadas ao grupo valem
+        para esses proxies, inclusive os que se registrarem depois; uma config associada direto ao proxy tem
+        precedência e, entre grupos, vence o de maior prioridade.
+      </p>
+
+      {loading ? (
+        <TableSkeleton cols={5} />
+      ) : groups.length === 0 ? (
+        <EmptyState title="Nenhum grupo cadastrado" />
+      ) : (
+        <div className="bg-white rounded-lg border overflow-hidden">
+          <table className="w-full text-sm">
+            <thead className="bg-gray-50 border-b">
+              <tr>
+                <th className="text-left px-4 py-3 font-medium text-gray-600">Nome</th>
+                <th className="text-left px-4 py-3 font-medium text-gray-600">Seletor</th>
+                <th className="text-right px-4 py-3 font-medium text-gray-600">Prioridade</th>
+                <th className="text-right px-4 py-3 font-medium text-gray-600">Proxies</th>
+                <th className="text-right px-4 py-3 font-medium text-gray-600">Ações</th>
+              </tr>
+            </thead>
+            <tbody className="divide-y">
+              {groups.map((group) => (
+                <Fragment key={group.id}>
+                  <tr className="hover:bg-gray-50">
+                    <td className="px-4 py-3">
+                      <span className="font-medium">{group.name}</span>
+                      {group.description && <div className="text-xs text-gray-500">{group.description}</div>}
+                    </td>
+                    <td className="px-4 py-3 font-mono text-xs">
+                      {formatLabels(group.selector)}
+                    </td>
+                    <td className="px-4 py-3 text-right">{group.priority}</td>
+                    <td className="px-4 py-3 text-right">
+                      <button onClick={() => toggleMembers(group)} className="text-blue-600 hover:underline">
+                        {group.proxy_count}
+                      </button>
+                    </td>
+                    <td className="px-4 py-3 text-right">
+                      {canEdit && (
+                        <div className="flex justify-end gap-1">
+                          <button
+                            onClick={() => openEdit(group)}
+                            className="px-2 py-1 text-xs text-blue-600 hover:bg-blue-50 rounded"
+                          >
+                            Editar
+                          </button>
+                          <button
+                            onClick={() => setDeleteTarget(group)}
+                            className="px-2 py-1 text-xs text-red-600 hover:bg-red-50 rounded"
+                          >
+                            Remover
+                          </button>
+                        </div>
+                      )}
+                    </td>
+                  </tr>
+                  {expanded === group.id && (
+                    <tr>
+                      <td colSpan={5} className="px-4 py-3 bg-gray-50">
+                        {members.length === 0 ? (
+                          <p className="text-sm text-gray-500">Nenhum proxy selecionado.</p>
+                        ) : (
+                          <div className="flex flex-wrap gap-2">
+                            {members.map((p) => (
+                              <Link
+                                key={p.id}
+                                href={`/proxies/${p.id}`}
+                                className="inline-flex items-center gap-1.5 px-3 py-1 bg-white border rounded-full text-sm hover:bg-gray-100"
+                              >
+                                <span className={`w-2 h-2 rounded-full ${p.is_online ? 'bg-green-500' : 'bg-red-500'}`} />
+                                {p.hostname}
+                              </Link>
+                            ))}
+                          </div>
+                        )}
+                      </td>
+                    </tr>
+                  )}
+                </Fragment>
+              ))}
+            </tbody>
+          </table>
+        </div>
+      )}
+
+      {showForm && (
+        <div className="fixed inset-0 z-50 flex items-center justify-center">
+          <div className="fixed inset-0 bg-black/50" onClick={() => setShowForm(false)} />
+          <div className="relative bg-white rounded-lg shadow-xl max-w-md w-full mx-4 p-6">
+            <h3 className="text-lg font-semibold mb-4">{editing ? 'Editar Grupo' : 'Novo Grupo'}</h3>
+            <form onSubmit={handleSave} className="space-y-3">
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Nome</label>
+                <input
+                  value={form.name}
+                  onChange={(e) => setForm({ ...form, name: e.target.value })}
+                  className={inputClass}
+                  required
+                />
+              </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Descrição</label>
+                <input
+                  value={form.description}
+                  onChange={(e) => setForm({ ...form, description: e.target.value })}
+                  className={inputClass}
+                />
+              </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Seletor</label>
+                <input
+                  value={form.selector}
+                  onChange={(e) => setForm({ ...form, selector: e.target.value })}
+                  placeholder="site=poa, env=prod"
+                  className={`${inputClass} font-mono`}
+                />
+                <p className="text-xs text-gray-500 mt-1">Seleciona os proxies que têm todos estes labels.</p>
+              </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Prioridade</label>
+                <input
+                  type="number"
+                  value={form.priority}
+                  onChange={(e) => setForm({ ...form, priority: parseInt(e.target.value) || 0 })}
+                  className={inputClass}
+                />
+              </div>
+              <div className="flex justify-end gap-2 pt-2">
+                <button
+                  type="button"
+                  onClick={() => setShowForm(false)}
+                  className="px-4 py-2 text-sm border rounded-md hover:bg-gray-50"
+                >
+                  Cancelar
+                </button>
+                <button
+                  type="submit"
+                  disabled={saving}
+                  className="px-4 py-2 text-sm bg-blue-600 text-white rounded-md hover:bg-blue-700 disabled:opacity-50"
+                >
+                  {saving ? 'Salvando...' : 'Salvar'}
+                </button>
+              </div>
+            </form>
+          </div>
+        </div>
+      )}
+
+      <ConfirmDialog
+        open={!!deleteTarget}
+        title="Remover Grupo"
+        message={`Deseja remover o grupo "${deleteTarget?.name}"? As configs associadas a ele deixam de valer para os seus proxies.`}
+        confirmLabel="Remover"
+        variant="danger"
+        onConfirm={handleDelete}
+        onCancel={() => setDeleteTarget(null)}
+      />
+    </div>
+  );
+}
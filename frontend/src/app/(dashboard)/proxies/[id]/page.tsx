@@ -1,14 +1,15 @@
 'use client';
 
-import { useEffect, useState, useCallback, useRef } from 'react';
+import { Fragment, useEffect, useState, useCallback, useRef } from 'react';
 import { useParams } from 'next/navigation';
 import Link from 'next/link';
 import toast from 'react-hot-toast';
 import { api } from '@/lib/api';
-import type { Proxy, ProxyLogs, Config, ApiError } from '@/types';
+import { useAuthStore } from '@/stores/auth-store';
+import type { Proxy, ProxyLogs, Config, ApiError, ProxyCommand, CommandType, DiagnosticsBundle } from '@/types';
 import { StatusBadge } from '@/components/status-badge';
 import { Loading } from '@/components/loading';
-import { formatDate, formatRelative, formatBytes } from '@/lib/utils';
+import { formatDate, formatRelative, formatBytes, formatLabels, parseLabels } from '@/lib/utils';
 
 export default function ProxyDetailPage() {
   const params = useParams();
@@ -19,10 +20,11 @@ export default function ProxyDetailPage() {
   const [logs, setLogs] = useState<ProxyLogs | null>(null);
   const [capturing, setCapturing] = useState(false);
   const [captureMinutes, setCaptureMinutes] = useState(1);
+  const [debugTags, setDebugTags] = useState('parent_select');
   const [activeConfigs, setActiveConfigs] = useState<Config[]>([]);
   const [selectedConfigId, setSelectedConfigId] = useState<string>('');
   const [savingConfig, setSavingConfig] = useState(false);
-  const pollRef = useRef<ReturnType<typeof setInterval> | null>(null);
+  const streamRef = useRef<AbortController | null>(null);
 
   const load = useCallback(async () => {
     try {
@@ -45,7 +47,7 @@ export default function ProxyDetailPage() {
     const interval = setInterval(load, 30000);
     return () => {
       clearInterval(interval);
-      if (pollRef.current) clearInterval(pollRef.current);
+      streamRef.current?.abort();
     };
   }, [load]);
 
@@ -64,28 +66,42 @@ export default function ProxyDetailPage() {
 
   async function startCapture() {
     try {
-      await api.proxies.startLogCapture(id, captureMinutes);
+      const tags = debugTags.split(/[\s,|]+/).filter(Boolean);
+      const { capture_until } = await api.proxies.startLogCapture(id, captureMinutes, tags);
       setCapturing(true);
       toast.success('Captura de logs iniciada');
-
-      pollRef.current = setInterval(async () => {
-        try {
-          const res = await api.proxies.getLogs(id);
-          setLogs(res);
-          if (res.capture_ended) {
-            setCapturing(false);
-            if (pollRef.current) clearInterval(pollRef.current);
-            toast.success('Captura finalizada');
-          }
-        } catch {
-          // keep polling
-        }
-      }, 3000);
+      streamLogs();
     } catch (err) {
       toast.error((err as ApiError).message || 'Erro ao iniciar captura');
     }
   }
 
+  // Acompanha a captura ao vivo até o backend encerrar o stream
+  async function streamLogs() {
+    streamRef.current?.abort();
+    const controller = new AbortController();
+    streamRef.current = controller;
+    setLogs({ proxy_id: id, lines: [] });
+
+    try {
+      await api.proxies.streamLogs(id, {
+        signal: controller.signal,
+        onLine: (line) =>
+          setLogs((prev) => ({ proxy_id: id, lines: [line, ...(prev?.lines || [])] })),
+      });
+      toast.success('Captura finalizada');
+    } catch (err) {
+      if (!controller.signal.aborted) {
+        toast.error((err as ApiError).message || 'Conexão com o stream de logs perdida');
+      }
+    } finally {
+      if (streamRef.current === controller) {
+        streamRef.current = null;
+        setCapturing(false);
+      }
+    }
+  }
+
   async function fetchLogs() {
     try {
       const res = await api.proxies.getLogs(id);
@@ -163,6 +179,8 @@ export default function ProxyDetailPage() {
         <InfoCard label="Hash no Proxy" value={proxy.current_config_hash?.slice(0, 12) || '-'} mono />
       </div>
 
+      <LabelsPanel proxyId={id} labels={proxy.labels} helperLabels={proxy.helper_labels} onSaved={load} />
+
       {/* Stats Overview */}
       {stats && (
         <div className="bg-white rounded-lg border p-5 mb-6">
@@ -224,15 +242,15 @@ export default function ProxyDetailPage() {
                   <tr key={i} className="hover:bg-gray-50">
                     <td className="px-3 py-2 text-gray-600 whitespace-nowrap">{formatDate(s.collected_at)}</td>
                     <td className="px-3 py-2 text-right">{s.active_connections}</td>
-                    <td className="px-3 py-2 text-right">{s.total_connections.toLocaleString()}</td>
-                    <td className="px-3 py-2 text-right">{s.total_requests.toLocaleString()}</td>
-                    <td className="px-3 py-2 text-right">{s.connect_requests.toLocaleString()}</td>
-                    <td className="px-3 py-2 text-right text-green-600">{s.responses_2xx.toLocaleString()}</td>
-                    <td className="px-3 py-2 text-right text-yellow-600">{s.responses_4xx > 0 ? s.responses_4xx.toLocaleString() : '-'}</td>
-                    <td className="px-3 py-2 text-right text-red-600">{s.responses_5xx > 0 ? s.responses_5xx.toLocaleString() : '-'}</td>
-                    <td className="px-3 py-2 text-right">{s.errors > 0 ? <span className="text-red-600">{s.errors}</span> : '-'}</td>
-                    <td className="px-3 py-2 text-right text-gray-500">{formatBytes(s.bytes_in)}</td>
-                    <td className="px-3 py-2 text-right text-gray-500">{formatBytes(s.bytes_out)}</td>
+                    <td className="px-3 py-2 text-right">{s.delta.total_connections.toLocaleString()}</td>
+                    <td className="px-3 py-2 text-right">{s.delta.total_requests.toLocaleString()}</td>
+                    <td className="px-3 py-2 text-right">{s.delta.connect_requests.toLocaleString()}</td>
+                    <td className="px-3 py-2 text-right text-green-600">{s.delta.responses_2xx.toLocaleString()}</td>
+                    <td className="px-3 py-2 text-right text-yellow-600">{s.delta.responses_4xx > 0 ? s.delta.responses_4xx.toLocaleString() : '-'}</td>
+                    <td className="px-3 py-2 text-right text-red-600">{s.delta.responses_5xx > 0 ? s.delta.responses_5xx.toLocaleString() : '-'}</td>
+                    <td className="px-3 py-2 text-right">{s.delta.errors > 0 ? <span className="text-red-600">{s.delta.errors}</span> : '-'}</td>
+                    <td className="px-3 py-2 text-right text-gray-500">{formatBytes(s.delta.bytes_in)}</td>
+                    <td className="px-3 py-2 text-right text-gray-500">{formatBytes(s.delta.bytes_out)}</td>
                   </tr>
                 ))}
               </tbody>
@@ -241,6 +259,9 @@ export default function ProxyDetailPage() {
         </div>
       )}
 
+      <CommandsPanel proxyId={id} />
+      <DiagnosticsPanel proxyId={id} />
+
       {/* Log Capture */}
       <div className="bg-white rounded-lg border p-5">
         <div className="flex items-center justify-between mb-4">
@@ -259,6 +280,13 @@ export default function ProxyDetailPage() {
                     </option>
                   ))}
                 </select>
+                <input
+                  value={debugTags}
+                  onChange={(e) => setDebugTags(e.target.value)}
+                  placeholder="debug tags (parent_select, http_trans)"
+                  title="Debug tags do ATS habilitados durante a captura"
+                  className="px-3 py-1.5 border border-gray-300 rounded-md text-sm font-mono w-56"
+                />
                 <button
                   onClick={startCapture}
                   className="px-4 py-1.5 text-sm bg-blue-600 text-white rounded-md hover:bg-blue-700"
@@ -295,10 +323,12 @@ export default function ProxyDetailPage() {
               ) : (
                 logs.lines.map((line, i) => (
                   <div key={i} className="py-0.5">
-                    <span className="text-gray-500">{formatDate(line.timestamp)}</span>{' '}
+                    <span className="text-gray-500">
+                      {new Date(line.timestamp).toLocaleTimeString('pt-BR')}
+                    </span>{' '}
                     <span
                       className={
-                        line.level === 'ERROR'
+                        line.level === 'ERROR' || line.level === 'FATAL'
                           ? 'text-red-400'
                           : line.level === 'WARN'
                             ? 'text-yellow-400'
@@ -307,6 +337,7 @@ export default function ProxyDetailPage() {
                     >
                       [{line.level}]
                     </span>{' '}
+                    {line.tag && <span className="text-purple-300">({line.tag}) </span>}
                     {line.message}
                   </div>
                 ))
@@ -319,6 +350,343 @@ export default function ProxyDetailPage() {
   );
 }
 
+const commandLabels: Record<CommandType, string> = {
+  resync: 'Resync',
+  reload: 'Reload ATS',
+  restart: 'Restart ATS',
+  diagnostics: 'Diagnóstico',
+  clear_hostdb: 'Limpar HostDB',
+  connectivity_test: 'Teste de conectividade',
+};
+
+const commandStatusColors: Record<string, string> = {
+  pending: 'text-gray-500',
+  delivered: 'text-blue-600',
+  succeeded: 'text-green-600',
+  failed: 'text-red-600',
+  timed_out: 'text-red-600',
+  expired: 'text-gray-400',
+  cancelled: 'text-gray-400',
+};
+
+function CommandsPanel({ proxyId }: { proxyId: string }) {
+  const user = useAuthStore((s) => s.user);
+  const canSend = user?.role === 'root' || user?.role === 'admin';
+
+  const [commands, setCommands] = useState<ProxyCommand[]>([]);
+  const [type, setType] = useState<CommandType>('reload');
+  const [url, setUrl] = useState('');
+  const [direct, setDirect] = useState(false);
+  const [sending, setSending] = useState(false);
+  const [expanded, setExpanded] = useState<string | null>(null);
+
+  const load = useCallback(async () => {
+    try {
+      const res = await api.proxies.listCommands(proxyId);
+      setCommands(res.data ?? []);
+    } catch {
+      // keep the last history
+    }
+  }, [proxyId]);
+
+  const hasOpen = commands.some((c) => c.status === 'pending' || c.status === 'delivered');
+
+  useEffect(() => {
+    load();
+    const interval = setInterval(load, hasOpen ? 3000 : 30000);
+    return () => clearInterval(interval);
+  }, [load, hasOpen]);
+
+  async function send() {
+    setSending(true);
+    try {
+      const args = type === 'connectivity_test' ? { url, direct: String(direct) } : undefined;
+      await api.proxies.sendCommand(proxyId, { type, args });
+      toast.success('Comando enviado');
+      await load();
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao enviar comando');
+    } finally {
+      setSending(false);
+    }
+  }
+
+  async function cancel(commandId: string) {
+    try {
+      await api.proxies.cancelCommand(proxyId, commandId);
+      await load();
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao cancelar comando');
+    }
+  }
+
+  return (
+    <div className="bg-white rounded-lg border p-5 mb-6">
+      <div className="flex items-center justify-between mb-4">
+        <h2 className="text-base font-semibold text-gray-900">Comandos</h2>
+        {canSend && (
+          <div className="flex items-center gap-2">
+            <select
+              value={type}
+              onChange={(e) => setType(e.target.value as CommandType)}
+              className="px-3 py-1.5 border border-gray-300 rounded-md text-sm"
+            >
+              {(Object.keys(commandLabels) as CommandType[]).map((t) => (
+                <option key={t} value={t}>{commandLabels[t]}</option>
+              ))}
+            </select>
+            {type === 'connectivity_test' && (
+              <>
+                <input
+                  value={url}
+                  onChange={(e) => setUrl(e.target.value)}
+                  placeholder="https://exemplo.com"
+                  className="px-3 py-1.5 border border-gray-300 rounded-md text-sm w-64"
+                />
+                <label className="flex items-center gap-1 text-sm text-gray-600">
+                  <input type="checkbox" checked={direct} onChange={(e) => setDirect(e.target.checked)} />
+                  Direto
+                </label>
+              </>
+            )}
+            <button
+              onClick={send}
+              disabled={sending || (type === 'connectivity_test' && !url)}
+              className="px-4 py-1.5 text-sm bg-blue-600 text-white rounded-md hover:bg-blue-700 disabled:opacity-50"
+            >
+              {sending ? '...' : 'Executar'}
+            </button>
+          </div>
+        )}
+      </div>
+
+      {commands.length === 0 ? (
+        <p className="text-sm text-gray-500">Nenhum comando enviado.</p>
+      ) : (
+        <table className="w-full text-sm">
+          <thead className="bg-gray-50">
+            <tr>
+              <th className="text-left px-3 py-2 font-medium text-gray-600">Criado</th>
+              <th className="text-left px-3 py-2 font-medium text-gray-600">Comando</th>
+              <th className="text-left px-3 py-2 font-medium text-gray-600">Status</th>
+              <th className="text-left px-3 py-2 font-medium text-gray-600">Finalizado</th>
+              <th className="px-3 py-2" />
+            </tr>
+          </thead>
+          <tbody className="divide-y">
+            {commands.map((c) => (
+              <Fragment key={c.id}>
+                <tr className="hover:bg-gray-50">
+                  <td className="px-3 py-2 text-gray-600 whitespace-nowrap">{formatDate(c.created_at)}</td>
+                  <td className="px-3 py-2">
+                    {commandLabels[c.type] ?? c.type}
+                    {c.args?.url && <span className="ml-2 text-xs text-gray-500 font-mono">{c.args.url}</span>}
+                  </td>
+                  <td className={`px-3 py-2 ${commandStatusColors[c.status] ?? ''}`}>{c.status}</td>
+                  <td className="px-3 py-2 text-gray-600 whitespace-nowrap">{c.finished_at ? formatDate(c.finished_at) : '-'}</td>
+                  <td className="px-3 py-2 text-right whitespace-nowrap">
+                    {(c.output || c.error) && (
+                      <button
+                        onClick={() => setExpanded(expanded === c.id ? null : c.id)}
+                        className="text-blue-600 hover:underline text-xs"
+                      >
+                        {expanded === c.id ? 'Ocultar' : 'Saída'}
+                      </button>
+                    )}
+                    {canSend && c.status === 'pending' && (
+                      <button onClick={() => cancel(c.id)} className="ml-3 text-red-600 hover:underline text-xs">
+                        Cancelar
+                      </button>
+                    )}
+                  </td>
+                </tr>
+                {expanded === c.id && (
+                  <tr>
+                    <td colSpan={5} className="px-3 pb-3">
+                      <pre className="bg-gray-900 text-gray-100 rounded-md p-3 max-h-72 overflow-auto font-mono text-xs whitespace-pre-wrap">
+                        {c.error && <span className="text-red-400">{c.error}{'\n'}</span>}
+                        {c.output}
+                      </pre>
+                    </td>
+                  </tr>
+                )}
+              </Fragment>
+            ))}
+          </tbody>
+        </table>
+      )}
+    </div>
+  );
+}
+
+// Labels usados pelos seletores dos grupos. Os do helper (--labels) não são
+// editáveis aqui; um label do admin com a mesma chave prevalece.
+function LabelsPanel({
+  proxyId,
+  labels,
+  helperLabels,
+  onSaved,
+}: {
+  proxyId: string;
+  labels: Record<string, string>;
+  helperLabels: Record<string, string>;
+  onSaved: () => void;
+}) {
+  const user = useAuthStore((s) => s.user);
+  const canEdit = user?.role === 'root' || user?.role === 'admin';
+  const [editing, setEditing] = useState(false);
+  const [text, setText] = useState('');
+  const [saving, setSaving] = useState(false);
+
+  function startEdit() {
+    setText(formatLabels(labels));
+    setEditing(true);
+  }
+
+  async function save() {
+    const parsed = parseLabels(text);
+    if (!parsed) {
+      toast.error('Use o formato chave=valor, separados por vírgula');
+      return;
+    }
+    setSaving(true);
+    try {
+      await api.proxies.setLabels(proxyId, parsed);
+      toast.success('Labels atualizados');
+      setEditing(false);
+      onSaved();
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao salvar labels');
+    } finally {
+      setSaving(false);
+    }
+  }
+
+  const entries = Object.entries(labels || {});
+  const helperEntries = Object.entries(helperLabels || {}).filter(([k]) => !(k in (labels || {})));
+
+  return (
+    <div className="bg-white rounded-lg border p-5 mb-6">
+      <div className="flex items-center justify-between mb-3">
+        <h2 className="text-base font-semibold text-gray-900">Labels</h2>
+        {canEdit && !editing && (
+          <button onClick={startEdit} className="text-sm text-blue-600 hover:underline">
+            Editar
+          </button>
+        )}
+      </div>
+      {editing ? (
+        <div className="flex gap-2">
+          <input
+            value={text}
+            onChange={(e) => setText(e.target.value)}
+            placeholder="site=poa, env=prod, role=edge"
+            className="flex-1 px-3 py-1.5 border border-gray-300 rounded-md text-sm font-mono"
+          />
+          <button
+            onClick={save}
+            disabled={saving}
+            className="px-3 py-1.5 text-sm bg-blue-600 text-white rounded-md hover:bg-blue-700 disabled:opacity-50"
+          >
+            {saving ? '...' : 'Salvar'}
+          </button>
+          <button onClick={() => setEditing(false)} className="px-3 py-1.5 text-sm border rounded-md hover:bg-gray-50">
+            Cancelar
+          </button>
+        </div>
+      ) : entries.length === 0 && helperEntries.length === 0 ? (
+        <p className="text-sm text-gray-500">Nenhum label.</p>
+      ) : (
+        <div className="flex flex-wrap gap-2">
+          {entries.map(([k, v]) => (
+            <span key={k} className="inline-flex px-2 py-0.5 rounded bg-gray-100 text-xs font-mono">
+              {k}={v}
+            </span>
+          ))}
+          {helperEntries.map(([k, v]) => (
+            <span
+              key={k}
+              title="Enviado pelo helper (--labels)"
+              className="inline-flex px-2 py-0.5 rounded bg-blue-50 text-blue-700 text-xs font-mono"
+            >
+              {k}={v}
+            </span>
+          ))}
+        </div>
+      )}
+    </div>
+  );
+}
+
+function DiagnosticsPanel({ proxyId }: { proxyId: string }) {
+  const user = useAuthStore((s) => s.user);
+  const canDownload = user?.role === 'root' || user?.role === 'admin';
+  const [bundles, setBundles] = useState<DiagnosticsBundle[]>([]);
+
+  const load = useCallback(async () => {
+    try {
+      const res = await api.proxies.listDiagnostics(proxyId);
+      setBundles(res.data ?? []);
+    } catch {
+      // keep the last list
+    }
+  }, [proxyId]);
+
+  useEffect(() => {
+    load();
+    const interval = setInterval(load, 15000);
+    return () => clearInterval(interval);
+  }, [load]);
+
+  async function download(b: DiagnosticsBundle) {
+    try {
+      const blob = await api.proxies.downloadDiagnostics(proxyId, b.id);
+      const url = URL.createObjectURL(blob);
+      const a = document.createElement('a');
+      a.href = url;
+      a.download = `diagnostics-${b.created_at.replace(/[:.]/g, '-')}.tar.gz`;
+      a.click();
+      URL.revokeObjectURL(url);
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao baixar bundle');
+    }
+  }
+
+  if (bundles.length === 0) return null;
+
+  return (
+    <div className="bg-white rounded-lg border p-5 mb-6">
+      <h2 className="text-base font-semibold text-gray-900 mb-4">Bundles de Diagnóstico</h2>
+      <table className="w-full text-sm">
+        <thead className="bg-gray-50">
+          <tr>
+            <th className="text-left px-3 py-2 font-medium text-gray-600">Gerado</th>
+            <th className="text-right px-3 py-2 font-medium text-gray-600">Tamanho</th>
+            <th className="text-left px-3 py-2 font-medium text-gray-600">Expira</th>
+            <th className="px-3 py-2" />
+          </tr>
+        </thead>
+        <tbody className="divide-y">
+          {bundles.map((b) => (
+            <tr key={b.id} className="hover:bg-gray-50">
+              <td className="px-3 py-2 text-gray-600 whitespace-nowrap">{formatDate(b.created_at)}</td>
+              <td className="px-3 py-2 text-right">{formatBytes(b.size_bytes)}</td>
+              <td className="px-3 py-2 text-gray-600 whitespace-nowrap">{formatDate(b.expires_at)}</td>
+              <td className="px-3 py-2 text-right">
+                {canDownload && (
+                  <button onClick={() => download(b)} className="text-blue-600 hover:underline text-xs">
+                    Baixar
+                  </button>
+                )}
+              </td>
+            </tr>
+          ))}
+        </tbody>
+      </table>
+    </div>
+  );
+}
+
 function StatCard({
   label,
   value,
@@ -9,7 +9,7 @@ import { StatusBadge } from '@/components/status-badge';
 import { EmptyState } from '@/components/empty-state';
 import { TableSkeleton } from '@/components/loading';
 import { ConfirmDialog } from '@/components/confirm-dialog';
-import { formatRelative, formatBytes } from '@/lib/utils';
+import { formatRelative, formatBytes, formatLabels } from '@/lib/utils';
 
 export default function ProxiesPage() {
   const [proxies, setProxies] = useState<Proxy[]>([]);
@@ -109,6 +109,11 @@ export default function ProxiesPage() {
                       <Link href={`/proxies/${proxy.id}`} className="text-blue-600 hover:underline font-medium">
                         {proxy.hostname}
                       </Link>
+                      {Object.keys({ ...proxy.helper_labels, ...proxy.labels }).length > 0 && (
+                        <div className="text-xs text-gray-500 font-mono mt-0.5">
+                          {formatLabels({ ...proxy.helper_labels, ...proxy.labels })}
+                        </div>
+                      )}
                     </td>
                     <td className="px-4 py-3">
                       <StatusBadge status={proxy.is_online ? 'online' : 'offline'} />
@@ -0,0 +1,369 @@
+'use client';
+
+import { Fragment, useEffect, useState } from 'react';
+import Link from 'next/link';
+import toast from 'react-hot-toast';
+import { api } from '@/lib/api';
+import { useAuthStore } from '@/stores/auth-store';
+import type { RuleSet, RuleSetVersion, RuleSetUpdate, RuleAction, ApiError } from '@/types';
+import { ConfirmDialog } from '@/components/confirm-dialog';
+import { TableSkeleton } from '@/components/loading';
+import { EmptyState } from '@/components/empty-state';
+import { formatDate } from '@/lib/utils';
+
+interface RuleSetForm {
+  name: string;
+  description: string;
+  domains: string;
+  ipRanges: string;
+}
+
+const emptyForm: RuleSetForm = { name: '', description: '', domains: '', ipRanges: '' };
+
+// Uma regra por linha: "<domínio ou CIDR> [direct|parent|deny]" (padrão direct)
+function parseEntries(text: string): { value: string; action: RuleAction }[] | null {
+  const entries: { value: string; action: RuleAction }[] = [];
+  for (const raw of text.split('\n')) {
+    const line = raw.trim();
+    if (!line) continue;
+    const [value, action = 'direct', ...rest] = line.split(/\s+/);
+    if (rest.length > 0 || !['direct', 'parent', 'deny'].includes(action)) return null;
+    entries.push({ value, action: action as RuleAction });
+  }
+  return entries;
+}
+
+export default function RuleSetsPage() {
+  const user = useAuthStore((s) => s.user);
+  const canEdit = user?.role === 'root' || user?.role === 'admin';
+
+  const [ruleSets, setRuleSets] = useState<RuleSet[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [expanded, setExpanded] = useState<string | null>(null);
+  const [versions, setVersions] = useState<RuleSetVersion[]>([]);
+
+  // Create / edit modal (editing null = novo conjunto)
+  const [showForm, setShowForm] = useState(false);
+  const [editing, setEditing] = useState<RuleSet | null>(null);
+  const [form, setForm] = useState<RuleSetForm>(emptyForm);
+  const [saving, setSaving] = useState(false);
+  const [lastUpdate, setLastUpdate] = useState<RuleSetUpdate | null>(null);
+
+  const [deleteTarget, setDeleteTarget] = useState<RuleSet | null>(null);
+
+  async function load() {
+    try {
+      const res = await api.ruleSets.list();
+      setRuleSets(res.data || []);
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao carregar conjuntos de regras');
+    } finally {
+      setLoading(false);
+    }
+  }
+
+  useEffect(() => {
+    load();
+  }, []);
+
+  async function toggleVersions(rs: RuleSet) {
+    if (expanded === rs.id) {
+      setExpanded(null);
+      return;
+    }
+    try {
+      const res = await api.ruleSets.versions(rs.id);
+      setVersions(res.data || []);
+      setExpanded(rs.id);
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao carregar versões');
+    }
+  }
+
+  function openCreate() {
+    setEditing(null);
+    setForm(emptyForm);
+    setShowForm(true);
+  }
+
+  function openEdit(rs: RuleSet) {
+    setEditing(rs);
+    setForm({
+      name: rs.name,
+      description: rs.description || '',
+      domains: rs.domains.map((d) => `${d.domain} ${d.action}`).join('\n'),
+      ipRanges: rs.ip_ranges.map((r) => `${r.cidr} ${r.action}`).join('\n'),
+    });
+    setShowForm(true);
+  }
+
+  async function handleSave(e: React.FormEvent) {
+    e.preventDefault();
+    const domains = parseEntries(form.domains);
+    const ipRanges = parseEntries(form.ipRanges);
+    if (!domains || !ipRanges) {
+      toast.error('Use uma regra por linha: valor seguido de direct, parent ou deny');
+      return;
+    }
+    const data = {
+      name: form.name.trim(),
+      description: form.description.trim() || undefined,
+      domains: domains.map((d) => ({ domain: d.value, action: d.action })),
+      ip_ranges: ipRanges.map((r) => ({ cidr: r.value, action: r.action })),
+    };
+    setSaving(true);
+    try {
+      if (editing) {
+        const res = await api.ruleSets.update(editing.id, data);
+        toast.success(`Conjunto atualizado (versão ${res.rule_set.version})`);
+        if (res.drafts.length > 0 || res.pending_approval.length > 0) setLastUpdate(res);
+      } else {
+        await api.ruleSets.create(data);
+        toast.success('Conjunto criado');
+      }
+      setShowForm(false);
+      setExpanded(null);
+      load();
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao salvar conjunto');
+    } finally {
+      setSaving(false);
+    }
+  }
+
+  async function handleDelete() {
+    if (!deleteTarget) return;
+    try {
+      await api.ruleSets.delete(deleteTarget.id);
+      toast.success('Conjunto removido');
+      setDeleteTarget(null);
+      load();
+    } catch (err) {
+      toast.error((err as ApiError).message || 'Erro ao remover conjunto');
+    }
+  }
+
+  const inputClass =
+    'w-full px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent';
+
+  return (
+    <div>
+      <div className="flex items-center justify-between mb-6">
+        <h1 className="text-2xl font-bold text-gray-900">Conjuntos de Regras</h1>
+        {canEdit && (
+          <button
+            onClick={openCreate}
+            className="px-4 py-2 bg-blue-600 text-white text-sm font-medium rounded-md hover:bg-blue-700"
+          >
+            Novo Conjunto
+          </button>
+        )}
+      </div>
+      <p className="text-sm text-gray-500 mb-4">
+        Listas de domínios e CIDRs que as configs referenciam em vez de copiar. Cada mudança cria uma versão
+        nova: drafts passam a usá-la e configs ativas ganham um draft novo, que precisa ser aprovado.
+      </p>
+
+      {lastUpdate && (
+        <div className="mb-4 p-4 bg-blue-50 border border-blue-200 rounded-lg text-sm">
+          <div className="flex items-start justify-between">
+            <div className="space-y-2">
+              {lastUpdate.drafts.length > 0 && (
+                <div>
+                  <span className="font-medium">Drafts com a versão {lastUpdate.rule_set.version}:</span>{' '}
+                  {lastUpdate.drafts.map((c, i) => (
+                    <Fragment key={c.id}>
+                      {i > 0 && ', '}
+                      <Link href={`/configs/${c.id}`} className="text-blue-600 hover:underline">
+                        {c.name} v{c.version}
+                      </Link>
+                    </Fragment>
+                  ))}
+                </div>
+              )}
+              {lastUpdate.pending_approval.length > 0 && (
+                <div>
+                  <span className="font-medium">Aguardando aprovação com versão antiga:</span>{' '}
+                  {lastUpdate.pending_approval.map((c, i) => (
+                    <Fragment key={c.id}>
+                      {i > 0 && ', '}
+                      <Link href={`/configs/${c.id}`} className="text-blue-600 hover:underline">
+                        {c.name} v{c.version}
+                      </Link>
+                    </Fragment>
+                  ))}
+                </div>
+              )}
+            </div>
+            <button onClick={() => setLastUpdate(null)} className="text-gray-400 hover:text-gray-600">
+              &times;
+            </button>
+          </div>
+        </div>
+      )}
+
+      {loading ? (
+        <TableSkeleton cols={5} />
+      ) : ruleSets.length === 0 ? (
+        <EmptyState title="Nenhum conjunto de regras cadastrado" />
+      ) : (
+        <div className="bg-white rounded-lg border overflow-hidden">
+          <table className="w-full text-sm">
+            <thead className="bg-gray-50 border-b">
+              <tr>
+                <th className="text-left px-4 py-3 font-medium text-gray-600">Nome</th>
+                <th className="text-right px-4 py-3 font-medium text-gray-600">Versão</th>
+                <th className="text-right px-4 py-3 font-medium text-gray-600">Regras</th>
+                <th className="text-right px-4 py-3 font-medium text-gray-600">Configs</th>
+                <th className="text-right px-4 py-3 font-medium text-gray-600">Ações</th>
+              </tr>
+            </thead>
+            <tbody className="divide-y">
+              {ruleSets.map((rs) => (
+                <Fragment key={rs.id}>
+                  <tr className="hover:bg-gray-50">
+                    <td className="px-4 py-3">
+                      <span className="font-medium">{rs.name}</span>
+                      {rs.description && <div className="text-xs text-gray-500">{rs.description}</div>}
+                    </td>
+                    <td className="px-4 py-3 text-right">
+                      <button onClick={() => toggleVersions(rs)} className="text-blue-600 hover:underline">
+                        v{rs.version}
+                      </button>
+                    </td>
+                    <td className="px-4 py-3 text-right text-gray-600">
+                      {rs.domains.length} domínios, {rs.ip_ranges.length} CIDRs
+                    </td>
+                    <td className="px-4 py-3 text-right">{rs.config_count}</td>
+                    <td className="px-4 py-3 text-right">
+                      {canEdit && (
+                        <div className="flex justify-end gap-1">
+                          <button
+                            onClick={() => openEdit(rs)}
+                            className="px-2 py-1 text-xs text-blue-600 hover:bg-blue-50 rounded"
+                          >
+                            Editar
+                          </button>
+                          <button
+                            onClick={() => setDeleteTarget(rs)}
+                            disabled={rs.config_count > 0}
+                            title={rs.config_count > 0 ? 'Referenciado por configs' : undefined}
+                            className="px-2 py-1 text-xs text-red-600 hover:bg-red-50 rounded disabled:opacity-40 disabled:hover:bg-transparent"
+                          >
+                            Remover
+                          </button>
+                        </div>
+                      )}
+                    </td>
+                  </tr>
+                  {expanded === rs.id && (
+                    <tr>
+                      <td colSpan={5} className="px-4 py-3 bg-gray-50">
+                        <div className="space-y-3">
+                          {versions.map((v) => (
+                            <div key={v.version}>
+                              <div className="text-xs text-gray-500 mb-1">
+                                v{v.version} · {formatDate(v.created_at)}
+                              </div>
+                              <div className="font-mono text-xs text-gray-700 whitespace-pre-wrap">
+                                {[
+                                  ...v.domains.map((d) => `${d.domain} ${d.action}`),
+                                  ...v.ip_ranges.map((r) => `${r.cidr} ${r.action}`),
+                                ].join('\n')}
+                              </div>
+                            </div>
+                          ))}
+                        </div>
+                      </td>
+                    </tr>
+                  )}
+                </Fragment>
+              ))}
+            </tbody>
+          </table>
+        </div>
+      )}
+
+      {showForm && (
+        <div className="fixed inset-0 z-50 flex items-center justify-center">
+          <div className="fixed inset-0 bg-black/50" onClick={() => setShowForm(false)} />
+          <div className="relative bg-white rounded-lg shadow-xl max-w-lg w-full mx-4 p-6">
+            <h3 className="text-lg font-semibold mb-4">{editing ? 'Editar Conjunto' : 'Novo Conjunto'}</h3>
+            <form onSubmit={handleSave} className="space-y-3">
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Nome</label>
+                <input
+                  value={form.name}
+                  onChange={(e) => setForm({ ...form, name: e.target.value })}
+                  className={inputClass}
+                  required
+                />
+              </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Descrição</label>
+                <input
+                  value={form.description}
+                  onChange={(e) => setForm({ ...form, description: e.target.value })}
+                  className={inputClass}
+                />
+              </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Domínios</label>
+                <textarea
+                  value={form.domains}
+                  onChange={(e) => setForm({ ...form, domains: e.target.value })}
+                  rows={5}
+                  placeholder={'*.bb.com.br direct\nportal.exemplo.com parent'}
+                  className={`${inputClass} font-mono`}
+                />
+              </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">CIDRs</label>
+                <textarea
+                  value={form.ipRanges}
+                  onChange={(e) => setForm({ ...form, ipRanges: e.target.value })}
+                  rows={3}
+                  placeholder="200.201.160.0/20 direct"
+                  className={`${inputClass} font-mono`}
+                />
+                <p className="text-xs text-gray-500 mt-1">Uma regra por linha; a ação padrão é direct.</p>
+              </div>
+              {editing && editing.config_count > 0 && (
+                <p className="text-xs text-amber-700">
+                  Mudar as regras cria a versão {editing.version + 1}; as configs que usam este conjunto
+                  precisam de nova aprovação para usá-la.
+                </p>
+              )}
+              <div className="flex justify-end gap-2 pt-2">
+                <button
+                  type="button"
+                  onClick={() => setShowForm(false)}
+                  className="px-4 py-2 text-sm border rounded-md hover:bg-gray-50"
+                >
+                  Cancelar
+                </button>
+                <button
+                  type="submit"
+                  disabled={saving}
+                  className="px-4 py-2 text-sm bg-blue-600 text-white rounded-md hover:bg-blue-700 disabled:opacity-50"
+                >
+                  {saving ? 'Salvando...' : 'Salvar'}
+                </button>
+              </div>
+            </form>
+          </div>
+        </div>
+      )}
+
+      <ConfirmDialog
+        open={!!deleteTarget}
+        title="Remover Conjunto"
+        message={`Deseja remover o conjunto "${deleteTarget?.name}" e todas as suas versões?`}
+        confirmLabel="Remover"
+        variant="danger"
+        onConfirm={handleDelete}
+        onCancel={() => setDeleteTarget(null)}
+      />
+    </div>
+  );
+}
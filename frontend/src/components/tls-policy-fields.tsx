@@ -0,0 +1,96 @@
+import type { TLSPolicy } from '@/types';
+
+interface TLSPolicyFieldsProps {
+  policy: TLSPolicy;
+  onChange: (policy: TLSPolicy) => void;
+  // forward_route is only accepted on direct rules
+  allowForwardRoute: boolean;
+}
+
+const selectClass = 'px-2 py-1.5 border border-gray-300 rounded-md text-xs focus:outline-none focus:ring-2 focus:ring-blue-500';
+
+// Editor of a domain rule's sni.yaml policy. Empty fields are sent as undefined
+// so the ATS defaults apply.
+export function TLSPolicyFields({ policy, onChange, allowForwardRoute }: TLSPolicyFieldsProps) {
+  function set<K extends keyof TLSPolicy>(field: K, value: TLSPolicy[K] | '') {
+    onChange({ ...policy, [field]: value === '' ? undefined : value });
+  }
+
+  return (
+    <div className="grid grid-cols-2 md:grid-cols-5 gap-2 p-3 bg-gray-50 border rounded-md">
+      <label className="text-xs text-gray-600 space-y-1">
+        <span className="block">Verificar servidor</span>
+        <select
+          value={policy.verify_server_policy || ''}
+          onChange={(e) => set('verify_server_policy', e.target.value as TLSPolicy['verify_server_policy'])}
+          className={`w-full ${selectClass}`}
+        >
+          <option value="">Padrão</option>
+          <option value="DISABLED">DISABLED</option>
+          <option value="PERMISSIVE">PERMISSIVE</option>
+          <option value="ENFORCED">ENFORCED</option>
+        </select>
+      </label>
+      <label className="text-xs text-gray-600 space-y-1">
+        <span className="block">Host × SNI</span>
+        <select
+          value={policy.host_sni_policy || ''}
+          onChange={(e) => set('host_sni_policy', e.target.value as TLSPolicy['host_sni_policy'])}
+          className={`w-full ${selectClass}`}
+        >
+          <option value="">Padrão</option>
+          <option value="DISABLED">DISABLED</option>
+          <option value="PERMISSIVE">PERMISSIVE</option>
+          <option value="ENFORCED">ENFORCED</option>
+        </select>
+      </label>
+      <label className="text-xs text-gray-600 space-y-1">
+        <span className="block">Certificado do cliente</span>
+        <select
+          value={policy.verify_client || ''}
+          onChange={(e) => set('verify_client', e.target.value as TLSPolicy['verify_client'])}
+          className={`w-full ${selectClass}`}
+        >
+          <option value="">Padrão</option>
+          <option value="NONE">NONE</option>
+          <option value="MODERATE">MODERATE</option>
+          <option value="STRICT">STRICT</option>
+        </select>
+      </label>
+      <label className="text-xs text-gray-600 space-y-1">
+        <span className="block">HTTP/2</span>
+        <select
+          value={policy.http2 === undefined ? '' : String(policy.http2)}
+          onChange={(e) => set('http2', e.target.value === '' ? '' : e.target.value === 'true')}
+          className={`w-full ${selectClass}`}
+        >
+          <option value="">Padrão</option>
+          <option value="true">Habilitado</option>
+          <option value="false">Desabilitado</option>
+        </select>
+      </label>
+      <label className="text-xs text-gray-600 space-y-1">
+        <span className="block">Forward route</span>
+        <input
+          value={policy.forward_route || ''}
+          onChange={(e) => set('forward_route', e.target.value.trim())}
+          disabled={!allowForwardRoute}
+          className={`w-full font-mono disabled:bg-gray-100 ${selectClass}`}
+          placeholder={allowForwardRoute ? 'host:porta' : 'só regras direct'}
+        />
+      </label>
+    </div>
+  );
+}
+
+// One-line summary of a TLS policy for read-only views
+export function formatTLSPolicy(policy?: TLSPolicy): string {
+  if (!policy) return '';
+  const parts: string[] = [];
+  if (policy.forward_route) parts.push(`→ ${policy.forward_route}`);
+  if (policy.verify_server_policy) parts.push(`servidor ${policy.verify_server_policy}`);
+  if (policy.host_sni_policy) parts.push(`host/SNI ${policy.host_sni_policy}`);
+  if (policy.verify_client) parts.push(`cliente ${policy.verify_client}`);
+  if (policy.http2 !== undefined) parts.push(policy.http2 ? 'h2 on' : 'h2 off');
+  return parts.join(' · ');
+}
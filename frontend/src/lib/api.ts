@@ -5,11 +5,26 @@ import type {
   PaginatedResponse,
   Config,
   ConfigPreview,
+  ConfigOverlay,
+  ConfigRuleSet,
+  TLSPolicy,
+  RuleSet,
+  RuleSetVersion,
+  RuleSetUpdate,
+  FeedSource,
+  FeedUpdate,
   User,
   Proxy,
   ProxiesListResponse,
+  ProxyGroup,
+  ProxySummary,
   ProxyLogs,
+  ProxyLogLine,
+  ProxyCommand,
+  CommandType,
+  DiagnosticsBundle,
   AuditLog,
+  AccessLog,
   ApiError,
 } from '@/types';
 
@@ -47,7 +62,8 @@ async function refreshToken(): Promise<string | null> {
   }
 }
 
-async function fetchAPI<T>(path: string, options: RequestInit = {}): Promise<T> {
+// authorizedFetch sends the request with the bearer token, refreshing it once on 401.
+async function authorizedFetch(path: string, options: RequestInit = {}): Promise<Response> {
   const { token } = useAuthStore.getState();
 
   const headers: Record<string, string> = {
@@ -90,6 +106,12 @@ async function fetchAPI<T>(path: string, options: RequestInit = {}): Promise<T>
     throw err;
   }
 
+  return res;
+}
+
+async function fetchAPI<T>(path: string, options: RequestInit = {}): Promise<T> {
+  const res = await authorizedFetch(path, options);
+
   if (res.status === 204) {
     return undefined as T;
   }
@@ -97,6 +119,43 @@ async function fetchAPI<T>(path: string, options: RequestInit = {}): Promise<T>
   return res.json();
 }
 
+async function fetchBlob(path: string): Promise<Blob> {
+  const res = await authorizedFetch(path);
+  return res.blob();
+}
+
+// streamEvents reads a text/event-stream response until the server closes it.
+async function streamEvents(
+  path: string,
+  onEvent: (event: string, data: string) => void,
+  signal?: AbortSignal
+): Promise<void> {
+  const res = await authorizedFetch(path, { signal, headers: { Accept: 'text/event-stream' } });
+  if (!res.body) return;
+
+  const reader = res.body.getReader();
+  const decoder = new TextDecoder();
+  let buffer = '';
+  for (;;) {
+    const { done, value } = await reader.read();
+    if (done) return;
+    buffer += decoder.decode(value, { stream: true });
+
+    let sep;
+    while ((sep = buffer.indexOf('\n\n')) >= 0) {
+      const block = buffer.slice(0, sep);
+      buffer = buffer.slice(sep + 2);
+      let event = 'message';
+      const data: string[] = [];
+      for (const line of block.split('\n')) {
+        if (line.startsWith('event: ')) event = line.slice(7);
+        else if (line.startsWith('data: ')) data.push(line.slice(6));
+      }
+      if (data.length > 0) onEvent(event, data.join('\n'));
+    }
+  }
+}
+
 export const api = {
   auth: {
     login: (email: string, password: string) =>
@@ -141,11 +200,16 @@ export const api = {
       name: string;
       description?: string;
       default_action: string;
-      domains: { domain: string; action: string; priority: number }[];
+      connect_ports?: string;
+      domains: { domain: string; action: string; priority: number; tls?: TLSPolicy }[];
       ip_ranges: { cidr: string; action: string; priority: number }[];
       parent_proxies: { address: string; port: number; priority: number; enabled: boolean }[];
-      client_acl?: { cidr: string; action: string; priority: number }[];
+      client_acl?: { cidr: string; action: string; apply?: string; methods?: string[]; priority: number }[];
+      records?: { name: string; value: string }[];
       proxy_ids: string[];
+      group_ids?: string[];
+      overlays?: ConfigOverlay[];
+      rule_sets?: ConfigRuleSet[];
     }) => fetchAPI<Config>('/configs', { method: 'POST', body: JSON.stringify(data) }),
     update: (
       id: string,
@@ -153,11 +217,16 @@ export const api = {
         name: string;
         description?: string;
         default_action: string;
-        domains: { domain: string; action: string; priority: number }[];
+        connect_ports?: string;
+        domains: { domain: string; action: string; priority: number; tls?: TLSPolicy }[];
         ip_ranges: { cidr: string; action: string; priority: number }[];
         parent_proxies: { address: string; port: number; priority: number; enabled: boolean }[];
-        client_acl?: { cidr: string; action: string; priority: number }[];
+        client_acl?: { cidr: string; action: string; apply?: string; methods?: string[]; priority: number }[];
+        records?: { name: string; value: string }[];
         proxy_ids: string[];
+        group_ids?: string[];
+        overlays?: ConfigOverlay[];
+        rule_sets?: ConfigRuleSet[];
       }
     ) => fetchAPI<Config>(`/configs/${id}`, { method: 'PUT', body: JSON.stringify(data) }),
     submit: (id: string) =>
@@ -173,25 +242,145 @@ export const api = {
       fetchAPI<Config>(`/configs/${id}/clone`, { method: 'POST' }),
     delete: (id: string) =>
       fetchAPI<void>(`/configs/${id}`, { method: 'DELETE' }),
-    preview: (id: string) =>
-      fetchAPI<ConfigPreview>(`/configs/${id}/preview`),
+    // hostname: preview the files of that proxy, with its overlays merged in
+    preview: (id: string, hostname?: string) =>
+      fetchAPI<ConfigPreview>(
+        `/configs/${id}/preview${hostname ? `?hostname=${encodeURIComponent(hostname)}` : ''}`
+      ),
   },
 
   proxies: {
     list: () => fetchAPI<ProxiesListResponse>('/proxies'),
     get: (id: string) => fetchAPI<Proxy>(`/proxies/${id}`),
-    startLogCapture: (id: string, durationMinutes: number) =>
-      fetchAPI<{ status: string; capture_until: string }>(`/proxies/${id}/logs`, {
+    startLogCapture: (id: string, durationMinutes: number, debugTags?: string[]) =>
+      fetchAPI<{ status: string; capture_until: string; debug_tags: string[] }>(`/proxies/${id}/logs`, {
         method: 'POST',
-        body: JSON.stringify({ duration_minutes: durationMinutes }),
+        body: JSON.stringify({ duration_minutes: durationMinutes, debug_tags: debugTags }),
       }),
     getLogs: (id: string) => fetchAPI<ProxyLogs>(`/proxies/${id}/logs`),
+    // streamLogs resolves when the capture ends (the server sends "end" and closes).
+    streamLogs: (id: string, opts: { onLine: (line: ProxyLogLine) => void; signal?: AbortSignal }) =>
+      streamEvents(
+        `/proxies/${id}/logs/stream`,
+        (event, data) => {
+          if (event === 'log') opts.onLine(JSON.parse(data));
+        },
+        opts.signal
+      ),
     delete: (id: string) => fetchAPI<void>(`/proxies/${id}`, { method: 'DELETE' }),
     assignConfig: (id: string, configId: string | null) =>
       fetchAPI<{ status: string }>(`/proxies/${id}/config`, {
         method: 'PUT',
         body: JSON.stringify({ config_id: configId }),
       }),
+    setLabels: (id: string, labels: Record<string, string>) =>
+      fetchAPI<{ labels: Record<string, string> }>(`/proxies/${id}/labels`, {
+        method: 'PUT',
+        body: JSON.stringify({ labels }),
+      }),
+    listCommands: (id: string) =>
+      fetchAPI<{ data: ProxyCommand[] }>(`/proxies/${id}/commands`),
+    sendCommand: (
+      id: string,
+      data: { type: CommandType; args?: Record<string, string>; timeout_seconds?: number }
+    ) =>
+      fetchAPI<ProxyCommand>(`/proxies/${id}/commands`, {
+        method: 'POST',
+        body: JSON.stringify(data),
+      }),
+    cancelCommand: (id: string, commandId: string) =>
+      fetchAPI<void>(`/proxies/${id}/commands/${commandId}`, { method: 'DELETE' }),
+    listDiagnostics: (id: string) =>
+      fetchAPI<{ data: DiagnosticsBundle[] }>(`/proxies/${id}/diagnostics`),
+    downloadDiagnostics: (id: string, bundleId: string) =>
+      fetchBlob(`/proxies/${id}/diagnostics/${bundleId}`),
+  },
+
+  groups: {
+    list: () => fetchAPI<{ data: ProxyGroup[] }>('/groups'),
+    proxies: (id: string) => fetchAPI<{ data: ProxySummary[] }>(`/groups/${id}/proxies`),
+    create: (data: { name: string; description?: string; selector: Record<string, string>; priority: number }) =>
+      fetchAPI<ProxyGroup>('/groups', { method: 'POST', body: JSON.stringify(data) }),
+    update: (
+      id: string,
+      data: { name: string; description?: string; selector: Record<string, string>; priority: number }
+    ) => fetchAPI<ProxyGroup>(`/groups/${id}`, { method: 'PUT', body: JSON.stringify(data) }),
+    delete: (id: string) => fetchAPI<void>(`/groups/${id}`, { method: 'DELETE' }),
+  },
+
+  ruleSets: {
+    list: () => fetchAPI<{ data: RuleSet[] }>('/rule-sets'),
+    get: (id: string) => fetchAPI<RuleSet>(`/rule-sets/${id}`),
+    versions: (id: string) => fetchAPI<{ data: RuleSetVersion[] }>(`/rule-sets/${id}/versions`),
+    create: (data: {
+      name: string;
+      description?: string;
+      domains: { domain: string; action: string }[];
+      ip_ranges: { cidr: string; action: string }[];
+    }) => fetchAPI<RuleSet>('/rule-sets', { method: 'POST', body: JSON.stringify(data) }),
+    update: (
+      id: string,
+      data: {
+        name: string;
+        description?: string;
+        domains: { domain: string; action: string }[];
+        ip_ranges: { cidr: string; action: string }[];
+      }
+    ) => fetchAPI<RuleSetUpdate>(`/rule-sets/${id}`, { method: 'PUT', body: JSON.stringify(data) }),
+    delete: (id: string) => fetchAPI<void>(`/rule-sets/${id}`, { method: 'DELETE' }),
+  },
+
+  feeds: {
+    list: () => fetchAPI<{ data: FeedSource[] }>('/feeds'),
+    formats: () => fetchAPI<{ data: string[] }>('/feeds/formats'),
+    updates: (id: string) => fetchAPI<{ data: FeedUpdate[] }>(`/feeds/${id}/updates`),
+    create: (data: {
+      name: string;
+      rule_set_id: string;
+      source: string;
+      format: string;
+      action: string;
+      interval_minutes: number;
+      enabled: boolean;
+    }) => fetchAPI<FeedSource>('/feeds', { method: 'POST', body: JSON.stringify(data) }),
+    update: (
+      id: string,
+      data: { name: string; source: string; format: string; action: string; interval_minutes: number; enabled: boolean }
+    ) => fetchAPI<FeedSource>(`/feeds/${id}`, { method: 'PUT', body: JSON.stringify(data) }),
+    delete: (id: string) => fetchAPI<void>(`/feeds/${id}`, { method: 'DELETE' }),
+    refresh: (id: string) =>
+      fetchAPI<{ update: FeedUpdate | null }>(`/feeds/${id}/refresh`, { method: 'POST' }),
+    apply: (id: string, updateId: string) =>
+      fetchAPI<RuleSetUpdate>(`/feeds/${id}/updates/${updateId}/apply`, { method: 'POST' }),
+    discard: (id: string, updateId: string) =>
+      fetchAPI<void>(`/feeds/${id}/updates/${updateId}/discard`, { method: 'POST' }),
+  },
+
+  accessLogs: {
+    list: (params?: {
+      proxy_id?: string;
+      client_ip?: string;
+      host?: string;
+      username?: string;
+      status?: string;
+      from?: string;
+      to?: string;
+      page?: number;
+      limit?: number;
+    }) => {
+      const q = new URLSearchParams();
+      if (params?.proxy_id) q.set('proxy_id', params.proxy_id);
+      if (params?.client_ip) q.set('client_ip', params.client_ip);
+      if (params?.host) q.set('host', params.host);
+      if (params?.username) q.set('username', params.username);
+      if (params?.status) q.set('status', params.status);
+      if (params?.from) q.set('from', params.from);
+      if (params?.to) q.set('to', params.to);
+      if (params?.page) q.set('page', String(params.page));
+      if (params?.limit) q.set('limit', String(params.limit));
+      const qs = q.toString();
+      return fetchAPI<PaginatedResponse<AccessLog>>(`/access-logs${qs ? `?${qs}` : ''}`);
+    },
   },
 
   audit: {
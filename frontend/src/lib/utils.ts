@@ -47,3 +47,29 @@ export function formatBytes(bytes: number): string {
   const i = Math.floor(Math.log(bytes) / Math.log(k));
   return `${(bytes / Math.pow(k, i)).toFixed(1)} ${sizes[i]}`;
 }
+
+// "site=poa, env=prod"
+export function formatLabels(labels: Record<string, string> | undefined | null): string {
+  return Object.entries(labels || {})
+    .map(([k, v]) => `${k}=${v}`)
+    .join(', ');
+}
+
+// Métodos HTTP de uma regra de ACL, separados por espaço ou vírgula. Mantém o
+// item vazio do fim para a digitação; os vazios são removidos ao salvar.
+export function parseMethods(text: string): string[] {
+  return text.toUpperCase().split(/[\s,]+/);
+}
+
+// Lê "chave=valor" separados por vírgula; retorna null se algum par for inválido
+export function parseLabels(text: string): Record<string, string> | null {
+  const labels: Record<string, string> = {};
+  for (const pair of text.split(',')) {
+    const trimmed = pair.trim();
+    if (!trimmed) continue;
+    const idx = trimmed.indexOf('=');
+    if (idx <= 0 || idx === trimmed.length - 1) return null;
+    labels[trimmed.slice(0, idx).trim()] = trimmed.slice(idx + 1).trim();
+  }
+  return labels;
+}
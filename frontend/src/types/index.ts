@@ -1,7 +1,9 @@
 export type UserRole = 'root' | 'admin' | 'regular';
 export type ConfigStatus = 'draft' | 'pending_approval' | 'approved' | 'active';
-export type RuleAction = 'direct' | 'parent';
+export type RuleAction = 'direct' | 'parent' | 'deny';
 export type ACLAction = 'allow' | 'deny';
+// in: client address; out: destination the proxy connects to
+export type ACLApply = 'in' | 'out';
 
 export interface User {
   id: string;
@@ -21,12 +23,17 @@ export interface Config {
   status: ConfigStatus;
   version: number;
   default_action: RuleAction;
+  connect_ports?: string; // ports and ranges allowed for CONNECT; unset keeps the image's
   proxy_count?: number;
   domains?: DomainRule[];
   ip_ranges?: IPRangeRule[];
   parent_proxies?: ParentProxy[];
   client_acl?: ClientACLRule[];
+  records?: RecordOverride[];
   proxies?: ProxySummary[];
+  groups?: ProxyGroup[];
+  overlays?: ConfigOverlay[];
+  rule_sets?: ConfigRuleSet[];
   created_by?: UserRef;
   modified_by?: UserRef;
   modified_at: string;
@@ -41,6 +48,109 @@ export interface ConfigPreview {
   parent_config: string;
   sni_yaml: string;
   ip_allow_yaml: string;
+  files?: Record<string, string>;
+  overlays?: string[];
+}
+
+// Extra rules of a config for one proxy (hostname) or one group (group_id)
+export interface ConfigOverlay {
+  id?: string;
+  hostname?: string;
+  group_id?: string;
+  group_name?: string;
+  description?: string;
+  domains: { domain: string; action: RuleAction; priority: number }[];
+  ip_ranges: { cidr: string; action: RuleAction; priority: number }[];
+  client_acl: { cidr: string; action: ACLAction; apply?: ACLApply; methods?: string[]; priority: number }[];
+}
+
+// Named, versioned list of domain and CIDR rules shared by configs
+export interface RuleSet {
+  id: string;
+  name: string;
+  description?: string;
+  version: number;
+  domains: { domain: string; action: RuleAction }[];
+  ip_ranges: { cidr: string; action: RuleAction }[];
+  config_count: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface RuleSetVersion {
+  rule_set_id: string;
+  version: number;
+  domains: { domain: string; action: RuleAction }[];
+  ip_ranges: { cidr: string; action: RuleAction }[];
+  created_at: string;
+}
+
+// A config's reference to a rule set, pinned to a version (0 = current on save)
+export interface ConfigRuleSet {
+  rule_set_id: string;
+  name?: string;
+  version: number;
+  latest_version?: number;
+  priority: number;
+}
+
+export interface RuleSetUpdate {
+  rule_set: RuleSet;
+  drafts: Config[];
+  pending_approval: Config[];
+}
+
+// External list (vendor endpoints, IP ranges) that keeps a rule set up to date
+export interface FeedSource {
+  id: string;
+  name: string;
+  rule_set_id: string;
+  rule_set_name: string;
+  source: string;
+  format: string;
+  action: RuleAction;
+  interval_minutes: number;
+  enabled: boolean;
+  last_fetched_at?: string;
+  last_status?: 'changed' | 'unchanged' | 'error';
+  last_error?: string;
+  pending_update_id?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export type FeedUpdateStatus = 'pending' | 'applied' | 'discarded';
+
+// What a feed fetch found, staged for review against base_version of the rule set
+export interface FeedUpdate {
+  id: string;
+  feed_id: string;
+  status: FeedUpdateStatus;
+  base_version: number;
+  domains: { domain: string; action: RuleAction }[];
+  ip_ranges: { cidr: string; action: RuleAction }[];
+  added: string[];
+  removed: string[];
+  skipped: number;
+  fetched_at: string;
+  reviewed_at?: string;
+  applied_version?: number;
+}
+
+export interface RecordOverride {
+  id?: string;
+  name: string;
+  value: string;
+}
+
+export type RecordKind = 'int' | 'ports' | 'tags';
+
+export interface ManagedRecord {
+  name: string;
+  kind: RecordKind;
+  min?: number;
+  max?: number;
+  default?: string;
 }
 
 export interface UserRef {
@@ -48,11 +158,24 @@ export interface UserRef {
   username: string;
 }
 
+export type TLSVerifyPolicy = 'DISABLED' | 'PERMISSIVE' | 'ENFORCED';
+export type TLSClientPolicy = 'NONE' | 'MODERATE' | 'STRICT';
+
+// sni.yaml policy of a domain rule; unset fields keep the ATS defaults
+export interface TLSPolicy {
+  verify_server_policy?: TLSVerifyPolicy;
+  host_sni_policy?: TLSVerifyPolicy;
+  forward_route?: string; // host:port, direct rules only
+  http2?: boolean;
+  verify_client?: TLSClientPolicy;
+}
+
 export interface DomainRule {
   id?: string;
   domain: string;
   action: RuleAction;
   priority: number;
+  tls?: TLSPolicy;
 }
 
 export interface IPRangeRule {
@@ -66,6 +189,8 @@ export interface ClientACLRule {
   id?: string;
   cidr: string;
   action: ACLAction;
+  apply?: ACLApply;
+  methods?: string[]; // empty = all methods
   priority: number;
 }
 
@@ -82,18 +207,89 @@ export interface ProxySummary {
   hostname: string;
   is_online: boolean;
   last_seen?: string;
+  labels?: Record<string, string>;
+  helper_labels?: Record<string, string>;
+}
+
+export interface ProxyGroup {
+  id: string;
+  name: string;
+  description?: string;
+  selector: Record<string, string>;
+  priority: number;
+  proxy_count: number;
+  created_at: string;
+  updated_at: string;
 }
 
 export interface Proxy {
   id: string;
   hostname: string;
+  labels: Record<string, string>;
+  helper_labels: Record<string, string>;
   config?: { id: string; name: string; version: number; config_hash?: string; in_sync: boolean };
   is_online: boolean;
   last_seen?: string;
   registered_at: string;
   current_config_hash?: string;
+  client_cert_fingerprint?: string;
   stats?: ProxyStats;
   stats_history?: ProxyStatsHistory[];
+  drift?: ProxyDrift;
+}
+
+export interface ProxyDrift {
+  proxy_id: string;
+  drifted: boolean;
+  expected_hash?: string;
+  actual_hash?: string;
+  files: string[];
+  remediated: boolean;
+  detected_at?: string;
+  checked_at: string;
+}
+
+export type CommandType =
+  | 'resync'
+  | 'reload'
+  | 'restart'
+  | 'diagnostics'
+  | 'clear_hostdb'
+  | 'connectivity_test';
+
+export type CommandStatus =
+  | 'pending'
+  | 'delivered'
+  | 'succeeded'
+  | 'failed'
+  | 'timed_out'
+  | 'expired'
+  | 'cancelled';
+
+export interface ProxyCommand {
+  id: string;
+  proxy_id: string;
+  type: CommandType;
+  args?: Record<string, string>;
+  status: CommandStatus;
+  timeout_seconds: number;
+  output?: string;
+  error?: string;
+  created_by?: string;
+  created_at: string;
+  expires_at: string;
+  delivered_at?: string;
+  finished_at?: string;
+}
+
+export interface DiagnosticsBundle {
+  id: string;
+  proxy_id: string;
+  command_id?: string;
+  size_bytes: number;
+  sha256: string;
+  created_at: string;
+  expires_at: string;
 }
 
 export interface ProxyStats {
@@ -127,11 +323,34 @@ export interface ProxyStatsHistory {
   broken_server_conns: number;
   bytes_in: number;
   bytes_out: number;
+  delta: ProxyStatsDelta;
+}
+
+// Incremento de cada contador cumulativo do ATS no intervalo da amostra
+export interface ProxyStatsDelta {
+  interval_seconds: number;
+  counter_reset: boolean;
+  total_connections: number;
+  cache_hits: number;
+  cache_misses: number;
+  errors: number;
+  total_requests: number;
+  connect_requests: number;
+  responses_2xx: number;
+  responses_3xx: number;
+  responses_4xx: number;
+  responses_5xx: number;
+  err_connect_fail: number;
+  err_client_abort: number;
+  broken_server_conns: number;
+  bytes_in: number;
+  bytes_out: number;
 }
 
 export interface ProxyLogLine {
   timestamp: string;
   level: string;
+  tag?: string;
   message: string;
 }
 
@@ -153,6 +372,24 @@ export interface AuditLog {
   created_at: string;
 }
 
+export interface AccessLog {
+  id: number;
+  timestamp: string;
+  proxy_id: string;
+  hostname?: string;
+  client_ip: string;
+  username?: string;
+  method: string;
+  url: string;
+  host: string;
+  status: number;
+  bytes: number;
+  duration_ms?: number;
+  route?: string;
+  upstream?: string;
+  user_agent?: string;
+}
+
 export interface Pagination {
   page: number;
   limit: number;
@@ -190,3 +427,94 @@ export interface ProxiesListResponse {
     offline: number;
   };
 }
+
+export type AlertRuleType = 'proxy_offline' | 'config_mismatch' | 'error_rate' | 'ack_failed' | 'parent_down' | 'config_drift';
+export type AlertStatus = 'pending' | 'firing' | 'resolved';
+
+export interface AlertRule {
+  id: string;
+  name: string;
+  type: AlertRuleType;
+  threshold: number;
+  duration_minutes: number;
+  enabled: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface Alert {
+  id: string;
+  rule_id: string;
+  rule_name: string;
+  rule_type: AlertRuleType;
+  proxy_id?: string;
+  proxy_hostname?: string;
+  subject?: string;
+  status: AlertStatus;
+  message?: string;
+  value?: number;
+  started_at: string;
+  fired_at?: string;
+  resolved_at?: string;
+  last_evaluated_at: string;
+}
+
+export type WebhookEvent =
+  | 'config.submit'
+  | 'config.approve'
+  | 'config.reject'
+  | 'proxy.offline'
+  | 'deploy.failed'
+  | 'alert.firing';
+
+export interface Webhook {
+  id: string;
+  name: string;
+  url: string;
+  events: WebhookEvent[];
+  enabled: boolean;
+  secret?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface WebhookDelivery {
+  id: string;
+  webhook_id: string;
+  event: WebhookEvent;
+  payload: Record<string, unknown>;
+  status: 'pending' | 'success' | 'failed';
+  attempts: number;
+  next_attempt_at?: string;
+  last_status_code?: number;
+  last_error?: string;
+  created_at: string;
+  delivered_at?: string;
+}
+
+export interface NotificationSubscriptions {
+  events: WebhookEvent[];
+}
+
+export interface EnrollmentToken {
+  id: string;
+  name: string;
+  config_id?: string;
+  group_id?: string;
+  max_uses?: number;
+  use_count: number;
+  expires_at?: string;
+  revoked_at?: string;
+  last_used_at?: string;
+  created_at: string;
+  token?: string;
+}
+
+export interface ProxyCredentials {
+  proxy_id: string;
+  enrollment_token_id?: string;
+  issued_at: string;
+  rotated_at?: string;
+  last_used_at?: string;
+  rotation_pending: boolean;
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/ats-proxy/proxy-helper/internal/ats"
+	"github.com/ats-proxy/proxy-helper/internal/config"
+	helpsync "github.com/ats-proxy/proxy-helper/internal/sync"
+	"github.com/ats-proxy/proxy-helper/internal/tail"
+)
+
+// accessLogBatch máximo de entradas por envio (limite do backend)
+const accessLogBatch = 5000
+
+// accessLogBatchBytes máximo de bytes de linhas por envio. O JSON do lote fica
+// maior que as linhas; com esta margem ele cabe no limite de 10 MiB do corpo
+// de /sync, que recusaria (400) o lote inteiro.
+const accessLogBatchBytes = 4 << 20
+
+// accessLogLoop acompanha o access log do ATS e envia as linhas novas ao
+// backend a cada AccessLogInterval. A posição no arquivo só é gravada depois
+// que o backend aceita o lote; um lote que falhou é reenviado no ciclo seguinte.
+func accessLogLoop(ctx context.Context, cfg *config.Config, client *helpsync.Client, connected *atomic.Bool) {
+	tailer := tail.New(cfg.AccessLog, filepath.Join(cfg.ConfigDir, ".access_log_state"))
+	defer tailer.Close()
+
+	ticker := time.NewTicker(cfg.AccessLogInterval)
+	defer ticker.Stop()
+
+	var pending []helpsync.AccessLogEntry
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !connected.Load() {
+				continue
+			}
+			pending = shipAccessLog(ctx, client, tailer, cfg.AccessLogFormat, pending)
+		}
+	}
+}
+
+// shipAccessLog envia o lote pendente e em seguida tudo o que foi escrito desde
+// o último envio, em lotes de até accessLogBatch entradas e accessLogBatchBytes
+// bytes. Retorna o lote que não pôde ser enviado.
+func shipAccessLog(ctx context.Context, client *helpsync.Client, tailer *tail.Tailer, format string, pending []helpsync.AccessLogEntry) []helpsync.AccessLogEntry {
+	more := true
+	for ctx.Err() == nil {
+		if len(pending) == 0 {
+			if !more {
+				return nil
+			}
+
+			lines, limited, err := tailer.ReadLimit(accessLogBatch, accessLogBatchBytes)
+			if err != nil {
+				log.Printf("WARN: Erro ao ler access log: %v", err)
+			}
+			more = err == nil && limited
+
+			invalid := 0
+			for _, line := range lines {
+				if line == "" {
+					continue
+				}
+				entry, err := ats.ParseAccessLog(line, format)
+				if err != nil {
+					invalid++
+					continue
+				}
+				pending = append(pending, entry)
+			}
+			if invalid > 0 {
+				log.Printf("WARN: %d linhas do access log ignoradas (formato %s)", invalid, format)
+			}
+
+			if len(pending) == 0 {
+				if err := tailer.Commit(); err != nil {
+					log.Printf("WARN: Erro ao gravar posição do access log: %v", err)
+				}
+				continue
+			}
+		}
+
+		res, err := client.SendAccessLogs(ctx, pending)
+		if err != nil {
+			if helpsync.IsHTTPStatus(err, http.StatusBadRequest) {
+				// Reenviar não adianta: descarta o lote
+				log.Printf("WARN: Backend recusou lote do access log (%d entradas), descartando: %v", len(pending), err)
+			} else {
+				log.Printf("WARN: Erro ao enviar access log (%d entradas pendentes): %v", len(pending), err)
+				return pending
+			}
+		} else if res.Dropped > 0 {
+			log.Printf("WARN: Backend descartou %d de %d entradas do access log", res.Dropped, len(pending))
+		}
+
+		pending = nil
+		if err := tailer.Commit(); err != nil {
+			log.Printf("WARN: Erro ao gravar posição do access log: %v", err)
+		}
+	}
+	return pending
+}
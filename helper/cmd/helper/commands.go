@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	stdsync "sync"
+	"time"
+
+	"github.com/ats-proxy/proxy-helper/internal/ats"
+	helpsync "github.com/ats-proxy/proxy-helper/internal/sync"
+)
+
+// commandQueueSize comandos aguardando execução além do que está rodando
+const commandQueueSize = 32
+
+// commandSeenSize quantos IDs de comandos já agendados são lembrados
+const commandSeenSize = 256
+
+// commandRunner executa os comandos remotos recebidos no sync, um por vez e
+// na ordem de chegada, cada um com o timeout definido pelo backend.
+type commandRunner struct {
+	client     *helpsync.Client
+	atsManager *ats.Manager
+	queue      chan helpsync.Command
+
+	// seen IDs dos últimos comandos agendados: o backend reenvia um comando
+	// até o helper confirmar o recebimento, então ele pode chegar duas vezes
+	seenMu stdsync.Mutex
+	seen   []string
+
+	// resync força a busca e aplicação do bundle completo
+	resync func(ctx context.Context) error
+	// state estado do helper incluído no bundle de diagnóstico
+	state func() helperState
+}
+
+// helperState vai como helper.json no bundle de diagnóstico
+type helperState struct {
+	Version        string    `json:"version"`
+	Commit         string    `json:"commit"`
+	Hostname       string    `json:"hostname"`
+	BackendURL     string    `json:"backend_url"`
+	ConfigHash     string    `json:"config_hash"`
+	Connected      bool      `json:"connected"`
+	StartedAt      time.Time `json:"started_at"`
+	SyncInterval   string    `json:"sync_interval"`
+	LongPoll       string    `json:"long_poll"`
+	DriftInterval  string    `json:"drift_interval"`
+	DriftRemediate bool      `json:"drift_remediate"`
+	CollectedAt    time.Time `json:"collected_at"`
+}
+
+func newCommandRunner(client *helpsync.Client, atsManager *ats.Manager) *commandRunner {
+	return &commandRunner{
+		client:     client,
+		atsManager: atsManager,
+		queue:      make(chan helpsync.Command, commandQueueSize),
+	}
+}
+
+// Enqueue agenda os comandos; com a fila cheia o comando é reportado como falho.
+func (r *commandRunner) Enqueue(ctx context.Context, commands []helpsync.Command) {
+	for _, cmd := range commands {
+		if !r.firstSeen(cmd.ID) {
+			continue
+		}
+		select {
+		case r.queue <- cmd:
+			log.Printf("Comando recebido: %s (%s)", cmd.Type, cmd.ID)
+		default:
+			log.Printf("WARN: Fila de comandos cheia, descartando %s (%s)", cmd.Type, cmd.ID)
+			r.report(ctx, cmd, "failed", "", errors.New("fila de comandos cheia"))
+		}
+	}
+}
+
+// firstSeen registra o comando e retorna false se ele já foi agendado
+func (r *commandRunner) firstSeen(id string) bool {
+	r.seenMu.Lock()
+	defer r.seenMu.Unlock()
+
+	if slices.Contains(r.seen, id) {
+		return false
+	}
+	r.seen = append(r.seen, id)
+	if len(r.seen) > commandSeenSize {
+		r.seen = r.seen[len(r.seen)-commandSeenSize:]
+	}
+	return true
+}
+
+// Run consome a fila até o contexto ser cancelado
+func (r *commandRunner) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case cmd := <-r.queue:
+			r.execute(ctx, cmd)
+		}
+	}
+}
+
+func (r *commandRunner) execute(ctx context.Context, cmd helpsync.Command) {
+	cmdCtx, cancel := context.WithTimeout(ctx, cmd.Timeout())
+	output, err := r.dispatch(cmdCtx, cmd)
+	timedOut := errors.Is(cmdCtx.Err(), context.DeadlineExceeded)
+	cancel()
+
+	if ctx.Err() != nil {
+		return // helper encerrando; o backend marca o comando como timed_out
+	}
+
+	status := "succeeded"
+	switch {
+	case timedOut:
+		status = "timed_out"
+		err = fmt.Errorf("tempo limite de %s excedido", cmd.Timeout())
+	case err != nil:
+		status = "failed"
+	}
+	log.Printf("Comando %s (%s): %s", cmd.Type, cmd.ID, status)
+	r.report(ctx, cmd, status, output, err)
+}
+
+func (r *commandRunner) dispatch(ctx context.Context, cmd helpsync.Command) (string, error) {
+	switch cmd.Type {
+	case "resync":
+		if err := r.resync(ctx); err != nil {
+			return "", err
+		}
+		return "config sincronizada (hash: " + r.atsManager.GetCurrentHash() + ")", nil
+	case "reload":
+		applyMu.Lock()
+		defer applyMu.Unlock()
+		return r.atsManager.ReloadContext(ctx)
+	case "restart":
+		applyMu.Lock()
+		defer applyMu.Unlock()
+		return r.atsManager.RestartContext(ctx)
+	case "clear_hostdb":
+		applyMu.Lock()
+		defer applyMu.Unlock()
+		return r.atsManager.ClearHostDB(ctx)
+	case "diagnostics":
+		return r.diagnostics(ctx, cmd)
+	case "connectivity_test":
+		return r.atsManager.ConnectivityTest(ctx, cmd.Args["url"], cmd.Args["direct"] == "true")
+	default:
+		return "", fmt.Errorf("comando desconhecido: %q", cmd.Type)
+	}
+}
+
+// diagnostics monta o bundle de diagnóstico e envia ao backend
+func (r *commandRunner) diagnostics(ctx context.Context, cmd helpsync.Command) (string, error) {
+	state, err := json.MarshalIndent(r.state(), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("erro ao serializar estado do helper: %w", err)
+	}
+
+	bundle, err := r.atsManager.DiagnosticsBundle(ctx, map[string][]byte{"helper.json": state})
+	if err != nil {
+		return "", err
+	}
+
+	upload, err := r.client.UploadDiagnostics(ctx, cmd.ID, bundle)
+	if err != nil {
+		return "", fmt.Errorf("erro ao enviar bundle (%d bytes): %w", len(bundle), err)
+	}
+	return fmt.Sprintf("bundle %s enviado (%d bytes, sha256 %s, expira em %s)",
+		upload.ID, upload.SizeBytes, upload.SHA256, upload.ExpiresAt.UTC().Format(time.RFC3339)), nil
+}
+
+func (r *commandRunner) report(ctx context.Context, cmd helpsync.Command, status, output string, err error) {
+	res := helpsync.CommandResult{ID: cmd.ID, Status: status, Output: output}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	if err := r.client.SendCommandResult(ctx, res); err != nil {
+		log.Printf("WARN: Erro ao enviar resultado do comando %s: %v", cmd.ID, err)
+	}
+}
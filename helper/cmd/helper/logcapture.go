@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ats-proxy/proxy-helper/internal/ats"
+	helpsync "github.com/ats-proxy/proxy-helper/internal/sync"
+	"github.com/ats-proxy/proxy-helper/internal/tail"
+)
+
+const (
+	logCaptureInterval = 5 * time.Second
+	// logCaptureBatch máximo de linhas enviadas por ciclo; o restante fica
+	// para o ciclo seguinte
+	logCaptureBatch = 2000
+	// logCapturePending máximo de linhas guardadas enquanto o backend não
+	// aceita o envio; além disso as mais antigas são descartadas
+	logCapturePending = 10000
+)
+
+// logCapture mantém no máximo uma captura de logs por vez. Enquanto o backend
+// continuar pedindo a captura, cada sync só estende o prazo (e troca os debug
+// tags, se mudaram). Só as linhas escritas no diags.log depois do início da
+// captura são enviadas, cada uma uma única vez.
+type logCapture struct {
+	client     *helpsync.Client
+	atsManager *ats.Manager
+
+	mu      sync.Mutex
+	running bool
+	until   time.Time
+	tags    []string
+}
+
+func newLogCapture(client *helpsync.Client, atsManager *ats.Manager) *logCapture {
+	return &logCapture{client: client, atsManager: atsManager}
+}
+
+// Start inicia a captura até until, ou atualiza a que está em andamento
+func (c *logCapture) Start(ctx context.Context, until time.Time, tags []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.until = until
+	if c.running {
+		if !slices.Equal(tags, c.tags) {
+			log.Printf("Captura de logs: debug tags alterados para %v", tags)
+			if err := c.atsManager.EnableDebug(tags); err != nil {
+				log.Printf("WARN: Erro ao alterar debug tags: %v", err)
+			}
+			c.tags = tags
+		}
+		return
+	}
+
+	// Posiciona no fim do diags.log antes de habilitar o debug
+	tailer := tail.New(ats.DiagsLogFile, "")
+	if _, err := tailer.Read(0); err != nil {
+		log.Printf("WARN: Erro ao abrir diags.log: %v", err)
+	}
+
+	log.Printf("Iniciando captura de logs até %s (debug tags: %v)", until.Format(time.RFC3339), tags)
+	if err := c.atsManager.EnableDebug(tags); err != nil {
+		log.Printf("WARN: Erro ao habilitar debug: %v", err)
+		tailer.Close()
+		return
+	}
+
+	c.running = true
+	c.tags = tags
+	go c.run(ctx, tailer)
+}
+
+func (c *logCapture) run(ctx context.Context, tailer *tail.Tailer) {
+	defer tailer.Close()
+
+	parser := ats.NewDiagsParser()
+	ticker := time.NewTicker(logCaptureInterval)
+	defer ticker.Stop()
+
+	var pending []helpsync.LogLine
+	for {
+		select {
+		case <-ctx.Done():
+			c.stop()
+			return
+
+		case <-ticker.C:
+			pending = c.send(ctx, tailer, parser, pending)
+
+			c.mu.Lock()
+			if time.Now().After(c.until) {
+				c.stopLocked()
+				c.mu.Unlock()
+				return
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// send envia as linhas que ficaram de um envio que falhou junto com as novas
+// do diags.log. Retorna as linhas que não puderam ser enviadas, para o ciclo
+// seguinte.
+func (c *logCapture) send(ctx context.Context, tailer *tail.Tailer, parser *ats.DiagsParser, pending []helpsync.LogLine) []helpsync.LogLine {
+	lines, err := tailer.Read(logCaptureBatch)
+	if err != nil {
+		log.Printf("WARN: Erro ao ler diags.log: %v", err)
+	}
+
+	logs := pending
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		logs = append(logs, parser.Parse(line))
+	}
+	if len(logs) == 0 {
+		return nil
+	}
+	if err := c.client.SendLogs(ctx, logs); err != nil {
+		if helpsync.IsHTTPStatus(err, http.StatusBadRequest) {
+			// Reenviar não adianta: descarta as linhas
+			log.Printf("WARN: Backend recusou logs (%d linhas), descartando: %v", len(logs), err)
+			return nil
+		}
+		log.Printf("WARN: Erro ao enviar logs (%d linhas pendentes): %v", len(logs), err)
+		if dropped := len(logs) - logCapturePending; dropped > 0 {
+			log.Printf("WARN: %d linhas de log mais antigas descartadas", dropped)
+			logs = logs[dropped:]
+		}
+		return logs
+	}
+	return nil
+}
+
+func (c *logCapture) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopLocked()
+}
+
+// stopLocked desabilita o debug; chamado com c.mu travado para não competir
+// com um Start que chegue nesse momento
+func (c *logCapture) stopLocked() {
+	if err := c.atsManager.DisableDebug(); err != nil {
+		log.Printf("WARN: Erro ao desabilitar debug: %v", err)
+	}
+	c.running = false
+	c.tags = nil
+	log.Println("Captura de logs finalizada")
+}
@@ -3,9 +3,12 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -25,13 +28,30 @@ const (
 	registerInterval = 10 * time.Second
 )
 
+// applyMu serializa a escrita dos arquivos (sync) e a verificação de drift,
+// para o drift nunca ver uma config aplicada pela metade.
+var applyMu sync.Mutex
+
 func main() {
 	// Flags
 	backendURL := flag.String("backend-url", "", "URL do backend API (obrigatório)")
 	configID := flag.String("config-id", "", "ID da configuração (obrigatório)")
 	hostname := flag.String("hostname", "", "Hostname deste proxy (default: hostname do sistema)")
+	labels := flag.String("labels", "", "Labels deste proxy enviados no registro (ex: site=poa,env=prod)")
 	syncInterval := flag.Duration("sync-interval", 30*time.Second, "Intervalo de sincronização")
+	longPoll := flag.Duration("long-poll", 25*time.Second, "Long-poll: tempo máximo de espera por mudança de config (0 desativa)")
+	driftInterval := flag.Duration("drift-interval", 60*time.Second, "Intervalo da verificação de drift dos arquivos em disco (0 desativa)")
+	driftRemediate := flag.Bool("drift-remediate", false, "Reaplica o último bundle verificado quando detecta drift")
+	accessLog := flag.String("access-log", "", "Access log do ATS enviado ao backend (vazio desativa)")
+	accessLogFormat := flag.String("access-log-format", ats.AccessLogJSON, "Formato do access log (json, combined)")
+	accessLogInterval := flag.Duration("access-log-interval", 10*time.Second, "Intervalo entre envios do access log")
 	configDir := flag.String("config-dir", "/opt/etc/trafficserver", "Diretório de configuração do ATS")
+	enrollToken := flag.String("enroll-token", os.Getenv("ENROLL_TOKEN"), "Token de registro (só necessário no primeiro registro)")
+	caFile := flag.String("ca-file", "", "CA (PEM) fixada para verificar o certificado do backend")
+	certFile := flag.String("cert-file", "", "Certificado de cliente (PEM) para mTLS")
+	keyFile := flag.String("key-file", "", "Chave do certificado de cliente (PEM)")
+	bundlePublicKey := flag.String("bundle-public-key", os.Getenv("BUNDLE_PUBLIC_KEY"), "Chave pública Ed25519 (base64) dos bundles; sem ela confia na primeira obtida do backend")
+	credentialsFile := flag.String("credentials-file", "", "Arquivo das credenciais de sync (default: <config-dir>/.helper_credentials)")
 	logLevel := flag.String("log-level", "info", "Nível de log (debug, info, warn, error)")
 	showVersion := flag.Bool("version", false, "Mostra versão e sai")
 
@@ -57,20 +77,63 @@ func main() {
 		*hostname = h
 	}
 
+	proxyLabels, err := config.ParseLabels(*labels)
+	if err != nil {
+		log.Fatalf("--labels: %v", err)
+	}
+
+	if (*certFile == "") != (*keyFile == "") {
+		log.Fatal("--cert-file e --key-file devem ser usados juntos")
+	}
+
+	if !ats.ValidAccessLogFormat(*accessLogFormat) {
+		log.Fatalf("--access-log-format inválido: %s", *accessLogFormat)
+	}
+	if *accessLog != "" && *accessLogInterval <= 0 {
+		log.Fatal("--access-log-interval deve ser maior que zero")
+	}
+
+	if *credentialsFile == "" {
+		*credentialsFile = filepath.Join(*configDir, ".helper_credentials")
+	}
+
 	cfg := &config.Config{
-		BackendURL:   *backendURL,
-		ConfigID:     *configID,
-		Hostname:     *hostname,
-		SyncInterval: *syncInterval,
-		ConfigDir:    *configDir,
-		LogLevel:     *logLevel,
+		BackendURL:      *backendURL,
+		ConfigID:        *configID,
+		Hostname:        *hostname,
+		Labels:          proxyLabels,
+		EnrollToken:     *enrollToken,
+		CredentialsFile: *credentialsFile,
+		CAFile:          *caFile,
+		CertFile:        *certFile,
+		KeyFile:         *keyFile,
+		SyncInterval:    *syncInterval,
+		LongPoll:        *longPoll,
+		DriftInterval:   *driftInterval,
+		DriftRemediate:  *driftRemediate,
+
+		AccessLog:         *accessLog,
+		AccessLogFormat:   *accessLogFormat,
+		AccessLogInterval: *accessLogInterval,
+
+		ConfigDir: *configDir,
+		LogLevel:  *logLevel,
 	}
 
+	startedAt := time.Now()
 	log.Printf("Iniciando proxy-helper v%s", version)
 	log.Printf("Backend: %s", cfg.BackendURL)
 	log.Printf("Config ID: %s", cfg.ConfigID)
 	log.Printf("Hostname: %s", cfg.Hostname)
+	if len(cfg.Labels) > 0 {
+		log.Printf("Labels: %v", cfg.Labels)
+	}
 	log.Printf("Sync Interval: %s", cfg.SyncInterval)
+	log.Printf("Long-poll: %s", cfg.LongPoll)
+	log.Printf("Drift: a cada %s (remediação: %v)", cfg.DriftInterval, cfg.DriftRemediate)
+	if cfg.AccessLog != "" {
+		log.Printf("Access log: %s (%s, a cada %s)", cfg.AccessLog, cfg.AccessLogFormat, cfg.AccessLogInterval)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -83,20 +146,87 @@ func main() {
 		cancel()
 	}()
 
-	syncClient := helpsync.NewClient(cfg)
+	syncClient, err := helpsync.NewClient(cfg)
+	if err != nil {
+		log.Fatalf("Erro ao configurar TLS: %v", err)
+	}
+
+	// SIGHUP recarrega CA e certificado de cliente (ex: após renovação)
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			if err := syncClient.ReloadTLS(); err != nil {
+				log.Printf("ERROR: Erro ao recarregar certificados, mantendo os atuais: %v", err)
+				continue
+			}
+			log.Println("Certificados TLS recarregados")
+		}
+	}()
 	atsManager := ats.NewManager(cfg.ConfigDir)
 
+	verifier, err := helpsync.NewBundleVerifier(*bundlePublicKey, filepath.Join(cfg.ConfigDir, ".bundle_pubkey"))
+	if err != nil {
+		log.Fatalf("Erro ao carregar chave de assinatura: %v", err)
+	}
+
+	// Fase 0: Garante os arquivos do último bundle verificado, mesmo sem backend
+	restoreCachedBundle(atsManager, verifier)
+
 	// Fase 1: Aguardar registro no backend (retry constante a cada 10s)
 	if !waitForRegister(ctx, syncClient) {
 		return // contexto cancelado
 	}
 
+	if err := verifier.TrustOnFirstUse(ctx, syncClient); err != nil {
+		log.Printf("WARN: Erro ao obter chave de assinatura: %v", err)
+	}
+
 	// Fase 2: Hello loop (goroutine) + Sync loop
 	var connected atomic.Bool
 	connected.Store(true) // acabou de registrar, está conectado
 
 	go helloLoop(ctx, syncClient, &connected)
 
+	capture := newLogCapture(syncClient, atsManager)
+	runner := newCommandRunner(syncClient, atsManager)
+	runner.resync = func(ctx context.Context) error {
+		// Sem hash local o backend devolve o bundle completo
+		atsManager.SaveHash("")
+		if _, ok := doSync(ctx, syncClient, atsManager, verifier, &connected, runner, capture, 0); !ok {
+			return fmt.Errorf("erro ao buscar ou aplicar config")
+		}
+		return nil
+	}
+	runner.state = func() helperState {
+		return helperState{
+			Version:        version,
+			Commit:         commit,
+			Hostname:       cfg.Hostname,
+			BackendURL:     cfg.BackendURL,
+			ConfigHash:     atsManager.GetCurrentHash(),
+			Connected:      connected.Load(),
+			StartedAt:      startedAt,
+			SyncInterval:   cfg.SyncInterval.String(),
+			LongPoll:       cfg.LongPoll.String(),
+			DriftInterval:  cfg.DriftInterval.String(),
+			DriftRemediate: cfg.DriftRemediate,
+			CollectedAt:    time.Now(),
+		}
+	}
+	go runner.Run(ctx)
+	if cfg.DriftInterval > 0 {
+		go driftLoop(ctx, cfg, syncClient, atsManager, verifier, &connected)
+	}
+	if cfg.AccessLog != "" {
+		go accessLogLoop(ctx, cfg, syncClient, &connected)
+	}
+
+	if cfg.LongPoll > 0 {
+		longPollLoop(ctx, cfg, syncClient, atsManager, verifier, &connected, runner, capture)
+		return
+	}
+
 	// Sync loop
 	ticker := time.NewTicker(cfg.SyncInterval)
 	defer ticker.Stop()
@@ -112,11 +242,83 @@ func main() {
 				log.Println("Sem conexão com o backend, aguardando reconexão...")
 				continue
 			}
-			doSync(ctx, syncClient, atsManager, &connected)
+			doSync(ctx, syncClient, atsManager, verifier, &connected, runner, capture, 0)
 		}
 	}
 }
 
+// longPollLoop encadeia long-polls: cada GET /sync fica pendente no backend até
+// a config mudar ou o tempo de espera acabar, e o próximo começa em seguida.
+// Em caso de erro, ou se o backend responder sem segurar a requisição (versão
+// sem suporte a long-poll), espera o --sync-interval antes de tentar de novo.
+func longPollLoop(ctx context.Context, cfg *config.Config, client *helpsync.Client, atsManager *ats.Manager, verifier *helpsync.BundleVerifier, connected *atomic.Bool, runner *commandRunner, capture *logCapture) {
+	for {
+		if ctx.Err() != nil {
+			log.Println("Encerrando helper...")
+			return
+		}
+
+		if !connected.Load() {
+			log.Println("Sem conexão com o backend, aguardando reconexão...")
+			sleepCtx(ctx, cfg.SyncInterval)
+			continue
+		}
+
+		start := time.Now()
+		changed, ok := doSync(ctx, client, atsManager, verifier, connected, runner, capture, cfg.LongPoll)
+		if !ok || (!changed && time.Since(start) < cfg.LongPoll/2) {
+			sleepCtx(ctx, cfg.SyncInterval)
+		}
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+// restoreCachedBundle garante, mesmo com o backend fora do ar, que os arquivos
+// em disco são os do último bundle verificado. Um bundle em cache com assinatura
+// inválida é ignorado.
+func restoreCachedBundle(atsManager *ats.Manager, verifier *helpsync.BundleVerifier) {
+	bundle, err := atsManager.LoadBundle()
+	if err != nil {
+		log.Printf("WARN: %v", err)
+		return
+	}
+	if bundle == nil {
+		log.Println("Nenhum bundle em cache, aguardando config do backend")
+		return
+	}
+
+	if err := verifier.Verify(bundle); err != nil {
+		log.Printf("ERROR: Bundle em cache rejeitado: %v", err)
+		return
+	}
+
+	if atsManager.FilesMatch(bundle.Config) {
+		log.Printf("Arquivos conferem com o bundle em cache (hash: %s)", bundle.Hash)
+		return
+	}
+
+	log.Printf("Arquivos em disco divergem do bundle em cache (hash: %s), restaurando...", bundle.Hash)
+	changed, err := atsManager.ApplyConfig(bundle.Config)
+	if err != nil {
+		log.Printf("ERROR: Erro ao restaurar bundle: %v", err)
+		return
+	}
+	if err := atsManager.Activate(changed); err != nil {
+		log.Printf("ERROR: %v", err)
+		return
+	}
+	atsManager.SaveHash(bundle.Hash)
+	log.Println("Bundle em cache restaurado")
+}
+
 // waitForRegister bloqueia até conseguir registrar no backend.
 // Tenta a cada 10s com timeout de 4s por tentativa.
 func waitForRegister(ctx context.Context, client *helpsync.Client) bool {
@@ -179,48 +381,60 @@ func helloLoop(ctx context.Context, client *helpsync.Client, connected *atomic.B
 	}
 }
 
-func doSync(ctx context.Context, client *helpsync.Client, ats *ats.Manager, connected *atomic.Bool) {
+// doSync busca a config (wait > 0 para long-poll), aplica se mudou e agenda os
+// comandos recebidos. Retorna changed=true se o backend enviou config nova ou
+// comandos e ok=false se a busca, a verificação ou a aplicação falhou, para que
+// o loop espere --sync-interval em vez de reaplicar (e reenviar o ack) sem pausa.
+func doSync(ctx context.Context, client *helpsync.Client, ats *ats.Manager, verifier *helpsync.BundleVerifier, connected *atomic.Bool, runner *commandRunner, capture *logCapture, wait time.Duration) (changed, ok bool) {
 	currentHash := ats.GetCurrentHash()
 
-	resp, err := client.GetConfig(ctx, currentHash)
+	resp, err := client.GetConfig(ctx, currentHash, wait)
 	if err != nil {
+		if ctx.Err() != nil {
+			return false, false
+		}
 		// Se 404, proxy sumiu do backend — marcar desconectado para forçar re-registro via hello
 		if helpsync.IsHTTPStatus(err, 404) {
 			log.Println("Proxy não encontrado no backend (404), aguardando re-registro...")
 			connected.Store(false)
-			return
+			return false, false
 		}
 		log.Printf("WARN: Erro ao buscar config: %v", err)
-		return
+		return false, false
 	}
 
 	// Verifica se há captura de logs ativa
 	if resp.CaptureLogs {
-		go captureAndSendLogs(ctx, client, ats, resp.CaptureUntil)
+		capture.Start(ctx, resp.CaptureUntil, resp.DebugTags)
 	}
 
+	runner.Enqueue(ctx, resp.Commands)
+
 	// Se não mudou, apenas envia stats
 	if resp.Unchanged {
 		sendStats(ctx, client, ats)
-		return
+		return len(resp.Commands) > 0, true
 	}
 
 	log.Printf("Config alterada (hash: %s -> %s), aplicando...", currentHash, resp.Hash)
 
-	if err := ats.ApplyConfig(resp.Config); err != nil {
-		log.Printf("ERROR: Erro ao aplicar config: %v", err)
-		client.Ack(ctx, resp.Hash, "error", err.Error())
-		return
+	// Nada é aplicado sem assinatura válida
+	bundle := resp.Bundle()
+	if err := verifier.TrustOnFirstUse(ctx, client); err != nil {
+		log.Printf("WARN: Erro ao obter chave de assinatura: %v", err)
+	}
+	if err := verifier.Verify(bundle); err != nil {
+		log.Printf("ERROR: Bundle rejeitado: %v", err)
+		client.Ack(ctx, resp.Hash, "error", "bundle rejeitado: "+err.Error())
+		return true, false
 	}
 
-	if err := ats.Reload(); err != nil {
-		log.Printf("ERROR: Erro ao recarregar ATS: %v", err)
+	if err := applyBundle(ats, bundle); err != nil {
+		log.Printf("ERROR: %v", err)
 		client.Ack(ctx, resp.Hash, "error", err.Error())
-		return
+		return true, false
 	}
 
-	ats.SaveHash(resp.Hash)
-
 	if err := client.Ack(ctx, resp.Hash, "ok", ""); err != nil {
 		log.Printf("WARN: Erro ao confirmar config: %v", err)
 	}
@@ -228,54 +442,150 @@ func doSync(ctx context.Context, client *helpsync.Client, ats *ats.Manager, conn
 	log.Printf("Config aplicada com sucesso (hash: %s)", resp.Hash)
 
 	sendStats(ctx, client, ats)
+	return true, true
 }
 
-func sendStats(ctx context.Context, client *helpsync.Client, atsManager *ats.Manager) {
-	stats, err := atsManager.CollectStats()
+// applyBundle escreve os arquivos do bundle, recarrega (ou reinicia) o ATS
+// conforme os arquivos alterados e guarda hash e bundle como os aplicados.
+func applyBundle(atsManager *ats.Manager, bundle *helpsync.SignedBundle) error {
+	applyMu.Lock()
+	defer applyMu.Unlock()
+
+	changed, err := atsManager.ApplyConfig(bundle.Config)
 	if err != nil {
-		log.Printf("WARN: Erro ao coletar stats: %v", err)
-		return
+		return fmt.Errorf("erro ao aplicar config: %w", err)
 	}
-
-	if err := client.SendStats(ctx, stats); err != nil {
-		log.Printf("WARN: Erro ao enviar stats: %v", err)
+	if err := atsManager.Activate(changed); err != nil {
+		return err
 	}
-}
-
-func captureAndSendLogs(ctx context.Context, client *helpsync.Client, atsManager *ats.Manager, until time.Time) {
-	log.Printf("Iniciando captura de logs até %s", until.Format(time.RFC3339))
 
-	if err := atsManager.EnableDebug(); err != nil {
-		log.Printf("WARN: Erro ao habilitar debug: %v", err)
-		return
+	atsManager.SaveHash(bundle.Hash)
+	if err := atsManager.SaveBundle(bundle); err != nil {
+		log.Printf("WARN: Erro ao guardar bundle: %v", err)
 	}
+	return nil
+}
 
-	defer func() {
-		if err := atsManager.DisableDebug(); err != nil {
-			log.Printf("WARN: Erro ao desabilitar debug: %v", err)
-		}
-		log.Println("Captura de logs finalizada")
-	}()
-
-	ticker := time.NewTicker(5 * time.Second)
+// driftLoop verifica periodicamente se os arquivos em disco ainda são os do
+// bundle aplicado (ex: alguém editou parent.config à mão no nó) e reporta o
+// resultado ao backend.
+func driftLoop(ctx context.Context, cfg *config.Config, client *helpsync.Client, atsManager *ats.Manager, verifier *helpsync.BundleVerifier, connected *atomic.Bool) {
+	ticker := time.NewTicker(cfg.DriftInterval)
 	defer ticker.Stop()
 
+	drifted := false
 	for {
 		select {
 		case <-ctx.Done():
 			return
-
 		case <-ticker.C:
-			if time.Now().After(until) {
-				return
+			report, ok := checkDrift(atsManager, verifier, cfg.DriftRemediate)
+			if !ok {
+				continue
 			}
 
-			logs := atsManager.CaptureLogs()
-			if len(logs) > 0 {
-				if err := client.SendLogs(ctx, logs); err != nil {
-					log.Printf("WARN: Erro ao enviar logs: %v", err)
+			if report.Drifted != drifted {
+				if report.Drifted {
+					log.Printf("WARN: Drift detectado em %v (hash em disco %s, esperado %s)", report.Files, report.ActualHash, report.ExpectedHash)
+				} else if !report.Remediated {
+					log.Println("Drift resolvido, arquivos conferem com o bundle aplicado")
 				}
+				drifted = report.Drifted
+			}
+
+			if !connected.Load() {
+				continue
+			}
+			if err := client.SendDrift(ctx, report); err != nil {
+				log.Printf("WARN: Erro ao reportar drift: %v", err)
 			}
 		}
 	}
 }
+
+// checkDrift recalcula o hash dos arquivos em disco e compara com o do bundle
+// aplicado. Com o bundle em cache, só conta como drift se algum arquivo que o
+// bundle escreve mudou (arquivos vazios no bundle não são escritos e podem
+// diferir sem ser drift). Com remediate, reaplica o bundle verificado e recarrega
+// o ATS. Retorna ok=false se ainda não há config aplicada.
+func checkDrift(atsManager *ats.Manager, verifier *helpsync.BundleVerifier, remediate bool) (report helpsync.DriftRequest, ok bool) {
+	applyMu.Lock()
+	defer applyMu.Unlock()
+
+	bundle, err := atsManager.LoadBundle()
+	if err != nil {
+		log.Printf("WARN: %v", err)
+	}
+
+	report.ExpectedHash = atsManager.GetCurrentHash()
+	if bundle != nil {
+		report.ExpectedHash = bundle.Hash
+	}
+	if report.ExpectedHash == "" {
+		return report, false
+	}
+
+	var extra []string
+	ports := false
+	if bundle != nil {
+		extra = bundle.Config.ExtraNames()
+		ports = bundle.Config.ConnectPorts != ""
+	}
+	report.ActualHash, err = atsManager.CalculateLocalHash(extra, ports)
+	if err != nil {
+		log.Printf("WARN: Erro ao calcular hash dos arquivos: %v", err)
+		return report, false
+	}
+	if report.ActualHash == report.ExpectedHash {
+		return report, true
+	}
+
+	if bundle == nil {
+		report.Drifted = true
+		return report, true
+	}
+	report.Files = atsManager.ChangedFiles(bundle.Config)
+	report.Drifted = len(report.Files) > 0
+	if !report.Drifted || !remediate {
+		return report, true
+	}
+
+	if err := verifier.Verify(bundle); err != nil {
+		log.Printf("ERROR: Bundle em cache rejeitado, drift não remediado: %v", err)
+		return report, true
+	}
+	log.Printf("Drift em %v, reaplicando bundle (hash: %s)...", report.Files, bundle.Hash)
+	changed, err := atsManager.ApplyConfig(bundle.Config)
+	if err != nil {
+		log.Printf("ERROR: Erro ao reaplicar bundle: %v", err)
+		return report, true
+	}
+	if err := atsManager.Activate(changed); err != nil {
+		log.Printf("ERROR: %v", err)
+		return report, true
+	}
+
+	report.Drifted = len(atsManager.ChangedFiles(bundle.Config)) > 0
+	report.Remediated = !report.Drifted
+	if actual, err := atsManager.CalculateLocalHash(extra, ports); err == nil {
+		report.ActualHash = actual
+	}
+	if report.Remediated {
+		log.Println("Drift remediado, bundle reaplicado")
+	}
+	return report, true
+}
+
+func sendStats(ctx context.Context, client *helpsync.Client, atsManager *ats.Manager) {
+	stats, err := atsManager.CollectStats()
+	if err != nil {
+		log.Printf("WARN: Erro ao coletar stats: %v", err)
+		return
+	}
+
+	parents := atsManager.CheckParents()
+
+	if err := client.SendStats(ctx, stats, parents); err != nil {
+		log.Printf("WARN: Erro ao enviar stats: %v", err)
+	}
+}
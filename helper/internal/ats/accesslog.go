@@ -0,0 +1,141 @@
+package ats
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ats-proxy/proxy-helper/internal/sync"
+)
+
+// Formatos de access log aceitos
+const (
+	// AccessLogJSON formato access_json do logging.yaml (um objeto JSON por linha)
+	AccessLogJSON = "json"
+	// AccessLogCombined formato combined padrão do ATS
+	AccessLogCombined = "combined"
+)
+
+// ValidAccessLogFormat indica se o formato é suportado
+func ValidAccessLogFormat(format string) bool {
+	return format == AccessLogJSON || format == AccessLogCombined
+}
+
+// jsonAccessLine campos do formato access_json. Campos vazios no ATS saem
+// como "-", por isso os numéricos vêm entre aspas e são lidos como
+// json.RawMessage.
+type jsonAccessLine struct {
+	TS         json.RawMessage `json:"ts"`
+	ClientIP   string          `json:"client_ip"`
+	Username   string          `json:"username"`
+	Method     string          `json:"method"`
+	URL        string          `json:"url"`
+	Host       string          `json:"host"`
+	Status     json.RawMessage `json:"status"`
+	Bytes      json.RawMessage `json:"bytes"`
+	DurationMs json.RawMessage `json:"duration_ms"`
+	Route      string          `json:"route"`
+	Upstream   string          `json:"upstream"`
+	UserAgent  string          `json:"user_agent"`
+}
+
+// combinedLine %<chi> - %<caun> [%<cqtn>] "%<cqtx>" %<pssc> %<pscl> "%<{Referer}cqh>" "%<{User-Agent}cqh>"
+var combinedLine = regexp.MustCompile(`^(\S+) \S+ (\S+) \[([^\]]+)\] "([^"]*)" (\d+|-) (\d+|-)(?: "[^"]*" "([^"]*)")?`)
+
+// ParseAccessLog converte uma linha do access log no formato informado
+func ParseAccessLog(line, format string) (sync.AccessLogEntry, error) {
+	if format == AccessLogCombined {
+		return parseCombined(line)
+	}
+	return parseJSONAccess(line)
+}
+
+func parseJSONAccess(line string) (sync.AccessLogEntry, error) {
+	var raw jsonAccessLine
+	if err := json.Unmarshal([]byte(line), &raw); err != nil {
+		return sync.AccessLogEntry{}, fmt.Errorf("linha JSON inválida: %w", err)
+	}
+
+	ts, ok := rawNumber(raw.TS)
+	if !ok {
+		return sync.AccessLogEntry{}, fmt.Errorf("timestamp inválido: %s", raw.TS)
+	}
+	sec, frac := math.Modf(ts)
+
+	entry := sync.AccessLogEntry{
+		Timestamp: time.Unix(int64(sec), int64(frac*1e9)).UTC().Round(time.Millisecond),
+		ClientIP:  raw.ClientIP,
+		Username:  dashEmpty(raw.Username),
+		Method:    raw.Method,
+		URL:       raw.URL,
+		Host:      dashEmpty(raw.Host),
+		Route:     dashEmpty(raw.Route),
+		Upstream:  dashEmpty(raw.Upstream),
+		UserAgent: dashEmpty(raw.UserAgent),
+	}
+	if status, ok := rawNumber(raw.Status); ok {
+		entry.Status = int(status)
+	}
+	if bytes, ok := rawNumber(raw.Bytes); ok {
+		entry.Bytes = int64(bytes)
+	}
+	if ms, ok := rawNumber(raw.DurationMs); ok {
+		d := int(ms)
+		entry.DurationMs = &d
+	}
+	return entry, nil
+}
+
+func parseCombined(line string) (sync.AccessLogEntry, error) {
+	m := combinedLine.FindStringSubmatch(line)
+	if m == nil {
+		return sync.AccessLogEntry{}, fmt.Errorf("linha fora do formato combined")
+	}
+
+	ts, err := time.Parse("02/Jan/2006:15:04:05 -0700", m[3])
+	if err != nil {
+		return sync.AccessLogEntry{}, fmt.Errorf("timestamp inválido: %s", m[3])
+	}
+
+	entry := sync.AccessLogEntry{
+		Timestamp: ts.UTC(),
+		ClientIP:  m[1],
+		Username:  dashEmpty(m[2]),
+		UserAgent: dashEmpty(m[7]),
+	}
+
+	// cqtx: "METHOD URL PROTO"
+	request := strings.Fields(m[4])
+	if len(request) >= 2 {
+		entry.Method = request[0]
+		entry.URL = request[1]
+	}
+	entry.Status, _ = strconv.Atoi(m[5])
+	entry.Bytes, _ = strconv.ParseInt(m[6], 10, 64)
+	return entry, nil
+}
+
+// rawNumber lê um número JSON, aceitando também números entre aspas. "-" e
+// campos ausentes retornam ok=false.
+func rawNumber(raw json.RawMessage) (float64, bool) {
+	s := strings.Trim(string(raw), `"`)
+	if s == "" || s == "-" {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+func dashEmpty(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,72 @@
+package ats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ats-proxy/proxy-helper/internal/sync"
+)
+
+const bundleFileName = ".config_bundle.json"
+
+// SaveBundle guarda o último bundle verificado e aplicado
+func (m *Manager) SaveBundle(b *sync.SignedBundle) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar bundle: %w", err)
+	}
+	return m.writeFile(filepath.Join(m.configDir, bundleFileName), string(data))
+}
+
+// LoadBundle lê o bundle guardado. Retorna nil se não existe.
+func (m *Manager) LoadBundle() (*sync.SignedBundle, error) {
+	data, err := os.ReadFile(filepath.Join(m.configDir, bundleFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler bundle: %w", err)
+	}
+
+	var b sync.SignedBundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("erro ao deserializar bundle: %w", err)
+	}
+	return &b, nil
+}
+
+// FilesMatch indica se os arquivos em disco têm o conteúdo do bundle.
+func (m *Manager) FilesMatch(cfg *sync.ConfigFiles) bool {
+	return len(m.ChangedFiles(cfg)) == 0
+}
+
+// ChangedFiles lista os arquivos em disco cujo conteúdo difere do bundle.
+// Arquivos vazios no bundle não são escritos por ApplyConfig e só entram na
+// lista se o helper ainda precisa restaurar a versão original deles.
+// Portas de CONNECT do bundle diferentes das do ATS entram como o record.
+func (m *Manager) ChangedFiles(cfg *sync.ConfigFiles) []string {
+	files := cfg.Named()
+	var changed []string
+	for _, name := range sortedManagedFiles() {
+		content := files[name]
+		if content == "" {
+			if m.hasOriginal(name) {
+				changed = append(changed, name)
+			}
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.configDir, name))
+		if err != nil || string(data) != content {
+			changed = append(changed, name)
+		}
+	}
+	if m.connectPortsChanged(cfg.ConnectPorts) {
+		changed = append(changed, connectPortsRecord)
+	}
+	sort.Strings(changed)
+	return changed
+}
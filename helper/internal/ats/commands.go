@@ -0,0 +1,150 @@
+package ats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Operações dos comandos remotos. Diferente de Reload/Restart, respeitam o
+// contexto (timeout do comando) e devolvem a saída para o backend.
+
+// localProxyURL porta HTTP do ATS no próprio nó (server_ports do records.yaml)
+const localProxyURL = "http://127.0.0.1:8080"
+
+// hostDBFile cache de DNS persistido pelo ATS; versões sem persistência
+// mantêm o HostDB só em memória, descartado no restart.
+const hostDBFile = "/opt/var/trafficserver/host.db"
+
+// trafficCtl executa traffic_ctl com o contexto do comando
+func trafficCtl(ctx context.Context, args ...string) (string, error) {
+	output, err := exec.CommandContext(ctx, "traffic_ctl", args...).CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("traffic_ctl %s: %w", strings.Join(args, " "), err)
+	}
+	return string(output), nil
+}
+
+// ReloadContext executa traffic_ctl config reload
+func (m *Manager) ReloadContext(ctx context.Context) (string, error) {
+	return trafficCtl(ctx, "config", "reload")
+}
+
+// RestartContext executa traffic_ctl server restart
+func (m *Manager) RestartContext(ctx context.Context) (string, error) {
+	return trafficCtl(ctx, "server", "restart")
+}
+
+// ClearHostDB descarta o cache de DNS do ATS: remove o host.db persistido (se
+// existir) e reinicia o servidor.
+func (m *Manager) ClearHostDB(ctx context.Context) (string, error) {
+	if err := os.Remove(hostDBFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("erro ao remover %s: %w", hostDBFile, err)
+	}
+	return trafficCtl(ctx, "server", "restart")
+}
+
+// Diagnostics monta um relatório em texto do estado do nó: versão e status do
+// ATS, hash aplicado, arquivos de config, parents e fim do diags.log.
+func (m *Manager) Diagnostics(ctx context.Context) string {
+	var b strings.Builder
+
+	section := func(title string) {
+		fmt.Fprintf(&b, "\n== %s ==\n", title)
+	}
+	run := func(name string, args ...string) {
+		output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+		b.Write(output)
+		if err != nil {
+			fmt.Fprintf(&b, "(erro: %v)\n", err)
+		}
+	}
+
+	fmt.Fprintf(&b, "gerado em %s\n", time.Now().UTC().Format(time.RFC3339))
+
+	section("versão")
+	run("traffic_server", "--version")
+
+	section("status")
+	run("traffic_ctl", "server", "status")
+
+	section("config")
+	fmt.Fprintf(&b, "hash aplicado: %s\n", m.GetCurrentHash())
+	for _, name := range sortedManagedFiles() {
+		info, err := os.Stat(filepath.Join(m.configDir, name))
+		if err != nil {
+			fmt.Fprintf(&b, "%-16s ausente\n", name)
+			continue
+		}
+		fmt.Fprintf(&b, "%-16s %8d bytes  %s\n", name, info.Size(), info.ModTime().UTC().Format(time.RFC3339))
+	}
+
+	section("parents")
+	for _, p := range m.CheckParents() {
+		state := "down"
+		if p.Up {
+			state = "up"
+		}
+		fmt.Fprintf(&b, "%s:%d %s\n", p.Address, p.Port, state)
+	}
+
+	section("diags.log")
+	for _, line := range m.readLastLines(filepath.Join(logDir, "diags.log"), 50) {
+		b.WriteString(line + "\n")
+	}
+
+	return b.String()
+}
+
+// ConnectivityTest faz um GET para target passando pelo ATS local (ou direto,
+// com direct=true) e descreve o resultado.
+func (m *Manager) ConnectivityTest(ctx context.Context, target string, direct bool) (string, error) {
+	transport := &http.Transport{}
+	if !direct {
+		proxyURL, _ := url.Parse(localProxyURL)
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	defer transport.CloseIdleConnections()
+
+	client := &http.Client{
+		Transport: transport,
+		// Mostra o redirect em vez de segui-lo
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return "", fmt.Errorf("URL inválida: %w", err)
+	}
+
+	route := "via ATS (" + localProxyURL + ")"
+	if direct {
+		route = "direto"
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("GET %s %s: %w", target, route, err)
+	}
+	defer resp.Body.Close()
+	n, _ := io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "GET %s %s\n", target, route)
+	fmt.Fprintf(&b, "%s em %s (%d bytes lidos)\n", resp.Status, time.Since(start).Round(time.Millisecond), n)
+	for _, h := range []string{"Via", "Server", "Location"} {
+		if v := resp.Header.Get(h); v != "" {
+			fmt.Fprintf(&b, "%s: %s\n", h, v)
+		}
+	}
+	return b.String(), nil
+}
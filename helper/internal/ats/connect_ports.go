@@ -0,0 +1,91 @@
+package ats
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// connectPortsRecord record das portas liberadas para CONNECT. O helper o
+// ajusta com traffic_ctl config set, que vale na hora; o records.yaml
+// gerenciado traz o mesmo valor para que ele sobreviva a um restart.
+const connectPortsRecord = "proxy.config.http.connect_ports"
+
+// connectPortsOriginal guarda, em originalsDir, o valor do ATS antes da
+// primeira alteração do helper, devolvido quando o bundle deixa de trazer
+// portas
+const connectPortsOriginal = connectPortsRecord + ".value"
+
+// ConnectPorts retorna as portas de CONNECT em uso no ATS
+func (m *Manager) ConnectPorts() (string, error) {
+	value, err := m.getConfig(connectPortsRecord)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(value), " "), nil
+}
+
+// applyConnectPorts ajusta as portas de CONNECT do ATS. Antes da primeira
+// alteração guarda o valor em uso; sem portas no bundle, devolve esse valor
+// (se o helper tinha alterado). Retorna changed=true se o valor foi alterado;
+// o ATS só o carrega no próximo config reload.
+func (m *Manager) applyConnectPorts(ports string) (changed bool, err error) {
+	path := filepath.Join(m.configDir, originalsDir, connectPortsOriginal)
+	if ports == "" {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("erro ao ler valor original de %s: %w", connectPortsRecord, err)
+		}
+		if err := m.setConnectPorts(string(data)); err != nil {
+			return false, err
+		}
+		return true, os.Remove(path)
+	}
+
+	current, err := m.ConnectPorts()
+	if err == nil && current == ports {
+		return false, nil
+	}
+	if _, statErr := os.Stat(path); errors.Is(statErr, os.ErrNotExist) {
+		if err != nil {
+			return false, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return false, fmt.Errorf("erro ao criar %s: %w", originalsDir, err)
+		}
+		if err := m.writeFile(path, current); err != nil {
+			return false, fmt.Errorf("erro ao guardar valor original de %s: %w", connectPortsRecord, err)
+		}
+	}
+
+	if err := m.setConnectPorts(ports); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *Manager) setConnectPorts(ports string) error {
+	output, err := exec.Command("traffic_ctl", "config", "set", connectPortsRecord, ports).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("erro ao definir %s: %w, output: %s", connectPortsRecord, err, string(output))
+	}
+	return nil
+}
+
+// connectPortsChanged indica se as portas de CONNECT do ATS diferem das do
+// bundle. Sem portas no bundle, difere enquanto o helper ainda precisa
+// devolver o valor original.
+func (m *Manager) connectPortsChanged(ports string) bool {
+	if ports == "" {
+		_, err := os.Stat(filepath.Join(m.configDir, originalsDir, connectPortsOriginal))
+		return err == nil
+	}
+	current, err := m.ConnectPorts()
+	return err != nil || current != ports
+}
@@ -0,0 +1,106 @@
+package ats
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// logDir diretório de logs do ATS
+const logDir = "/opt/var/log/trafficserver"
+
+// diagnosticsLogTail bytes finais de cada log incluídos no bundle
+const diagnosticsLogTail = 1 << 20
+
+// diagnosticsLogs logs do ATS incluídos no bundle (só o final de cada um)
+var diagnosticsLogs = []string{"diags.log", "error.log", "access.log"}
+
+// DiagnosticsBundle monta um tar.gz com o relatório de Diagnostics, os arquivos
+// de config gerenciados, status e dump de métricas do traffic_ctl e o final
+// dos logs. extra entra na raiz do arquivo (ex: helper.json com o estado do
+// helper). Credenciais e chaves do helper nunca são incluídas.
+func (m *Manager) DiagnosticsBundle(ctx context.Context, extra map[string][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	command := func(name string, args ...string) []byte {
+		output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+		if err != nil {
+			output = append(output, []byte(fmt.Sprintf("\n(erro: %v)\n", err))...)
+		}
+		return output
+	}
+
+	files := map[string][]byte{
+		"report.txt":                    []byte(m.Diagnostics(ctx)),
+		"traffic_ctl/server_status.txt": command("traffic_ctl", "server", "status"),
+		"traffic_ctl/metrics.txt":       command("traffic_ctl", "metric", "match", "."),
+	}
+	for name, data := range extra {
+		files[name] = data
+	}
+	for _, name := range sortedManagedFiles() {
+		if data, err := os.ReadFile(filepath.Join(m.configDir, name)); err == nil {
+			files["config/"+name] = data
+		}
+	}
+	for _, name := range diagnosticsLogs {
+		if data, err := readTail(filepath.Join(logDir, name), diagnosticsLogTail); err == nil {
+			files["logs/"+name] = data
+		}
+	}
+
+	for _, name := range sortedKeys(files) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := add(name, files[name]); err != nil {
+			return nil, fmt.Errorf("erro ao montar bundle (%s): %w", name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("erro ao fechar tar: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("erro ao fechar gzip: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// readTail lê no máximo os últimos n bytes de um arquivo
+func readTail(path string, n int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > n {
+		if _, err := f.Seek(-n, io.SeekEnd); err != nil {
+			return nil, err
+		}
+	}
+	return io.ReadAll(io.LimitReader(f, n))
+}
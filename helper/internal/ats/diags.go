@@ -0,0 +1,90 @@
+package ats
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ats-proxy/proxy-helper/internal/sync"
+)
+
+// DiagsLogFile log de diagnóstico do ATS (onde saem os debug tags)
+var DiagsLogFile = filepath.Join(logDir, "diags.log")
+
+// diagsLine [Feb  3 22:00:01.123] [ET_NET 0] DIAG: <Arquivo.cc:123 (func)> (tag) mensagem
+var diagsLine = regexp.MustCompile(`^\[([A-Z][a-z]{2} +\d{1,2} \d{2}:\d{2}:\d{2}\.\d{3})\] (?:.*?\s)?(DIAG|DEBUG|STATUS|NOTE|WARNING|ERROR|FATAL|ALERT|EMERGENCY): (.*)$`)
+
+var (
+	diagsLocation = regexp.MustCompile(`^<[^>]*> ?`)
+	diagsTag      = regexp.MustCompile(`^\(([^()\s]+)\) ?`)
+)
+
+// diagsLevels nível do ATS -> nível enviado ao backend
+var diagsLevels = map[string]string{
+	"DIAG":      "DEBUG",
+	"DEBUG":     "DEBUG",
+	"STATUS":    "INFO",
+	"NOTE":      "INFO",
+	"WARNING":   "WARN",
+	"ERROR":     "ERROR",
+	"FATAL":     "FATAL",
+	"ALERT":     "FATAL",
+	"EMERGENCY": "FATAL",
+}
+
+// DiagsParser interpreta linhas do diags.log. Linhas sem cabeçalho (continuação
+// de uma mensagem de várias linhas) herdam timestamp, nível e tag da anterior.
+type DiagsParser struct {
+	last sync.LogLine
+	now  func() time.Time
+}
+
+// NewDiagsParser cria um parser para linhas lidas em sequência
+func NewDiagsParser() *DiagsParser {
+	return &DiagsParser{now: time.Now}
+}
+
+// Parse converte uma linha do diags.log
+func (p *DiagsParser) Parse(line string) sync.LogLine {
+	m := diagsLine.FindStringSubmatch(line)
+	if m == nil {
+		out := p.last
+		if out.Timestamp.IsZero() {
+			out.Timestamp = p.now()
+			out.Level = "INFO"
+		}
+		out.Message = line
+		return out
+	}
+
+	msg := diagsLocation.ReplaceAllString(m[3], "")
+	out := sync.LogLine{
+		Timestamp: p.timestamp(m[1]),
+		Level:     diagsLevels[m[2]],
+	}
+	if t := diagsTag.FindStringSubmatch(msg); t != nil {
+		out.Tag = t[1]
+		msg = msg[len(t[0]):]
+	}
+	out.Message = msg
+
+	p.last = out
+	return out
+}
+
+// timestamp interpreta o horário do diags.log, que não traz o ano nem o fuso
+// (é o horário local do processo).
+func (p *DiagsParser) timestamp(s string) time.Time {
+	now := p.now()
+	ts, err := time.ParseInLocation("Jan _2 15:04:05.000", strings.Join(strings.Fields(s), " "), time.Local)
+	if err != nil {
+		return now
+	}
+	ts = ts.AddDate(now.Year(), 0, 0)
+	// Linha de dezembro lida em janeiro
+	if ts.After(now.Add(24 * time.Hour)) {
+		ts = ts.AddDate(-1, 0, 0)
+	}
+	return ts
+}
@@ -0,0 +1,39 @@
+package ats
+
+import "sort"
+
+// FileAction o que o ATS precisa para carregar a mudança de um arquivo
+type FileAction int
+
+const (
+	ActionReload  FileAction = iota // traffic_ctl config reload
+	ActionRestart                   // traffic_ctl server restart
+)
+
+// managedFiles arquivos que o backend pode enviar no bundle e como o ATS os
+// carrega. Qualquer outro nome é rejeitado.
+var managedFiles = map[string]FileAction{
+	"parent.config":  ActionReload,
+	"sni.yaml":       ActionReload,
+	"ip_allow.yaml":  ActionReload,
+	"records.yaml":   ActionReload, // o backend só permite records recarregáveis
+	"logging.yaml":   ActionReload,
+	"remap.config":   ActionReload,
+	"storage.config": ActionRestart,
+	"plugin.config":  ActionRestart,
+}
+
+// sortedManagedFiles nomes de managedFiles em ordem
+func sortedManagedFiles() []string {
+	return sortedKeys(managedFiles)
+}
+
+// sortedKeys chaves de um map em ordem
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -5,9 +5,11 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	stdsync "sync"
@@ -21,9 +23,9 @@ type Manager struct {
 	configDir string
 	hashFile  string
 
-	// Para captura de logs
-	logBuffer []sync.LogLine
-	logMu     stdsync.Mutex
+	// debugOriginal valores de debug do ATS antes do EnableDebug, devolvidos
+	// pelo DisableDebug (nil = debug não habilitado pelo helper)
+	debugOriginal map[string]string
 }
 
 // NewManager cria um novo Manager
@@ -31,43 +33,63 @@ func NewManager(configDir string) *Manager {
 	return &Manager{
 		configDir: configDir,
 		hashFile:  filepath.Join(configDir, ".config_hash"),
-		logBuffer: make([]sync.LogLine, 0),
 	}
 }
 
 // ========== Config Management ==========
 
-// ApplyConfig escreve os arquivos de configuração
-func (m *Manager) ApplyConfig(cfg *sync.ConfigFiles) error {
+// ApplyConfig escreve os arquivos de configuração que mudaram e retorna seus
+// nomes. Arquivos vazios não são escritos; se o helper já tinha escrito o
+// arquivo, a versão anterior a ele (a da imagem) é restaurada. Nomes fora de
+// managedFiles são rejeitados antes de escrever qualquer arquivo. Se o bundle traz portas de
+// CONNECT diferentes das do ATS, elas são definidas e o record entra na lista;
+// se não traz e o helper as tinha alterado, o valor original volta.
+func (m *Manager) ApplyConfig(cfg *sync.ConfigFiles) ([]string, error) {
 	if cfg == nil {
-		return fmt.Errorf("config is nil")
+		return nil, fmt.Errorf("config is nil")
 	}
 
-	// Escreve parent.config
-	if cfg.ParentConfig != "" {
-		path := filepath.Join(m.configDir, "parent.config")
-		if err := m.writeFile(path, cfg.ParentConfig); err != nil {
-			return fmt.Errorf("erro ao escrever parent.config: %w", err)
+	files := cfg.Named()
+	for name := range files {
+		if _, ok := managedFiles[name]; !ok {
+			return nil, fmt.Errorf("arquivo não gerenciado: %q", name)
 		}
 	}
 
-	// Escreve sni.yaml
-	if cfg.SNIYaml != "" {
-		path := filepath.Join(m.configDir, "sni.yaml")
-		if err := m.writeFile(path, cfg.SNIYaml); err != nil {
-			return fmt.Errorf("erro ao escrever sni.yaml: %w", err)
+	var changed []string
+	for _, name := range sortedManagedFiles() {
+		content := files[name]
+		if content == "" {
+			if m.hasOriginal(name) {
+				if err := m.restoreOriginal(name); err != nil {
+					return changed, fmt.Errorf("erro ao restaurar %s: %w", name, err)
+				}
+				changed = append(changed, name)
+			}
+			continue
+		}
+		path := filepath.Join(m.configDir, name)
+		if current, err := os.ReadFile(path); err == nil && string(current) == content {
+			continue
+		}
+		if err := m.saveOriginal(name); err != nil {
+			return changed, fmt.Errorf("erro ao guardar original de %s: %w", name, err)
 		}
+		if err := m.writeFile(path, content); err != nil {
+			return changed, fmt.Errorf("erro ao escrever %s: %w", name, err)
+		}
+		changed = append(changed, name)
 	}
 
-	// Escreve ip_allow.yaml (opcional)
-	if cfg.IPAllowYaml != "" {
-		path := filepath.Join(m.configDir, "ip_allow.yaml")
-		if err := m.writeFile(path, cfg.IPAllowYaml); err != nil {
-			return fmt.Errorf("erro ao escrever ip_allow.yaml: %w", err)
-		}
+	portsChanged, err := m.applyConnectPorts(cfg.ConnectPorts)
+	if err != nil {
+		return changed, err
+	}
+	if portsChanged {
+		changed = append(changed, connectPortsRecord)
 	}
 
-	return nil
+	return changed, nil
 }
 
 // writeFile escreve conteúdo em um arquivo de forma atômica
@@ -84,6 +106,31 @@ func (m *Manager) writeFile(path, content string) error {
 
 // ========== Reload ==========
 
+// Activate faz o ATS carregar os arquivos alterados: restart se algum exige,
+// senão config reload (também para o record de portas de CONNECT). Sem
+// arquivos alterados não faz nada.
+func (m *Manager) Activate(changed []string) error {
+	if len(changed) == 0 {
+		return nil
+	}
+	for _, name := range changed {
+		if managedFiles[name] == ActionRestart {
+			return m.Restart()
+		}
+	}
+	return m.Reload()
+}
+
+// Restart executa traffic_ctl server restart
+func (m *Manager) Restart() error {
+	cmd := exec.Command("traffic_ctl", "server", "restart")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("erro ao reiniciar ATS: %w, output: %s", err, string(output))
+	}
+	return nil
+}
+
 // Reload executa traffic_ctl config reload
 func (m *Manager) Reload() error {
 	cmd := exec.Command("traffic_ctl", "config", "reload")
@@ -110,25 +157,48 @@ func (m *Manager) SaveHash(hash string) error {
 	return os.WriteFile(m.hashFile, []byte(hash), 0644)
 }
 
-// CalculateLocalHash calcula hash dos arquivos locais
-func (m *Manager) CalculateLocalHash() (string, error) {
-	files := []string{
-		filepath.Join(m.configDir, "parent.config"),
-		filepath.Join(m.configDir, "sni.yaml"),
+// CalculateLocalHash calcula o hash dos arquivos em disco da mesma forma que o
+// backend calcula o hash da config: sha256 de parent.config + sni.yaml +
+// ip_allow.yaml, nessa ordem, seguido de cada arquivo adicional (extra, em ordem
+// de nome) como "<nome>\n<tamanho>\n<conteúdo>". Arquivo ausente conta como vazio.
+// Com connectPorts, as portas de CONNECT em uso no ATS entram no fim da mesma forma.
+func (m *Manager) CalculateLocalHash(extra []string, connectPorts bool) (string, error) {
+	read := func(name string) ([]byte, error) {
+		data, err := os.ReadFile(filepath.Join(m.configDir, name))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return data, err
 	}
 
 	hasher := sha256.New()
-	for _, f := range files {
-		data, err := os.ReadFile(f)
+	for _, name := range []string{"parent.config", "sni.yaml", "ip_allow.yaml"} {
+		data, err := read(name)
+		if err != nil {
+			return "", err
+		}
+		hasher.Write(data)
+	}
+
+	sorted := append([]string(nil), extra...)
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		data, err := read(name)
 		if err != nil {
-			if os.IsNotExist(err) {
-				continue
-			}
 			return "", err
 		}
+		fmt.Fprintf(hasher, "%s\n%d\n", name, len(data))
 		hasher.Write(data)
 	}
 
+	if connectPorts {
+		ports, err := m.ConnectPorts()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hasher, "connect_ports\n%d\n%s", len(ports), ports)
+	}
+
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
@@ -252,19 +322,146 @@ func (m *Manager) getMetric(name string) (int64, error) {
 	return strconv.ParseInt(parts[len(parts)-1], 10, 64)
 }
 
+// ========== Parent Health ==========
+
+// parentDialTimeout tempo máximo para abrir conexão TCP com um parent
+const parentDialTimeout = 3 * time.Second
+
+// CheckParents testa conexão TCP com cada parent listado no parent.config.
+// Retorna slice vazio (não nil) quando não há parents configurados.
+func (m *Manager) CheckParents() []sync.ParentStatus {
+	parents := m.listParents()
+	result := make([]sync.ParentStatus, len(parents))
+
+	var wg stdsync.WaitGroup
+	for i, hostPort := range parents {
+		host, portStr, err := net.SplitHostPort(hostPort)
+		if err != nil {
+			continue
+		}
+		port, _ := strconv.Atoi(portStr)
+		result[i] = sync.ParentStatus{Address: host, Port: port}
+
+		wg.Add(1)
+		go func(i int, hostPort string) {
+			defer wg.Done()
+			conn, err := net.DialTimeout("tcp", hostPort, parentDialTimeout)
+			if err != nil {
+				return
+			}
+			conn.Close()
+			result[i].Up = true
+		}(i, hostPort)
+	}
+	wg.Wait()
+
+	// Remove entradas que não puderam ser interpretadas
+	valid := result[:0]
+	for _, p := range result {
+		if p.Address != "" {
+			valid = append(valid, p)
+		}
+	}
+	return valid
+}
+
+// listParents extrai os host:port únicos das diretivas parent="..." do parent.config
+func (m *Manager) listParents() []string {
+	data, err := os.ReadFile(filepath.Join(m.configDir, "parent.config"))
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var parents []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.Index(line, "parent=\"")
+		if idx < 0 {
+			continue
+		}
+		rest := line[idx+len("parent=\""):]
+		end := strings.Index(rest, "\"")
+		if end < 0 {
+			continue
+		}
+		for _, p := range strings.Split(rest[:end], ";") {
+			// Formato ATS: host:port ou host:port|peso
+			p = strings.TrimSpace(strings.SplitN(p, "|", 2)[0])
+			if p == "" || seen[p] {
+				continue
+			}
+			seen[p] = true
+			parents = append(parents, p)
+		}
+	}
+	return parents
+}
+
 // ========== Debug/Logs ==========
 
-// EnableDebug habilita logs de debug do ATS
-func (m *Manager) EnableDebug() error {
-	if err := m.setConfig("proxy.config.diags.debug.enabled", "1"); err != nil {
+const (
+	debugEnabledRecord = "proxy.config.diags.debug.enabled"
+	debugTagsRecord    = "proxy.config.diags.debug.tags"
+)
+
+// EnableDebug habilita logs de debug do ATS para os tags informados
+// (default parent_select). Na primeira chamada guarda os valores em uso, que
+// o DisableDebug devolve. Não é seguro para uso concorrente.
+func (m *Manager) EnableDebug(tags []string) error {
+	if len(tags) == 0 {
+		tags = []string{"parent_select"}
+	}
+	if m.debugOriginal == nil {
+		original := make(map[string]string)
+		for _, name := range []string{debugEnabledRecord, debugTagsRecord} {
+			value, err := m.getConfig(name)
+			if err != nil {
+				return err
+			}
+			original[name] = value
+		}
+		m.debugOriginal = original
+	}
+	if err := m.setConfig(debugEnabledRecord, "1"); err != nil {
 		return err
 	}
-	return m.setConfig("proxy.config.diags.debug.tags", "parent_select")
+	return m.setConfig(debugTagsRecord, strings.Join(tags, "|"))
 }
 
-// DisableDebug desabilita logs de debug do ATS
+// DisableDebug devolve o debug do ATS aos valores de antes do EnableDebug
+// (sem eles, só desabilita o debug)
 func (m *Manager) DisableDebug() error {
-	return m.setConfig("proxy.config.diags.debug.enabled", "0")
+	if m.debugOriginal == nil {
+		return m.setConfig(debugEnabledRecord, "0")
+	}
+	// Os tags antes do enabled: o debug original não chega a rodar com os
+	// tags da captura
+	for _, name := range []string{debugTagsRecord, debugEnabledRecord} {
+		if err := m.setConfig(name, m.debugOriginal[name]); err != nil {
+			return err
+		}
+	}
+	m.debugOriginal = nil
+	return nil
+}
+
+// getConfig lê uma configuração via traffic_ctl
+func (m *Manager) getConfig(name string) (string, error) {
+	output, err := exec.Command("traffic_ctl", "config", "get", name).Output()
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler %s: %w", name, err)
+	}
+
+	// Formato: "proxy.config.http.connect_ports: 443 563"
+	_, value, ok := strings.Cut(strings.TrimSpace(string(output)), ":")
+	if !ok {
+		return "", fmt.Errorf("formato inesperado: %s", string(output))
+	}
+	return strings.TrimSpace(value), nil
 }
 
 // setConfig define uma configuração via traffic_ctl
@@ -277,31 +474,6 @@ func (m *Manager) setConfig(name, value string) error {
 	return nil
 }
 
-// CaptureLogs captura logs recentes e retorna
-func (m *Manager) CaptureLogs() []sync.LogLine {
-	m.logMu.Lock()
-	defer m.logMu.Unlock()
-
-	// Lê logs do processo ATS (docker logs ou arquivo)
-	// Por simplicidade, vamos ler das últimas linhas do diags.log
-	logPath := "/opt/var/log/trafficserver/diags.log"
-	lines := m.readLastLines(logPath, 100)
-
-	result := make([]sync.LogLine, 0, len(lines))
-	for _, line := range lines {
-		// Filtra apenas linhas relevantes
-		if strings.Contains(line, "Result for") || strings.Contains(line, "parent") {
-			result = append(result, sync.LogLine{
-				Timestamp: time.Now(),
-				Level:     "DEBUG",
-				Message:   line,
-			})
-		}
-	}
-
-	return result
-}
-
 // readLastLines lê as últimas N linhas de um arquivo
 func (m *Manager) readLastLines(path string, n int) []string {
 	file, err := os.Open(path)
@@ -0,0 +1,74 @@
+package ats
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// originalsDir guarda, para cada arquivo gerenciado, a versão anterior à
+// primeira escrita do helper (a da imagem). Quando o bundle deixa de trazer o
+// arquivo, essa versão volta ao lugar; sem isso o último conteúdo gerenciado
+// (records.yaml, remap.config, ...) ficaria em disco para sempre.
+const originalsDir = ".originals"
+
+// absentSuffix marca um arquivo que não existia antes da primeira escrita
+const absentSuffix = ".absent"
+
+// hasOriginal indica se o helper já escreveu o arquivo e guardou o original
+func (m *Manager) hasOriginal(name string) bool {
+	dir := filepath.Join(m.configDir, originalsDir)
+	for _, path := range []string{filepath.Join(dir, name), filepath.Join(dir, name+absentSuffix)} {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// saveOriginal guarda o arquivo em disco antes da primeira escrita do helper.
+// Se ele não existe, guarda só o marcador <nome>.absent.
+func (m *Manager) saveOriginal(name string) error {
+	if m.hasOriginal(name) {
+		return nil
+	}
+	dir := filepath.Join(m.configDir, originalsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("erro ao criar %s: %w", originalsDir, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(m.configDir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return os.WriteFile(filepath.Join(dir, name+absentSuffix), nil, 0644)
+	}
+	if err != nil {
+		return err
+	}
+	return m.writeFile(filepath.Join(dir, name), string(data))
+}
+
+// restoreOriginal volta o arquivo à versão anterior ao helper (removendo-o se
+// ele não existia) e descarta a cópia; o arquivo deixa de ser gerenciado.
+func (m *Manager) restoreOriginal(name string) error {
+	dir := filepath.Join(m.configDir, originalsDir)
+	path := filepath.Join(m.configDir, name)
+
+	absent := filepath.Join(dir, name+absentSuffix)
+	if _, err := os.Stat(absent); err == nil {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return os.Remove(absent)
+	}
+
+	saved := filepath.Join(dir, name)
+	data, err := os.ReadFile(saved)
+	if err != nil {
+		return err
+	}
+	if err := m.writeFile(path, string(data)); err != nil {
+		return err
+	}
+	return os.Remove(saved)
+}
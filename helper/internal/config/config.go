@@ -1,6 +1,10 @@
 package config
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Config armazena as configurações do helper
 type Config struct {
@@ -8,9 +12,29 @@ type Config struct {
 	BackendURL string
 	ConfigID   string
 	Hostname   string
+	Labels     map[string]string // enviados no registro; grupos do backend selecionam proxies por eles
+
+	// Autenticação do sync
+	EnrollToken     string // token de registro criado por um admin, usado só no primeiro registro
+	CredentialsFile string // onde o segredo do proxy é guardado após o registro
+
+	// mTLS com o backend
+	CAFile   string // CA fixada para verificar o backend
+	CertFile string // certificado de cliente
+	KeyFile  string // chave do certificado de cliente
 
 	// Sync
 	SyncInterval time.Duration
+	LongPoll     time.Duration // tempo máximo que o backend segura o GET /sync (0 = polling simples)
+
+	// Drift
+	DriftInterval  time.Duration // intervalo da verificação dos arquivos em disco (0 desativa)
+	DriftRemediate bool          // reaplica o bundle em cache quando detecta drift
+
+	// Access log
+	AccessLog         string        // arquivo do access log enviado ao backend (vazio desativa)
+	AccessLogFormat   string        // json (access_json do logging.yaml) ou combined
+	AccessLogInterval time.Duration // intervalo entre envios
 
 	// ATS
 	ConfigDir string
@@ -35,3 +59,21 @@ func DefaultBackoff() BackoffConfig {
 		Multiplier:      2.0,
 	}
 }
+
+// ParseLabels interpreta labels no formato "chave=valor,chave=valor"
+func ParseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || k == "" || v == "" {
+			return nil, fmt.Errorf("label inválido %q (esperado chave=valor)", pair)
+		}
+		labels[k] = v
+	}
+	return labels, nil
+}
@@ -0,0 +1,190 @@
+package sync
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	stdsync "sync"
+)
+
+// bundleSignatureVersion deve ser igual ao do backend
+const bundleSignatureVersion = "ats-proxy-bundle/v1"
+
+// SignedBundle config recebida do backend com sua assinatura, guardada em
+// disco para operação offline
+type SignedBundle struct {
+	Hash      string       `json:"hash"`
+	Config    *ConfigFiles `json:"config"`
+	Signature string       `json:"signature"`
+	KeyID     string       `json:"key_id,omitempty"`
+}
+
+// Named retorna os arquivos indexados pelo nome no proxy, como assinados pelo backend
+func (f *ConfigFiles) Named() map[string]string {
+	named := map[string]string{
+		"parent.config": f.ParentConfig,
+		"sni.yaml":      f.SNIYaml,
+		"ip_allow.yaml": f.IPAllowYaml,
+	}
+	for name, content := range f.Files {
+		named[name] = content
+	}
+	return named
+}
+
+// ExtraNames retorna os nomes dos arquivos adicionais em ordem
+func (f *ConfigFiles) ExtraNames() []string {
+	names := make([]string, 0, len(f.Files))
+	for name := range f.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BundleMessage monta a mensagem assinada: linha de versão, linha do hash,
+// cada arquivo em ordem de nome como "<nome>\n<tamanho>\n<conteúdo>" e, se
+// houver, as portas de CONNECT como "connect_ports\n<tamanho>\n<portas>"
+func BundleMessage(hash string, files map[string]string, connectPorts string) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msg := []byte(bundleSignatureVersion + "\n" + hash + "\n")
+	for _, name := range names {
+		msg = append(msg, name+"\n"+strconv.Itoa(len(files[name]))+"\n"...)
+		msg = append(msg, files[name]...)
+	}
+	if connectPorts != "" {
+		msg = append(msg, "connect_ports\n"+strconv.Itoa(len(connectPorts))+"\n"+connectPorts...)
+	}
+	return msg
+}
+
+// SigningKeyResponse chave pública dos bundles
+type SigningKeyResponse struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+}
+
+// SigningKey busca a chave pública usada pelo backend para assinar os bundles
+func (c *Client) SigningKey(ctx context.Context) (*SigningKeyResponse, error) {
+	var resp SigningKeyResponse
+	if err := c.doRequestWith(ctx, c.ctrlClient, "GET", "/sync/signing-key", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ErrNoTrustedKey nenhuma chave pública confiável disponível ainda
+var ErrNoTrustedKey = errors.New("nenhuma chave de assinatura confiável")
+
+// BundleVerifier verifica assinaturas de bundles com a chave confiável: a fixada
+// via --bundle-public-key ou, sem ela, a primeira obtida do backend (TOFU),
+// guardada em keyFile
+type BundleVerifier struct {
+	keyFile string
+	pinned  bool
+
+	mu  stdsync.Mutex
+	key ed25519.PublicKey
+}
+
+// NewBundleVerifier usa a chave fixada (base64) se informada, senão a salva em keyFile
+func NewBundleVerifier(pinned, keyFile string) (*BundleVerifier, error) {
+	v := &BundleVerifier{keyFile: keyFile}
+
+	if pinned != "" {
+		key, err := parsePublicKey(pinned)
+		if err != nil {
+			return nil, err
+		}
+		v.key = key
+		v.pinned = true
+		return v, nil
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("erro ao ler chave de assinatura: %w", err)
+	}
+	if len(data) > 0 {
+		key, err := parsePublicKey(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, err
+		}
+		v.key = key
+	}
+	return v, nil
+}
+
+func parsePublicKey(b64 string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("chave pública Ed25519 inválida")
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// HasKey indica se já existe uma chave confiável
+func (v *BundleVerifier) HasKey() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.key != nil
+}
+
+// TrustOnFirstUse busca a chave no backend e a salva, se ainda não houver uma
+func (v *BundleVerifier) TrustOnFirstUse(ctx context.Context, client *Client) error {
+	if v.HasKey() {
+		return nil
+	}
+
+	resp, err := client.SigningKey(ctx)
+	if err != nil {
+		return err
+	}
+	key, err := parsePublicKey(resp.PublicKey)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(v.keyFile, []byte(resp.PublicKey+"\n"), 0644); err != nil {
+		return fmt.Errorf("erro ao salvar chave de assinatura: %w", err)
+	}
+
+	v.mu.Lock()
+	v.key = key
+	v.mu.Unlock()
+	return nil
+}
+
+// Verify confere a assinatura do bundle
+func (v *BundleVerifier) Verify(b *SignedBundle) error {
+	v.mu.Lock()
+	key := v.key
+	v.mu.Unlock()
+
+	if key == nil {
+		return ErrNoTrustedKey
+	}
+	if b.Config == nil || b.Signature == "" {
+		return fmt.Errorf("bundle sem assinatura")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(b.Signature)
+	if err != nil {
+		return fmt.Errorf("assinatura mal formada: %w", err)
+	}
+	if !ed25519.Verify(key, BundleMessage(b.Hash, b.Config.Named(), b.Config.ConnectPorts), sig) {
+		return fmt.Errorf("assinatura do bundle inválida (key_id: %s)", b.KeyID)
+	}
+	return nil
+}
@@ -3,6 +3,10 @@ package sync
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +14,10 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	stdsync "sync"
 	"time"
 
 	"github.com/ats-proxy/proxy-helper/internal/config"
@@ -20,38 +28,85 @@ type Client struct {
 	cfg        *config.Config
 	httpClient *http.Client
 	ctrlClient *http.Client // timeout curto para hello/register
+	pollClient *http.Client // sem timeout fixo, usado no long-poll (timeout via contexto)
 	backoff    *Backoff
 	proxyID    string // ID retornado no primeiro registro, usado para re-registro
+	transport  *reloadableTransport
+
+	credsMu stdsync.Mutex
+	creds   *Credentials // segredo usado para assinar as requisições de sync
+
+	receivedMu stdsync.Mutex
+	received   []string // comandos recebidos ainda não confirmados ao backend
 }
 
-// NewClient cria um novo cliente de sincronização
-func NewClient(cfg *config.Config) *Client {
+// NewClient cria um novo cliente de sincronização.
+// Carrega as credenciais salvas em cfg.CredentialsFile, se existirem, e os
+// arquivos de TLS (CA fixada e certificado de cliente) se configurados.
+func NewClient(cfg *config.Config) (*Client, error) {
+	tr, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	transport := &reloadableTransport{}
+	transport.swap(tr)
+
+	var creds *Credentials
+	if cfg.CredentialsFile != "" {
+		c, err := LoadCredentials(cfg.CredentialsFile)
+		if err != nil {
+			log.Printf("WARN: %v", err)
+		}
+		creds = c
+	}
+
 	return &Client{
 		cfg: cfg,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: transport,
 		},
 		ctrlClient: &http.Client{
-			Timeout: 4 * time.Second,
+			Timeout:   4 * time.Second,
+			Transport: transport,
 		},
-		backoff: NewBackoff(config.DefaultBackoff()),
+		pollClient: &http.Client{
+			Transport: transport,
+		},
+		backoff:   NewBackoff(config.DefaultBackoff()),
+		transport: transport,
+		creds:     creds,
+	}, nil
+}
+
+// ReloadTLS relê a CA e o certificado de cliente. Em caso de erro mantém os atuais.
+func (c *Client) ReloadTLS() error {
+	tr, err := newTransport(c.cfg)
+	if err != nil {
+		return err
 	}
+	c.transport.swap(tr)
+	return nil
 }
 
 // ========== Request/Response Types ==========
 
 // RegisterRequest payload de registro
 type RegisterRequest struct {
-	Hostname string `json:"hostname"`
-	ConfigID string `json:"config_id"`
-	ProxyID  string `json:"proxy_id,omitempty"`
+	Hostname        string `json:"hostname"`
+	ConfigID        string `json:"config_id"`
+	ProxyID         string `json:"proxy_id,omitempty"`
+	EnrollmentToken string `json:"enrollment_token,omitempty"`
+	// Labels sobrescrevem as mesmas chaves no backend; as demais são mantidas
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // RegisterResponse resposta do registro
 type RegisterResponse struct {
-	ProxyID  string `json:"proxy_id"`
-	ConfigID string `json:"config_id"`
-	Status   string `json:"status"`
+	ProxyID     string `json:"proxy_id"`
+	ConfigID    string `json:"config_id"`
+	Status      string `json:"status"`
+	ProxySecret string `json:"proxy_secret,omitempty"` // só quando o token de registro foi trocado
 }
 
 // ConfigResponse resposta do sync
@@ -61,6 +116,41 @@ type ConfigResponse struct {
 	Config       *ConfigFiles `json:"config,omitempty"`
 	CaptureLogs  bool         `json:"capture_logs"`
 	CaptureUntil time.Time    `json:"capture_until,omitempty"`
+	DebugTags    []string     `json:"debug_tags,omitempty"`    // debug tags do ATS habilitadas na captura
+	RotateSecret string       `json:"rotate_secret,omitempty"` // novo segredo após rotação pelo admin
+	Signature    string       `json:"signature,omitempty"`     // Ed25519 (base64) do bundle
+	KeyID        string       `json:"key_id,omitempty"`
+	Commands     []Command    `json:"commands,omitempty"` // comandos remotos, entregues uma única vez
+}
+
+// Command comando remoto enfileirado pelo admin (resync, reload, restart, ...)
+type Command struct {
+	ID             string            `json:"id"`
+	Type           string            `json:"type"`
+	Args           map[string]string `json:"args,omitempty"`
+	TimeoutSeconds int               `json:"timeout_seconds"`
+}
+
+// Timeout tempo máximo de execução do comando
+func (c Command) Timeout() time.Duration {
+	if c.TimeoutSeconds <= 0 {
+		return time.Minute
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// CommandResult resultado de um comando remoto
+type CommandResult struct {
+	Hostname string `json:"hostname"`
+	ID       string `json:"id"`
+	Status   string `json:"status"` // succeeded | failed | timed_out
+	Output   string `json:"output,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Bundle retorna a config recebida com sua assinatura
+func (r *ConfigResponse) Bundle() *SignedBundle {
+	return &SignedBundle{Hash: r.Hash, Config: r.Config, Signature: r.Signature, KeyID: r.KeyID}
 }
 
 // ConfigFiles arquivos de configuração
@@ -68,6 +158,11 @@ type ConfigFiles struct {
 	ParentConfig string `json:"parent_config"`
 	SNIYaml      string `json:"sni_yaml"`
 	IPAllowYaml  string `json:"ip_allow_yaml,omitempty"`
+	// Files arquivos adicionais gerenciados (records.yaml, ...) por nome no proxy
+	Files map[string]string `json:"files,omitempty"`
+	// ConnectPorts portas liberadas para CONNECT, aplicadas com traffic_ctl
+	// config set; vazio mantém as do ATS
+	ConnectPorts string `json:"connect_ports,omitempty"`
 }
 
 // AckRequest confirmação de aplicação
@@ -80,9 +175,17 @@ type AckRequest struct {
 
 // StatsRequest métricas do proxy
 type StatsRequest struct {
-	Hostname  string    `json:"hostname"`
-	Timestamp time.Time `json:"timestamp"`
-	Metrics   Metrics   `json:"metrics"`
+	Hostname  string         `json:"hostname"`
+	Timestamp time.Time      `json:"timestamp"`
+	Metrics   Metrics        `json:"metrics"`
+	Parents   []ParentStatus `json:"parents"`
+}
+
+// ParentStatus alcançabilidade de um parent proxy do parent.config
+type ParentStatus struct {
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+	Up      bool   `json:"up"`
 }
 
 // Metrics métricas coletadas do ATS
@@ -113,13 +216,52 @@ type LogsRequest struct {
 	Lines    []LogLine `json:"lines"`
 }
 
+// DriftRequest resultado da comparação dos arquivos em disco com o bundle aplicado
+type DriftRequest struct {
+	Hostname     string   `json:"hostname"`
+	ExpectedHash string   `json:"expected_hash"`
+	ActualHash   string   `json:"actual_hash"`
+	Drifted      bool     `json:"drifted"`
+	Files        []string `json:"files,omitempty"`
+	Remediated   bool     `json:"remediated,omitempty"`
+}
+
 // LogLine linha de log
 type LogLine struct {
 	Timestamp time.Time `json:"timestamp"`
 	Level     string    `json:"level"`
+	Tag       string    `json:"tag,omitempty"` // debug tag do ATS
 	Message   string    `json:"message"`
 }
 
+// AccessLogEntry requisição do access log do ATS
+type AccessLogEntry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	ClientIP   string    `json:"client_ip"`
+	Username   string    `json:"username,omitempty"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	Host       string    `json:"host,omitempty"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMs *int      `json:"duration_ms,omitempty"`
+	Route      string    `json:"route,omitempty"`
+	Upstream   string    `json:"upstream,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// AccessLogsRequest lote de entradas do access log
+type AccessLogsRequest struct {
+	Hostname string           `json:"hostname"`
+	Entries  []AccessLogEntry `json:"entries"`
+}
+
+// AccessLogsResult quantas entradas o backend guardou e quantas descartou
+type AccessLogsResult struct {
+	Accepted int `json:"accepted"`
+	Dropped  int `json:"dropped"`
+}
+
 // ========== Client Methods ==========
 
 // Hello verifica conectividade com o backend via /health (timeout 4s)
@@ -147,39 +289,112 @@ func (c *Client) Hello(ctx context.Context) error {
 // Register registra este proxy no backend (timeout 4s).
 // Envia o proxy_id obtido em registros anteriores para permitir re-registro
 // do mesmo proxy sem conflito com proxies online.
+// Com credenciais salvas a requisição é assinada; sem elas o token de registro
+// é trocado por um segredo próprio do proxy.
 func (c *Client) Register(ctx context.Context) error {
 	req := RegisterRequest{
 		Hostname: c.cfg.Hostname,
 		ConfigID: c.cfg.ConfigID,
 		ProxyID:  c.proxyID,
+		Labels:   c.cfg.Labels,
+	}
+
+	hasCreds := c.credentials() != nil
+	if !hasCreds {
+		req.EnrollmentToken = c.cfg.EnrollToken
 	}
 
 	var resp RegisterResponse
 	err := c.doRequestWith(ctx, c.ctrlClient, "POST", "/sync/register", req, &resp)
 	if err != nil {
+		// Credenciais revogadas: descarta para registrar de novo com o token
+		if hasCreds && IsHTTPStatus(err, http.StatusUnauthorized) {
+			log.Println("Credenciais rejeitadas pelo backend, descartando (será necessário um token de registro)")
+			c.setCredentials(nil)
+		}
 		return err
 	}
 
 	c.proxyID = resp.ProxyID
+	if resp.ProxySecret != "" {
+		c.setCredentials(&Credentials{ProxyID: resp.ProxyID, Secret: resp.ProxySecret})
+		log.Println("Credenciais de sync recebidas e salvas")
+	}
+
 	log.Printf("Registrado com sucesso (proxy_id: %s)", resp.ProxyID)
 	return nil
 }
 
-// GetConfig busca configuração do backend
-func (c *Client) GetConfig(ctx context.Context, currentHash string) (*ConfigResponse, error) {
+// GetConfig busca configuração do backend.
+// Com wait > 0 usa long-poll: o backend segura a requisição até a config
+// mudar ou o tempo acabar.
+func (c *Client) GetConfig(ctx context.Context, currentHash string, wait time.Duration) (*ConfigResponse, error) {
 	params := url.Values{}
 	params.Add("hostname", c.cfg.Hostname)
 	params.Add("hash", currentHash)
 
+	// Confirma os comandos recebidos no sync anterior; até lá o backend os reenvia
+	received := c.receivedCommands()
+	if len(received) > 0 {
+		params.Add("received", strings.Join(received, ","))
+	}
+
+	hc := c.httpClient
+	if wait > 0 {
+		params.Add("wait", strconv.Itoa(int(wait.Seconds())))
+		hc = c.pollClient
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, wait+30*time.Second)
+		defer cancel()
+	}
+
 	var resp ConfigResponse
-	err := c.doRequest(ctx, "GET", "/sync?"+params.Encode(), nil, &resp)
+	err := c.doRequestWith(ctx, hc, "GET", "/sync?"+params.Encode(), nil, &resp)
 	if err != nil {
 		return nil, err
 	}
+	c.commandsReceived(received, resp.Commands)
+
+	// Rotação de credenciais: passa a assinar com o novo segredo
+	if resp.RotateSecret != "" {
+		if creds := c.credentials(); creds != nil {
+			c.setCredentials(&Credentials{ProxyID: creds.ProxyID, Secret: resp.RotateSecret})
+			log.Println("Segredo de sync rotacionado")
+		}
+	}
 
 	return &resp, nil
 }
 
+// receivedCommands IDs dos comandos a confirmar no próximo sync
+func (c *Client) receivedCommands() []string {
+	c.receivedMu.Lock()
+	defer c.receivedMu.Unlock()
+	return append([]string(nil), c.received...)
+}
+
+// commandsReceived descarta os IDs confirmados nesta requisição e guarda os
+// dos comandos que acabaram de chegar. Se a requisição falhar, nada muda e os
+// IDs são enviados de novo.
+func (c *Client) commandsReceived(confirmed []string, commands []Command) {
+	c.receivedMu.Lock()
+	defer c.receivedMu.Unlock()
+
+	pending := c.received[:0]
+	for _, id := range c.received {
+		if !slices.Contains(confirmed, id) {
+			pending = append(pending, id)
+		}
+	}
+	for _, cmd := range commands {
+		if !slices.Contains(pending, cmd.ID) {
+			pending = append(pending, cmd.ID)
+		}
+	}
+	c.received = pending
+}
+
 // Ack confirma aplicação da configuração
 func (c *Client) Ack(ctx context.Context, hash, status, message string) error {
 	req := AckRequest{
@@ -192,12 +407,13 @@ func (c *Client) Ack(ctx context.Context, hash, status, message string) error {
 	return c.doRequest(ctx, "POST", "/sync/ack", req, nil)
 }
 
-// SendStats envia métricas do proxy
-func (c *Client) SendStats(ctx context.Context, metrics Metrics) error {
+// SendStats envia métricas do proxy e o estado dos parents
+func (c *Client) SendStats(ctx context.Context, metrics Metrics, parents []ParentStatus) error {
 	req := StatsRequest{
 		Hostname:  c.cfg.Hostname,
 		Timestamp: time.Now(),
 		Metrics:   metrics,
+		Parents:   parents,
 	}
 
 	return c.doRequest(ctx, "POST", "/sync/stats", req, nil)
@@ -213,6 +429,56 @@ func (c *Client) SendLogs(ctx context.Context, lines []LogLine) error {
 	return c.doRequest(ctx, "POST", "/sync/logs", req, nil)
 }
 
+// SendDrift reporta o resultado da verificação de drift
+func (c *Client) SendDrift(ctx context.Context, req DriftRequest) error {
+	req.Hostname = c.cfg.Hostname
+	return c.doRequest(ctx, "POST", "/sync/drift", req, nil)
+}
+
+// SendAccessLogs envia um lote do access log
+func (c *Client) SendAccessLogs(ctx context.Context, entries []AccessLogEntry) (*AccessLogsResult, error) {
+	req := AccessLogsRequest{
+		Hostname: c.cfg.Hostname,
+		Entries:  entries,
+	}
+
+	var resp AccessLogsResult
+	if err := c.doRequest(ctx, "POST", "/sync/access-logs", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendCommandResult reporta o resultado de um comando remoto
+func (c *Client) SendCommandResult(ctx context.Context, res CommandResult) error {
+	res.Hostname = c.cfg.Hostname
+	return c.doRequest(ctx, "POST", "/sync/command-result", res, nil)
+}
+
+// DiagnosticsUpload bundle de diagnóstico guardado pelo backend
+type DiagnosticsUpload struct {
+	ID        string    `json:"id"`
+	SizeBytes int       `json:"size_bytes"`
+	SHA256    string    `json:"sha256"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UploadDiagnostics envia um bundle de diagnóstico (tar.gz) gerado pelo comando commandID
+func (c *Client) UploadDiagnostics(ctx context.Context, commandID string, bundle []byte) (*DiagnosticsUpload, error) {
+	params := url.Values{}
+	params.Add("hostname", c.cfg.Hostname)
+	if commandID != "" {
+		params.Add("command_id", commandID)
+	}
+
+	var resp DiagnosticsUpload
+	err := c.doRawRequest(ctx, c.httpClient, "POST", "/sync/diagnostics?"+params.Encode(), "application/gzip", bundle, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 // ========== HTTP Helpers ==========
 
 // doRequest executa uma requisição HTTP simples com o client padrão (30s timeout)
@@ -222,24 +488,30 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body interf
 
 // doRequestWith executa uma requisição HTTP com um http.Client específico
 func (c *Client) doRequestWith(ctx context.Context, hc *http.Client, method, path string, body interface{}, response interface{}) error {
-	fullURL := c.cfg.BackendURL + "/api/v1" + path
-
-	var bodyReader io.Reader
+	var jsonBody []byte
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
+		var err error
+		jsonBody, err = json.Marshal(body)
 		if err != nil {
 			return fmt.Errorf("erro ao serializar body: %w", err)
 		}
-		bodyReader = bytes.NewReader(jsonBody)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, fullURL, bodyReader)
+	return c.doRawRequest(ctx, hc, method, path, "application/json", jsonBody, response)
+}
+
+// doRawRequest envia body já serializado com o Content-Type informado
+func (c *Client) doRawRequest(ctx context.Context, hc *http.Client, method, path, contentType string, body []byte, response interface{}) error {
+	fullURL := c.cfg.BackendURL + "/api/v1" + path
+
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("erro ao criar request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("User-Agent", "proxy-helper/1.0")
+	c.sign(req, body)
 
 	resp, err := hc.Do(req)
 	if err != nil {
@@ -265,6 +537,56 @@ func (c *Client) doRequestWith(ctx context.Context, hc *http.Client, method, pat
 	return nil
 }
 
+// sign assina a requisição com o segredo do proxy:
+// HMAC-SHA256("<timestamp>.<nonce>.<METHOD>.<request URI>.<body>").
+// O backend aceita cada nonce uma única vez, o que impede reenviar a requisição.
+func (c *Client) sign(req *http.Request, body []byte) {
+	creds := c.credentials()
+	if creds == nil {
+		return
+	}
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	n := hex.EncodeToString(nonce)
+
+	mac := hmac.New(sha256.New, []byte(creds.Secret))
+	mac.Write([]byte(ts + "." + n + "." + req.Method + "." + req.URL.RequestURI() + "."))
+	mac.Write(body)
+
+	req.Header.Set("X-Proxy-ID", creds.ProxyID)
+	req.Header.Set("X-Sync-Timestamp", ts)
+	req.Header.Set("X-Sync-Nonce", n)
+	req.Header.Set("X-Sync-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+}
+
+func (c *Client) credentials() *Credentials {
+	c.credsMu.Lock()
+	defer c.credsMu.Unlock()
+	return c.creds
+}
+
+// setCredentials troca as credenciais em memória e no disco (nil apaga)
+func (c *Client) setCredentials(creds *Credentials) {
+	c.credsMu.Lock()
+	c.creds = creds
+	c.credsMu.Unlock()
+
+	if c.cfg.CredentialsFile == "" {
+		return
+	}
+	var err error
+	if creds == nil {
+		err = RemoveCredentials(c.cfg.CredentialsFile)
+	} else {
+		err = SaveCredentials(c.cfg.CredentialsFile, creds)
+	}
+	if err != nil {
+		log.Printf("WARN: Erro ao salvar credenciais: %v", err)
+	}
+}
+
 // HTTPError representa um erro HTTP com status code acessível
 type HTTPError struct {
 	StatusCode int
@@ -0,0 +1,56 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Credentials identidade do proxy no backend, obtida ao trocar o token de registro
+type Credentials struct {
+	ProxyID string `json:"proxy_id"`
+	Secret  string `json:"secret"`
+}
+
+// LoadCredentials lê as credenciais salvas. Retorna nil se o arquivo não existe.
+func LoadCredentials(path string) (*Credentials, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler credenciais: %w", err)
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("erro ao deserializar credenciais: %w", err)
+	}
+	if creds.ProxyID == "" || creds.Secret == "" {
+		return nil, nil
+	}
+	return &creds, nil
+}
+
+// SaveCredentials grava as credenciais com permissão restrita ao dono
+func SaveCredentials(path string, creds *Credentials) error {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar credenciais: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("erro ao gravar credenciais: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// RemoveCredentials apaga as credenciais salvas (revogadas no backend)
+func RemoveCredentials(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package sync
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/ats-proxy/proxy-helper/internal/config"
+)
+
+// reloadableTransport delega para um http.Transport que pode ser trocado em
+// tempo de execução (recarga de certificados via SIGHUP)
+type reloadableTransport struct {
+	current atomic.Pointer[http.Transport]
+}
+
+func (t *reloadableTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.current.Load().RoundTrip(req)
+}
+
+// swap instala um novo transport e fecha as conexões ociosas do anterior
+func (t *reloadableTransport) swap(tr *http.Transport) {
+	if old := t.current.Swap(tr); old != nil {
+		old.CloseIdleConnections()
+	}
+}
+
+// newTransport cria o transport com a CA fixada e o certificado de cliente
+// configurados. Sem nenhum arquivo, usa o transport padrão do Go.
+func newTransport(cfg *config.Config) (*http.Transport, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.CAFile == "" && cfg.CertFile == "" {
+		return tr, nil
+	}
+
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("nenhum certificado encontrado em %s", cfg.CAFile)
+		}
+		tlsCfg.RootCAs = pool // apenas a CA fixada, sem as CAs do sistema
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao carregar certificado de cliente: %w", err)
+		}
+		if cert.Leaf == nil && len(cert.Certificate) > 0 {
+			cert.Leaf, _ = x509.ParseCertificate(cert.Certificate[0])
+		}
+		if cert.Leaf != nil && time.Now().After(cert.Leaf.NotAfter) {
+			return nil, fmt.Errorf("certificado de cliente expirado em %s", cert.Leaf.NotAfter.Format(time.RFC3339))
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	tr.TLSClientConfig = tlsCfg
+	return tr, nil
+}
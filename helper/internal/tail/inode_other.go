@@ -0,0 +1,10 @@
+//go:build !unix
+
+package tail
+
+import "os"
+
+// Sem inode a rotação só é percebida quando o arquivo encolhe
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
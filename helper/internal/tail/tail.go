@@ -0,0 +1,275 @@
+// Package tail lê incrementalmente as linhas novas de arquivos de log do ATS,
+// acompanhando rotação e truncamento e lembrando a posição entre reinícios.
+package tail
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// MaxLineSize é o tamanho máximo de uma linha; o excedente é descartado
+const MaxLineSize = 64 << 10
+
+// State é a posição persistida de um Tailer
+type State struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// Tailer entrega as linhas completas escritas num arquivo desde a última
+// leitura. Na rotação (o caminho passa a apontar para outro inode) termina de
+// ler o arquivo antigo antes de abrir o novo desde o início; se o arquivo
+// encolher (copytruncate) volta ao início.
+//
+// A posição só avança no disco com Commit, então linhas lidas e não
+// confirmadas são relidas após um reinício. Sem estado salvo, a primeira
+// leitura começa no fim do arquivo.
+type Tailer struct {
+	path      string
+	stateFile string
+
+	file   *os.File
+	inode  uint64
+	offset int64 // posição após a última linha completa entregue
+
+	committed State
+	loaded    bool
+}
+
+// New cria um Tailer para path. stateFile vazio desativa a persistência.
+func New(path, stateFile string) *Tailer {
+	return &Tailer{path: path, stateFile: stateFile}
+}
+
+// Path retorna o caminho acompanhado
+func (t *Tailer) Path() string {
+	return t.path
+}
+
+// Read retorna até max linhas novas (max <= 0 = sem limite). Um arquivo que
+// ainda não existe não é erro: retorna nenhuma linha.
+func (t *Tailer) Read(max int) ([]string, error) {
+	lines, _, err := t.ReadLimit(max, 0)
+	return lines, err
+}
+
+// ReadLimit é Read limitado também a maxBytes bytes de linhas (maxBytes <= 0 =
+// sem limite). A primeira linha sempre é entregue, mesmo maior que o limite.
+// limited indica que a leitura parou num dos limites e pode haver mais linhas.
+func (t *Tailer) ReadLimit(max, maxBytes int) (lines []string, limited bool, err error) {
+	if t.file == nil {
+		if err := t.open(); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, false, nil
+			}
+			return nil, false, err
+		}
+	}
+
+	budget := int64(maxBytes)
+	for {
+		n, full, err := t.readLines(&lines, max, &budget)
+		if err != nil {
+			return lines, false, err
+		}
+		if full || (max > 0 && len(lines) >= max) {
+			return lines, true, nil
+		}
+		if n > 0 {
+			continue
+		}
+
+		// Chegou ao fim do arquivo aberto: confere rotação e truncamento
+		info, err := os.Stat(t.path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// Rotacionado e o novo ainda não foi criado
+				return lines, false, nil
+			}
+			return lines, false, fmt.Errorf("erro ao verificar %s: %w", t.path, err)
+		}
+		if inode := fileInode(info); inode != 0 && inode != t.inode {
+			t.file.Close()
+			t.file = nil
+			if err := t.openAt(0); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return lines, false, nil
+				}
+				return lines, false, err
+			}
+			continue
+		}
+		if info.Size() < t.offset {
+			t.offset = 0
+			continue
+		}
+		return lines, false, nil
+	}
+}
+
+// readLines lê linhas completas a partir do offset atual e retorna quantas
+// foram lidas. Uma linha sem '\n' no fim fica para a próxima leitura. Com
+// *budget > 0, para antes da linha que o excederia (full=true); a linha fica
+// para a próxima leitura.
+func (t *Tailer) readLines(lines *[]string, max int, budget *int64) (n int, full bool, err error) {
+	if _, err := t.file.Seek(t.offset, io.SeekStart); err != nil {
+		return 0, false, fmt.Errorf("erro ao posicionar em %s: %w", t.path, err)
+	}
+
+	limited := *budget > 0
+	r := bufio.NewReaderSize(t.file, 64<<10)
+	for max <= 0 || len(*lines) < max {
+		line, size, err := readLine(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, false, nil
+			}
+			return n, false, fmt.Errorf("erro ao ler %s: %w", t.path, err)
+		}
+		if limited {
+			if int64(len(line)) > *budget && len(*lines) > 0 {
+				return n, true, nil
+			}
+			*budget -= int64(len(line))
+		}
+		t.offset += size
+		*lines = append(*lines, line)
+		n++
+		if limited && *budget <= 0 {
+			return n, true, nil
+		}
+	}
+	return n, false, nil
+}
+
+// readLine lê uma linha completa, limitada a MaxLineSize, e retorna também o
+// número de bytes consumidos. Retorna io.EOF se não há linha completa.
+func readLine(r *bufio.Reader) (string, int64, error) {
+	var buf []byte
+	var size int64
+	for {
+		chunk, err := r.ReadSlice('\n')
+		size += int64(len(chunk))
+		if len(buf) < MaxLineSize {
+			room := MaxLineSize - len(buf)
+			if len(chunk) < room {
+				room = len(chunk)
+			}
+			buf = append(buf, chunk[:room]...)
+		}
+		if err == nil {
+			break
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		return "", 0, err
+	}
+
+	n := len(buf)
+	for n > 0 && (buf[n-1] == '\n' || buf[n-1] == '\r') {
+		n--
+	}
+	return string(buf[:n]), size, nil
+}
+
+// open abre o arquivo na posição salva, se o estado ainda vale para ele
+func (t *Tailer) open() error {
+	if !t.loaded {
+		t.committed = t.loadState()
+		t.loaded = true
+	}
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case t.committed.Inode != 0 && t.committed.Inode == fileInode(info) && t.committed.Offset <= info.Size():
+		return t.openAt(t.committed.Offset)
+	case t.committed.Inode != 0:
+		// O arquivo rotacionou enquanto o helper estava parado
+		return t.openAt(0)
+	default:
+		return t.openAt(info.Size())
+	}
+}
+
+func (t *Tailer) openAt(offset int64) error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("erro ao verificar %s: %w", t.path, err)
+	}
+	if offset > info.Size() {
+		offset = 0
+	}
+	t.file = f
+	t.inode = fileInode(info)
+	t.offset = offset
+	return nil
+}
+
+// Commit grava a posição das linhas já entregues
+func (t *Tailer) Commit() error {
+	if t.file == nil {
+		return nil
+	}
+	state := State{Inode: t.inode, Offset: t.offset}
+	if state == t.committed || t.stateFile == "" {
+		t.committed = state
+		return nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar estado: %w", err)
+	}
+	tmp := t.stateFile + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(t.stateFile), 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório de estado: %w", err)
+	}
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("erro ao gravar estado: %w", err)
+	}
+	if err := os.Rename(tmp, t.stateFile); err != nil {
+		return fmt.Errorf("erro ao gravar estado: %w", err)
+	}
+	t.committed = state
+	return nil
+}
+
+func (t *Tailer) loadState() State {
+	var state State
+	if t.stateFile == "" {
+		return state
+	}
+	data, err := os.ReadFile(t.stateFile)
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return State{}
+	}
+	return state
+}
+
+// Close fecha o arquivo aberto
+func (t *Tailer) Close() error {
+	if t.file == nil {
+		return nil
+	}
+	err := t.file.Close()
+	t.file = nil
+	return err
+}
@@ -6,7 +6,25 @@ export PATH="/opt/bin:${PATH}"
 BACKEND_URL="${BACKEND_URL:-http://backend:8080}"
 CONFIG_ID="${CONFIG_ID:-default}"
 HOSTNAME="${PROXY_HOSTNAME:-$(hostname)}"
+# LABELS (ex: site=poa,env=prod) são usados pelos grupos do backend para escolher a config
+LABELS="${LABELS:-}"
 SYNC_INTERVAL="${SYNC_INTERVAL:-30s}"
+LONG_POLL="${LONG_POLL:-25s}"
+DRIFT_INTERVAL="${DRIFT_INTERVAL:-60s}"
+# ACCESS_LOG vazio desativa o envio do access log ao backend
+ACCESS_LOG="${ACCESS_LOG-/opt/var/log/trafficserver/access_json.log}"
+ACCESS_LOG_FORMAT="${ACCESS_LOG_FORMAT:-json}"
+ACCESS_LOG_INTERVAL="${ACCESS_LOG_INTERVAL:-10s}"
+# ENROLL_TOKEN (token de registro) é lido pelo helper direto do ambiente
+
+# DRIFT_REMEDIATE=true reaplica o bundle quando alguém edita os arquivos no container
+DRIFT_ARGS=()
+[ "$DRIFT_REMEDIATE" = "true" ] && DRIFT_ARGS+=(--drift-remediate)
+
+# mTLS opcional com o backend (arquivos PEM montados no container)
+TLS_ARGS=()
+[ -n "$HELPER_CA_FILE" ] && TLS_ARGS+=(--ca-file="$HELPER_CA_FILE")
+[ -n "$HELPER_CERT_FILE" ] && TLS_ARGS+=(--cert-file="$HELPER_CERT_FILE" --key-file="$HELPER_KEY_FILE")
 
 echo "=== ATS Proxy Full Container ==="
 echo "Hostname: $HOSTNAME"
@@ -42,6 +60,14 @@ exec /usr/local/bin/helper \
     --backend-url="$BACKEND_URL" \
     --config-id="$CONFIG_ID" \
     --hostname="$HOSTNAME" \
+    --labels="$LABELS" \
     --sync-interval="$SYNC_INTERVAL" \
+    --long-poll="$LONG_POLL" \
+    --drift-interval="$DRIFT_INTERVAL" \
+    --access-log="$ACCESS_LOG" \
+    --access-log-format="$ACCESS_LOG_FORMAT" \
+    --access-log-interval="$ACCESS_LOG_INTERVAL" \
     --config-dir="/opt/etc/trafficserver" \
-    --log-level="info"
+    --log-level="info" \
+    "${DRIFT_ARGS[@]}" \
+    "${TLS_ARGS[@]}"